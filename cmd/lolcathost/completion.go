@@ -0,0 +1,368 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lukaszraczylo/lolcathost/internal/client"
+)
+
+// completionCommands lists the subcommands offered by shell completion. Keep
+// it in sync with the subcommand switch in main.
+var completionCommands = []struct {
+	name string
+	desc string
+}{
+	{"init", "Guided first-time setup"},
+	{"list", "List all entries"},
+	{"show", "Show a single entry"},
+	{"on", "Enable entries"},
+	{"off", "Disable entries"},
+	{"add", "Add an entry"},
+	{"add-file", "Add one entry per line of a file"},
+	{"delete", "Delete an entry"},
+	{"preset", "Apply a preset"},
+	{"group", "Enable, disable or re-point every host in a group"},
+	{"profile", "List or switch config profiles"},
+	{"groups", "Manage groups"},
+	{"presets", "Manage presets"},
+	{"status", "Show daemon status"},
+	{"metrics", "Print daemon metrics"},
+	{"check", "Check a domain resolves to its managed IP"},
+	{"verify", "Check hosts file against config"},
+	{"explain", "Diagnose why an entry isn't resolving"},
+	{"sync", "Rewrite hosts file from config"},
+	{"flush", "Flush the DNS cache"},
+	{"preview", "Print the managed section sync would write"},
+	{"import", "Add existing /etc/hosts entries to the config"},
+	{"import-config", "Merge hosts and presets from a config file"},
+	{"export", "Print the whole config"},
+	{"backup", "Back up the hosts file"},
+	{"reset", "Restore the default config"},
+	{"completion", "Print a shell completion script"},
+}
+
+// completeCommand is the hidden subcommand the completion scripts call to
+// list aliases, groups or presets.
+const completeCommand = "__complete"
+
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost completion bash|zsh|fish")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print(zshCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported shell %q (use bash, zsh or fish)\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// runComplete prints the names of the given kind (aliases, groups or
+// presets), one per line. The scripts pass on a --socket from the command line
+// being completed. It prints nothing when the daemon is unavailable so
+// completion falls back to subcommands only.
+func runComplete(args []string) {
+	fs := flag.NewFlagSet(completeCommand, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&socketPath, "socket", socketPath, "")
+	if fs.Parse(args) != nil || fs.NArg() != 1 {
+		return
+	}
+	args = fs.Args()
+
+	c := client.New(socketPath)
+	if err := c.Connect(); err != nil {
+		return
+	}
+	defer c.Close()
+
+	names, err := completionNames(c, args[0])
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
+
+func completionNames(c *client.Client, kind string) ([]string, error) {
+	switch kind {
+	case "aliases":
+		entries, err := c.List()
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Alias)
+		}
+		return names, nil
+	case "groups":
+		return c.ListGroups()
+	case "presets":
+		presets, err := c.ListPresets()
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(presets))
+		for _, p := range presets {
+			names = append(names, p.Name)
+		}
+		return names, nil
+	case "profiles":
+		data, err := c.ListProfiles()
+		if err != nil {
+			return nil, err
+		}
+		return data.Profiles, nil
+	default:
+		return nil, fmt.Errorf("unknown completion kind: %s", kind)
+	}
+}
+
+func bashCompletion() string {
+	names := make([]string, 0, len(completionCommands))
+	for _, cmd := range completionCommands {
+		names = append(names, cmd.name)
+	}
+
+	return fmt.Sprintf(`# bash completion for lolcathost
+# Load with: source <(lolcathost completion bash)
+
+_lolcathost_names() {
+    lolcathost %[2]s ${socket:+--socket "$socket"} "$1" 2>/dev/null
+}
+
+_lolcathost() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    # Skip the global flags before the subcommand, keeping --socket so names
+    # come from the same daemon the command will talk to
+    local socket="" i=1
+    while [[ $i -lt $COMP_CWORD && "${COMP_WORDS[i]}" == -* ]]; do
+        case "${COMP_WORDS[i]}" in
+            --socket|-socket|--config|-config|--profile|-profile|--http|-http)
+                local opt="${COMP_WORDS[i]}"
+                ((i++))
+                # bash splits --socket=path into --socket, = and path
+                [[ "${COMP_WORDS[i]}" == "=" ]] && ((i++))
+                [[ "$opt" == *socket ]] && socket="${COMP_WORDS[i]}"
+                ;;
+            --socket=*|-socket=*)
+                socket="${COMP_WORDS[i]#*=}"
+                ;;
+        esac
+        ((i++))
+    done
+    local words=("${COMP_WORDS[0]}" "${COMP_WORDS[@]:i}")
+    local cword=$((COMP_CWORD - i + 1))
+
+    if [[ $cword -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "%[1]s" -- "$cur"))
+        return
+    fi
+
+    case "${words[1]}" in
+        show|on|off|delete|explain)
+            COMPREPLY=($(compgen -W "$(_lolcathost_names aliases)" -- "$cur"))
+            ;;
+        preset)
+            if [[ $cword -eq 2 ]]; then
+                COMPREPLY=($(compgen -W "run $(_lolcathost_names presets)" -- "$cur"))
+            elif [[ $cword -eq 3 && "${words[2]}" == "run" ]]; then
+                COMPREPLY=($(compgen -W "$(_lolcathost_names presets)" -- "$cur"))
+            fi
+            ;;
+        group)
+            if [[ $cword -eq 2 ]]; then
+                COMPREPLY=($(compgen -W "on off set-ip" -- "$cur"))
+            elif [[ $cword -eq 3 ]]; then
+                COMPREPLY=($(compgen -W "$(_lolcathost_names groups)" -- "$cur"))
+            fi
+            ;;
+        profile)
+            if [[ $cword -eq 2 ]]; then
+                COMPREPLY=($(compgen -W "list use" -- "$cur"))
+            elif [[ "${words[2]}" == "use" ]]; then
+                COMPREPLY=($(compgen -W "$(_lolcathost_names profiles)" -- "$cur"))
+            fi
+            ;;
+        groups|presets)
+            if [[ $cword -eq 2 ]]; then
+                COMPREPLY=($(compgen -W "reorder" -- "$cur"))
+            else
+                COMPREPLY=($(compgen -W "$(_lolcathost_names "${words[1]}")" -- "$cur"))
+            fi
+            ;;
+        add|add-file|import)
+            if [[ "$prev" == "--group" ]]; then
+                COMPREPLY=($(compgen -W "$(_lolcathost_names groups)" -- "$cur"))
+            fi
+            ;;
+        backup)
+            if [[ $cword -eq 2 ]]; then
+                COMPREPLY=($(compgen -W "create" -- "$cur"))
+            fi
+            ;;
+        completion)
+            COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+            ;;
+    esac
+}
+
+complete -F _lolcathost lolcathost
+`, strings.Join(names, " "), completeCommand)
+}
+
+func zshCompletion() string {
+	var commands strings.Builder
+	for _, cmd := range completionCommands {
+		fmt.Fprintf(&commands, "        '%s:%s'\n", cmd.name, cmd.desc)
+	}
+
+	return fmt.Sprintf(`#compdef lolcathost
+# zsh completion for lolcathost
+# Load with: source <(lolcathost completion zsh)
+
+_lolcathost_names() {
+    local -a opts
+    [[ -n $socket ]] && opts=(--socket $socket)
+    compadd -- ${(f)"$(lolcathost %[2]s $opts $1 2>/dev/null)"}
+}
+
+_lolcathost() {
+    local -a commands
+    commands=(
+%[1]s    )
+
+    # Skip the global flags before the subcommand, keeping --socket so names
+    # come from the same daemon the command will talk to
+    local socket i=2
+    while (( i < CURRENT )) && [[ $words[i] == -* ]]; do
+        case $words[i] in
+            --socket|-socket)
+                socket=${(Q)words[i+1]}
+                (( i++ ))
+                ;;
+            --socket=*|-socket=*)
+                socket=${(Q)words[i]#*=}
+                ;;
+            --config|-config|--profile|-profile|--http|-http)
+                (( i++ ))
+                ;;
+        esac
+        (( i++ ))
+    done
+    words=($words[1] $words[i,-1])
+    (( CURRENT -= i - 2 ))
+
+    if (( CURRENT == 2 )); then
+        _describe 'command' commands
+        return
+    fi
+
+    case $words[2] in
+        show|on|off|delete|explain)
+            _lolcathost_names aliases
+            ;;
+        preset)
+            if (( CURRENT == 3 )); then
+                compadd run
+                _lolcathost_names presets
+            elif (( CURRENT == 4 )) && [[ $words[3] == run ]]; then
+                _lolcathost_names presets
+            fi
+            ;;
+        group)
+            if (( CURRENT == 3 )); then
+                compadd on off set-ip
+            elif (( CURRENT == 4 )); then
+                _lolcathost_names groups
+            fi
+            ;;
+        profile)
+            if (( CURRENT == 3 )); then
+                compadd list use
+            elif [[ $words[3] == use ]]; then
+                _lolcathost_names profiles
+            fi
+            ;;
+        groups|presets)
+            if (( CURRENT == 3 )); then
+                compadd reorder
+            else
+                _lolcathost_names $words[2]
+            fi
+            ;;
+        add|add-file|import)
+            if [[ $words[CURRENT-1] == --group ]]; then
+                _lolcathost_names groups
+            fi
+            ;;
+        backup)
+            if (( CURRENT == 3 )); then
+                compadd create
+            fi
+            ;;
+        completion)
+            compadd bash zsh fish
+            ;;
+    esac
+}
+
+compdef _lolcathost lolcathost
+`, commands.String(), completeCommand)
+}
+
+func fishCompletion() string {
+	var sb strings.Builder
+	sb.WriteString("# fish completion for lolcathost\n")
+	sb.WriteString("# Load with: lolcathost completion fish | source\n\n")
+	sb.WriteString("complete -c lolcathost -f\n\n")
+	fmt.Fprintf(&sb, `# Names come from the daemon given by --socket on the command line, if any
+function __lolcathost_names
+    set -l tokens (commandline -opc)
+    set -l opts
+    for i in (seq 2 (count $tokens))
+        switch $tokens[$i]
+            case --socket -socket
+                set -q tokens[(math $i + 1)]; and set opts --socket $tokens[(math $i + 1)]
+            case '--socket=*' '-socket=*'
+                set opts --socket (string replace -r '^-+socket=' '' -- $tokens[$i])
+        end
+    end
+    lolcathost %s $opts $argv 2>/dev/null
+end
+
+`, completeCommand)
+	for _, cmd := range completionCommands {
+		fmt.Fprintf(&sb, "complete -c lolcathost -n __fish_use_subcommand -a %s -d '%s'\n", cmd.name, cmd.desc)
+	}
+
+	names := func(kind string) string {
+		return fmt.Sprintf("(__lolcathost_names %s)", kind)
+	}
+	fmt.Fprintf(&sb, "complete -c lolcathost -n '__fish_seen_subcommand_from show on off delete explain' -a '%s'\n", names("aliases"))
+	fmt.Fprintf(&sb, "complete -c lolcathost -n '__fish_seen_subcommand_from preset' -a 'run %s'\n", names("presets"))
+	fmt.Fprintf(&sb, "complete -c lolcathost -n '__fish_seen_subcommand_from group' -a 'on off set-ip %s'\n", names("groups"))
+	fmt.Fprintf(&sb, "complete -c lolcathost -n '__fish_seen_subcommand_from profile' -a 'list use %s'\n", names("profiles"))
+	fmt.Fprintf(&sb, "complete -c lolcathost -n '__fish_seen_subcommand_from groups' -a 'reorder %s'\n", names("groups"))
+	fmt.Fprintf(&sb, "complete -c lolcathost -n '__fish_seen_subcommand_from presets' -a 'reorder %s'\n", names("presets"))
+	fmt.Fprintf(&sb, "complete -c lolcathost -n '__fish_seen_subcommand_from add add-file import' -l group -x -a '%s'\n", names("groups"))
+	sb.WriteString("complete -c lolcathost -n '__fish_seen_subcommand_from backup' -a 'create'\n")
+	sb.WriteString("complete -c lolcathost -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'\n")
+
+	return sb.String()
+}
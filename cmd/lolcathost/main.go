@@ -2,16 +2,33 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
 	"github.com/lukaszraczylo/lolcathost/internal/client"
 	"github.com/lukaszraczylo/lolcathost/internal/config"
 	"github.com/lukaszraczylo/lolcathost/internal/daemon"
+	"github.com/lukaszraczylo/lolcathost/internal/gateway"
 	"github.com/lukaszraczylo/lolcathost/internal/installer"
 	"github.com/lukaszraczylo/lolcathost/internal/protocol"
 	"github.com/lukaszraczylo/lolcathost/internal/tui"
@@ -22,12 +39,27 @@ import (
 // version is set at compile time via ldflags
 var appVersion = "dev"
 
+// useColor controls whether ANSI color codes are emitted on stdout.
+var useColor = true
+
+// jsonOutput makes read commands print JSON instead of text, set by --json.
+var jsonOutput bool
+
+// socketPath is the daemon's Unix socket, overridden by --socket.
+var socketPath = protocol.SocketPath
+
 const (
 	githubOwner = "lukaszraczylo"
 	githubRepo  = "lolcathost"
 )
 
 func main() {
+	// Completion scripts run this on every Tab press; keep it quick and quiet
+	if len(os.Args) > 1 && os.Args[1] == completeCommand {
+		runComplete(os.Args[2:])
+		return
+	}
+
 	telemetry.Send("lolcathost", appVersion)
 
 	// Flags
@@ -37,27 +69,91 @@ func main() {
 	versionFlag := flag.Bool("version", false, "Show version")
 	updateFlag := flag.Bool("update", false, "Check for updates")
 	configPath := flag.String("config", config.DefaultConfigPath(), "Path to config file")
+	httpAddr := flag.String("http", "", "Serve a REST API relaying to the daemon on this address, e.g. :8099 (127.0.0.1 unless a host is given)")
+	profileFlag := flag.String("profile", "", "Switch the daemon to this profile before running the command")
+	plainFlag := flag.Bool("plain", false, "Disable colored output (automatic when stdout is not a terminal or NO_COLOR is set)")
+	jsonFlag := flag.Bool("json", false, "Print list, show, status, profile list, check, verify, explain, preview and export output as JSON")
+	noDaemonFlag := flag.Bool("no-daemon", false, "Run sync as root in this process instead of through the daemon (recovery only, uses sudo)")
+	socketFlag := flag.String("socket", protocol.SocketPath, "Path to the daemon's Unix socket")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "lolcathost - Dynamic Host Management\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n")
 		fmt.Fprintf(os.Stderr, "  lolcathost                  Launch TUI\n")
-		fmt.Fprintf(os.Stderr, "  lolcathost list             List all entries\n")
-		fmt.Fprintf(os.Stderr, "  lolcathost on <alias>       Enable entry\n")
-		fmt.Fprintf(os.Stderr, "  lolcathost off <alias>      Disable entry\n")
-		fmt.Fprintf(os.Stderr, "  lolcathost preset <name>    Apply preset\n")
-		fmt.Fprintf(os.Stderr, "  lolcathost status           Show daemon status\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost init             Guided first-time setup, then launch TUI\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost list [--enabled|--disabled] [--group <name>] [--output wide] [--watch [--interval <d>]] [term]\n")
+		fmt.Fprintf(os.Stderr, "                              List entries, optionally those matching a search term\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost show <alias>     Show a single entry\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost on <alias>... [--ttl <d>]\n")
+		fmt.Fprintf(os.Stderr, "                              Enable entries, optionally only for a while\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost off <alias>...   Disable entries\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost add [--group <name>] [--alias <alias>] [--description <text>] [--disabled] [<domain> <ip>]\n")
+		fmt.Fprintf(os.Stderr, "                              Add entry (prompts for whatever is omitted)\n")
+		fmt.Fprintf(os.Stderr, "  sudo lolcathost add <domain> <ip> --force-blocked\n")
+		fmt.Fprintf(os.Stderr, "                              Map a blocked domain anyway (audited)\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost add-file [--ip <ip>] [--group <name>] [--disabled] <file>\n")
+		fmt.Fprintf(os.Stderr, "                              Add one entry per domain listed in a file\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost delete [--if-exists] <alias>\n")
+		fmt.Fprintf(os.Stderr, "                              Delete entry\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost preset [--strict] <name>\n")
+		fmt.Fprintf(os.Stderr, "                              Apply preset\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost preset run <name> -- <cmd>\n")
+		fmt.Fprintf(os.Stderr, "                              Apply preset, run command, restore state\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost group set-ip <group> <ip>\n")
+		fmt.Fprintf(os.Stderr, "                              Point every host in a group at a new IP\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost group on|off [--force] <group>\n")
+		fmt.Fprintf(os.Stderr, "                              Enable or disable every host in a group\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost profile [list]    List profiles (* marks the active one)\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost profile use [--create] <name>\n")
+		fmt.Fprintf(os.Stderr, "                              Switch the daemon to a profile and sync it\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost groups reorder <name>...\n")
+		fmt.Fprintf(os.Stderr, "                              Set group order (must list every group)\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost presets reorder <name>...\n")
+		fmt.Fprintf(os.Stderr, "                              Set preset order (must list every preset)\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost status [--watch [--interval <d>]]\n")
+		fmt.Fprintf(os.Stderr, "                              Show daemon status\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost metrics [--prometheus]\n")
+		fmt.Fprintf(os.Stderr, "                              Print request, error and reload counters\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost check [--dns-server <host:port>] <domain>\n")
+		fmt.Fprintf(os.Stderr, "                              Check a domain resolves to its managed IP\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost verify           Check hosts file against config (exit 1 on drift)\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost explain <alias>  Diagnose why an entry isn't resolving (exit 1 on problems)\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost sync             Rewrite hosts file from config\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost flush            Flush the DNS cache\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost preview          Print the managed section sync would write\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost import [--group <name>]\n")
+		fmt.Fprintf(os.Stderr, "                              Add existing /etc/hosts entries to the config, disabled\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost import-config [--dry-run] [--strict] [--replace] [file]\n")
+		fmt.Fprintf(os.Stderr, "                              Merge hosts and presets from a config file (or stdin)\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost export [--format yaml|json]\n")
+		fmt.Fprintf(os.Stderr, "                              Print the whole config\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost backup create [--label <text>]\n")
+		fmt.Fprintf(os.Stderr, "                              Back up the hosts file now (rotated out like any other backup)\n")
+		fmt.Fprintf(os.Stderr, "  sudo lolcathost reset --yes\n")
+		fmt.Fprintf(os.Stderr, "                              Back up, then restore the default config\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost completion bash|zsh|fish\n")
+		fmt.Fprintf(os.Stderr, "                              Print a shell completion script\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Installation:\n")
 		fmt.Fprintf(os.Stderr, "  sudo lolcathost --install   Install daemon\n")
 		fmt.Fprintf(os.Stderr, "  sudo lolcathost --uninstall Uninstall daemon\n")
 		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Recovery:\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost --no-daemon sync Rewrite hosts file as root without the daemon\n")
+		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "HTTP API:\n")
+		fmt.Fprintf(os.Stderr, "  lolcathost --http :8099     Serve a local REST API (token in ~/.config/lolcathost/http-token)\n")
+		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
 
 	flag.Parse()
 
+	useColor = colorEnabled(*plainFlag)
+	jsonOutput = *jsonFlag
+	socketPath = *socketFlag
+
 	// Version
 	if *versionFlag {
 		fmt.Printf("lolcathost version %s\n", appVersion)
@@ -87,8 +183,36 @@ func main() {
 		return
 	}
 
+	// HTTP gateway mode
+	if *httpAddr != "" {
+		runHTTP(*httpAddr)
+		return
+	}
+
+	if *noDaemonFlag {
+		if *profileFlag != "" {
+			fmt.Fprintln(os.Stderr, "Error: --profile can't be combined with --no-daemon")
+			os.Exit(1)
+		}
+		// The daemon reads the system config, not the user's default
+		path := config.SystemConfigPath
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "config" {
+				path = *configPath
+			}
+		})
+		runNoDaemon(flag.Args(), path)
+		return
+	}
+
+	// Profile selection applies to whatever runs next, TUI included
+	if *profileFlag != "" {
+		switchProfile(*profileFlag, false)
+	}
+
 	// Parse subcommand
 	args := flag.Args()
+
 	if len(args) == 0 {
 		// No subcommand - launch TUI
 		runTUI()
@@ -97,28 +221,102 @@ func main() {
 
 	// Handle subcommands
 	switch args[0] {
+	case "init":
+		runInit()
 	case "list":
-		runList()
+		runList(args[1:])
+	case "show":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: lolcathost show <alias>")
+			os.Exit(1)
+		}
+		runShow(args[1])
 	case "on":
 		if len(args) < 2 {
-			fmt.Fprintln(os.Stderr, "Usage: lolcathost on <alias>")
+			fmt.Fprintln(os.Stderr, "Usage: lolcathost on <alias> [alias...] [--ttl <duration>]")
 			os.Exit(1)
 		}
-		runOn(args[1])
+		runOn(args[1:])
 	case "off":
 		if len(args) < 2 {
-			fmt.Fprintln(os.Stderr, "Usage: lolcathost off <alias>")
+			fmt.Fprintln(os.Stderr, "Usage: lolcathost off <alias> [alias...]")
 			os.Exit(1)
 		}
-		runOff(args[1])
+		runOff(args[1:])
+	case "add":
+		runAdd(args[1:])
+	case "add-file":
+		runAddFile(args[1:])
+	case "delete":
+		runDelete(args[1:])
 	case "preset":
 		if len(args) < 2 {
-			fmt.Fprintln(os.Stderr, "Usage: lolcathost preset <name>")
+			fmt.Fprintln(os.Stderr, "Usage: lolcathost preset [--strict] <name>")
+			os.Exit(1)
+		}
+		if args[1] == "run" {
+			if len(args) < 5 || args[3] != "--" {
+				fmt.Fprintln(os.Stderr, "Usage: lolcathost preset run <name> -- <command> [args...]")
+				os.Exit(1)
+			}
+			os.Exit(runPresetCommand(args[2], args[4:]))
+		}
+		runPreset(args[1:])
+	case "profile":
+		runProfile(args[1:])
+	case "group":
+		if len(args) >= 2 && (args[1] == "on" || args[1] == "off") {
+			runGroupSet(args[2:], args[1] == "on")
+			return
+		}
+		if len(args) != 4 || args[1] != "set-ip" {
+			fmt.Fprintln(os.Stderr, "Usage: lolcathost group set-ip <group> <ip>")
+			fmt.Fprintln(os.Stderr, "       lolcathost group on|off [--force] <group>")
+			os.Exit(1)
+		}
+		runGroupSetIP(args[2], args[3])
+	case "groups", "presets":
+		if len(args) < 3 || args[1] != "reorder" {
+			fmt.Fprintf(os.Stderr, "Usage: lolcathost %s reorder <name> [name...]\n", args[0])
 			os.Exit(1)
 		}
-		runPreset(args[1])
+		runReorder(args[0], args[2:])
 	case "status":
-		runStatus()
+		runStatus(args[1:])
+	case "metrics":
+		runMetrics(args[1:])
+	case "check":
+		runCheck(args[1:])
+	case "verify":
+		runVerify(args[1:])
+	case "explain":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "Usage: lolcathost explain <alias>")
+			os.Exit(1)
+		}
+		runExplain(args[1])
+	case "sync":
+		runSync()
+	case "flush":
+		runFlush()
+	case "preview":
+		runPreview()
+	case "import":
+		runImport(args[1:])
+	case "import-config":
+		runImportConfig(args[1:])
+	case "export":
+		runExport(args[1:])
+	case "backup":
+		if len(args) < 2 || args[1] != "create" {
+			fmt.Fprintln(os.Stderr, "Usage: lolcathost backup create [--label <text>]")
+			os.Exit(1)
+		}
+		runBackupCreate(args[2:])
+	case "reset":
+		runReset(args[1:])
+	case "completion":
+		runCompletion(args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", args[0])
 		flag.Usage()
@@ -127,7 +325,7 @@ func main() {
 }
 
 func runInstall() {
-	inst, err := installer.New()
+	inst, err := installer.New(socketPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -140,7 +338,7 @@ func runInstall() {
 }
 
 func runUninstall() {
-	inst, err := installer.New()
+	inst, err := installer.New(socketPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -154,7 +352,7 @@ func runUninstall() {
 
 func runDaemon(configPath string) {
 	daemon.Version = appVersion
-	d, err := daemon.New(configPath)
+	d, err := daemon.New(configPath, socketPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create daemon: %v\n", err)
 		os.Exit(1)
@@ -166,145 +364,1785 @@ func runDaemon(configPath string) {
 	}
 }
 
+// runHTTP serves the REST gateway until interrupted. It runs as the invoking
+// user, so the daemon applies the usual lolcathost group check to every call.
+func runHTTP(addr string) {
+	listenAddr, err := gateway.ListenAddr(addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	tokenPath := filepath.Join(config.DefaultConfigDir(), "http-token")
+	token, err := gateway.LoadOrCreateToken(tokenPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	gw := gateway.New(func() (gateway.Conn, error) {
+		c := client.New(socketPath)
+		if err := c.Connect(); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}, token)
+
+	srv := &http.Server{
+		Addr:              listenAddr,
+		Handler:           gw,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving HTTP API on http://%s (token: %s)\n", listenAddr, tokenPath)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func runTUI() {
 	// Check installation
-	if err := installer.CheckInstallation(); err != nil {
+	if err := installer.CheckInstallation(socketPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		fmt.Fprintln(os.Stderr, "\nTo install, run: sudo lolcathost --install")
 		os.Exit(1)
 	}
 
-	if err := tui.RunWithVersion(protocol.SocketPath, appVersion, githubOwner, githubRepo); err != nil {
+	if err := tui.RunWithVersion(socketPath, appVersion, githubOwner, githubRepo); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func runList() {
+// runInit walks a new user through installation, checks the daemon answers
+// and then launches the TUI.
+func runInit() {
+	in := bufio.NewReader(os.Stdin)
+
+	fmt.Println("[1/3] Checking installation...")
+	err := installer.CheckInstallation(socketPath)
+	if errors.Is(err, installer.ErrNotInstalled) || errors.Is(err, installer.ErrNotInGroup) {
+		fmt.Printf("      %v\n", err)
+		if !isTerminal(os.Stdin) {
+			fmt.Fprintln(os.Stderr, "Error: not a terminal; run 'sudo lolcathost --install' manually")
+			os.Exit(1)
+		}
+		answer, perr := prompt(in, os.Stdout, "      Run 'sudo lolcathost --install' now? [Y/n]: ")
+		if perr != nil || (answer != "" && !strings.HasPrefix(strings.ToLower(answer), "y")) {
+			fmt.Println("      Skipped. Run 'sudo lolcathost --install', then 'lolcathost init' again.")
+			os.Exit(1)
+		}
+		if err := installWithSudo(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: installation failed: %v\n", err)
+			os.Exit(1)
+		}
+		err = waitForInstallation(10 * time.Second)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n%v\n", err)
+		if errors.Is(err, installer.ErrGroupInactive) || errors.Is(err, installer.ErrNotInGroup) {
+			fmt.Fprintln(os.Stderr, "\nThen run 'lolcathost init' again to finish setup.")
+		}
+		os.Exit(1)
+	}
+	fmt.Println("      Installed.")
+
+	fmt.Println("[2/3] Contacting daemon...")
+	c := client.New(socketPath)
+	if err := c.Connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to connect to daemon: %v\n", err)
+		os.Exit(1)
+	}
+	err = c.Ping()
+	_ = c.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: daemon did not answer: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("      Daemon is running.")
+
+	fmt.Println("[3/3] Launching TUI...")
+	runTUI()
+}
+
+// installWithSudo runs the install step with elevated privileges, re-executing
+// this binary under sudo unless we're already root.
+func installWithSudo() error {
+	if os.Geteuid() == 0 {
+		inst, err := installer.New(socketPath)
+		if err != nil {
+			return err
+		}
+		return inst.Install()
+	}
+
+	if code := reexecWithSudo("--socket", socketPath, "--install"); code != 0 {
+		return fmt.Errorf("sudo lolcathost --install exited with status %d", code)
+	}
+	return nil
+}
+
+// waitForInstallation re-checks the installation until the daemon socket
+// appears or the timeout elapses, since the service may take a moment to start.
+func waitForInstallation(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := installer.CheckInstallation(socketPath)
+		if !errors.Is(err, installer.ErrNotInstalled) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func runList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	enabledOnly := fs.Bool("enabled-only", false, "Only list enabled entries")
+	disabledOnly := fs.Bool("disabled-only", false, "Only list disabled entries")
+	fs.BoolVar(enabledOnly, "enabled", false, "Shorthand for --enabled-only")
+	fs.BoolVar(disabledOnly, "disabled", false, "Shorthand for --disabled-only")
+	group := fs.String("group", "", "Only list entries in this group")
+	watch := fs.Bool("watch", false, "Redraw the list until interrupted")
+	interval := fs.Duration("interval", 2*time.Second, "Refresh interval for --watch")
+	output := fs.String("output", "", "Output format: empty for status/domain/IP, or wide")
+	_ = fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost list [flags] [term]")
+		os.Exit(1)
+	}
+	filter := entryFilter{term: fs.Arg(0), group: *group}
+
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --interval must be positive")
+		os.Exit(1)
+	}
+
+	var wide bool
+	switch *output {
+	case "":
+	case "wide":
+		wide = true
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown --output %q (use wide)\n", *output)
+		os.Exit(1)
+	}
+
+	state := protocol.ListStateAll
+	switch {
+	case *enabledOnly && *disabledOnly:
+		fmt.Fprintln(os.Stderr, "Error: --enabled and --disabled are mutually exclusive")
+		os.Exit(1)
+	case *enabledOnly:
+		state = protocol.ListStateEnabled
+	case *disabledOnly:
+		state = protocol.ListStateDisabled
+	}
+
+	if *watch && jsonOutput {
+		fmt.Fprintln(os.Stderr, "Error: --json can't be combined with --watch")
+		os.Exit(1)
+	}
+
 	c := connectClient()
 	defer c.Close()
 
-	entries, err := c.List()
+	if *watch {
+		watchList(c, state, filter, *interval, wide)
+		return
+	}
+
+	entries, err := c.ListState(state)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	entries = filter.apply(entries)
+
+	if jsonOutput {
+		if entries == nil {
+			entries = []protocol.HostEntry{}
+		}
+		printJSON(entries)
+		return
+	}
+
+	filter.print(os.Stdout, entries, wide)
+}
+
+// entryFilter narrows a listing to entries matching a search term, as the
+// TUI's search does, and to a single group.
+type entryFilter struct {
+	term  string
+	group string
+}
+
+func (f entryFilter) apply(entries []protocol.HostEntry) []protocol.HostEntry {
+	if f.term == "" && f.group == "" {
+		return entries
+	}
+	var filtered []protocol.HostEntry
+	for _, e := range entries {
+		if (f.group == "" || e.Group == f.group) && e.Matches(f.term) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// print writes entries like printEntries, saying no entries matched rather
+// than none are configured when a filter emptied the list.
+func (f entryFilter) print(out io.Writer, entries []protocol.HostEntry, wide bool) {
+	if len(entries) == 0 && (f.term != "" || f.group != "") {
+		fmt.Fprintln(out, "No matching entries.")
+		return
+	}
+	printEntries(out, entries, wide)
+}
+
+// watchList redraws the host table every interval until interrupted.
+func watchList(c *client.Client, state string, filter entryFilter, interval time.Duration, wide bool) {
+	watch(c, "list", interval, func(out io.Writer) error {
+		entries, err := c.ListState(state)
+		if err != nil {
+			return err
+		}
+		filter.print(out, filter.apply(entries), wide)
+		return nil
+	})
+}
+
+// watch redraws what draw writes every interval until interrupted, keeping
+// one connection open across refreshes. Errors are shown in place of the
+// output so a restarting daemon doesn't end the watch.
+func watch(c *client.Client, command string, interval time.Duration, draw func(io.Writer) error) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Render before clearing so a slow daemon doesn't leave a blank screen
+		var buf bytes.Buffer
+		err := draw(&buf)
+
+		if isTerminal(os.Stdout) {
+			fmt.Print("\033[H\033[2J")
+		}
+		fmt.Printf("Every %s: lolcathost %s    %s\n\n", interval, command, time.Now().Format("15:04:05"))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			// Reconnect for the next round in case the daemon restarted
+			_ = c.Close()
+			_ = c.Connect()
+		} else {
+			_, _ = buf.WriteTo(os.Stdout)
+		}
+
+		select {
+		case <-sigCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
 
+// printEntries writes entries as a table, or a note when there are none.
+// The default table is kept narrow; wide adds alias, group, sync state and
+// metadata columns.
+func printEntries(out io.Writer, entries []protocol.HostEntry, wide bool) {
 	if len(entries) == 0 {
-		fmt.Println("No entries configured.")
+		fmt.Fprintln(out, "No entries configured.")
 		return
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "STATUS\tDOMAIN\tIP\tALIAS\tGROUP")
-	fmt.Fprintln(w, "------\t------\t--\t-----\t-----")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if wide {
+		fmt.Fprintln(w, "STATUS\tDOMAIN\tIP\tALIAS\tGROUP\tSYNCED\tMETADATA")
+		fmt.Fprintln(w, "------\t------\t--\t-----\t-----\t------\t--------")
+	} else {
+		fmt.Fprintln(w, "STATUS\tDOMAIN\tIP")
+		fmt.Fprintln(w, "------\t------\t--")
+	}
 
 	for _, e := range entries {
 		status := "○"
 		if e.Enabled {
 			status = "●"
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status, e.Domain, e.IP, e.Alias, e.Group)
+		if !wide {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", status, e.Domain, e.FormatIPs())
+			continue
+		}
+		synced := "yes"
+		if !e.Synced {
+			synced = "no"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", status, e.Domain, e.FormatIPs(), e.Alias, e.Group, synced, protocol.FormatMetadata(e.Metadata))
 	}
 
 	_ = w.Flush()
 }
 
-func runOn(alias string) {
+// runShow prints every field of a single entry, one per line.
+func runShow(alias string) {
 	c := connectClient()
 	defer c.Close()
 
-	data, err := c.Enable(alias)
+	e, err := c.Get(alias)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("✓ Enabled: %s → %s\n", alias, data.Domain)
+	if jsonOutput {
+		printJSON(e)
+		return
+	}
+
+	created := "-"
+	if e.CreatedAt > 0 {
+		created = time.Unix(e.CreatedAt, 0).Format(time.RFC3339)
+	}
+	expires := "-"
+	if e.ExpiresAt > 0 {
+		expires = time.Unix(e.ExpiresAt, 0).Format(time.RFC3339)
+	}
+	metadata := protocol.FormatMetadata(e.Metadata)
+	if metadata == "" {
+		metadata = "-"
+	}
+	description := e.Description
+	if description == "" {
+		description = "-"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Alias:\t%s\n", e.Alias)
+	fmt.Fprintf(w, "Domain:\t%s\n", e.Domain)
+	fmt.Fprintf(w, "IP:\t%s\n", e.FormatIPs())
+	fmt.Fprintf(w, "Group:\t%s\n", e.Group)
+	fmt.Fprintf(w, "Enabled:\t%t\n", e.Enabled)
+	fmt.Fprintf(w, "Description:\t%s\n", description)
+	fmt.Fprintf(w, "Sticky:\t%t\n", e.Sticky)
+	fmt.Fprintf(w, "Synced:\t%t\n", e.Synced)
+	fmt.Fprintf(w, "Created:\t%s\n", created)
+	fmt.Fprintf(w, "Expires:\t%s\n", expires)
+	fmt.Fprintf(w, "Metadata:\t%s\n", metadata)
+	_ = w.Flush()
 }
 
-func runOff(alias string) {
-	c := connectClient()
-	defer c.Close()
+// runOn enables entries. With --ttl the daemon disables them again once the
+// duration has passed.
+func runOn(args []string) {
+	fs := flag.NewFlagSet("on", flag.ExitOnError)
+	ttl := fs.Duration("ttl", 0, "Disable the entries again after this long, e.g. 30m")
 
-	data, err := c.Disable(alias)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	// Accept the flag after the aliases too, as in "on api --ttl 30m".
+	aliases := parseInterspersed(fs, args)
+
+	if len(aliases) == 0 || *ttl < 0 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost on <alias> [alias...] [--ttl <duration>]")
 		os.Exit(1)
 	}
 
-	fmt.Printf("✓ Disabled: %s → %s\n", alias, data.Domain)
+	var expiresAt time.Time
+	if *ttl > 0 {
+		expiresAt = time.Now().Add(*ttl)
+	}
+	runSet(aliases, true, expiresAt)
+}
+
+// parseInterspersed parses args with fs, allowing flags before, between and
+// after the positional arguments, which it returns in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for rest := args; len(rest) > 0; {
+		_ = fs.Parse(rest)
+		rest = fs.Args()
+		if len(rest) > 0 {
+			positional = append(positional, rest[0])
+			rest = rest[1:]
+		}
+	}
+	return positional
+}
+
+func runOff(aliases []string) {
+	runSet(aliases, false, time.Time{})
 }
 
-func runPreset(name string) {
+// runSet enables or disables each alias in turn, reporting every result and
+// exiting non-zero if any of them failed. A non-zero expiresAt has the daemon
+// disable enabled aliases again at that time.
+func runSet(aliases []string, enabled bool, expiresAt time.Time) {
 	c := connectClient()
 	defer c.Close()
 
-	if err := c.ApplyPreset(name); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	verb := "Disabled"
+	if enabled {
+		verb = "Enabled"
 	}
 
-	fmt.Printf("✓ Applied preset: %s\n", name)
+	failed := false
+	for _, alias := range aliases {
+		var data *protocol.SetData
+		var err error
+		if expiresAt.IsZero() {
+			data, err = c.Set(alias, enabled, false)
+		} else {
+			data, err = c.EnableUntil(alias, expiresAt)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", alias, err)
+			failed = true
+			continue
+		}
+		if expiresAt.IsZero() {
+			fmt.Printf("✓ %s: %s → %s\n", verb, alias, data.Domain)
+		} else {
+			fmt.Printf("✓ %s: %s → %s until %s\n", verb, alias, data.Domain, expiresAt.Format("15:04:05"))
+		}
+		printWarning(c)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
 }
 
-func runStatus() {
+func runAdd(args []string) {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	group := fs.String("group", "", "Group to add the entry to (asked for when omitted)")
+	alias := fs.String("alias", "", "Alias for the entry (generated from the domain if empty)")
+	disabled := fs.Bool("disabled", false, "Add the entry disabled")
+	description := fs.String("description", "", "What the entry is for (kept in the config, not the hosts file)")
+	forceBlocked := fs.Bool("force-blocked", false, "Map the domain even if it is blocked (root only)")
+
+	// Accept flags after the domain and IP too, as in "add icloud.com 127.0.0.1 --force-blocked".
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) != 0 && len(positional) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost add [--group <name>] [--alias <alias>] [--description <text>] [--disabled] [--force-blocked] [<domain> <ip>]")
+		os.Exit(1)
+	}
+	if *forceBlocked && len(positional) == 0 {
+		// The override is deliberate, so the domain isn't prompted for
+		fmt.Fprintln(os.Stderr, "Error: --force-blocked needs the domain and IP as arguments")
+		os.Exit(1)
+	}
+
 	c := connectClient()
 	defer c.Close()
 
-	status, err := c.Status()
+	domain, ip, enabled := "", "", !*disabled
+	if len(positional) == 2 {
+		domain, ip = positional[0], positional[1]
+		if *group == "" {
+			if !isTerminal(os.Stdin) {
+				fmt.Fprintln(os.Stderr, "Error: --group is required when stdin is not a terminal")
+				os.Exit(1)
+			}
+
+			groups, err := c.ListGroups()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			*group, err = promptGroup(bufio.NewReader(os.Stdin), os.Stdout, groups)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	} else {
+		if !isTerminal(os.Stdin) {
+			fmt.Fprintln(os.Stderr, "Error: domain and IP are required when stdin is not a terminal")
+			os.Exit(1)
+		}
+
+		groups, err := c.ListGroups()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Let the prompt reject user-blocked domains up front; the daemon
+		// checks again either way
+		if status, err := c.Status(); err == nil {
+			config.SetCustomBlockedDomains(status.BlockedDomains)
+		}
+
+		in := bufio.NewReader(os.Stdin)
+		domain, ip, *group, enabled, err = promptHost(in, os.Stdout, groups, *group)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	data, err := c.AddEntry(protocol.AddPayload{
+		Domain:      domain,
+		IP:          ip,
+		Alias:       *alias,
+		Group:       *group,
+		Enabled:     enabled,
+		Description: *description,
+		Force:       *forceBlocked,
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Status: %s\n", greenIf("running", status.Running))
-	fmt.Printf("Version: %s\n", status.Version)
-	fmt.Printf("Uptime: %d seconds\n", status.Uptime)
-	fmt.Printf("Active entries: %d\n", status.ActiveCount)
-	fmt.Printf("Total requests: %d\n", status.RequestCount)
+	fmt.Printf("✓ Added: %s → %s (%s)\n", domain, ip, *group)
+	printWarning(c)
+	if data.Alias != *alias {
+		// Generated from the domain, or normalized from what was given
+		fmt.Printf("  alias: %s\n", data.Alias)
+	}
 }
 
-func connectClient() *client.Client {
-	// Check installation first
-	if err := installer.CheckInstallation(); err != nil {
+func runAddFile(args []string) {
+	fs := flag.NewFlagSet("add-file", flag.ExitOnError)
+	ip := fs.String("ip", "127.0.0.1", "IP address for every domain (comma-separate several)")
+	group := fs.String("group", "default", "Group to add the entries to")
+	disabled := fs.Bool("disabled", false, "Add the entries disabled")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost add-file [--ip <ip>] [--group <name>] [--disabled] <file>")
+		os.Exit(1)
+	}
+
+	if err := config.CheckIPs(config.SplitIPs(*ip)); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		fmt.Fprintln(os.Stderr, "\nTo install, run: sudo lolcathost --install")
 		os.Exit(1)
 	}
 
-	c := client.New(protocol.SocketPath)
-	if err := c.Connect(); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	defer f.Close()
 
-	return c
-}
+	var hosts []protocol.AddPayload
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hosts = append(hosts, protocol.AddPayload{
+			Domain:  line,
+			IP:      *ip,
+			Group:   *group,
+			Enabled: !*disabled,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", fs.Arg(0), err)
+		os.Exit(1)
+	}
 
-func greenIf(s string, condition bool) string {
-	if condition {
-		return "\033[32m" + s + "\033[0m"
+	if len(hosts) == 0 {
+		fmt.Println("No domains found.")
+		return
+	}
+
+	c := connectClient()
+	defer c.Close()
+
+	data, err := c.AddBatch(hosts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for _, d := range data.Added {
+		fmt.Printf("✓ Added: %s → %s\n", d, *ip)
+	}
+	for _, d := range data.Skipped {
+		fmt.Printf("- Skipped: %s (already configured)\n", d)
+	}
+	for _, failure := range data.Failed {
+		fmt.Fprintf(os.Stderr, "✗ %s: %s\n", failure.Domain, failure.Error)
+	}
+
+	fmt.Printf("\n%d added, %d skipped, %d failed\n", len(data.Added), len(data.Skipped), len(data.Failed))
+	printWarning(c)
+	if len(data.Failed) > 0 {
+		os.Exit(1)
 	}
-	return "\033[31mnot " + s + "\033[0m"
 }
 
-func checkForUpdates() {
-	fmt.Printf("lolcathost version %s\n", appVersion)
-	fmt.Println("Checking for updates...")
+// runImport brings the hosts file entries lolcathost doesn't manage into the
+// config. Lines that can't be imported are reported without failing the
+// rest.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	group := fs.String("group", protocol.DefaultImportGroup, "Group to add the imported entries to")
+	_ = fs.Parse(args)
 
-	checker := version.NewChecker(githubOwner, githubRepo, appVersion)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost import [--group <name>]")
+		os.Exit(1)
+	}
 
-	update := checker.CheckForUpdate(ctx)
-	if update == nil {
-		fmt.Println("You are running the latest version.")
-		return
+	c := connectClient()
+	defer c.Close()
+
+	data, err := c.Import(*group)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("\n\033[32mUpdate available: v%s\033[0m\n", update.LatestVersion)
-	fmt.Printf("Download: %s\n", update.ReleaseURL)
+	for _, d := range data.Added {
+		fmt.Printf("✓ Imported: %s\n", d)
+	}
+	for _, d := range data.Skipped {
+		fmt.Printf("- Skipped: %s (already configured)\n", d)
+	}
+	for _, failure := range data.Failed {
+		fmt.Fprintf(os.Stderr, "✗ %s: %s\n", failure.Domain, failure.Error)
+	}
+
+	fmt.Printf("\n%d imported into %s (disabled), %d skipped, %d failed\n",
+		len(data.Added), data.Group, len(data.Skipped), len(data.Failed))
+	if len(data.Added) > 0 {
+		fmt.Println("Enable an entry and remove its original line from /etc/hosts to hand it over to lolcathost.")
+	}
+	printWarning(c)
+}
+
+// promptHost asks for the fields of a new host entry, re-asking until each
+// answer is valid. A non-empty group skips the group question.
+func promptHost(in *bufio.Reader, out io.Writer, groups []string, group string) (domain, ip, groupName string, enabled bool, err error) {
+	for {
+		if domain, err = prompt(in, out, "Domain: "); err != nil {
+			return
+		}
+		if !config.ValidateDomain(domain) {
+			fmt.Fprintf(out, "  invalid domain: %q\n", domain)
+			continue
+		}
+		if config.IsBlockedDomain(domain) {
+			fmt.Fprintf(out, "  domain %s is blocked\n", domain)
+			continue
+		}
+		break
+	}
+
+	for {
+		if ip, err = prompt(in, out, "IP [127.0.0.1]: "); err != nil {
+			return
+		}
+		if ip == "" {
+			ip = "127.0.0.1"
+		}
+		if err := config.CheckIPs(config.SplitIPs(ip)); err != nil {
+			fmt.Fprintf(out, "  %v\n", err)
+			continue
+		}
+		break
+	}
+
+	groupName = group
+	if groupName == "" {
+		if groupName, err = promptGroup(in, out, groups); err != nil {
+			return
+		}
+	}
+
+	for {
+		var answer string
+		if answer, err = prompt(in, out, "Enabled [Y/n]: "); err != nil {
+			return
+		}
+		switch strings.ToLower(answer) {
+		case "", "y", "yes":
+			enabled = true
+		case "n", "no":
+			enabled = false
+		default:
+			fmt.Fprintln(out, "  please answer y or n")
+			continue
+		}
+		break
+	}
+
+	return domain, ip, groupName, enabled, nil
+}
+
+// promptGroup lists groups by number and asks for one, or for a new group
+// name. An empty answer picks the first group.
+func promptGroup(in *bufio.Reader, out io.Writer, groups []string) (string, error) {
+	fallback := "default"
+	if len(groups) > 0 {
+		fmt.Fprintln(out, "Groups:")
+		for i, g := range groups {
+			fmt.Fprintf(out, "  %d) %s\n", i+1, g)
+		}
+		fallback = groups[0]
+	}
+
+	for {
+		answer, err := prompt(in, out, fmt.Sprintf("Group (number or new name) [%s]: ", fallback))
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			return fallback, nil
+		}
+		if n, convErr := strconv.Atoi(answer); convErr == nil {
+			if n < 1 || n > len(groups) {
+				fmt.Fprintf(out, "  no group numbered %d\n", n)
+				continue
+			}
+			return groups[n-1], nil
+		}
+		if !config.ValidateName(answer) {
+			fmt.Fprintf(out, "  invalid group name: %q\n", answer)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// prompt writes label and returns the trimmed line read from in.
+func prompt(in *bufio.Reader, out io.Writer, label string) (string, error) {
+	fmt.Fprint(out, label)
+	line, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func runDelete(args []string) {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	ifExists := fs.Bool("if-exists", false, "Succeed if the alias doesn't exist")
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost delete [--if-exists] <alias>")
+		os.Exit(1)
+	}
+	alias := fs.Arg(0)
+
+	c := connectClient()
+	defer c.Close()
+
+	var err error
+	if *ifExists {
+		err = c.DeleteIfExists(alias)
+	} else {
+		err = c.Delete(alias)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Deleted: %s\n", alias)
+	printWarning(c)
+}
+
+func runPreset(args []string) {
+	fs := flag.NewFlagSet("preset", flag.ExitOnError)
+	strict := fs.Bool("strict", false, "Fail instead of skipping aliases that no longer exist")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost preset [--strict] <name>")
+		os.Exit(1)
+	}
+	name := fs.Arg(0)
+
+	c := connectClient()
+	defer c.Close()
+
+	data, err := c.ApplyPresetReport(name, *strict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Applied preset: %s\n", name)
+	printWarning(c)
+	if len(data.Skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d alias(es) that no longer exist: %s\n", len(data.Skipped), strings.Join(data.Skipped, ", "))
+	}
+}
+
+// runPresetCommand applies a preset, runs the given command and restores the
+// previous enabled state afterwards, even if the command fails.
+// It returns the exit code to use for the process.
+func runPresetCommand(name string, command []string) (code int) {
+	c := connectClient()
+	defer c.Close()
+
+	entries, err := c.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	snapshot := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		snapshot[e.Alias] = e.Enabled
+	}
+
+	if err := c.ApplyPreset(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := restoreSnapshot(snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to restore previous state: %v\n", err)
+			if code == 0 {
+				code = 1
+			}
+			return
+		}
+		fmt.Println("✓ Restored previous state")
+	}()
+
+	fmt.Printf("✓ Applied preset: %s\n", name)
+	printWarning(c)
+
+	// Keep running on Ctrl-C so the snapshot is restored; the child still
+	// receives the signal since handled signals are reset on exec.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	cmd := exec.Command(command[0], command[1:]...) // #nosec G204 - Command is explicitly provided by the invoking user
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// restoreSnapshot sets every entry back to the enabled state recorded in
+// snapshot, in one batch so the hosts file is rewritten once. It uses a new
+// connection that retries, since the daemon drops connections left idle
+// while a long command ran. Entries that failed to restore are reported.
+func restoreSnapshot(snapshot map[string]bool) error {
+	// With retries set, the first request connects
+	c := client.NewWithOptions(socketPath, client.Options{MaxRetries: 2, Backoff: 500 * time.Millisecond})
+	defer c.Close()
+
+	entries, err := c.List()
+	if err != nil {
+		return err
+	}
+
+	var reqs []*protocol.Request
+	var aliases []string
+	for _, e := range entries {
+		enabled, ok := snapshot[e.Alias]
+		if !ok || enabled == e.Enabled {
+			continue
+		}
+		req, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{Alias: e.Alias, Enabled: enabled, Force: true})
+		reqs = append(reqs, req)
+		aliases = append(aliases, e.Alias)
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	results, err := c.Batch(reqs)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, resp := range results {
+		if !resp.IsOK() {
+			fmt.Fprintf(os.Stderr, "Error: failed to restore %s: %s\n", aliases[i], resp.Message)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d entries not restored", failed, len(reqs))
+	}
+	printWarning(c)
+	return nil
+}
+
+func runStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	watchStatus := fs.Bool("watch", false, "Redraw the status until interrupted")
+	interval := fs.Duration("interval", 2*time.Second, "Refresh interval for --watch")
+	_ = fs.Parse(args)
+
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --interval must be positive")
+		os.Exit(1)
+	}
+	if *watchStatus && jsonOutput {
+		fmt.Fprintln(os.Stderr, "Error: --json can't be combined with --watch")
+		os.Exit(1)
+	}
+
+	c := connectClient()
+	defer c.Close()
+
+	if *watchStatus {
+		watch(c, "status", *interval, func(out io.Writer) error {
+			status, err := c.Status()
+			if err != nil {
+				return err
+			}
+			printStatus(out, status)
+			return nil
+		})
+		return
+	}
+
+	status, err := c.Status()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		printJSON(status)
+		return
+	}
+
+	printStatus(os.Stdout, status)
+}
+
+// printStatus writes the daemon status as label: value lines.
+func printStatus(out io.Writer, status *protocol.StatusData) {
+	fmt.Fprintf(out, "Status: %s\n", greenIf("running", status.Running))
+	fmt.Fprintf(out, "Version: %s\n", status.Version)
+	if status.Profile != "" {
+		fmt.Fprintf(out, "Profile: %s\n", status.Profile)
+	}
+	fmt.Fprintf(out, "Uptime: %d seconds\n", status.Uptime)
+	fmt.Fprintf(out, "Active entries: %d\n", status.ActiveCount)
+	fmt.Fprintf(out, "Total requests: %d\n", status.RequestCount)
+
+	if r := status.Reconcile; r != nil {
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(out, "Startup check: failed (%s)\n", r.Error)
+		case r.Synced:
+			fmt.Fprintf(out, "Startup check: %d discrepancies, resynced\n", r.Drift)
+		case r.Drift > 0 || r.MarkersMissing:
+			fmt.Fprintf(out, "Startup check: %d discrepancies, run sync to fix\n", r.Drift)
+		default:
+			fmt.Fprintf(out, "Startup check: %s\n", greenIf("clean", true))
+		}
+	}
+
+	if f := status.Flush; f != nil {
+		tools := strings.Join(f.Tools, ", ")
+		if tools == "" {
+			tools = "none found"
+		}
+		method := f.Method
+		if f.Method == "auto" {
+			method = fmt.Sprintf("auto (uses %s)", f.AutoMethod)
+			if f.AutoMethod == "auto" {
+				method = "auto (no flush tool; hosts file changes apply directly)"
+			}
+		}
+		fmt.Fprintf(out, "DNS flush: %s on %s\n", method, f.Platform)
+		fmt.Fprintf(out, "Flush tools: %s\n", tools)
+	}
+}
+
+// checkReport is the --json output of check. OK is false for a domain with no
+// enabled entry, though only a managed domain resolving elsewhere exits 1.
+type checkReport struct {
+	Domain      string              `json:"domain"`
+	Managed     *protocol.HostEntry `json:"managed,omitempty"`
+	Resolved    []string            `json:"resolved"`
+	LookupError string              `json:"lookup_error,omitempty"`
+	OK          bool                `json:"ok"`
+}
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	dnsServer := fs.String("dns-server", "", "Query this DNS server (host:port) instead of the system resolver")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost check [--dns-server <host:port>] <domain>")
+		os.Exit(1)
+	}
+	domain := fs.Arg(0)
+
+	c := connectClient()
+	defer c.Close()
+
+	entries, err := c.ListState(protocol.ListStateEnabled)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var managed *protocol.HostEntry
+	for i := range entries {
+		if entries[i].Domain == domain {
+			managed = &entries[i]
+			break
+		}
+	}
+
+	resolver := net.DefaultResolver
+	if *dnsServer != "" {
+		server := *dnsServer
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, lookupErr := resolver.LookupHost(ctx, domain)
+	ok := managed != nil && lookupErr == nil &&
+		slices.ContainsFunc(managed.Addresses(), func(ip string) bool { return resolvesTo(addrs, ip) })
+
+	if jsonOutput {
+		report := checkReport{Domain: domain, Managed: managed, Resolved: addrs, OK: ok}
+		if lookupErr != nil {
+			report.LookupError = lookupErr.Error()
+		}
+		printJSON(report)
+		if managed != nil && !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if managed != nil {
+		fmt.Printf("Managed:  %s → %s (%s)\n", domain, managed.FormatIPs(), managed.Alias)
+	} else {
+		fmt.Printf("Managed:  %s has no enabled entry\n", domain)
+	}
+	if lookupErr != nil {
+		fmt.Printf("Resolved: %s\n", colorize("31", lookupErr.Error()))
+	} else {
+		fmt.Printf("Resolved: %s\n", strings.Join(addrs, ", "))
+	}
+
+	if managed == nil {
+		return
+	}
+
+	if ok {
+		fmt.Println(colorize("32", "✓ Resolves to the managed IP"))
+		return
+	}
+
+	fmt.Println(colorize("31", "✗ Does not resolve to the managed IP"))
+	if *dnsServer != "" {
+		fmt.Println("  DNS servers don't read /etc/hosts; drop --dns-server to check local resolution")
+	} else {
+		fmt.Println("  A cached answer or another resolver may be winning; run 'lolcathost verify' and flush the DNS cache")
+	}
+	os.Exit(1)
+}
+
+// resolvesTo reports whether any of addrs is the same address as ip.
+func resolvesTo(addrs []string, ip string) bool {
+	want, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if got, err := netip.ParseAddr(a); err == nil && got == want {
+			return true
+		}
+	}
+	return false
+}
+
+func runMetrics(args []string) {
+	fs := flag.NewFlagSet("metrics", flag.ExitOnError)
+	prometheus := fs.Bool("prometheus", false, "Print metrics in Prometheus text exposition format")
+	_ = fs.Parse(args)
+
+	c := connectClient()
+	defer c.Close()
+
+	if *prometheus {
+		text, err := c.Prometheus()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(text)
+		return
+	}
+
+	m, err := c.Metrics()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		printJSON(m)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Uptime:\t%d seconds\n", m.Uptime)
+	fmt.Fprintf(w, "Requests:\t%d\n", m.Requests)
+	fmt.Fprintf(w, "Rate limited:\t%d\n", m.RateLimited)
+	fmt.Fprintf(w, "Config reloads:\t%d\n", m.ConfigReloads)
+	_ = w.Flush()
+
+	if len(m.RequestsByType) > 0 {
+		fmt.Println("\nRequests by type:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, t := range slices.Sorted(maps.Keys(m.RequestsByType)) {
+			fmt.Fprintf(w, "  %s\t%d\n", t, m.RequestsByType[t])
+		}
+		_ = w.Flush()
+	}
+	if len(m.ErrorsByCode) > 0 {
+		fmt.Println("\nErrors by code:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, code := range slices.Sorted(maps.Keys(m.ErrorsByCode)) {
+			fmt.Fprintf(w, "  %s\t%d\n", code, m.ErrorsByCode[code])
+		}
+		_ = w.Flush()
+	}
+}
+
+func runSync() {
+	c := connectClient()
+	defer c.Close()
+
+	if err := c.Sync(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("✓ Hosts file synced")
+	printWarning(c)
+}
+
+// runNoDaemon performs a privileged subcommand in this process instead of
+// through the daemon, re-running itself under sudo when not root. It exists
+// to recover from a broken daemon: the work runs as root with none of the
+// daemon's checks, so it refuses to run while the daemon is up and only sync
+// is supported.
+func runNoDaemon(args []string, configPath string) {
+	if len(args) != 1 || args[0] != "sync" {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost --no-daemon sync")
+		os.Exit(1)
+	}
+
+	if os.Geteuid() != 0 {
+		abs, err := filepath.Abs(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "Running sync as root via sudo, bypassing the daemon")
+		os.Exit(reexecWithSudo("--no-daemon", "--config", abs, "--socket", socketPath, "sync"))
+	}
+
+	if daemonResponds() {
+		fmt.Fprintln(os.Stderr, "Error: the daemon is running; use lolcathost sync instead")
+		os.Exit(1)
+	}
+
+	if err := daemon.SyncWithoutDaemon(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Hosts file synced from %s without the daemon\n", configPath)
+}
+
+// reexecWithSudo runs this binary again under sudo with args, letting sudo
+// prompt for a password, and returns its exit code.
+func reexecWithSudo(args ...string) int {
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	cmd := exec.Command("sudo", append([]string{exe}, args...)...) // #nosec G204 - exe is this binary's own path
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// daemonResponds reports whether a daemon answers on the socket.
+func daemonResponds() bool {
+	c := client.New(socketPath)
+	if err := c.Connect(); err != nil {
+		return false
+	}
+	defer c.Close()
+	return c.Ping() == nil
+}
+
+func runFlush() {
+	c := connectClient()
+	defer c.Close()
+
+	if err := c.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("✓ DNS cache flushed")
+}
+
+func runPreview() {
+	c := connectClient()
+	defer c.Close()
+
+	section, err := c.PreviewHosts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		printJSON(map[string]string{"section": section})
+		return
+	}
+	fmt.Print(section)
+}
+
+// runGroupSetIP points every host in a group at ip.
+func runGroupSetIP(group, ip string) {
+	c := connectClient()
+	defer c.Close()
+
+	updated, err := c.SetGroupIP(group, ip)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Updated %d host(s) in %s to %s\n", updated, group, ip)
+	printWarning(c)
+}
+
+// runGroupSet enables or disables a whole group in one request, so the
+// daemon syncs the hosts file once however large the group is.
+func runGroupSet(args []string, enabled bool) {
+	fs := flag.NewFlagSet("group", flag.ExitOnError)
+	force := fs.Bool("force", false, "Enable even if a domain is already mapped by another alias")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost group on|off [--force] <group>")
+		os.Exit(1)
+	}
+	group := fs.Arg(0)
+
+	c := connectClient()
+	defer c.Close()
+
+	updated, err := c.SetGroup(group, enabled, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	verb := "Disabled"
+	if enabled {
+		verb = "Enabled"
+	}
+	fmt.Printf("✓ %s %d host(s) in %s\n", verb, updated, group)
+	printWarning(c)
+}
+
+// runReset restores the default config. The daemon backs up the hosts file
+// first and only accepts the request from root.
+func runReset(args []string) {
+	fs := flag.NewFlagSet("reset", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Confirm deleting every host, group and preset")
+	_ = fs.Parse(args)
+
+	if !*yes {
+		fmt.Fprintln(os.Stderr, "Error: reset deletes every host, group and preset and restores the default config")
+		fmt.Fprintln(os.Stderr, "Rerun with --yes to confirm: sudo lolcathost reset --yes")
+		os.Exit(1)
+	}
+
+	c := connectClient()
+	defer c.Close()
+
+	data, err := c.Reset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Reset to the default config (removed %d host(s), %d group(s), %d preset(s))\n",
+		data.HostsRemoved, data.GroupsRemoved, data.PresetsRemoved)
+	fmt.Println("  The previous hosts file was backed up with the before-reset label")
+	printWarning(c)
+}
+
+// runProfile lists profiles or switches the daemon to one.
+func runProfile(args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		c := connectClient()
+		defer c.Close()
+
+		data, err := c.ListProfiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if jsonOutput {
+			printJSON(data)
+			return
+		}
+		for _, name := range data.Profiles {
+			marker := " "
+			if name == data.Active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+		return
+	}
+
+	if args[0] != "use" {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost profile [list] | profile use [--create] <name>")
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("profile use", flag.ExitOnError)
+	create := fs.Bool("create", false, "Create the profile from the default config if it doesn't exist")
+	_ = fs.Parse(args[1:])
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost profile use [--create] <name>")
+		os.Exit(1)
+	}
+	switchProfile(fs.Arg(0), *create)
+}
+
+// switchProfile makes name the daemon's active profile, exiting on failure.
+func switchProfile(name string, create bool) {
+	c := connectClient()
+	defer c.Close()
+
+	if _, err := c.SwitchProfile(name, create); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Active profile: %s\n", name)
+	printWarning(c)
+}
+
+// runReorder sets the order of groups or presets, depending on kind.
+func runReorder(kind string, names []string) {
+	c := connectClient()
+	defer c.Close()
+
+	reorder := c.ReorderGroups
+	if kind == "presets" {
+		reorder = c.ReorderPresets
+	}
+	if err := reorder(names); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Reordered %s: %s\n", kind, strings.Join(names, ", "))
+}
+
+func runImportConfig(args []string) {
+	fs := flag.NewFlagSet("import-config", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Show what would change without applying it")
+	strict := fs.Bool("strict", false, "Reject configs whose presets reference unknown aliases")
+	replace := fs.Bool("replace", false, "Replace all hosts and presets instead of merging the file into them")
+	_ = fs.Parse(args)
+
+	var content []byte
+	var err error
+	if fs.NArg() > 0 && fs.Arg(0) != "-" {
+		content, err = os.ReadFile(fs.Arg(0))
+	} else {
+		content, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to read config: %v\n", err)
+		os.Exit(1)
+	}
+
+	c := connectClient()
+	defer c.Close()
+
+	importConfig := c.MergeConfig
+	if *replace {
+		importConfig = c.ImportConfig
+	}
+	data, err := importConfig(string(content), *dryRun, *strict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, conflict := range data.Conflicts {
+		fmt.Fprintf(os.Stderr, "✗ conflict: %s\n", conflict)
+	}
+
+	changes := []struct {
+		label string
+		names []string
+	}{
+		{"+ host", data.HostsAdded},
+		{"- host", data.HostsRemoved},
+		{"~ host", data.HostsChanged},
+		{"+ group", data.GroupsAdded},
+		{"- group", data.GroupsRemoved},
+		{"+ preset", data.PresetsAdded},
+		{"- preset", data.PresetsRemoved},
+		{"~ preset", data.PresetsChanged},
+	}
+
+	count := 0
+	for _, change := range changes {
+		for _, name := range change.names {
+			fmt.Printf("%s %s\n", change.label, name)
+			count++
+		}
+	}
+
+	switch {
+	case len(data.Conflicts) > 0:
+		fmt.Printf("Dry run: %d changes, %d conflicts to resolve before importing\n", count, len(data.Conflicts))
+		os.Exit(1)
+	case count == 0:
+		fmt.Println("✓ No changes")
+	case data.Applied:
+		fmt.Printf("✓ Imported config (%d changes)\n", count)
+		printWarning(c)
+	default:
+		fmt.Printf("Dry run: %d changes not applied\n", count)
+	}
+}
+
+// runExport prints the daemon's config, settings included, for sharing or
+// for import-config.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", config.ExportYAML, "Output format: yaml or json")
+	_ = fs.Parse(args)
+
+	if jsonOutput {
+		*format = config.ExportJSON
+	}
+
+	c := connectClient()
+	defer c.Close()
+
+	content, err := c.Export(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(content)
+}
+
+func runBackupCreate(args []string) {
+	fs := flag.NewFlagSet("backup create", flag.ExitOnError)
+	label := fs.String("label", "", "Label to add to the backup file name (the backup still counts toward settings.maxBackups)")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: lolcathost backup create [--label <text>]")
+		os.Exit(1)
+	}
+
+	c := connectClient()
+	defer c.Close()
+
+	backup, err := c.CreateBackup(*label)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOutput {
+		printJSON(backup)
+		return
+	}
+	fmt.Printf("Created backup %s\n", backup.Name)
+}
+
+// verifyReport is the --json output of verify, with drift split by kind so
+// CI can gate on it.
+type verifyReport struct {
+	Missing    []protocol.VerifyIssue `json:"missing"`
+	Extra      []protocol.VerifyIssue `json:"extra"`
+	Mismatched []protocol.VerifyIssue `json:"mismatched"`
+	Other      []protocol.VerifyIssue `json:"other"`
+}
+
+func newVerifyReport(issues []protocol.VerifyIssue) verifyReport {
+	r := verifyReport{
+		Missing:    []protocol.VerifyIssue{},
+		Extra:      []protocol.VerifyIssue{},
+		Mismatched: []protocol.VerifyIssue{},
+		Other:      []protocol.VerifyIssue{},
+	}
+	for _, issue := range issues {
+		switch issue.Kind {
+		case "missing":
+			r.Missing = append(r.Missing, issue)
+		case "extra":
+			r.Extra = append(r.Extra, issue)
+		case "mismatch":
+			r.Mismatched = append(r.Mismatched, issue)
+		default:
+			r.Other = append(r.Other, issue)
+		}
+	}
+	return r
+}
+
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	c := connectClient()
+	defer c.Close()
+
+	issues, err := c.Verify()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		printJSON(newVerifyReport(issues))
+		if len(issues) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(issues) == 0 {
+		fmt.Println("✓ Hosts file matches config")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tKIND\tISSUE\tSUGGESTION")
+	fmt.Fprintln(w, "--------\t----\t-----\t----------")
+
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Severity, issue.Kind, issue.Message, issue.Suggestion)
+	}
+
+	_ = w.Flush()
+	os.Exit(1)
+}
+
+func runExplain(alias string) {
+	c := connectClient()
+	defer c.Close()
+
+	data, err := c.Explain(alias)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		printJSON(data)
+		if len(data.Issues) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	e := data.Entry
+	lastSync := "none since the daemon started"
+	if data.LastSync != nil {
+		lastSync = time.Unix(data.LastSync.Timestamp, 0).Format(time.RFC3339)
+		if data.LastSync.Error != "" {
+			lastSync += " (failed)"
+		} else if data.LastSync.FlushError != "" {
+			lastSync += " (DNS flush failed)"
+		}
+	}
+	conflicts := "-"
+	if len(data.Conflicts) > 0 {
+		conflicts = strings.Join(data.Conflicts, ", ")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Entry:\t%s → %s (%s)\n", e.Domain, e.FormatIPs(), e.Alias)
+	fmt.Fprintf(w, "Enabled:\t%t\n", e.Enabled)
+	fmt.Fprintf(w, "In hosts file:\t%t\n", data.InHostsFile)
+	fmt.Fprintf(w, "Synced:\t%t\n", e.Synced)
+	fmt.Fprintf(w, "Conflicts:\t%s\n", conflicts)
+	fmt.Fprintf(w, "Other lines:\t%d outside the managed section\n", len(data.External))
+	fmt.Fprintf(w, "Last sync:\t%s\n", lastSync)
+	_ = w.Flush()
+	fmt.Println()
+
+	if len(data.Issues) == 0 {
+		fmt.Println(colorize("32", fmt.Sprintf("✓ Nothing stops %s resolving to %s", e.Domain, e.FormatIPs())))
+		fmt.Printf("  If it still doesn't, a cached answer may be winning; run 'lolcathost check %s'\n", e.Domain)
+		return
+	}
+
+	fmt.Println(colorize("31", "✗ Diagnosis:"))
+	for _, issue := range data.Issues {
+		fmt.Printf("  [%s] %s\n", issue.Severity, issue.Message)
+		if issue.Suggestion != "" {
+			fmt.Printf("         → %s\n", issue.Suggestion)
+		}
+	}
+	os.Exit(1)
+}
+
+// printJSON writes v to stdout as indented JSON, exiting on failure.
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func connectClient() *client.Client {
+	// Check installation first
+	if err := installer.CheckInstallation(socketPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "\nTo install, run: sudo lolcathost --install")
+		os.Exit(1)
+	}
+
+	c := client.New(socketPath)
+	if err := c.Connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
+		os.Exit(1)
+	}
+	if err := c.CheckVersion(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	return c
+}
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled reports whether CLI output should carry ANSI colors. Color is
+// off with --plain, when NO_COLOR is set (https://no-color.org) or when stdout
+// isn't a terminal. The TUI honors NO_COLOR through lipgloss.
+func colorEnabled(plain bool) bool {
+	if plain || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// colorize wraps s in the given ANSI color code when color output is enabled.
+func colorize(code, s string) string {
+	if !useColor {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+// printWarning prints the warning the daemon attached to the last successful
+// response, e.g. that the hosts file was synced but the DNS flush failed.
+func printWarning(c *client.Client) {
+	if w := c.Warning(); w != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+}
+
+func greenIf(s string, condition bool) string {
+	if condition {
+		return colorize("32", s)
+	}
+	return colorize("31", "not "+s)
+}
+
+func checkForUpdates() {
+	fmt.Printf("lolcathost version %s\n", appVersion)
+	fmt.Println("Checking for updates...")
+
+	checker := version.NewChecker(githubOwner, githubRepo, appVersion)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := checker.CheckForUpdate(ctx)
+	if update == nil {
+		fmt.Println("You are running the latest version.")
+		return
+	}
+
+	fmt.Printf("\n%s\n", colorize("32", "Update available: v"+update.LatestVersion))
+	fmt.Printf("Download: %s\n", update.ReleaseURL)
+	if update.ReleaseNotes != "" {
+		fmt.Printf("\nWhat's new:\n%s\n", update.ReleaseNotes)
+	}
 	fmt.Println("\nTo update, download the latest release from the URL above")
 	fmt.Println("or use your package manager (e.g., 'brew upgrade lolcathost').")
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
+)
+
+// fakeDaemon answers on a temporary socket, which socketPath points at for
+// the rest of the test, and records every request it receives. Requests
+// handle doesn't answer get an empty OK response.
+type fakeDaemon struct {
+	mu       sync.Mutex
+	requests []protocol.Request
+}
+
+func newFakeDaemon(t *testing.T, handle func(*protocol.Request) *protocol.Response) *fakeDaemon {
+	// Use /tmp directly to stay under the Unix socket path length limit
+	tmpDir, err := os.MkdirTemp("/tmp", "lolcat")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	path := filepath.Join(tmpDir, "s.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	old := socketPath
+	socketPath = path
+	t.Cleanup(func() { socketPath = old })
+
+	d := &fakeDaemon{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn, handle)
+		}
+	}()
+	return d
+}
+
+func (d *fakeDaemon) serve(conn net.Conn, handle func(*protocol.Request) *protocol.Response) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var req protocol.Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			return
+		}
+		d.mu.Lock()
+		d.requests = append(d.requests, req)
+		d.mu.Unlock()
+
+		var resp *protocol.Response
+		if req.Type == protocol.RequestPing {
+			resp, _ = protocol.NewOKResponse(protocol.PingData{Pong: "pong", Version: protocol.Version, MinVersion: protocol.MinVersion})
+		} else if handle != nil {
+			resp = handle(&req)
+		}
+		if resp == nil {
+			resp, _ = protocol.NewOKResponse(nil)
+		}
+		data, _ := json.Marshal(resp)
+		_, _ = conn.Write(append(data, '\n'))
+	}
+}
+
+// received returns the requests of the given type seen so far.
+func (d *fakeDaemon) received(typ protocol.RequestType) []protocol.Request {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var reqs []protocol.Request
+	for _, req := range d.requests {
+		if req.Type == typ {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+func TestRunReset_AsRoot(t *testing.T) {
+	// Root is never in the lolcathost group; "sudo lolcathost reset --yes"
+	// must still get past the installation check to the daemon
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root")
+	}
+
+	d := newFakeDaemon(t, func(req *protocol.Request) *protocol.Response {
+		resp, _ := protocol.NewOKResponse(protocol.ResetData{HostsRemoved: 3, GroupsRemoved: 2})
+		return resp
+	})
+
+	runReset([]string{"--yes"})
+
+	reqs := d.received(protocol.RequestReset)
+	require.Len(t, reqs, 1)
+	var payload protocol.ResetPayload
+	require.NoError(t, reqs[0].ParsePayload(&payload))
+	assert.True(t, payload.Confirm)
+}
+
+func TestRunAdd_ForceBlockedAsRoot(t *testing.T) {
+	// The override is root only, so it must work under sudo
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root")
+	}
+
+	d := newFakeDaemon(t, func(req *protocol.Request) *protocol.Response {
+		if req.Type != protocol.RequestAdd {
+			return nil
+		}
+		resp, _ := protocol.NewOKResponse(protocol.SetData{Alias: "icloud-com"})
+		return resp
+	})
+
+	runAdd([]string{"icloud.com", "127.0.0.1", "--group", "x", "--force-blocked"})
+
+	reqs := d.received(protocol.RequestAdd)
+	require.Len(t, reqs, 1)
+	var payload protocol.AddPayload
+	require.NoError(t, reqs[0].ParsePayload(&payload))
+	assert.Equal(t, "icloud.com", payload.Domain)
+	assert.Equal(t, "x", payload.Group)
+	assert.True(t, payload.Force)
+}
@@ -3,45 +3,98 @@ package client
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/lukaszraczylo/lolcathost/internal/protocol"
 )
 
+// DefaultTimeout bounds connecting and each request round trip unless
+// Options.Timeout says otherwise.
+const DefaultTimeout = 5 * time.Second
+
+// errNotConnected is returned by requests sent before Connect.
+var errNotConnected = errors.New("not connected")
+
+// ErrIncompatibleVersion is wrapped by CheckVersion's error when the daemon
+// and this client can't talk to each other.
+var ErrIncompatibleVersion = errors.New("incompatible protocol version")
+
 // Client is a client for the lolcathost daemon.
 type Client struct {
 	socketPath string
 	conn       net.Conn
 	reader     *bufio.Reader
 	timeout    time.Duration
+	maxRetries int
+	backoff    time.Duration
+	warning    string
 	mu         sync.Mutex
 }
 
-// New creates a new client.
+// Options configures a client created with NewWithOptions.
+type Options struct {
+	// Timeout bounds connecting and each request round trip. Zero uses
+	// DefaultTimeout.
+	Timeout time.Duration
+	// MaxRetries is how many times a request is retried on a fresh
+	// connection after the current one broke, as it does when the daemon
+	// restarts. Zero disables reconnecting. A request the daemon received
+	// before the connection dropped may be sent twice.
+	MaxRetries int
+	// Backoff is the wait before the first reconnect; it doubles with each
+	// further attempt.
+	Backoff time.Duration
+}
+
+// New creates a new client that doesn't reconnect on its own.
 func New(socketPath string) *Client {
+	return NewWithOptions(socketPath, Options{})
+}
+
+// NewWithOptions creates a new client configured by opts.
+func NewWithOptions(socketPath string, opts Options) *Client {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
 	return &Client{
 		socketPath: socketPath,
-		timeout:    5 * time.Second,
+		timeout:    timeout,
+		maxRetries: max(opts.MaxRetries, 0),
+		backoff:    max(opts.Backoff, 0),
 	}
 }
 
 // Connect establishes a connection to the daemon.
 func (c *Client) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext is like Connect but gives up when ctx is done.
+func (c *Client) ConnectContext(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Close existing connection if any
-	if c.conn != nil {
-		_ = c.conn.Close()
-		c.conn = nil
-		c.reader = nil
-	}
+	return c.dial(ctx)
+}
 
-	conn, err := net.DialTimeout("unix", c.socketPath, c.timeout)
+// dial replaces the current connection, if any, with a new one. The caller
+// must hold c.mu.
+func (c *Client) dial(ctx context.Context) error {
+	c.dropConn()
+
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, "unix", c.socketPath)
 	if err != nil {
 		return fmt.Errorf("failed to connect to daemon: %w", err)
 	}
@@ -51,6 +104,15 @@ func (c *Client) Connect() error {
 	return nil
 }
 
+// dropConn closes the current connection, if any. The caller must hold c.mu.
+func (c *Client) dropConn() {
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+		c.reader = nil
+	}
+}
+
 // Close closes the connection.
 func (c *Client) Close() error {
 	c.mu.Lock()
@@ -65,17 +127,50 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// send sends a request and receives a response.
-func (c *Client) send(req *protocol.Request) (*protocol.Response, error) {
+// send sends a request and receives a response. With retries enabled, a
+// request that fails because the connection is gone is sent again on a new
+// one.
+func (c *Client) send(ctx context.Context, req *protocol.Request) (*protocol.Response, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	resp, err := c.roundTrip(ctx, req)
+	for attempt := 0; attempt < c.maxRetries && isConnError(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.backoff << attempt):
+		}
+		if err = c.dial(ctx); err == nil {
+			resp, err = c.roundTrip(ctx, req)
+		}
+	}
+	return resp, err
+}
+
+// roundTrip writes req on the current connection and reads its response.
+// The deadline is the client timeout or ctx's deadline, whichever comes
+// first, and ctx being cancelled interrupts the exchange. A failed exchange
+// drops the connection, as a late response would otherwise be read as the
+// answer to the next request. The caller must hold c.mu.
+func (c *Client) roundTrip(ctx context.Context, req *protocol.Request) (*protocol.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if c.conn == nil {
-		return nil, fmt.Errorf("not connected")
+		return nil, errNotConnected
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
 	}
+	_ = c.conn.SetDeadline(deadline)
 
-	// Set deadline
-	_ = c.conn.SetDeadline(time.Now().Add(c.timeout))
+	// Unblock the write or read below as soon as ctx is cancelled
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
 
 	// Send request
 	data, err := json.Marshal(req)
@@ -85,13 +180,15 @@ func (c *Client) send(req *protocol.Request) (*protocol.Response, error) {
 	data = append(data, '\n')
 
 	if _, err := c.conn.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		c.dropConn()
+		return nil, fmt.Errorf("failed to send request: %w", ctxErrOr(ctx, err))
 	}
 
 	// Read response
 	line, err := c.reader.ReadBytes('\n')
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		c.dropConn()
+		return nil, fmt.Errorf("failed to read response: %w", ctxErrOr(ctx, err))
 	}
 
 	var resp protocol.Response
@@ -99,26 +196,156 @@ func (c *Client) send(req *protocol.Request) (*protocol.Response, error) {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	c.warning = ""
+	if resp.IsOK() {
+		c.warning = resp.Message
+	}
+
+	// A daemon older than this client rejects newer request types without
+	// saying why, so add the likely cause
+	if resp.Code == protocol.ErrCodeInvalidRequest && resp.Version < protocol.Version &&
+		strings.HasPrefix(resp.Message, "unknown request type") {
+		resp.Message += fmt.Sprintf(" (daemon speaks protocol %d, client %d; please upgrade the daemon)", resp.Version, protocol.Version)
+	}
+
 	return &resp, nil
 }
 
+// ctxErrOr returns ctx's error if it is done, so a cancelled or expired
+// request reports why rather than the I/O error it caused, and err otherwise.
+func ctxErrOr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	// The connection deadline may fire just before ctx's own timer
+	if d, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// isConnError reports whether err means the connection is missing or was
+// dropped, or the daemon isn't accepting connections, as opposed to a
+// timeout or a malformed response.
+func isConnError(err error) bool {
+	return errors.Is(err, errNotConnected) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ENOENT)
+}
+
+// Warning returns the warning the daemon attached to the last successful
+// response, such as a DNS flush failure after the hosts file was written, or
+// "" if there was none.
+func (c *Client) Warning() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.warning
+}
+
+// Do sends a raw request and returns the daemon's response as is, error
+// responses included. It is meant for transports that relay requests, such
+// as the HTTP gateway.
+func (c *Client) Do(req *protocol.Request) (*protocol.Response, error) {
+	return c.DoContext(context.Background(), req)
+}
+
+// DoContext is like Do but honors ctx for cancellation and deadlines.
+func (c *Client) DoContext(ctx context.Context, req *protocol.Request) (*protocol.Response, error) {
+	return c.send(ctx, req)
+}
+
+// Batch sends several requests in one round trip. The daemon saves the
+// config and syncs the hosts file once after running them all, and returns
+// one response per request in order, error responses included.
+func (c *Client) Batch(reqs []*protocol.Request) ([]*protocol.Response, error) {
+	return c.BatchContext(context.Background(), reqs)
+}
+
+// BatchContext is like Batch but honors ctx for cancellation and deadlines.
+func (c *Client) BatchContext(ctx context.Context, reqs []*protocol.Request) ([]*protocol.Response, error) {
+	req, _ := protocol.NewRequest(protocol.RequestBatch, protocol.BatchPayload{Requests: reqs})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.BatchData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return data.Results, nil
+}
+
 // Ping checks if the daemon is responsive.
 func (c *Client) Ping() error {
+	return c.PingContext(context.Background())
+}
+
+// PingContext is like Ping but honors ctx for cancellation and deadlines.
+func (c *Client) PingContext(ctx context.Context) error {
+	req, _ := protocol.NewRequest(protocol.RequestPing, nil)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("ping failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// CheckVersion pings the daemon and returns an error wrapping
+// ErrIncompatibleVersion, and naming the side to upgrade, when its protocol
+// version and the client's don't overlap.
+func (c *Client) CheckVersion() error {
+	return c.CheckVersionContext(context.Background())
+}
+
+// CheckVersionContext is like CheckVersion but honors ctx for cancellation and deadlines.
+func (c *Client) CheckVersionContext(ctx context.Context) error {
 	req, _ := protocol.NewRequest(protocol.RequestPing, nil)
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
 	if !resp.IsOK() {
 		return fmt.Errorf("ping failed: %s", resp.Message)
 	}
+
+	var data protocol.PingData
+	if err := resp.ParseData(&data); err != nil {
+		return err
+	}
+
+	switch {
+	case data.Version < protocol.MinVersion:
+		return fmt.Errorf("%w: daemon speaks protocol %d, client needs at least %d; please upgrade the daemon",
+			ErrIncompatibleVersion, data.Version, protocol.MinVersion)
+	case data.MinVersion > protocol.Version:
+		return fmt.Errorf("%w: daemon needs protocol %d or newer, client speaks %d; please upgrade lolcathost",
+			ErrIncompatibleVersion, data.MinVersion, protocol.Version)
+	}
 	return nil
 }
 
 // Status returns the daemon's status.
 func (c *Client) Status() (*protocol.StatusData, error) {
+	return c.StatusContext(context.Background())
+}
+
+// StatusContext is like Status but honors ctx for cancellation and deadlines.
+func (c *Client) StatusContext(ctx context.Context) (*protocol.StatusData, error) {
 	req, _ := protocol.NewRequest(protocol.RequestStatus, nil)
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -135,8 +362,24 @@ func (c *Client) Status() (*protocol.StatusData, error) {
 
 // List returns all host entries.
 func (c *Client) List() ([]protocol.HostEntry, error) {
-	req, _ := protocol.NewRequest(protocol.RequestList, nil)
-	resp, err := c.send(req)
+	return c.ListContext(context.Background())
+}
+
+// ListContext is like List but honors ctx for cancellation and deadlines.
+func (c *Client) ListContext(ctx context.Context) ([]protocol.HostEntry, error) {
+	return c.ListStateContext(ctx, protocol.ListStateAll)
+}
+
+// ListState returns the host entries matching a state filter
+// (protocol.ListStateAll, ListStateEnabled or ListStateDisabled).
+func (c *Client) ListState(state string) ([]protocol.HostEntry, error) {
+	return c.ListStateContext(context.Background(), state)
+}
+
+// ListStateContext is like ListState but honors ctx for cancellation and deadlines.
+func (c *Client) ListStateContext(ctx context.Context, state string) ([]protocol.HostEntry, error) {
+	req, _ := protocol.NewRequest(protocol.RequestList, protocol.ListPayload{State: state})
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -151,15 +394,62 @@ func (c *Client) List() ([]protocol.HostEntry, error) {
 	return data.Entries, nil
 }
 
+// Get returns a single host entry by alias.
+func (c *Client) Get(alias string) (*protocol.HostEntry, error) {
+	return c.GetContext(context.Background(), alias)
+}
+
+// GetContext is like Get but honors ctx for cancellation and deadlines.
+func (c *Client) GetContext(ctx context.Context, alias string) (*protocol.HostEntry, error) {
+	req, _ := protocol.NewRequest(protocol.RequestGet, protocol.GetPayload{Alias: alias})
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var entry protocol.HostEntry
+	if err := resp.ParseData(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
 // Set enables or disables a host entry by alias.
 func (c *Client) Set(alias string, enabled bool, force bool) (*protocol.SetData, error) {
-	req, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{
+	return c.SetContext(context.Background(), alias, enabled, force)
+}
+
+// SetContext is like Set but honors ctx for cancellation and deadlines.
+func (c *Client) SetContext(ctx context.Context, alias string, enabled bool, force bool) (*protocol.SetData, error) {
+	return c.set(ctx, protocol.SetPayload{
 		Alias:   alias,
 		Enabled: enabled,
 		Force:   force,
 	})
+}
+
+// EnableUntil enables a host entry by alias and has the daemon disable it
+// again at expiresAt.
+func (c *Client) EnableUntil(alias string, expiresAt time.Time) (*protocol.SetData, error) {
+	return c.EnableUntilContext(context.Background(), alias, expiresAt)
+}
+
+// EnableUntilContext is like EnableUntil but honors ctx for cancellation and deadlines.
+func (c *Client) EnableUntilContext(ctx context.Context, alias string, expiresAt time.Time) (*protocol.SetData, error) {
+	return c.set(ctx, protocol.SetPayload{
+		Alias:     alias,
+		Enabled:   true,
+		ExpiresAt: expiresAt.Unix(),
+	})
+}
+
+func (c *Client) set(ctx context.Context, payload protocol.SetPayload) (*protocol.SetData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestSet, payload)
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -176,25 +466,87 @@ func (c *Client) Set(alias string, enabled bool, force bool) (*protocol.SetData,
 
 // Enable enables a host entry by alias.
 func (c *Client) Enable(alias string) (*protocol.SetData, error) {
-	return c.Set(alias, true, false)
+	return c.EnableContext(context.Background(), alias)
+}
+
+// EnableContext is like Enable but honors ctx for cancellation and deadlines.
+func (c *Client) EnableContext(ctx context.Context, alias string) (*protocol.SetData, error) {
+	return c.SetContext(ctx, alias, true, false)
 }
 
 // Disable disables a host entry by alias.
 func (c *Client) Disable(alias string) (*protocol.SetData, error) {
-	return c.Set(alias, false, false)
+	return c.DisableContext(context.Background(), alias)
+}
+
+// DisableContext is like Disable but honors ctx for cancellation and deadlines.
+func (c *Client) DisableContext(ctx context.Context, alias string) (*protocol.SetData, error) {
+	return c.SetContext(ctx, alias, false, false)
 }
 
 // Add adds a new host entry.
 func (c *Client) Add(domain, ip, alias, group string, enabled bool) (*protocol.SetData, error) {
-	req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
-		Domain:  domain,
-		IP:      ip,
-		Alias:   alias,
-		Group:   group,
-		Enabled: enabled,
+	return c.AddContext(context.Background(), domain, ip, alias, group, enabled)
+}
+
+// AddContext is like Add but honors ctx for cancellation and deadlines.
+func (c *Client) AddContext(ctx context.Context, domain, ip, alias, group string, enabled bool) (*protocol.SetData, error) {
+	return c.AddWithMetadataContext(ctx, domain, ip, alias, group, enabled, nil)
+}
+
+// AddWithMetadata adds a new host entry carrying free-form metadata.
+func (c *Client) AddWithMetadata(domain, ip, alias, group string, enabled bool, metadata map[string]string) (*protocol.SetData, error) {
+	return c.AddWithMetadataContext(context.Background(), domain, ip, alias, group, enabled, metadata)
+}
+
+// AddWithMetadataContext is like AddWithMetadata but honors ctx for cancellation and deadlines.
+func (c *Client) AddWithMetadataContext(ctx context.Context, domain, ip, alias, group string, enabled bool, metadata map[string]string) (*protocol.SetData, error) {
+	return c.AddEntryContext(ctx, protocol.AddPayload{
+		Domain:   domain,
+		IP:       ip,
+		Alias:    alias,
+		Group:    group,
+		Enabled:  enabled,
+		Metadata: metadata,
 	})
+}
 
-	resp, err := c.send(req)
+// AddEntry adds a new host entry with every field of payload, including
+// metadata and description.
+func (c *Client) AddEntry(payload protocol.AddPayload) (*protocol.SetData, error) {
+	return c.AddEntryContext(context.Background(), payload)
+}
+
+// AddEntryContext is like AddEntry but honors ctx for cancellation and deadlines.
+func (c *Client) AddEntryContext(ctx context.Context, payload protocol.AddPayload) (*protocol.SetData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestAdd, payload)
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.SetData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Update changes the fields of a host entry that payload sets, keeping the
+// rest, including whether it is enabled.
+func (c *Client) Update(payload protocol.UpdatePayload) (*protocol.SetData, error) {
+	return c.UpdateContext(context.Background(), payload)
+}
+
+// UpdateContext is like Update but honors ctx for cancellation and deadlines.
+func (c *Client) UpdateContext(ctx context.Context, payload protocol.UpdatePayload) (*protocol.SetData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestUpdate, payload)
+
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -209,13 +561,110 @@ func (c *Client) Add(domain, ip, alias, group string, enabled bool) (*protocol.S
 	return &data, nil
 }
 
+// AddBatch adds several host entries with a single sync.
+func (c *Client) AddBatch(hosts []protocol.AddPayload) (*protocol.AddBatchData, error) {
+	return c.AddBatchContext(context.Background(), hosts)
+}
+
+// AddBatchContext is like AddBatch but honors ctx for cancellation and deadlines.
+func (c *Client) AddBatchContext(ctx context.Context, hosts []protocol.AddPayload) (*protocol.AddBatchData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestAddBatch, protocol.AddBatchPayload{
+		Hosts: hosts,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("add batch failed: %s", resp.Message)
+	}
+
+	var data protocol.AddBatchData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
 // Delete removes a host entry by alias.
 func (c *Client) Delete(alias string) error {
+	return c.DeleteContext(context.Background(), alias)
+}
+
+// DeleteContext is like Delete but honors ctx for cancellation and deadlines.
+func (c *Client) DeleteContext(ctx context.Context, alias string) error {
+	req, _ := protocol.NewRequest(protocol.RequestDelete, protocol.DeletePayload{
+		Alias: alias,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+// DeleteIfExists removes a host entry by alias, succeeding if it doesn't exist.
+func (c *Client) DeleteIfExists(alias string) error {
+	return c.DeleteIfExistsContext(context.Background(), alias)
+}
+
+// DeleteIfExistsContext is like DeleteIfExists but honors ctx for cancellation and deadlines.
+func (c *Client) DeleteIfExistsContext(ctx context.Context, alias string) error {
 	req, _ := protocol.NewRequest(protocol.RequestDelete, protocol.DeletePayload{
+		Alias:    alias,
+		IfExists: true,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+// MoveHost moves a host entry to another group, creating the group if needed.
+func (c *Client) MoveHost(alias, group string) error {
+	return c.MoveHostContext(context.Background(), alias, group)
+}
+
+// MoveHostContext is like MoveHost but honors ctx for cancellation and deadlines.
+func (c *Client) MoveHostContext(ctx context.Context, alias, group string) error {
+	req, _ := protocol.NewRequest(protocol.RequestMoveHost, protocol.MoveHostPayload{
 		Alias: alias,
+		Group: group,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+// SetSticky sets whether presets may disable a host.
+func (c *Client) SetSticky(alias string, sticky bool) error {
+	return c.SetStickyContext(context.Background(), alias, sticky)
+}
+
+// SetStickyContext is like SetSticky but honors ctx for cancellation and deadlines.
+func (c *Client) SetStickyContext(ctx context.Context, alias string, sticky bool) error {
+	req, _ := protocol.NewRequest(protocol.RequestSetSticky, protocol.SetStickyPayload{
+		Alias:  alias,
+		Sticky: sticky,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -227,11 +676,16 @@ func (c *Client) Delete(alias string) error {
 
 // AddGroup adds a new group.
 func (c *Client) AddGroup(name string) error {
+	return c.AddGroupContext(context.Background(), name)
+}
+
+// AddGroupContext is like AddGroup but honors ctx for cancellation and deadlines.
+func (c *Client) AddGroupContext(ctx context.Context, name string) error {
 	req, _ := protocol.NewRequest(protocol.RequestAddGroup, protocol.GroupPayload{
 		Name: name,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -243,11 +697,16 @@ func (c *Client) AddGroup(name string) error {
 
 // DeleteGroup removes a group and all its hosts.
 func (c *Client) DeleteGroup(name string) error {
+	return c.DeleteGroupContext(context.Background(), name)
+}
+
+// DeleteGroupContext is like DeleteGroup but honors ctx for cancellation and deadlines.
+func (c *Client) DeleteGroupContext(ctx context.Context, name string) error {
 	req, _ := protocol.NewRequest(protocol.RequestDeleteGroup, protocol.GroupPayload{
 		Name: name,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -259,8 +718,13 @@ func (c *Client) DeleteGroup(name string) error {
 
 // ListGroups returns all group names.
 func (c *Client) ListGroups() ([]string, error) {
+	return c.ListGroupsContext(context.Background())
+}
+
+// ListGroupsContext is like ListGroups but honors ctx for cancellation and deadlines.
+func (c *Client) ListGroupsContext(ctx context.Context) ([]string, error) {
 	req, _ := protocol.NewRequest(protocol.RequestListGroups, nil)
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -277,8 +741,13 @@ func (c *Client) ListGroups() ([]string, error) {
 
 // Sync synchronizes the config to the hosts file.
 func (c *Client) Sync() error {
+	return c.SyncContext(context.Background())
+}
+
+// SyncContext is like Sync but honors ctx for cancellation and deadlines.
+func (c *Client) SyncContext(ctx context.Context) error {
 	req, _ := protocol.NewRequest(protocol.RequestSync, nil)
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -290,27 +759,83 @@ func (c *Client) Sync() error {
 
 // ApplyPreset applies a named preset.
 func (c *Client) ApplyPreset(name string) error {
+	return c.ApplyPresetContext(context.Background(), name)
+}
+
+// ApplyPresetContext is like ApplyPreset but honors ctx for cancellation and deadlines.
+func (c *Client) ApplyPresetContext(ctx context.Context, name string) error {
+	_, err := c.ApplyPresetReportContext(ctx, name, false)
+	return err
+}
+
+// ApplyPresetReport applies a named preset and returns what changed and
+// which referenced aliases were skipped because they no longer exist. With
+// strict set, missing aliases fail the request instead.
+func (c *Client) ApplyPresetReport(name string, strict bool) (*protocol.PresetData, error) {
+	return c.ApplyPresetReportContext(context.Background(), name, strict)
+}
+
+// ApplyPresetReportContext is like ApplyPresetReport but honors ctx for cancellation and deadlines.
+func (c *Client) ApplyPresetReportContext(ctx context.Context, name string, strict bool) (*protocol.PresetData, error) {
 	req, _ := protocol.NewRequest(protocol.RequestPreset, protocol.PresetPayload{
-		Name: name,
+		Name:   name,
+		Strict: strict,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !resp.IsOK() {
-		return fmt.Errorf("preset failed: %s", resp.Message)
+		return nil, fmt.Errorf("preset failed: %s", resp.Message)
 	}
-	return nil
+
+	var data protocol.PresetData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// PreviewPreset returns what applying a preset would change without applying it.
+func (c *Client) PreviewPreset(name string) (*protocol.PresetData, error) {
+	return c.PreviewPresetContext(context.Background(), name)
+}
+
+// PreviewPresetContext is like PreviewPreset but honors ctx for cancellation and deadlines.
+func (c *Client) PreviewPresetContext(ctx context.Context, name string) (*protocol.PresetData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestPreset, protocol.PresetPayload{
+		Name:   name,
+		DryRun: true,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("preset preview failed: %s", resp.Message)
+	}
+
+	var data protocol.PresetData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
 }
 
 // Rollback restores a backup by name.
 func (c *Client) Rollback(backupName string) error {
+	return c.RollbackContext(context.Background(), backupName)
+}
+
+// RollbackContext is like Rollback but honors ctx for cancellation and deadlines.
+func (c *Client) RollbackContext(ctx context.Context, backupName string) error {
 	req, _ := protocol.NewRequest(protocol.RequestRollback, protocol.RollbackPayload{
 		BackupName: backupName,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -320,10 +845,63 @@ func (c *Client) Rollback(backupName string) error {
 	return nil
 }
 
-// ListBackups returns available backups.
-func (c *Client) ListBackups() ([]protocol.BackupInfo, error) {
-	req, _ := protocol.NewRequest(protocol.RequestBackups, nil)
-	resp, err := c.send(req)
+// CreateBackup backs up the hosts file now. A non-empty label is added to the
+// backup's file name.
+func (c *Client) CreateBackup(label string) (*protocol.BackupInfo, error) {
+	return c.CreateBackupContext(context.Background(), label)
+}
+
+// CreateBackupContext is like CreateBackup but honors ctx for cancellation and deadlines.
+func (c *Client) CreateBackupContext(ctx context.Context, label string) (*protocol.BackupInfo, error) {
+	req, _ := protocol.NewRequest(protocol.RequestCreateBackup, protocol.CreateBackupPayload{
+		Label: label,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("backup failed: %s", resp.Message)
+	}
+
+	var data protocol.BackupInfo
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// DeleteBackup removes a backup by name.
+func (c *Client) DeleteBackup(backupName string) error {
+	return c.DeleteBackupContext(context.Background(), backupName)
+}
+
+// DeleteBackupContext is like DeleteBackup but honors ctx for cancellation and deadlines.
+func (c *Client) DeleteBackupContext(ctx context.Context, backupName string) error {
+	req, _ := protocol.NewRequest(protocol.RequestDeleteBackup, protocol.DeleteBackupPayload{
+		BackupName: backupName,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("delete backup failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// ListBackups returns available backups.
+func (c *Client) ListBackups() ([]protocol.BackupInfo, error) {
+	return c.ListBackupsContext(context.Background())
+}
+
+// ListBackupsContext is like ListBackups but honors ctx for cancellation and deadlines.
+func (c *Client) ListBackupsContext(ctx context.Context) ([]protocol.BackupInfo, error) {
+	req, _ := protocol.NewRequest(protocol.RequestBackups, nil)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -340,11 +918,16 @@ func (c *Client) ListBackups() ([]protocol.BackupInfo, error) {
 
 // GetBackupContent returns the content of a backup file.
 func (c *Client) GetBackupContent(backupName string) (string, error) {
+	return c.GetBackupContentContext(context.Background(), backupName)
+}
+
+// GetBackupContentContext is like GetBackupContent but honors ctx for cancellation and deadlines.
+func (c *Client) GetBackupContentContext(ctx context.Context, backupName string) (string, error) {
 	req, _ := protocol.NewRequest(protocol.RequestBackupContent, protocol.BackupContentPayload{
 		BackupName: backupName,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -359,14 +942,458 @@ func (c *Client) GetBackupContent(backupName string) (string, error) {
 	return data.Content, nil
 }
 
+// GetBackupDiff returns the line-level changes from a backup to the current
+// hosts file.
+func (c *Client) GetBackupDiff(backupName string) ([]protocol.DiffLine, error) {
+	return c.GetBackupDiffContext(context.Background(), backupName)
+}
+
+// GetBackupDiffContext is like GetBackupDiff but honors ctx for cancellation and deadlines.
+func (c *Client) GetBackupDiffContext(ctx context.Context, backupName string) ([]protocol.DiffLine, error) {
+	req, _ := protocol.NewRequest(protocol.RequestBackupDiff, protocol.BackupDiffPayload{
+		BackupName: backupName,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("backup diff failed: %s", resp.Message)
+	}
+
+	var data protocol.BackupDiffData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return data.Lines, nil
+}
+
+// PreviewHosts returns the managed section a sync would write from the current
+// configuration. Nothing is written.
+func (c *Client) PreviewHosts() (string, error) {
+	return c.PreviewHostsContext(context.Background())
+}
+
+// PreviewHostsContext is like PreviewHosts but honors ctx for cancellation and deadlines.
+func (c *Client) PreviewHostsContext(ctx context.Context) (string, error) {
+	req, _ := protocol.NewRequest(protocol.RequestPreviewHosts, nil)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsOK() {
+		return "", fmt.Errorf("preview failed: %s", resp.Message)
+	}
+
+	var data protocol.PreviewHostsData
+	if err := resp.ParseData(&data); err != nil {
+		return "", err
+	}
+	return data.Section, nil
+}
+
+// Verify checks the hosts file against the configuration and returns any issues.
+func (c *Client) Verify() ([]protocol.VerifyIssue, error) {
+	return c.VerifyContext(context.Background())
+}
+
+// VerifyContext is like Verify but honors ctx for cancellation and deadlines.
+func (c *Client) VerifyContext(ctx context.Context) ([]protocol.VerifyIssue, error) {
+	req, _ := protocol.NewRequest(protocol.RequestVerify, nil)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("verify failed: %s", resp.Message)
+	}
+
+	var data protocol.VerifyData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return data.Issues, nil
+}
+
+// Explain returns the daemon's diagnosis of why an entry may not resolve.
+func (c *Client) Explain(alias string) (*protocol.ExplainData, error) {
+	return c.ExplainContext(context.Background(), alias)
+}
+
+// ExplainContext is like Explain but honors ctx for cancellation and deadlines.
+func (c *Client) ExplainContext(ctx context.Context, alias string) (*protocol.ExplainData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestExplain, protocol.ExplainPayload{Alias: alias})
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.ExplainData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Prometheus returns the daemon metrics in Prometheus text exposition format.
+func (c *Client) Prometheus() (string, error) {
+	return c.PrometheusContext(context.Background())
+}
+
+// PrometheusContext is like Prometheus but honors ctx for cancellation and deadlines.
+func (c *Client) PrometheusContext(ctx context.Context) (string, error) {
+	req, _ := protocol.NewRequest(protocol.RequestPrometheus, nil)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsOK() {
+		return "", fmt.Errorf("prometheus failed: %s", resp.Message)
+	}
+
+	var data protocol.PrometheusData
+	if err := resp.ParseData(&data); err != nil {
+		return "", err
+	}
+	return data.Text, nil
+}
+
+// Metrics returns the daemon's request, error and reload counters.
+func (c *Client) Metrics() (*protocol.MetricsData, error) {
+	return c.MetricsContext(context.Background())
+}
+
+// MetricsContext is like Metrics but honors ctx for cancellation and deadlines.
+func (c *Client) MetricsContext(ctx context.Context) (*protocol.MetricsData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestMetrics, nil)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("metrics failed: %s", resp.Message)
+	}
+
+	var data protocol.MetricsData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Reload asks the daemon to re-read its config file.
+func (c *Client) Reload() error {
+	return c.ReloadContext(context.Background())
+}
+
+// ReloadContext is like Reload but honors ctx for cancellation and deadlines.
+func (c *Client) ReloadContext(ctx context.Context) error {
+	req, _ := protocol.NewRequest(protocol.RequestReload, nil)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("reload failed: %s", resp.Message)
+	}
+	return nil
+}
+
+// Reset replaces the daemon's config with the defaults after backing up the
+// hosts file. The daemon only accepts it from root.
+func (c *Client) Reset() (*protocol.ResetData, error) {
+	return c.ResetContext(context.Background())
+}
+
+// ResetContext is like Reset but honors ctx for cancellation and deadlines.
+func (c *Client) ResetContext(ctx context.Context) (*protocol.ResetData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestReset, protocol.ResetPayload{Confirm: true})
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.ResetData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// ListProfiles returns the available profiles and which one is active.
+func (c *Client) ListProfiles() (*protocol.ProfilesData, error) {
+	return c.ListProfilesContext(context.Background())
+}
+
+// ListProfilesContext is like ListProfiles but honors ctx for cancellation and deadlines.
+func (c *Client) ListProfilesContext(ctx context.Context) (*protocol.ProfilesData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestListProfiles, nil)
+	return c.profiles(ctx, req)
+}
+
+// SwitchProfile makes the named profile the daemon's active config and syncs
+// the hosts file to it. With create set, a missing profile is created from
+// the default config.
+func (c *Client) SwitchProfile(name string, create bool) (*protocol.ProfilesData, error) {
+	return c.SwitchProfileContext(context.Background(), name, create)
+}
+
+// SwitchProfileContext is like SwitchProfile but honors ctx for cancellation and deadlines.
+func (c *Client) SwitchProfileContext(ctx context.Context, name string, create bool) (*protocol.ProfilesData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestSwitchProfile, protocol.SwitchProfilePayload{
+		Name:   name,
+		Create: create,
+	})
+	return c.profiles(ctx, req)
+}
+
+func (c *Client) profiles(ctx context.Context, req *protocol.Request) (*protocol.ProfilesData, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.ProfilesData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// ImportConfig replaces the daemon's groups and presets with those in the given
+// YAML configuration. With dryRun set, it only returns what would change.
+func (c *Client) ImportConfig(content string, dryRun, strict bool) (*protocol.ImportConfigData, error) {
+	return c.ImportConfigContext(context.Background(), content, dryRun, strict)
+}
+
+// ImportConfigContext is like ImportConfig but honors ctx for cancellation and deadlines.
+func (c *Client) ImportConfigContext(ctx context.Context, content string, dryRun, strict bool) (*protocol.ImportConfigData, error) {
+	return c.importConfig(ctx, protocol.ImportConfigPayload{
+		Content: content,
+		DryRun:  dryRun,
+		Strict:  strict,
+	})
+}
+
+// MergeConfig adds the groups, hosts and presets of a YAML or JSON config to
+// the daemon's config. If any alias or preset already exists with different
+// contents, nothing is applied and the returned error lists the conflicts;
+// a dry run returns them in the data instead.
+func (c *Client) MergeConfig(content string, dryRun, strict bool) (*protocol.ImportConfigData, error) {
+	return c.MergeConfigContext(context.Background(), content, dryRun, strict)
+}
+
+// MergeConfigContext is like MergeConfig but honors ctx for cancellation and deadlines.
+func (c *Client) MergeConfigContext(ctx context.Context, content string, dryRun, strict bool) (*protocol.ImportConfigData, error) {
+	return c.importConfig(ctx, protocol.ImportConfigPayload{
+		Content: content,
+		DryRun:  dryRun,
+		Strict:  strict,
+		Merge:   true,
+	})
+}
+
+func (c *Client) importConfig(ctx context.Context, payload protocol.ImportConfigPayload) (*protocol.ImportConfigData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestImportConfig, payload)
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.ImportConfigData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
 // RenameGroup renames a group.
 func (c *Client) RenameGroup(oldName, newName string) error {
+	return c.RenameGroupContext(context.Background(), oldName, newName)
+}
+
+// RenameGroupContext is like RenameGroup but honors ctx for cancellation and deadlines.
+func (c *Client) RenameGroupContext(ctx context.Context, oldName, newName string) error {
 	req, _ := protocol.NewRequest(protocol.RequestRenameGroup, protocol.RenameGroupPayload{
 		OldName: oldName,
 		NewName: newName,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+// SetGroupIP points every host in a group at ip and returns how many hosts
+// changed.
+func (c *Client) SetGroupIP(group, ip string) (int, error) {
+	return c.SetGroupIPContext(context.Background(), group, ip)
+}
+
+// SetGroupIPContext is like SetGroupIP but honors ctx for cancellation and deadlines.
+func (c *Client) SetGroupIPContext(ctx context.Context, group, ip string) (int, error) {
+	req, _ := protocol.NewRequest(protocol.RequestSetGroupIP, protocol.SetGroupIPPayload{
+		Group: group,
+		IP:    ip,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	if !resp.IsOK() {
+		return 0, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.SetGroupIPData
+	if err := resp.ParseData(&data); err != nil {
+		return 0, err
+	}
+	return data.Updated, nil
+}
+
+// SetGroup enables or disables every host in a group and returns how many
+// hosts changed. Force skips the domain conflict check when enabling.
+func (c *Client) SetGroup(group string, enabled, force bool) (int, error) {
+	return c.SetGroupContext(context.Background(), group, enabled, force)
+}
+
+// SetGroupContext is like SetGroup but honors ctx for cancellation and deadlines.
+func (c *Client) SetGroupContext(ctx context.Context, group string, enabled, force bool) (int, error) {
+	req, _ := protocol.NewRequest(protocol.RequestSetGroup, protocol.SetGroupPayload{
+		Group:   group,
+		Enabled: enabled,
+		Force:   force,
+	})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	if !resp.IsOK() {
+		return 0, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.SetGroupData
+	if err := resp.ParseData(&data); err != nil {
+		return 0, err
+	}
+	return data.Updated, nil
+}
+
+// Export returns the daemon's whole config as YAML or JSON (format "yaml" or
+// "json"; empty means YAML).
+func (c *Client) Export(format string) (string, error) {
+	return c.ExportContext(context.Background(), format)
+}
+
+// ExportContext is like Export but honors ctx for cancellation and deadlines.
+func (c *Client) ExportContext(ctx context.Context, format string) (string, error) {
+	req, _ := protocol.NewRequest(protocol.RequestExport, protocol.ExportPayload{Format: format})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !resp.IsOK() {
+		return "", fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.ExportData
+	if err := resp.ParseData(&data); err != nil {
+		return "", err
+	}
+	return data.Content, nil
+}
+
+// Import adds the hosts file entries lolcathost doesn't manage to the config
+// as disabled hosts in group, or in protocol.DefaultImportGroup when group
+// is empty.
+func (c *Client) Import(group string) (*protocol.ImportData, error) {
+	return c.ImportContext(context.Background(), group)
+}
+
+// ImportContext is like Import but honors ctx for cancellation and deadlines.
+func (c *Client) ImportContext(ctx context.Context, group string) (*protocol.ImportData, error) {
+	req, _ := protocol.NewRequest(protocol.RequestImport, protocol.ImportPayload{Group: group})
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsOK() {
+		return nil, fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+
+	var data protocol.ImportData
+	if err := resp.ParseData(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Flush asks the daemon to flush the DNS cache without changing any entry.
+func (c *Client) Flush() error {
+	return c.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush but honors ctx for cancellation and deadlines.
+func (c *Client) FlushContext(ctx context.Context) error {
+	req, _ := protocol.NewRequest(protocol.RequestFlush, nil)
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !resp.IsOK() {
+		return fmt.Errorf("%s: %s", resp.Code, resp.Message)
+	}
+	return nil
+}
+
+// ReorderGroups sets the group order. Names must list every group exactly once.
+func (c *Client) ReorderGroups(names []string) error {
+	return c.ReorderGroupsContext(context.Background(), names)
+}
+
+// ReorderGroupsContext is like ReorderGroups but honors ctx for cancellation and deadlines.
+func (c *Client) ReorderGroupsContext(ctx context.Context, names []string) error {
+	return c.reorder(ctx, protocol.RequestReorderGroups, names)
+}
+
+// ReorderPresets sets the preset order. Names must list every preset exactly once.
+func (c *Client) ReorderPresets(names []string) error {
+	return c.ReorderPresetsContext(context.Background(), names)
+}
+
+// ReorderPresetsContext is like ReorderPresets but honors ctx for cancellation and deadlines.
+func (c *Client) ReorderPresetsContext(ctx context.Context, names []string) error {
+	return c.reorder(ctx, protocol.RequestReorderPresets, names)
+}
+
+func (c *Client) reorder(ctx context.Context, reqType protocol.RequestType, names []string) error {
+	req, _ := protocol.NewRequest(reqType, protocol.ReorderPayload{Names: names})
+
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -377,30 +1404,42 @@ func (c *Client) RenameGroup(oldName, newName string) error {
 }
 
 // AddPreset adds a new preset.
-func (c *Client) AddPreset(name string, enable, disable []string) error {
+// The returned string carries any non-fatal warnings reported by the daemon,
+// such as two enabled aliases mapping the same domain.
+func (c *Client) AddPreset(name string, enable, disable []string) (string, error) {
+	return c.AddPresetContext(context.Background(), name, enable, disable)
+}
+
+// AddPresetContext is like AddPreset but honors ctx for cancellation and deadlines.
+func (c *Client) AddPresetContext(ctx context.Context, name string, enable, disable []string) (string, error) {
 	req, _ := protocol.NewRequest(protocol.RequestAddPreset, protocol.AddPresetPayload{
 		Name:    name,
 		Enable:  enable,
 		Disable: disable,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if !resp.IsOK() {
-		return fmt.Errorf("%s: %s", resp.Code, resp.Message)
+		return "", fmt.Errorf("%s: %s", resp.Code, resp.Message)
 	}
-	return nil
+	return resp.Message, nil
 }
 
 // DeletePreset removes a preset by name.
 func (c *Client) DeletePreset(name string) error {
+	return c.DeletePresetContext(context.Background(), name)
+}
+
+// DeletePresetContext is like DeletePreset but honors ctx for cancellation and deadlines.
+func (c *Client) DeletePresetContext(ctx context.Context, name string) error {
 	req, _ := protocol.NewRequest(protocol.RequestDeletePreset, protocol.PresetPayload{
 		Name: name,
 	})
 
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -412,8 +1451,13 @@ func (c *Client) DeletePreset(name string) error {
 
 // ListPresets returns all presets.
 func (c *Client) ListPresets() ([]protocol.PresetInfo, error) {
+	return c.ListPresetsContext(context.Background())
+}
+
+// ListPresetsContext is like ListPresets but honors ctx for cancellation and deadlines.
+func (c *Client) ListPresetsContext(ctx context.Context) ([]protocol.PresetInfo, error) {
 	req, _ := protocol.NewRequest(protocol.RequestListPresets, nil)
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, err
 	}
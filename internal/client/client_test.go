@@ -2,11 +2,15 @@ package client
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -73,6 +77,10 @@ func (ms *mockServer) handleConn(conn net.Conn) {
 		} else {
 			resp, _ = protocol.NewOKResponse(nil)
 		}
+		// A nil response drops the connection, as a dying daemon would
+		if resp == nil {
+			return
+		}
 
 		data, _ := json.Marshal(resp)
 		conn.Write(append(data, '\n'))
@@ -126,6 +134,76 @@ func TestClient_Ping(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestClient_CheckVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    any
+		wantErr string
+	}{
+		{
+			name: "same version",
+			data: protocol.PingData{Pong: "ok", Version: protocol.Version, MinVersion: protocol.MinVersion},
+		},
+		{
+			name: "daemon predates versioning",
+			data: map[string]string{"pong": "ok"},
+		},
+		{
+			name:    "daemon too old",
+			data:    protocol.PingData{Pong: "ok", Version: protocol.MinVersion - 1},
+			wantErr: "please upgrade the daemon",
+		},
+		{
+			name:    "daemon too new",
+			data:    protocol.PingData{Pong: "ok", Version: protocol.Version + 2, MinVersion: protocol.Version + 1},
+			wantErr: "please upgrade lolcathost",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMockServer(t)
+			defer server.close()
+
+			server.handler = func(req *protocol.Request) *protocol.Response {
+				assert.Equal(t, protocol.Version, req.Version)
+				resp, _ := protocol.NewOKResponse(tt.data)
+				return resp
+			}
+
+			client := New(server.path)
+			require.NoError(t, client.Connect())
+			defer client.Close()
+
+			err := client.CheckVersion()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrIncompatibleVersion)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestClient_UnknownRequestTypeFromOlderDaemon(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unknown request type: "+string(req.Type))
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	_, err := client.Metrics()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown request type: metrics")
+	assert.Contains(t, err.Error(), "please upgrade the daemon")
+}
+
 func TestClient_Status(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
@@ -191,6 +269,36 @@ func TestClient_List(t *testing.T) {
 	assert.False(t, entries[1].Enabled)
 }
 
+func TestClient_ListState(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestList {
+			var payload protocol.ListPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, protocol.ListStateEnabled, payload.State)
+
+			resp, _ := protocol.NewOKResponse(protocol.ListData{
+				Entries: []protocol.HostEntry{
+					{Domain: "a.com", IP: "127.0.0.1", Alias: "a", Enabled: true, Group: "dev"},
+				},
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	entries, err := client.ListState(protocol.ListStateEnabled)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
 func TestClient_Set(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
@@ -246,15 +354,17 @@ func TestClient_Enable(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestClient_Disable(t *testing.T) {
+func TestClient_EnableUntil(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
+	expiresAt := time.Now().Add(30 * time.Minute)
 	server.handler = func(req *protocol.Request) *protocol.Response {
 		if req.Type == protocol.RequestSet {
 			var payload protocol.SetPayload
 			req.ParsePayload(&payload)
-			assert.False(t, payload.Enabled)
+			assert.True(t, payload.Enabled)
+			assert.Equal(t, expiresAt.Unix(), payload.ExpiresAt)
 
 			resp, _ := protocol.NewOKResponse(protocol.SetData{Domain: "test.com", Applied: true})
 			return resp
@@ -267,17 +377,26 @@ func TestClient_Disable(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	_, err = client.Disable("test")
-	assert.NoError(t, err)
+	data, err := client.EnableUntil("test", expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, "test.com", data.Domain)
 }
 
-func TestClient_Sync(t *testing.T) {
+func TestClient_Update(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestSync {
-			resp, _ := protocol.NewOKResponse(map[string]bool{"synced": true})
+		if req.Type == protocol.RequestUpdate {
+			var payload protocol.UpdatePayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "api", payload.Alias)
+			require.NotNil(t, payload.IP)
+			assert.Equal(t, "10.0.0.5", *payload.IP)
+			assert.Nil(t, payload.Domain)
+			assert.Nil(t, payload.Metadata)
+
+			resp, _ := protocol.NewOKResponse(protocol.SetData{Domain: "api.local", Alias: "api", Applied: true})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
@@ -288,21 +407,23 @@ func TestClient_Sync(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.Sync()
-	assert.NoError(t, err)
+	ip := "10.0.0.5"
+	data, err := client.Update(protocol.UpdatePayload{Alias: "api", IP: &ip})
+	require.NoError(t, err)
+	assert.Equal(t, "api.local", data.Domain)
 }
 
-func TestClient_ApplyPreset(t *testing.T) {
+func TestClient_Disable(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestPreset {
-			var payload protocol.PresetPayload
+		if req.Type == protocol.RequestSet {
+			var payload protocol.SetPayload
 			req.ParsePayload(&payload)
-			assert.Equal(t, "local", payload.Name)
+			assert.False(t, payload.Enabled)
 
-			resp, _ := protocol.NewOKResponse(map[string]string{"preset": "local"})
+			resp, _ := protocol.NewOKResponse(protocol.SetData{Domain: "test.com", Applied: true})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
@@ -313,21 +434,17 @@ func TestClient_ApplyPreset(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.ApplyPreset("local")
+	_, err = client.Disable("test")
 	assert.NoError(t, err)
 }
 
-func TestClient_Rollback(t *testing.T) {
+func TestClient_Sync(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestRollback {
-			var payload protocol.RollbackPayload
-			req.ParsePayload(&payload)
-			assert.Equal(t, "hosts.backup.bak", payload.BackupName)
-
-			resp, _ := protocol.NewOKResponse(map[string]string{"restored": payload.BackupName})
+		if req.Type == protocol.RequestSync {
+			resp, _ := protocol.NewOKResponse(map[string]bool{"synced": true})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
@@ -338,21 +455,21 @@ func TestClient_Rollback(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.Rollback("hosts.backup.bak")
+	err = client.Sync()
 	assert.NoError(t, err)
 }
 
-func TestClient_ListBackups(t *testing.T) {
+func TestClient_Import(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestBackups {
-			resp, _ := protocol.NewOKResponse(protocol.BackupsData{
-				Backups: []protocol.BackupInfo{
-					{Name: "hosts.20231201.bak", Timestamp: 1701432000, Size: 1024},
-					{Name: "hosts.20231130.bak", Timestamp: 1701345600, Size: 1000},
-				},
+		var payload protocol.ImportPayload
+		if req.Type == protocol.RequestImport && req.ParsePayload(&payload) == nil {
+			resp, _ := protocol.NewOKResponse(protocol.ImportData{
+				Group:  payload.Group,
+				Added:  []string{"api.local"},
+				Failed: []protocol.BatchFailure{{Domain: "bad!", Error: "invalid domain"}},
 			})
 			return resp
 		}
@@ -360,109 +477,56 @@ func TestClient_ListBackups(t *testing.T) {
 	}
 
 	client := New(server.path)
-	err := client.Connect()
-	require.NoError(t, err)
+	require.NoError(t, client.Connect())
 	defer client.Close()
 
-	backups, err := client.ListBackups()
+	data, err := client.Import("legacy")
 	require.NoError(t, err)
-
-	assert.Len(t, backups, 2)
-	assert.Equal(t, "hosts.20231201.bak", backups[0].Name)
+	assert.Equal(t, "legacy", data.Group)
+	assert.Equal(t, []string{"api.local"}, data.Added)
+	require.Len(t, data.Failed, 1)
+	assert.Equal(t, "bad!", data.Failed[0].Domain)
 }
 
-func TestClient_ErrorResponse(t *testing.T) {
+func TestClient_Flush(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
+	fail := false
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		return protocol.NewErrorResponse(protocol.ErrCodeBlockedDomain, "domain is blocked")
+		if req.Type != protocol.RequestFlush {
+			return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+		}
+		if fail {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "DNS flush failed")
+		}
+		resp, _ := protocol.NewOKResponse(map[string]string{"method": "auto"})
+		return resp
 	}
 
 	client := New(server.path)
-	err := client.Connect()
-	require.NoError(t, err)
+	require.NoError(t, client.Connect())
 	defer client.Close()
 
-	_, err = client.Set("test", true, false)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "domain is blocked")
-}
-
-func TestClient_NotConnected(t *testing.T) {
-	client := New("/nonexistent/socket.sock")
-
-	_, err := client.Status()
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "not connected")
-}
-
-// Matrix test for request types
-func TestClient_RequestTypes_Matrix(t *testing.T) {
-	types := []struct {
-		name    string
-		reqType protocol.RequestType
-		call    func(*Client) error
-	}{
-		{"ping", protocol.RequestPing, func(c *Client) error { return c.Ping() }},
-		{"status", protocol.RequestStatus, func(c *Client) error { _, err := c.Status(); return err }},
-		{"list", protocol.RequestList, func(c *Client) error { _, err := c.List(); return err }},
-		{"sync", protocol.RequestSync, func(c *Client) error { return c.Sync() }},
-		{"preset", protocol.RequestPreset, func(c *Client) error { return c.ApplyPreset("test") }},
-		{"backups", protocol.RequestBackups, func(c *Client) error { _, err := c.ListBackups(); return err }},
-	}
-
-	for _, tt := range types {
-		t.Run(tt.name, func(t *testing.T) {
-			server := newMockServer(t)
-			defer server.close()
-
-			receivedType := protocol.RequestType("")
-			server.handler = func(req *protocol.Request) *protocol.Response {
-				receivedType = req.Type
-
-				switch req.Type {
-				case protocol.RequestStatus:
-					resp, _ := protocol.NewOKResponse(protocol.StatusData{})
-					return resp
-				case protocol.RequestList:
-					resp, _ := protocol.NewOKResponse(protocol.ListData{})
-					return resp
-				case protocol.RequestBackups:
-					resp, _ := protocol.NewOKResponse(protocol.BackupsData{})
-					return resp
-				default:
-					resp, _ := protocol.NewOKResponse(nil)
-					return resp
-				}
-			}
-
-			client := New(server.path)
-			err := client.Connect()
-			require.NoError(t, err)
-			defer client.Close()
+	assert.NoError(t, client.Flush())
 
-			_ = tt.call(client)
-			assert.Equal(t, tt.reqType, receivedType)
-		})
-	}
+	fail = true
+	err := client.Flush()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), protocol.ErrCodeInternalError)
 }
 
-func TestClient_Add(t *testing.T) {
+func TestClient_ApplyPreset(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestAdd {
-			var payload protocol.AddPayload
+		if req.Type == protocol.RequestPreset {
+			var payload protocol.PresetPayload
 			req.ParsePayload(&payload)
-			assert.Equal(t, "test.local", payload.Domain)
-			assert.Equal(t, "127.0.0.1", payload.IP)
-			assert.Equal(t, "test-local", payload.Alias)
-			assert.Equal(t, "dev", payload.Group)
-			assert.True(t, payload.Enabled)
+			assert.Equal(t, "local", payload.Name)
 
-			resp, _ := protocol.NewOKResponse(protocol.SetData{Domain: payload.Domain, Applied: true})
+			resp, _ := protocol.NewOKResponse(map[string]string{"preset": "local"})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
@@ -473,48 +537,68 @@ func TestClient_Add(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	data, err := client.Add("test.local", "127.0.0.1", "test-local", "dev", true)
+	err = client.ApplyPreset("local")
 	assert.NoError(t, err)
-	assert.Equal(t, "test.local", data.Domain)
-	assert.True(t, data.Applied)
 }
 
-func TestClient_Delete(t *testing.T) {
+func TestClient_ApplyPresetReport(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestDelete {
-			var payload protocol.DeletePayload
-			req.ParsePayload(&payload)
-			assert.Equal(t, "test-alias", payload.Alias)
-
-			resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Alias})
+		if req.Type == protocol.RequestPreset {
+			resp, _ := protocol.NewOKResponse(protocol.PresetData{
+				Preset:  "local",
+				Applied: true,
+				Enable:  []string{"api-local"},
+				Skipped: []string{"gone"},
+			})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
 	}
 
 	client := New(server.path)
-	err := client.Connect()
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	data, err := client.ApplyPresetReport("local", false)
 	require.NoError(t, err)
+	assert.True(t, data.Applied)
+	assert.Equal(t, []string{"gone"}, data.Skipped)
+}
+
+func TestClient_ApplyPresetReport_Strict(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.PresetPayload
+		req.ParsePayload(&payload)
+		assert.True(t, payload.Strict)
+		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, "preset local references missing aliases: gone")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
 	defer client.Close()
 
-	err = client.Delete("test-alias")
-	assert.NoError(t, err)
+	_, err := client.ApplyPresetReport("local", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gone")
 }
 
-func TestClient_AddGroup(t *testing.T) {
+func TestClient_Rollback(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestAddGroup {
-			var payload protocol.GroupPayload
+		if req.Type == protocol.RequestRollback {
+			var payload protocol.RollbackPayload
 			req.ParsePayload(&payload)
-			assert.Equal(t, "newgroup", payload.Name)
+			assert.Equal(t, "hosts.backup.bak", payload.BackupName)
 
-			resp, _ := protocol.NewOKResponse(map[string]string{"added": payload.Name})
+			resp, _ := protocol.NewOKResponse(map[string]string{"restored": payload.BackupName})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
@@ -525,21 +609,25 @@ func TestClient_AddGroup(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.AddGroup("newgroup")
+	err = client.Rollback("hosts.backup.bak")
 	assert.NoError(t, err)
 }
 
-func TestClient_DeleteGroup(t *testing.T) {
+func TestClient_CreateBackup(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestDeleteGroup {
-			var payload protocol.GroupPayload
+		if req.Type == protocol.RequestCreateBackup {
+			var payload protocol.CreateBackupPayload
 			req.ParsePayload(&payload)
-			assert.Equal(t, "todelete", payload.Name)
+			assert.Equal(t, "before-refactor", payload.Label)
 
-			resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Name})
+			resp, _ := protocol.NewOKResponse(protocol.BackupInfo{
+				Name:  "hosts.20231201-120000.before-refactor.bak",
+				Label: payload.Label,
+				Size:  1024,
+			})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
@@ -550,22 +638,25 @@ func TestClient_DeleteGroup(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.DeleteGroup("todelete")
-	assert.NoError(t, err)
+	backup, err := client.CreateBackup("before-refactor")
+	require.NoError(t, err)
+	assert.Equal(t, "hosts.20231201-120000.before-refactor.bak", backup.Name)
+	assert.Equal(t, "before-refactor", backup.Label)
 }
 
-func TestClient_RenameGroup(t *testing.T) {
+func TestClient_DeleteBackup(t *testing.T) {
 	server := newMockServer(t)
 	defer server.close()
 
 	server.handler = func(req *protocol.Request) *protocol.Response {
-		if req.Type == protocol.RequestRenameGroup {
-			var payload protocol.RenameGroupPayload
+		if req.Type == protocol.RequestDeleteBackup {
+			var payload protocol.DeleteBackupPayload
 			req.ParsePayload(&payload)
-			assert.Equal(t, "oldname", payload.OldName)
-			assert.Equal(t, "newname", payload.NewName)
+			if payload.BackupName != "hosts.20231201-120000.bak" {
+				return protocol.NewErrorResponse(protocol.ErrCodeNotFound, "backup not found")
+			}
 
-			resp, _ := protocol.NewOKResponse(map[string]string{"renamed": payload.NewName})
+			resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.BackupName})
 			return resp
 		}
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
@@ -576,8 +667,1091 @@ func TestClient_RenameGroup(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.RenameGroup("oldname", "newname")
-	assert.NoError(t, err)
+	assert.NoError(t, client.DeleteBackup("hosts.20231201-120000.bak"))
+	assert.Error(t, client.DeleteBackup("hosts.20231130-120000.bak"))
+}
+
+func TestClient_ListBackups(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestBackups {
+			resp, _ := protocol.NewOKResponse(protocol.BackupsData{
+				Backups: []protocol.BackupInfo{
+					{Name: "hosts.20231201.bak", Timestamp: 1701432000, Size: 1024},
+					{Name: "hosts.20231130.bak", Timestamp: 1701345600, Size: 1000},
+				},
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	backups, err := client.ListBackups()
+	require.NoError(t, err)
+
+	assert.Len(t, backups, 2)
+	assert.Equal(t, "hosts.20231201.bak", backups[0].Name)
+}
+
+func TestClient_GetBackupDiff(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestBackupDiff {
+			var payload protocol.BackupDiffPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "hosts.20231201-120000.bak", payload.BackupName)
+
+			resp, _ := protocol.NewOKResponse(protocol.BackupDiffData{
+				Lines: []protocol.DiffLine{
+					{Op: protocol.DiffContext, Text: "127.0.0.1\tlocalhost"},
+					{Op: protocol.DiffAdded, Text: "127.0.0.1\tapi.local"},
+				},
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	lines, err := client.GetBackupDiff("hosts.20231201-120000.bak")
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, protocol.DiffAdded, lines[1].Op)
+}
+
+func TestClient_Verify(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestVerify {
+			resp, _ := protocol.NewOKResponse(protocol.VerifyData{
+				Issues: []protocol.VerifyIssue{
+					{Severity: protocol.SeverityHigh, Kind: "domain_conflict", Domain: "api.local"},
+				},
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	issues, err := client.Verify()
+	require.NoError(t, err)
+
+	require.Len(t, issues, 1)
+	assert.Equal(t, "api.local", issues[0].Domain)
+}
+
+func TestClient_PreviewHosts(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	section := "# BEGIN lolcathost\n127.0.0.1\tapi.local\t# lolcathost:api-local\n# END lolcathost\n"
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestPreviewHosts {
+			resp, _ := protocol.NewOKResponse(protocol.PreviewHostsData{Section: section})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	got, err := client.PreviewHosts()
+	require.NoError(t, err)
+	assert.Equal(t, section, got)
+}
+
+func TestClient_SetGroupIP(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.SetGroupIPPayload
+		if req.Type == protocol.RequestSetGroupIP && req.ParsePayload(&payload) == nil {
+			if payload.Group != "dev" {
+				return protocol.NewErrorResponse(protocol.ErrCodeNotFound, "group not found: "+payload.Group)
+			}
+			resp, _ := protocol.NewOKResponse(protocol.SetGroupIPData{Group: payload.Group, IP: payload.IP, Updated: 3})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	updated, err := client.SetGroupIP("dev", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, updated)
+
+	_, err = client.SetGroupIP("missing", "10.0.0.1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), protocol.ErrCodeNotFound)
+}
+
+func TestClient_SetGroup(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.SetGroupPayload
+		if req.Type == protocol.RequestSetGroup && req.ParsePayload(&payload) == nil {
+			if payload.Group != "dev" {
+				return protocol.NewErrorResponse(protocol.ErrCodeNotFound, "group not found: "+payload.Group)
+			}
+			if payload.Enabled && !payload.Force {
+				return protocol.NewErrorResponse(protocol.ErrCodeConflict, "domain api.local already mapped")
+			}
+			resp, _ := protocol.NewOKResponse(protocol.SetGroupData{Group: payload.Group, Enabled: payload.Enabled, Updated: 4})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	updated, err := client.SetGroup("dev", true, true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, updated)
+
+	_, err = client.SetGroup("dev", true, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), protocol.ErrCodeConflict)
+
+	_, err = client.SetGroup("missing", false, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), protocol.ErrCodeNotFound)
+}
+
+func TestClient_Batch(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.BatchPayload
+		if req.Type != protocol.RequestBatch || req.ParsePayload(&payload) != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+		}
+		var data protocol.BatchData
+		for _, item := range payload.Requests {
+			if item.Type != protocol.RequestSet {
+				data.Results = append(data.Results, protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "can't be batched"))
+				continue
+			}
+			resp, _ := protocol.NewOKResponse(protocol.SetData{Applied: true})
+			data.Results = append(data.Results, resp)
+		}
+		resp, _ := protocol.NewOKResponse(data)
+		return resp
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	set, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{Alias: "api-local", Enabled: true})
+	status, _ := protocol.NewRequest(protocol.RequestStatus, nil)
+	results, err := client.Batch([]*protocol.Request{set, status})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.True(t, results[0].IsOK())
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, results[1].Code)
+}
+
+func TestClient_Get(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.GetPayload
+		if req.Type == protocol.RequestGet && req.ParsePayload(&payload) == nil {
+			if payload.Alias != "api-local" {
+				return protocol.NewErrorResponse(protocol.ErrCodeNotFound, "alias not found: "+payload.Alias)
+			}
+			resp, _ := protocol.NewOKResponse(protocol.HostEntry{Domain: "api.local", IP: "127.0.0.1", Alias: "api-local", Enabled: true})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	entry, err := client.Get("api-local")
+	require.NoError(t, err)
+	assert.Equal(t, "api.local", entry.Domain)
+	assert.True(t, entry.Enabled)
+
+	_, err = client.Get("missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), protocol.ErrCodeNotFound)
+}
+
+func TestClient_AddBatch(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.AddBatchPayload
+		if req.Type == protocol.RequestAddBatch && req.ParsePayload(&payload) == nil {
+			var data protocol.AddBatchData
+			for _, h := range payload.Hosts {
+				data.Added = append(data.Added, h.Domain)
+			}
+			resp, _ := protocol.NewOKResponse(data)
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	data, err := client.AddBatch([]protocol.AddPayload{
+		{Domain: "a.local", IP: "127.0.0.1", Group: "dev"},
+		{Domain: "b.local", IP: "127.0.0.1", Group: "dev"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.local", "b.local"}, data.Added)
+}
+
+func TestClient_PreviewPreset(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.PresetPayload
+		if req.Type == protocol.RequestPreset && req.ParsePayload(&payload) == nil && payload.DryRun {
+			resp, _ := protocol.NewOKResponse(protocol.PresetData{
+				Preset:  payload.Name,
+				Disable: []string{"a", "b"},
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	preview, err := client.PreviewPreset("work")
+	require.NoError(t, err)
+	assert.Equal(t, "work", preview.Preset)
+	assert.False(t, preview.Applied)
+	assert.Equal(t, []string{"a", "b"}, preview.Disable)
+}
+
+func TestClient_Prometheus(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestPrometheus {
+			resp, _ := protocol.NewOKResponse(protocol.PrometheusData{Text: "lolcathost_hosts_total 3\n"})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	text, err := client.Prometheus()
+	require.NoError(t, err)
+	assert.Equal(t, "lolcathost_hosts_total 3\n", text)
+}
+
+func TestClient_Metrics(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestMetrics {
+			resp, _ := protocol.NewOKResponse(protocol.MetricsData{
+				Requests:       7,
+				RequestsByType: map[protocol.RequestType]int64{protocol.RequestList: 7},
+				ErrorsByCode:   map[protocol.ErrorCode]int64{protocol.ErrCodeRateLimited: 2},
+				RateLimited:    2,
+				ConfigReloads:  1,
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	m, err := client.Metrics()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), m.RequestsByType[protocol.RequestList])
+	assert.Equal(t, int64(2), m.ErrorsByCode[protocol.ErrCodeRateLimited])
+	assert.Equal(t, int64(2), m.RateLimited)
+	assert.Equal(t, int64(1), m.ConfigReloads)
+}
+
+func TestClient_ImportConfig(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestImportConfig {
+			var payload protocol.ImportConfigPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "groups: []\n", payload.Content)
+			assert.True(t, payload.DryRun)
+
+			resp, _ := protocol.NewOKResponse(protocol.ImportConfigData{
+				HostsRemoved: []string{"old-host"},
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	data, err := client.ImportConfig("groups: []\n", true, false)
+	require.NoError(t, err)
+	assert.False(t, data.Applied)
+	assert.Equal(t, []string{"old-host"}, data.HostsRemoved)
+}
+
+func TestClient_MergeConfig(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.ImportConfigPayload
+		if req.Type == protocol.RequestImportConfig && req.ParsePayload(&payload) == nil && payload.Merge {
+			resp, _ := protocol.NewOKResponse(protocol.ImportConfigData{Conflicts: []string{"host a differs from the current one"}})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	data, err := client.MergeConfig("groups: []\n", true, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host a differs from the current one"}, data.Conflicts)
+}
+
+func TestClient_Export(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.ExportPayload
+		if req.Type == protocol.RequestExport && req.ParsePayload(&payload) == nil {
+			if payload.Format != "json" {
+				return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unsupported export format")
+			}
+			resp, _ := protocol.NewOKResponse(protocol.ExportData{Format: payload.Format, Content: "{}\n"})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	content, err := client.Export("json")
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", content)
+
+	_, err = client.Export("xml")
+	assert.Error(t, err)
+}
+
+func TestClient_Warning(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestSync {
+			resp, _ := protocol.NewOKResponse(map[string]bool{"synced": true})
+			resp.Message = "synced; DNS flush failed: exit status 1"
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	require.NoError(t, client.Sync())
+	assert.Equal(t, "synced; DNS flush failed: exit status 1", client.Warning())
+
+	// Error messages are not warnings
+	require.Error(t, client.Reload())
+	assert.Empty(t, client.Warning())
+}
+
+func TestClient_SwitchProfile(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		switch req.Type {
+		case protocol.RequestSwitchProfile:
+			var payload protocol.SwitchProfilePayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "work", payload.Name)
+			assert.True(t, payload.Create)
+			resp, _ := protocol.NewOKResponse(protocol.ProfilesData{Active: "work", Profiles: []string{"default", "work"}})
+			return resp
+		case protocol.RequestListProfiles:
+			resp, _ := protocol.NewOKResponse(protocol.ProfilesData{Active: "default", Profiles: []string{"default"}})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	data, err := client.SwitchProfile("work", true)
+	require.NoError(t, err)
+	assert.Equal(t, "work", data.Active)
+
+	data, err = client.ListProfiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default"}, data.Profiles)
+}
+
+func TestClient_Reset(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestReset {
+			var payload protocol.ResetPayload
+			req.ParsePayload(&payload)
+			assert.True(t, payload.Confirm)
+
+			resp, _ := protocol.NewOKResponse(protocol.ResetData{HostsRemoved: 3, GroupsRemoved: 1})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	data, err := client.Reset()
+	require.NoError(t, err)
+	assert.Equal(t, 3, data.HostsRemoved)
+	assert.Equal(t, 1, data.GroupsRemoved)
+}
+
+func TestClient_Reload(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestReload {
+			resp, _ := protocol.NewOKResponse(map[string]string{"reloaded": "/etc/lolcathost/config.yaml"})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.Reload()
+	assert.NoError(t, err)
+}
+
+func TestClient_ErrorResponse(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		return protocol.NewErrorResponse(protocol.ErrCodeBlockedDomain, "domain is blocked")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Set("test", true, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "domain is blocked")
+}
+
+func TestClient_NotConnected(t *testing.T) {
+	client := New("/nonexistent/socket.sock")
+
+	_, err := client.Status()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not connected")
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	newDroppingServer := func(t *testing.T, drops int) (*mockServer, *atomic.Int32) {
+		server := newMockServer(t)
+		t.Cleanup(server.close)
+
+		var calls atomic.Int32
+		server.handler = func(req *protocol.Request) *protocol.Response {
+			if int(calls.Add(1)) <= drops {
+				return nil
+			}
+			resp, _ := protocol.NewOKResponse(protocol.StatusData{Running: true})
+			return resp
+		}
+		return server, &calls
+	}
+
+	t.Run("retries on a dropped connection", func(t *testing.T) {
+		server, calls := newDroppingServer(t, 2)
+
+		client := NewWithOptions(server.path, Options{MaxRetries: 2, Backoff: time.Millisecond})
+		require.NoError(t, client.Connect())
+		defer client.Close()
+
+		status, err := client.Status()
+		require.NoError(t, err)
+		assert.True(t, status.Running)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		server, calls := newDroppingServer(t, 5)
+
+		client := NewWithOptions(server.path, Options{MaxRetries: 2})
+		require.NoError(t, client.Connect())
+		defer client.Close()
+
+		_, err := client.Status()
+		require.Error(t, err)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("connects on first use", func(t *testing.T) {
+		server, _ := newDroppingServer(t, 0)
+
+		client := NewWithOptions(server.path, Options{MaxRetries: 1})
+		defer client.Close()
+
+		_, err := client.Status()
+		require.NoError(t, err)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		server, calls := newDroppingServer(t, 1)
+
+		client := New(server.path)
+		require.NoError(t, client.Connect())
+		defer client.Close()
+
+		_, err := client.Status()
+		require.Error(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("retries while the daemon is down", func(t *testing.T) {
+		client := NewWithOptions("/nonexistent/socket.sock", Options{MaxRetries: 2, Backoff: time.Millisecond})
+
+		start := time.Now()
+		_, err := client.Status()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to connect to daemon")
+		assert.GreaterOrEqual(t, time.Since(start), 3*time.Millisecond)
+	})
+}
+
+func TestClient_Context(t *testing.T) {
+	newSlowServer := func(t *testing.T) *mockServer {
+		server := newMockServer(t)
+		t.Cleanup(server.close)
+
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		server.handler = func(req *protocol.Request) *protocol.Response {
+			if req.Type == protocol.RequestList {
+				<-release
+			}
+			resp, _ := protocol.NewOKResponse(protocol.StatusData{Running: true})
+			return resp
+		}
+		return server
+	}
+
+	t.Run("cancel aborts a pending request", func(t *testing.T) {
+		server := newSlowServer(t)
+
+		client := New(server.path)
+		require.NoError(t, client.Connect())
+		defer client.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := client.ListContext(ctx)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), DefaultTimeout)
+
+		// The late response must not be taken for the next request's
+		_, err = client.Status()
+		assert.Contains(t, err.Error(), "not connected")
+	})
+
+	t.Run("deadline shorter than the timeout", func(t *testing.T) {
+		server := newSlowServer(t)
+
+		client := New(server.path)
+		require.NoError(t, client.Connect())
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.ListContext(ctx)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("done context sends nothing", func(t *testing.T) {
+		server := newMockServer(t)
+		defer server.close()
+
+		var calls atomic.Int32
+		server.handler = func(req *protocol.Request) *protocol.Response {
+			calls.Add(1)
+			resp, _ := protocol.NewOKResponse(nil)
+			return resp
+		}
+
+		client := New(server.path)
+		require.NoError(t, client.Connect())
+		defer client.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := client.PingContext(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+		require.NoError(t, client.Ping())
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("cancel stops retrying", func(t *testing.T) {
+		client := NewWithOptions("/nonexistent/socket.sock", Options{MaxRetries: 5, Backoff: time.Hour})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.StatusContext(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// Matrix test for request types
+func TestClient_RequestTypes_Matrix(t *testing.T) {
+	types := []struct {
+		name    string
+		reqType protocol.RequestType
+		call    func(*Client) error
+	}{
+		{"ping", protocol.RequestPing, func(c *Client) error { return c.Ping() }},
+		{"status", protocol.RequestStatus, func(c *Client) error { _, err := c.Status(); return err }},
+		{"list", protocol.RequestList, func(c *Client) error { _, err := c.List(); return err }},
+		{"sync", protocol.RequestSync, func(c *Client) error { return c.Sync() }},
+		{"preset", protocol.RequestPreset, func(c *Client) error { return c.ApplyPreset("test") }},
+		{"backups", protocol.RequestBackups, func(c *Client) error { _, err := c.ListBackups(); return err }},
+	}
+
+	for _, tt := range types {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMockServer(t)
+			defer server.close()
+
+			receivedType := protocol.RequestType("")
+			server.handler = func(req *protocol.Request) *protocol.Response {
+				receivedType = req.Type
+
+				switch req.Type {
+				case protocol.RequestStatus:
+					resp, _ := protocol.NewOKResponse(protocol.StatusData{})
+					return resp
+				case protocol.RequestList:
+					resp, _ := protocol.NewOKResponse(protocol.ListData{})
+					return resp
+				case protocol.RequestBackups:
+					resp, _ := protocol.NewOKResponse(protocol.BackupsData{})
+					return resp
+				default:
+					resp, _ := protocol.NewOKResponse(nil)
+					return resp
+				}
+			}
+
+			client := New(server.path)
+			err := client.Connect()
+			require.NoError(t, err)
+			defer client.Close()
+
+			_ = tt.call(client)
+			assert.Equal(t, tt.reqType, receivedType)
+		})
+	}
+}
+
+func TestClient_Add(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestAdd {
+			var payload protocol.AddPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "test.local", payload.Domain)
+			assert.Equal(t, "127.0.0.1", payload.IP)
+			assert.Equal(t, "test-local", payload.Alias)
+			assert.Equal(t, "dev", payload.Group)
+			assert.True(t, payload.Enabled)
+
+			resp, _ := protocol.NewOKResponse(protocol.SetData{Domain: payload.Domain, Applied: true})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	data, err := client.Add("test.local", "127.0.0.1", "test-local", "dev", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "test.local", data.Domain)
+	assert.True(t, data.Applied)
+}
+
+func TestClient_Delete(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestDelete {
+			var payload protocol.DeletePayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "test-alias", payload.Alias)
+
+			resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Alias})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.Delete("test-alias")
+	assert.NoError(t, err)
+}
+
+func TestClient_DeleteIfExists(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestDelete {
+			var payload protocol.DeletePayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "gone-alias", payload.Alias)
+			assert.True(t, payload.IfExists)
+
+			resp, _ := protocol.NewOKResponse(map[string]string{"deleted": ""})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.DeleteIfExists("gone-alias")
+	assert.NoError(t, err)
+}
+
+func TestClient_AddEntry(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestAdd {
+			var payload protocol.AddPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "test.local", payload.Domain)
+			assert.Equal(t, "staging API", payload.Description)
+
+			resp, _ := protocol.NewOKResponse(protocol.SetData{Domain: payload.Domain, Alias: "test-local", Applied: true})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	data, err := client.AddEntry(protocol.AddPayload{
+		Domain:      "test.local",
+		IP:          "127.0.0.1",
+		Group:       "dev",
+		Description: "staging API",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "test-local", data.Alias)
+}
+
+func TestClient_SetSticky(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestSetSticky {
+			var payload protocol.SetStickyPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "myalias", payload.Alias)
+			assert.True(t, payload.Sticky)
+
+			resp, _ := protocol.NewOKResponse(nil)
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.SetSticky("myalias", true)
+	assert.NoError(t, err)
+}
+
+func TestClient_Explain(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestExplain {
+			var payload protocol.ExplainPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "myalias", payload.Alias)
+
+			resp, _ := protocol.NewOKResponse(protocol.ExplainData{
+				Entry:  protocol.HostEntry{Alias: payload.Alias},
+				Issues: []protocol.VerifyIssue{{Severity: protocol.SeverityHigh, Kind: "disabled"}},
+			})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	data, err := client.Explain("myalias")
+	require.NoError(t, err)
+	assert.Equal(t, "myalias", data.Entry.Alias)
+	require.Len(t, data.Issues, 1)
+	assert.Equal(t, "disabled", data.Issues[0].Kind)
+}
+
+func TestClient_MoveHost(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestMoveHost {
+			var payload protocol.MoveHostPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "myalias", payload.Alias)
+			assert.Equal(t, "staging", payload.Group)
+
+			resp, _ := protocol.NewOKResponse(map[string]string{"moved": payload.Alias})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.MoveHost("myalias", "staging")
+	assert.NoError(t, err)
+}
+
+func TestClient_AddGroup(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestAddGroup {
+			var payload protocol.GroupPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "newgroup", payload.Name)
+
+			resp, _ := protocol.NewOKResponse(map[string]string{"added": payload.Name})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.AddGroup("newgroup")
+	assert.NoError(t, err)
+}
+
+func TestClient_DeleteGroup(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestDeleteGroup {
+			var payload protocol.GroupPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "todelete", payload.Name)
+
+			resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Name})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.DeleteGroup("todelete")
+	assert.NoError(t, err)
+}
+
+func TestClient_RenameGroup(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		if req.Type == protocol.RequestRenameGroup {
+			var payload protocol.RenameGroupPayload
+			req.ParsePayload(&payload)
+			assert.Equal(t, "oldname", payload.OldName)
+			assert.Equal(t, "newname", payload.NewName)
+
+			resp, _ := protocol.NewOKResponse(map[string]string{"renamed": payload.NewName})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "unexpected")
+	}
+
+	client := New(server.path)
+	err := client.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	err = client.RenameGroup("oldname", "newname")
+	assert.NoError(t, err)
+}
+
+func TestClient_Reorder(t *testing.T) {
+	server := newMockServer(t)
+	defer server.close()
+
+	var got map[protocol.RequestType][]string
+	server.handler = func(req *protocol.Request) *protocol.Response {
+		var payload protocol.ReorderPayload
+		_ = req.ParsePayload(&payload)
+		got[req.Type] = payload.Names
+		if len(payload.Names) == 1 {
+			return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "missing presets: work")
+		}
+		resp, _ := protocol.NewOKResponse(nil)
+		return resp
+	}
+
+	client := New(server.path)
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	got = make(map[protocol.RequestType][]string)
+	require.NoError(t, client.ReorderGroups([]string{"b", "a"}))
+	require.NoError(t, client.ReorderPresets([]string{"home", "work"}))
+	assert.Equal(t, map[protocol.RequestType][]string{
+		protocol.RequestReorderGroups:  {"b", "a"},
+		protocol.RequestReorderPresets: {"home", "work"},
+	}, got)
+
+	err := client.ReorderPresets([]string{"home"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing presets: work")
 }
 
 func TestClient_ListGroups(t *testing.T) {
@@ -659,8 +1833,9 @@ func TestClient_AddPreset(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.AddPreset("newpreset", []string{"a", "b"}, []string{"c"})
+	warning, err := client.AddPreset("newpreset", []string{"a", "b"}, []string{"c"})
 	assert.NoError(t, err)
+	assert.Empty(t, warning)
 }
 
 func TestClient_DeletePreset(t *testing.T) {
@@ -2,11 +2,18 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
@@ -32,6 +39,9 @@ func DefaultConfigPath() string {
 	return filepath.Join(DefaultConfigDir(), "config.yaml")
 }
 
+// defaultGroupName is the group hosts land in when no other group applies.
+const defaultGroupName = "default"
+
 // FlushMethod defines DNS cache flush methods.
 type FlushMethod string
 
@@ -40,12 +50,70 @@ const (
 	FlushMethodDscacheutil FlushMethod = "dscacheutil"
 	FlushMethodKillall     FlushMethod = "killall"
 	FlushMethodBoth        FlushMethod = "both"
+	FlushMethodSystemd     FlushMethod = "systemd"
+	FlushMethodNscd        FlushMethod = "nscd"
+)
+
+// LogFormat defines how the daemon writes its audit log.
+type LogFormat string
+
+const (
+	// LogFormatJSON writes one JSON object per line. It is the default.
+	LogFormatJSON LogFormat = "json"
+	// LogFormatText writes one logfmt-style line per entry.
+	LogFormatText LogFormat = "text"
 )
 
 // Settings holds global configuration settings.
 type Settings struct {
-	AutoApply   bool        `yaml:"autoApply"`
-	FlushMethod FlushMethod `yaml:"flushMethod"`
+	AutoApply        bool        `yaml:"autoApply"`
+	FlushMethod      FlushMethod `yaml:"flushMethod"`
+	ReconcileOnStart bool        `yaml:"reconcileOnStart"`
+
+	// IdleTimeout closes daemon connections that send nothing for this long,
+	// e.g. "10m". Zero uses the daemon's default.
+	IdleTimeout time.Duration `yaml:"idleTimeout,omitempty"`
+
+	// AutoBackupInterval backs up the hosts file this often whether or not
+	// anything changed, e.g. "24h". Zero disables it.
+	AutoBackupInterval time.Duration `yaml:"autoBackupInterval,omitempty"`
+
+	// RateLimit caps how many requests a client process may send per
+	// RateLimitWindow. Zero for either uses the daemon's default of 100 per
+	// minute.
+	RateLimit       int           `yaml:"rateLimit,omitempty"`
+	RateLimitWindow time.Duration `yaml:"rateLimitWindow,omitempty"`
+
+	// RateLimitExemptRoot lets root's requests past the rate limit, so
+	// scripts run with sudo aren't throttled. Unset counts as true.
+	RateLimitExemptRoot *bool `yaml:"rateLimitExemptRoot,omitempty"`
+
+	// MaxBackups is how many hosts file backups the daemon keeps. Zero uses
+	// the daemon's default of 10.
+	MaxBackups int `yaml:"maxBackups,omitempty"`
+
+	// BlockedDomains adds to the built-in list of domains that can't be
+	// redirected, e.g. an organization's SSO domain. Subdomains are blocked
+	// too.
+	BlockedDomains []string `yaml:"blockedDomains,omitempty"`
+
+	// Verbose makes the daemon print each change a sync makes to the hosts
+	// file, e.g. "sync: + app.local → 127.0.0.1".
+	Verbose bool `yaml:"verbose,omitempty"`
+
+	// LogFormat is the audit log format, json or text. Empty means json.
+	LogFormat LogFormat `yaml:"logFormat,omitempty"`
+
+	// AuditMaxSize is the size in bytes at which the audit log is rotated,
+	// and AuditMaxFiles how many rotated logs are kept. Zero for either
+	// uses the daemon's default of 10MB and 5 files.
+	AuditMaxSize  int64 `yaml:"auditMaxSize,omitempty"`
+	AuditMaxFiles int   `yaml:"auditMaxFiles,omitempty"`
+}
+
+// ExemptsRoot reports whether root is exempt from the rate limit.
+func (s Settings) ExemptsRoot() bool {
+	return s.RateLimitExemptRoot == nil || *s.RateLimitExemptRoot
 }
 
 // Host represents a single host entry in configuration.
@@ -54,12 +122,65 @@ type Host struct {
 	IP      string `yaml:"ip"`
 	Alias   string `yaml:"alias"`
 	Enabled bool   `yaml:"enabled"`
+
+	// IPs lists every address when the host maps to more than one, e.g. an
+	// IPv4 and an IPv6 loopback. IP then holds the first of them, so configs
+	// with a single IP look the same as before.
+	IPs []string `yaml:"ips,omitempty"`
+
+	// CreatedAt is when the host was added, as a Unix timestamp. Hosts added
+	// before it was recorded have zero.
+	CreatedAt int64 `yaml:"createdAt,omitempty"`
+
+	// ExpiresAt is when the daemon disables the host again, as a Unix
+	// timestamp. Zero means it stays enabled until switched off.
+	ExpiresAt int64 `yaml:"expiresAt,omitempty"`
+
+	// Metadata holds free-form notes about the entry, such as an owner or a
+	// ticket link. lolcathost doesn't interpret the keys.
+	Metadata map[string]string `yaml:"metadata,omitempty"`
+
+	// Sticky hosts are never disabled by presets, though presets may still
+	// enable them.
+	Sticky bool `yaml:"sticky,omitempty"`
+
+	// Description explains what the entry is for. It lives only in the
+	// config; the hosts file never carries it.
+	Description string `yaml:"description,omitempty"`
+
+	// AllowBlocked marks a host root added with a blocked domain on purpose,
+	// e.g. to point icloud.com at a local mock. Validation lets its domain
+	// through.
+	AllowBlocked bool `yaml:"allowBlocked,omitempty"`
+}
+
+// Addresses returns every IP the host maps to.
+func (h Host) Addresses() []string {
+	if len(h.IPs) > 0 {
+		return h.IPs
+	}
+	return []string{h.IP}
+}
+
+// SetAddresses points the host at ips, keeping IP and IPs consistent.
+func (h *Host) SetAddresses(ips []string) {
+	h.IP, h.IPs = "", nil
+	if len(ips) > 0 {
+		h.IP = ips[0]
+	}
+	if len(ips) > 1 {
+		h.IPs = slices.Clone(ips)
+	}
 }
 
 // Group represents a group of host entries.
 type Group struct {
 	Name  string `yaml:"name"`
 	Hosts []Host `yaml:"hosts"`
+
+	// Color is an optional header color for the TUI: a name such as "blue"
+	// or a 256-color code such as "33".
+	Color string `yaml:"color,omitempty"`
 }
 
 // Preset defines a named preset that enables/disables specific aliases.
@@ -84,6 +205,10 @@ type Manager struct {
 	watcher  *fsnotify.Watcher
 	onChange func(*Config)
 	stopCh   chan struct{}
+
+	// diskHash is the SHA-256 of the file as last loaded or saved. Save
+	// compares it with the file to avoid overwriting edits made since.
+	diskHash []byte
 }
 
 // NewManager creates a new config manager.
@@ -94,29 +219,96 @@ func NewManager(path string) *Manager {
 	}
 }
 
-// Load reads and parses the configuration file.
-func (m *Manager) Load() error {
-	data, err := os.ReadFile(m.path)
+// Path returns the path of the configuration file.
+func (m *Manager) Path() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.path
+}
+
+// SwitchPath loads the configuration at path and, if it is valid, makes it
+// the managed file: later loads, saves and change notifications use it. On
+// error the current file stays in use.
+func (m *Manager) SwitchPath(path string) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	cfg, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	old := m.path
+	m.path = path
+	m.config = cfg
+	m.diskHash = hashConfig(data)
+	watcher := m.watcher
+	m.mu.Unlock()
+
+	if watcher != nil && old != path {
+		_ = watcher.Remove(old)
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch config file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Parse parses and validates YAML configuration data.
+func Parse(data []byte) (*Config, error) {
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	for _, fix := range cfg.normalizeGroups() {
+		fmt.Fprintf(os.Stderr, "config: %s\n", fix)
 	}
+	cfg.normalizeAddresses()
 
 	if err := ValidateConfig(&cfg); err != nil {
-		return fmt.Errorf("invalid config: %w", err)
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Load reads and parses the configuration file.
+func (m *Manager) Load() error {
+	data, err := os.ReadFile(m.Path())
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return err
 	}
 
 	m.mu.Lock()
-	m.config = &cfg
+	m.config = cfg
+	m.diskHash = hashConfig(data)
 	m.mu.Unlock()
 
 	return nil
 }
 
+// ErrConfigChanged is returned by Save when the file was changed on disk
+// since it was last loaded, so saving would discard those edits.
+var ErrConfigChanged = errors.New("config file changed on disk since it was loaded")
+
+func hashConfig(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// ErrNoConfig is returned when no configuration has been loaded yet.
+var ErrNoConfig = errors.New("no configuration loaded")
+
 // Get returns the current configuration.
 func (m *Manager) Get() *Config {
 	m.mu.RLock()
@@ -124,6 +316,30 @@ func (m *Manager) Get() *Config {
 	return m.config
 }
 
+// MustGet returns the current configuration, panicking if none is loaded.
+// Use it only where a successful Load is guaranteed.
+func (m *Manager) MustGet() *Config {
+	cfg := m.Get()
+	if cfg == nil {
+		panic(ErrNoConfig)
+	}
+	return cfg
+}
+
+// With runs fn with the current configuration while holding the manager's
+// lock, so a concurrent reload can't swap it out mid-change. It returns
+// ErrNoConfig if none is loaded, otherwise fn's error. fn must not call other
+// Manager methods.
+func (m *Manager) With(fn func(*Config) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.config == nil {
+		return ErrNoConfig
+	}
+	return fn(m.config)
+}
+
 // Reload reloads the configuration from disk.
 // This is useful for rolling back after a failed operation.
 func (m *Manager) Reload() error {
@@ -142,7 +358,7 @@ func (m *Manager) Watch(onChange func(*Config)) error {
 
 	go m.watchLoop()
 
-	if err := watcher.Add(m.path); err != nil {
+	if err := watcher.Add(m.Path()); err != nil {
 		return fmt.Errorf("failed to watch config file: %w", err)
 	}
 
@@ -184,16 +400,13 @@ func (m *Manager) loadLocked() error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
-	}
-
-	if err := ValidateConfig(&cfg); err != nil {
-		return fmt.Errorf("invalid config: %w", err)
+	cfg, err := Parse(data)
+	if err != nil {
+		return err
 	}
 
-	m.config = &cfg
+	m.config = cfg
+	m.diskHash = hashConfig(data)
 	return nil
 }
 
@@ -205,7 +418,9 @@ func (m *Manager) Stop() {
 	}
 }
 
-// GetAllHosts returns all hosts from all groups.
+// GetAllHosts returns all hosts from all groups, in group order and then in
+// the order hosts appear within each group. Both are slices in the config
+// file, so the order is stable across saves and reloads.
 func (c *Config) GetAllHosts() []Host {
 	var hosts []Host
 	for _, g := range c.Groups {
@@ -236,6 +451,40 @@ func (c *Config) FindHostByAlias(alias string) (*Host, *Group) {
 	return &c.Groups[groupIdx].Hosts[hostIdx], &c.Groups[groupIdx]
 }
 
+// SetHostMetadata replaces a host's metadata. Empty metadata clears it.
+func (c *Config) SetHostMetadata(alias string, metadata map[string]string) error {
+	host, _ := c.FindHostByAlias(alias)
+	if host == nil {
+		return fmt.Errorf("alias not found: %s", alias)
+	}
+	if len(metadata) == 0 {
+		host.Metadata = nil
+		return nil
+	}
+	host.Metadata = maps.Clone(metadata)
+	return nil
+}
+
+// SetHostDescription sets a host's description. An empty one clears it.
+func (c *Config) SetHostDescription(alias, description string) error {
+	host, _ := c.FindHostByAlias(alias)
+	if host == nil {
+		return fmt.Errorf("alias not found: %s", alias)
+	}
+	host.Description = description
+	return nil
+}
+
+// SetHostAllowBlocked sets whether a host may use a blocked domain.
+func (c *Config) SetHostAllowBlocked(alias string, allow bool) error {
+	host, _ := c.FindHostByAlias(alias)
+	if host == nil {
+		return fmt.Errorf("alias not found: %s", alias)
+	}
+	host.AllowBlocked = allow
+	return nil
+}
+
 // FindPreset finds a preset by name.
 func (c *Config) FindPreset(name string) *Preset {
 	for i := range c.Presets {
@@ -246,22 +495,71 @@ func (c *Config) FindPreset(name string) *Preset {
 	return nil
 }
 
-// SetHostEnabled sets the enabled state of a host by alias.
+// SetHostEnabled sets the enabled state of a host by alias. Any expiry is
+// cleared, so a host switched on this way stays on.
 func (c *Config) SetHostEnabled(alias string, enabled bool) bool {
 	groupIdx, hostIdx := c.findHostIndices(alias)
 	if groupIdx < 0 {
 		return false
 	}
 	c.Groups[groupIdx].Hosts[hostIdx].Enabled = enabled
+	c.Groups[groupIdx].Hosts[hostIdx].ExpiresAt = 0
 	return true
 }
 
+// SetHostExpiry sets when a host is disabled again; zero clears it.
+func (c *Config) SetHostExpiry(alias string, expiresAt int64) bool {
+	groupIdx, hostIdx := c.findHostIndices(alias)
+	if groupIdx < 0 {
+		return false
+	}
+	c.Groups[groupIdx].Hosts[hostIdx].ExpiresAt = expiresAt
+	return true
+}
+
+// ExpireHosts disables every enabled host whose expiry is at or before now
+// and returns their aliases.
+func (c *Config) ExpireHosts(now int64) []string {
+	var expired []string
+	for i := range c.Groups {
+		for j := range c.Groups[i].Hosts {
+			h := &c.Groups[i].Hosts[j]
+			if h.ExpiresAt == 0 || h.ExpiresAt > now {
+				continue
+			}
+			h.ExpiresAt = 0
+			if h.Enabled {
+				h.Enabled = false
+				expired = append(expired, h.Alias)
+			}
+		}
+	}
+	return expired
+}
+
+// SetHostSticky sets whether presets may disable a host.
+func (c *Config) SetHostSticky(alias string, sticky bool) bool {
+	groupIdx, hostIdx := c.findHostIndices(alias)
+	if groupIdx < 0 {
+		return false
+	}
+	c.Groups[groupIdx].Hosts[hostIdx].Sticky = sticky
+	return true
+}
+
+// NormalizeAlias lowercases s and replaces dots, underscores and runs of
+// whitespace with dashes, so "My App.local" becomes "my-app-local".
+func NormalizeAlias(s string) string {
+	alias := strings.Join(strings.Fields(s), "-")
+	alias = strings.ReplaceAll(alias, ".", "-")
+	alias = strings.ReplaceAll(alias, "_", "-")
+	return strings.ToLower(alias)
+}
+
 // GenerateAlias creates a unique alias from a domain name.
 func (c *Config) GenerateAlias(domain string) string {
 	// Convert domain to alias format: example.com -> example-com
-	alias := strings.ReplaceAll(domain, ".", "-")
-	alias = strings.ReplaceAll(alias, "_", "-")
-	alias = strings.ToLower(alias)
+	alias := NormalizeAlias(domain)
 
 	// Check if alias exists, if so append a number
 	baseAlias := alias
@@ -290,11 +588,12 @@ func (c *Config) AddHost(domain, ip, alias, groupName string, enabled bool) erro
 	}
 
 	host := Host{
-		Domain:  domain,
-		IP:      ip,
-		Alias:   alias,
-		Enabled: enabled,
+		Domain:    domain,
+		Alias:     alias,
+		Enabled:   enabled,
+		CreatedAt: time.Now().Unix(),
 	}
+	host.SetAddresses(SplitIPs(ip))
 
 	// Find or create group
 	for i := range c.Groups {
@@ -314,6 +613,10 @@ func (c *Config) AddHost(domain, ip, alias, groupName string, enabled bool) erro
 
 // AddGroup adds a new empty group.
 func (c *Config) AddGroup(name string) error {
+	if !ValidateName(name) {
+		return fmt.Errorf("invalid group name: %q", name)
+	}
+
 	// Check if group already exists
 	for _, g := range c.Groups {
 		if g.Name == name {
@@ -341,6 +644,10 @@ func (c *Config) DeleteGroup(name string) error {
 
 // RenameGroup renames an existing group.
 func (c *Config) RenameGroup(oldName, newName string) error {
+	if !ValidateName(newName) {
+		return fmt.Errorf("invalid group name: %q", newName)
+	}
+
 	// Check if new name already exists
 	for _, g := range c.Groups {
 		if g.Name == newName {
@@ -357,6 +664,49 @@ func (c *Config) RenameGroup(oldName, newName string) error {
 	return fmt.Errorf("group not found: %s", oldName)
 }
 
+// SetGroupIP points every host in a group at ip and returns how many hosts
+// changed.
+func (c *Config) SetGroupIP(groupName, ip string) (int, error) {
+	if !ValidateIP(ip) {
+		return 0, fmt.Errorf("invalid IP address: %s", ip)
+	}
+
+	for i := range c.Groups {
+		if c.Groups[i].Name != groupName {
+			continue
+		}
+		updated := 0
+		for j := range c.Groups[i].Hosts {
+			if !slices.Equal(c.Groups[i].Hosts[j].Addresses(), []string{ip}) {
+				c.Groups[i].Hosts[j].SetAddresses([]string{ip})
+				updated++
+			}
+		}
+		return updated, nil
+	}
+	return 0, fmt.Errorf("group not found: %s", groupName)
+}
+
+// SetGroupEnabled enables or disables every host in a group and returns how
+// many hosts changed.
+func (c *Config) SetGroupEnabled(groupName string, enabled bool) (int, error) {
+	for i := range c.Groups {
+		if c.Groups[i].Name != groupName {
+			continue
+		}
+		updated := 0
+		for j := range c.Groups[i].Hosts {
+			if c.Groups[i].Hosts[j].Enabled != enabled {
+				c.Groups[i].Hosts[j].Enabled = enabled
+				c.Groups[i].Hosts[j].ExpiresAt = 0
+				updated++
+			}
+		}
+		return updated, nil
+	}
+	return 0, fmt.Errorf("group not found: %s", groupName)
+}
+
 // GetGroups returns all group names.
 func (c *Config) GetGroups() []string {
 	names := make([]string, len(c.Groups))
@@ -366,6 +716,74 @@ func (c *Config) GetGroups() []string {
 	return names
 }
 
+// ReorderGroups rearranges groups to follow names, which must list every
+// existing group exactly once.
+func (c *Config) ReorderGroups(names []string) error {
+	order, err := reorderIndices(c.GetGroups(), names, "group")
+	if err != nil {
+		return err
+	}
+	groups := make([]Group, len(order))
+	for i, idx := range order {
+		groups[i] = c.Groups[idx]
+	}
+	c.Groups = groups
+	return nil
+}
+
+// ReorderPresets rearranges presets to follow names, which must list every
+// existing preset exactly once.
+func (c *Config) ReorderPresets(names []string) error {
+	current := make([]string, len(c.Presets))
+	for i, p := range c.Presets {
+		current[i] = p.Name
+	}
+	order, err := reorderIndices(current, names, "preset")
+	if err != nil {
+		return err
+	}
+	presets := make([]Preset, len(order))
+	for i, idx := range order {
+		presets[i] = c.Presets[idx]
+	}
+	c.Presets = presets
+	return nil
+}
+
+// reorderIndices maps the requested names to their indices in current,
+// rejecting unknown, duplicate or missing names.
+func reorderIndices(current, names []string, kind string) ([]int, error) {
+	index := make(map[string]int, len(current))
+	for i, name := range current {
+		index[name] = i
+	}
+
+	order := make([]int, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		i, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("%s not found: %s", kind, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s listed more than once: %s", kind, name)
+		}
+		seen[name] = true
+		order = append(order, i)
+	}
+
+	var missing []string
+	for _, name := range current {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing %ss: %s", kind, strings.Join(missing, ", "))
+	}
+	return order, nil
+}
+
 // DeleteHost removes a host by alias.
 func (c *Config) DeleteHost(alias string) bool {
 	groupIdx, hostIdx := c.findHostIndices(alias)
@@ -391,21 +809,18 @@ func (c *Config) UpdateHost(oldAlias, domain, ip, newAlias, groupName string) er
 		}
 	}
 
-	// Get current enabled state
-	enabled := c.Groups[foundGroup].Hosts[foundHost].Enabled
-
 	// If group is changing, move to new group
 	if c.Groups[foundGroup].Name != groupName {
+		// Keep the enabled state, creation time, metadata and stickiness
+		host := c.Groups[foundGroup].Hosts[foundHost]
+		host.Domain = domain
+		host.SetAddresses(SplitIPs(ip))
+		host.Alias = newAlias
+
 		// Remove from old group
 		c.Groups[foundGroup].Hosts = append(c.Groups[foundGroup].Hosts[:foundHost], c.Groups[foundGroup].Hosts[foundHost+1:]...)
 
 		// Add to new group
-		host := Host{
-			Domain:  domain,
-			IP:      ip,
-			Alias:   newAlias,
-			Enabled: enabled,
-		}
 
 		// Find or create target group
 		found := false
@@ -425,13 +840,42 @@ func (c *Config) UpdateHost(oldAlias, domain, ip, newAlias, groupName string) er
 	} else {
 		// Update in place
 		c.Groups[foundGroup].Hosts[foundHost].Domain = domain
-		c.Groups[foundGroup].Hosts[foundHost].IP = ip
+		c.Groups[foundGroup].Hosts[foundHost].SetAddresses(SplitIPs(ip))
 		c.Groups[foundGroup].Hosts[foundHost].Alias = newAlias
 	}
 
 	return nil
 }
 
+// MoveHost moves a host to another group, creating the group if needed.
+// All other host fields, including the enabled state, are preserved.
+func (c *Config) MoveHost(alias, groupName string) error {
+	groupIdx, hostIdx := c.findHostIndices(alias)
+	if groupIdx < 0 {
+		return fmt.Errorf("alias not found: %s", alias)
+	}
+
+	if c.Groups[groupIdx].Name == groupName {
+		return nil
+	}
+
+	host := c.Groups[groupIdx].Hosts[hostIdx]
+	c.Groups[groupIdx].Hosts = append(c.Groups[groupIdx].Hosts[:hostIdx], c.Groups[groupIdx].Hosts[hostIdx+1:]...)
+
+	for i := range c.Groups {
+		if c.Groups[i].Name == groupName {
+			c.Groups[i].Hosts = append(c.Groups[i].Hosts, host)
+			return nil
+		}
+	}
+
+	c.Groups = append(c.Groups, Group{
+		Name:  groupName,
+		Hosts: []Host{host},
+	})
+	return nil
+}
+
 // ApplyPreset applies a preset to the configuration.
 func (c *Config) ApplyPreset(name string) error {
 	preset := c.FindPreset(name)
@@ -443,13 +887,71 @@ func (c *Config) ApplyPreset(name string) error {
 		c.SetHostEnabled(alias, true)
 	}
 	for _, alias := range preset.Disable {
-		c.SetHostEnabled(alias, false)
+		if host, _ := c.FindHostByAlias(alias); host != nil && !host.Sticky {
+			c.SetHostEnabled(alias, false)
+		}
 	}
 	return nil
 }
 
+// PresetChanges returns the aliases whose state applying the named preset
+// would change. Aliases that don't exist, are already in the target state or
+// are sticky hosts the preset would disable are left out.
+func (c *Config) PresetChanges(name string) (enable, disable []string, err error) {
+	preset := c.FindPreset(name)
+	if preset == nil {
+		return nil, nil, fmt.Errorf("preset not found: %s", name)
+	}
+
+	for _, alias := range preset.Enable {
+		if host, _ := c.FindHostByAlias(alias); host != nil && !host.Enabled {
+			enable = append(enable, alias)
+		}
+	}
+	for _, alias := range preset.Disable {
+		if host, _ := c.FindHostByAlias(alias); host != nil && host.Enabled && !host.Sticky {
+			disable = append(disable, alias)
+		}
+	}
+	return enable, disable, nil
+}
+
+// PresetMissingAliases returns the aliases the named preset references that
+// no longer exist, in preset order. Applying the preset skips them.
+func (c *Config) PresetMissingAliases(name string) ([]string, error) {
+	preset := c.FindPreset(name)
+	if preset == nil {
+		return nil, fmt.Errorf("preset not found: %s", name)
+	}
+
+	var missing []string
+	for _, alias := range slices.Concat(preset.Enable, preset.Disable) {
+		if host, _ := c.FindHostByAlias(alias); host == nil && !slices.Contains(missing, alias) {
+			missing = append(missing, alias)
+		}
+	}
+	return missing, nil
+}
+
+// UnknownPresetAliases returns every alias a preset references that no host
+// has, formatted as "preset: alias", in preset order.
+func (c *Config) UnknownPresetAliases() []string {
+	var unknown []string
+	for _, p := range c.Presets {
+		missing, _ := c.PresetMissingAliases(p.Name)
+		for _, alias := range missing {
+			unknown = append(unknown, p.Name+": "+alias)
+		}
+	}
+	return unknown
+}
+
 // AddPreset adds a new preset.
 func (c *Config) AddPreset(name string, enable, disable []string) error {
+	if !ValidateName(name) {
+		return fmt.Errorf("invalid preset name: %q", name)
+	}
+
 	// Check if preset already exists
 	for _, p := range c.Presets {
 		if p.Name == name {
@@ -465,6 +967,66 @@ func (c *Config) AddPreset(name string, enable, disable []string) error {
 	return nil
 }
 
+// PresetConflicts returns a warning for every alias in the enable list whose
+// domain is already enabled by an earlier alias in the same list. Applying such
+// a preset would leave the domain mapped twice in the hosts file.
+func (c *Config) PresetConflicts(enable []string) []string {
+	var warnings []string
+	domainOwner := make(map[string]string)
+	for _, alias := range enable {
+		host, _ := c.FindHostByAlias(alias)
+		if host == nil {
+			continue
+		}
+		if owner, exists := domainOwner[host.Domain]; exists {
+			warnings = append(warnings, fmt.Sprintf("aliases %s and %s both map domain %s", owner, alias, host.Domain))
+			continue
+		}
+		domainOwner[host.Domain] = alias
+	}
+	return warnings
+}
+
+// DomainConflict describes a domain that is mapped to more than one IP by
+// enabled hosts. Aliases and IPs are in configuration order.
+type DomainConflict struct {
+	Domain  string
+	Aliases []string
+	IPs     []string
+}
+
+// DomainConflicts returns every domain that enabled hosts map to differing IPs.
+// Such entries make resolution depend on how the OS picks between hosts lines.
+func (c *Config) DomainConflicts() []DomainConflict {
+	var order []string
+	byDomain := make(map[string]*DomainConflict)
+	for _, h := range c.GetAllHosts() {
+		if !h.Enabled {
+			continue
+		}
+		conflict, exists := byDomain[h.Domain]
+		if !exists {
+			conflict = &DomainConflict{Domain: h.Domain}
+			byDomain[h.Domain] = conflict
+			order = append(order, h.Domain)
+		}
+		conflict.Aliases = append(conflict.Aliases, h.Alias)
+		conflict.IPs = append(conflict.IPs, strings.Join(h.Addresses(), ", "))
+	}
+
+	var conflicts []DomainConflict
+	for _, domain := range order {
+		conflict := byDomain[domain]
+		for _, ip := range conflict.IPs[1:] {
+			if ip != conflict.IPs[0] {
+				conflicts = append(conflicts, *conflict)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
 // DeletePreset removes a preset by name.
 func (c *Config) DeletePreset(name string) error {
 	for i, p := range c.Presets {
@@ -481,6 +1043,19 @@ func (c *Config) GetPresets() []Preset {
 	return c.Presets
 }
 
+// sameHost reports whether two hosts have the same fields and metadata.
+func sameHost(a, b Host) bool {
+	return a.Domain == b.Domain &&
+		a.IP == b.IP &&
+		slices.Equal(a.IPs, b.IPs) &&
+		a.Alias == b.Alias &&
+		a.Enabled == b.Enabled &&
+		a.CreatedAt == b.CreatedAt &&
+		a.Sticky == b.Sticky &&
+		a.Description == b.Description &&
+		maps.Equal(a.Metadata, b.Metadata)
+}
+
 // Clone creates a deep copy of the configuration.
 func (c *Config) Clone() *Config {
 	clone := &Config{
@@ -493,8 +1068,13 @@ func (c *Config) Clone() *Config {
 		clone.Groups[i] = Group{
 			Name:  g.Name,
 			Hosts: make([]Host, len(g.Hosts)),
+			Color: g.Color,
 		}
 		copy(clone.Groups[i].Hosts, g.Hosts)
+		for j := range clone.Groups[i].Hosts {
+			clone.Groups[i].Hosts[j].IPs = slices.Clone(g.Hosts[j].IPs)
+			clone.Groups[i].Hosts[j].Metadata = maps.Clone(g.Hosts[j].Metadata)
+		}
 	}
 
 	for i, p := range c.Presets {
@@ -510,48 +1090,270 @@ func (c *Config) Clone() *Config {
 	return clone
 }
 
+// ConfigDiff lists the hosts (by alias), groups and presets (by name) that
+// differ between two configurations.
+type ConfigDiff struct {
+	HostsAdded     []string
+	HostsRemoved   []string
+	HostsChanged   []string
+	GroupsAdded    []string
+	GroupsRemoved  []string
+	PresetsAdded   []string
+	PresetsRemoved []string
+	PresetsChanged []string
+}
+
+// IsEmpty returns true if the diff contains no changes.
+func (d ConfigDiff) IsEmpty() bool {
+	return len(d.HostsAdded) == 0 && len(d.HostsRemoved) == 0 && len(d.HostsChanged) == 0 &&
+		len(d.GroupsAdded) == 0 && len(d.GroupsRemoved) == 0 &&
+		len(d.PresetsAdded) == 0 && len(d.PresetsRemoved) == 0 && len(d.PresetsChanged) == 0
+}
+
+// Diff returns the changes needed to turn c into other. Settings are ignored.
+func (c *Config) Diff(other *Config) ConfigDiff {
+	var diff ConfigDiff
+
+	type placedHost struct {
+		host  Host
+		group string
+	}
+	hostsByAlias := func(cfg *Config) map[string]placedHost {
+		hosts := make(map[string]placedHost)
+		for _, g := range cfg.Groups {
+			for _, h := range g.Hosts {
+				// Creation time is bookkeeping, not something an import changes
+				h.CreatedAt = 0
+				hosts[h.Alias] = placedHost{host: h, group: g.Name}
+			}
+		}
+		return hosts
+	}
+
+	oldHosts, newHosts := hostsByAlias(c), hostsByAlias(other)
+	for _, h := range other.GetAllHosts() {
+		old, exists := oldHosts[h.Alias]
+		switch {
+		case !exists:
+			diff.HostsAdded = append(diff.HostsAdded, h.Alias)
+		case !sameHost(old.host, newHosts[h.Alias].host) || old.group != newHosts[h.Alias].group:
+			diff.HostsChanged = append(diff.HostsChanged, h.Alias)
+		}
+	}
+	for _, h := range c.GetAllHosts() {
+		if _, exists := newHosts[h.Alias]; !exists {
+			diff.HostsRemoved = append(diff.HostsRemoved, h.Alias)
+		}
+	}
+
+	oldGroups, newGroups := make(map[string]bool), make(map[string]bool)
+	for _, name := range c.GetGroups() {
+		oldGroups[name] = true
+	}
+	for _, name := range other.GetGroups() {
+		newGroups[name] = true
+		if !oldGroups[name] {
+			diff.GroupsAdded = append(diff.GroupsAdded, name)
+		}
+	}
+	for _, name := range c.GetGroups() {
+		if !newGroups[name] {
+			diff.GroupsRemoved = append(diff.GroupsRemoved, name)
+		}
+	}
+
+	for _, p := range other.Presets {
+		old := c.FindPreset(p.Name)
+		switch {
+		case old == nil:
+			diff.PresetsAdded = append(diff.PresetsAdded, p.Name)
+		case !slices.Equal(old.Enable, p.Enable) || !slices.Equal(old.Disable, p.Disable):
+			diff.PresetsChanged = append(diff.PresetsChanged, p.Name)
+		}
+	}
+	for _, p := range c.Presets {
+		if other.FindPreset(p.Name) == nil {
+			diff.PresetsRemoved = append(diff.PresetsRemoved, p.Name)
+		}
+	}
+
+	return diff
+}
+
+// Merge adds other's groups, hosts and presets to c. Hosts and presets that
+// c already has with the same contents are left alone. One that exists with
+// different contents, or a host in a different group, is not overwritten;
+// it is returned as a conflict instead. Settings are ignored.
+func (c *Config) Merge(other *Config) (conflicts []string) {
+	for _, g := range other.Groups {
+		groupIdx := slices.IndexFunc(c.Groups, func(existing Group) bool { return existing.Name == g.Name })
+		if groupIdx < 0 {
+			c.Groups = append(c.Groups, Group{Name: g.Name, Color: g.Color, Hosts: []Host{}})
+			groupIdx = len(c.Groups) - 1
+		}
+
+		for _, h := range g.Hosts {
+			existing, existingGroup := c.FindHostByAlias(h.Alias)
+			if existing == nil {
+				c.Groups[groupIdx].Hosts = append(c.Groups[groupIdx].Hosts, h)
+				continue
+			}
+			if existingGroup.Name != g.Name {
+				conflicts = append(conflicts, fmt.Sprintf("host %s is in group %s, not %s", h.Alias, existingGroup.Name, g.Name))
+				continue
+			}
+			// Creation time is bookkeeping, not part of the entry
+			a, b := *existing, h
+			a.CreatedAt, b.CreatedAt = 0, 0
+			if !sameHost(a, b) {
+				conflicts = append(conflicts, fmt.Sprintf("host %s differs from the current one", h.Alias))
+			}
+		}
+	}
+
+	for _, p := range other.Presets {
+		existing := c.FindPreset(p.Name)
+		switch {
+		case existing == nil:
+			c.Presets = append(c.Presets, Preset{Name: p.Name, Enable: slices.Clone(p.Enable), Disable: slices.Clone(p.Disable)})
+		case !slices.Equal(existing.Enable, p.Enable) || !slices.Equal(existing.Disable, p.Disable):
+			conflicts = append(conflicts, fmt.Sprintf("preset %s differs from the current one", p.Name))
+		}
+	}
+
+	return conflicts
+}
+
+// Export formats.
+const (
+	ExportYAML = "yaml"
+	ExportJSON = "json"
+)
+
+// Export serializes the config as YAML, the config file format, or JSON.
+// Either can be read back by Parse, since JSON is valid YAML.
+func (c *Config) Export(format string) ([]byte, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	switch format {
+	case "", ExportYAML:
+		return data, nil
+	case ExportJSON:
+		// Round-trip through YAML so the JSON uses the config file's keys
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to convert config: %w", err)
+		}
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return append(out, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q (use yaml or json)", format)
+	}
+}
+
+// normalizeGroups moves hosts out of groups with a blank name, as left by a
+// hand edit, into the default group, creating it if needed. It returns a
+// description of each fix-up.
+func (c *Config) normalizeGroups() []string {
+	var fixes []string
+	var orphans []Host
+	kept := c.Groups[:0]
+	for _, g := range c.Groups {
+		if strings.TrimSpace(g.Name) != "" {
+			kept = append(kept, g)
+			continue
+		}
+		orphans = append(orphans, g.Hosts...)
+		if len(g.Hosts) == 0 {
+			fixes = append(fixes, "removed empty group with a blank name")
+		} else {
+			fixes = append(fixes, fmt.Sprintf("moved %d host(s) from a group with a blank name to %q", len(g.Hosts), defaultGroupName))
+		}
+	}
+	c.Groups = kept
+
+	if len(orphans) == 0 {
+		return fixes
+	}
+	for i := range c.Groups {
+		if c.Groups[i].Name == defaultGroupName {
+			c.Groups[i].Hosts = append(c.Groups[i].Hosts, orphans...)
+			return fixes
+		}
+	}
+	c.Groups = append(c.Groups, Group{Name: defaultGroupName, Hosts: orphans})
+	return fixes
+}
+
+// normalizeAddresses makes IP mirror the first of IPs, and drops IPs when it
+// lists a single address, so hand-edited hosts follow the same shape as
+// those written by lolcathost.
+func (c *Config) normalizeAddresses() {
+	for i := range c.Groups {
+		for j := range c.Groups[i].Hosts {
+			h := &c.Groups[i].Hosts[j]
+			if len(h.IPs) > 0 {
+				h.SetAddresses(h.IPs)
+			}
+		}
+	}
+}
+
 // EnsureDefaultGroup ensures at least one group exists, creating "default" if needed.
 func (c *Config) EnsureDefaultGroup() {
 	if len(c.Groups) == 0 {
 		c.Groups = append(c.Groups, Group{
-			Name:  "default",
+			Name:  defaultGroupName,
 			Hosts: []Host{},
 		})
 	}
 }
 
-// Save writes the configuration to the file.
+// Save writes the configuration to the file. It returns ErrConfigChanged
+// instead if the file was edited since it was last loaded or saved, for
+// example by hand before the watcher picked the change up, or with a syntax
+// error the watcher couldn't load.
 func (m *Manager) Save() error {
-	m.mu.RLock()
-	cfg := m.config
-	m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if cfg == nil {
+	if m.config == nil {
 		return fmt.Errorf("no config loaded")
 	}
 
-	data, err := yaml.Marshal(cfg)
+	data, err := yaml.Marshal(m.config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
+	if m.diskHash != nil {
+		current, err := os.ReadFile(m.path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		if err == nil && !bytes.Equal(hashConfig(current), m.diskHash) {
+			return fmt.Errorf("%w; reload it or merge the edits before saving", ErrConfigChanged)
+		}
+	}
+
 	// #nosec G306 - Config file permissions are intentionally 0644
 	if err := os.WriteFile(m.path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
+	m.diskHash = hashConfig(data)
 
 	return nil
 }
 
-// CreateDefault creates a default configuration file.
-func CreateDefault(path string) error {
-	dir := filepath.Dir(path)
-	// #nosec G301 - Config directory permissions are intentionally 0755
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	cfg := &Config{
+// Default returns the configuration a fresh install starts with.
+func Default() *Config {
+	return &Config{
 		Settings: Settings{
 			AutoApply:   true,
 			FlushMethod: FlushMethodAuto,
@@ -582,8 +1384,17 @@ func CreateDefault(path string) error {
 			},
 		},
 	}
+}
+
+// CreateDefault creates a default configuration file.
+func CreateDefault(path string) error {
+	dir := filepath.Dir(path)
+	// #nosec G301 - Config directory permissions are intentionally 0755
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
 
-	data, err := yaml.Marshal(cfg)
+	data, err := yaml.Marshal(Default())
 	if err != nil {
 		return fmt.Errorf("failed to marshal default config: %w", err)
 	}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -83,6 +84,37 @@ func TestConfig_FindPreset(t *testing.T) {
 	})
 }
 
+func TestConfig_ExpireHosts(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{
+				Name: "dev",
+				Hosts: []Host{
+					{Domain: "a.local", IP: "127.0.0.1", Alias: "a", Enabled: true, ExpiresAt: 100},
+					{Domain: "b.local", IP: "127.0.0.1", Alias: "b", Enabled: true, ExpiresAt: 200},
+					{Domain: "c.local", IP: "127.0.0.1", Alias: "c", Enabled: true},
+				},
+			},
+		},
+	}
+
+	assert.Empty(t, cfg.ExpireHosts(99))
+	assert.Equal(t, []string{"a"}, cfg.ExpireHosts(100))
+	assert.False(t, cfg.Groups[0].Hosts[0].Enabled)
+	assert.Zero(t, cfg.Groups[0].Hosts[0].ExpiresAt)
+	assert.True(t, cfg.Groups[0].Hosts[1].Enabled)
+	assert.True(t, cfg.Groups[0].Hosts[2].Enabled)
+
+	// Switching a host on or off by hand drops its expiry
+	cfg.SetHostEnabled("b", true)
+	assert.Zero(t, cfg.Groups[0].Hosts[1].ExpiresAt)
+	assert.Empty(t, cfg.ExpireHosts(1000))
+
+	assert.True(t, cfg.SetHostExpiry("c", 300))
+	assert.False(t, cfg.SetHostExpiry("missing", 300))
+	assert.Equal(t, []string{"c"}, cfg.ExpireHosts(300))
+}
+
 func TestConfig_SetHostEnabled(t *testing.T) {
 	cfg := &Config{
 		Groups: []Group{
@@ -142,6 +174,128 @@ func TestConfig_ApplyPreset(t *testing.T) {
 	})
 }
 
+func TestConfig_ApplyPreset_Sticky(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{
+				Name: "dev",
+				Hosts: []Host{
+					{Domain: "a.com", IP: "127.0.0.1", Alias: "a", Enabled: false, Sticky: true},
+					{Domain: "b.com", IP: "127.0.0.1", Alias: "b", Enabled: true, Sticky: true},
+				},
+			},
+		},
+		Presets: []Preset{
+			{Name: "swap", Enable: []string{"a"}, Disable: []string{"b"}},
+		},
+	}
+
+	enable, disable, err := cfg.PresetChanges("swap")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, enable)
+	assert.Empty(t, disable)
+
+	require.NoError(t, cfg.ApplyPreset("swap"))
+	assert.True(t, cfg.Groups[0].Hosts[0].Enabled, "sticky hosts can still be enabled")
+	assert.True(t, cfg.Groups[0].Hosts[1].Enabled, "sticky hosts are never disabled")
+
+	assert.True(t, cfg.SetHostSticky("b", false))
+	require.NoError(t, cfg.ApplyPreset("swap"))
+	assert.False(t, cfg.Groups[0].Hosts[1].Enabled)
+
+	assert.False(t, cfg.SetHostSticky("missing", true))
+}
+
+func TestConfig_PresetChanges(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{
+				Name: "dev",
+				Hosts: []Host{
+					{Domain: "a.com", IP: "127.0.0.1", Alias: "a", Enabled: false},
+					{Domain: "b.com", IP: "127.0.0.1", Alias: "b", Enabled: true},
+					{Domain: "c.com", IP: "127.0.0.1", Alias: "c", Enabled: false},
+				},
+			},
+		},
+		Presets: []Preset{
+			{Name: "swap", Enable: []string{"a", "missing"}, Disable: []string{"b", "c"}},
+		},
+	}
+
+	t.Run("lists only real changes", func(t *testing.T) {
+		enable, disable, err := cfg.PresetChanges("swap")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a"}, enable)
+		assert.Equal(t, []string{"b"}, disable)
+
+		// Previewing doesn't apply anything
+		assert.False(t, cfg.Groups[0].Hosts[0].Enabled)
+		assert.True(t, cfg.Groups[0].Hosts[1].Enabled)
+	})
+
+	t.Run("nonexistent preset", func(t *testing.T) {
+		_, _, err := cfg.PresetChanges("nonexistent")
+		assert.Error(t, err)
+	})
+}
+
+func TestConfig_PresetMissingAliases(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{Name: "dev", Hosts: []Host{{Domain: "a.com", IP: "127.0.0.1", Alias: "a"}}},
+		},
+		Presets: []Preset{
+			{Name: "stale", Enable: []string{"a", "gone"}, Disable: []string{"old", "gone"}},
+			{Name: "fresh", Enable: []string{"a"}},
+		},
+	}
+
+	missing, err := cfg.PresetMissingAliases("stale")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gone", "old"}, missing)
+
+	missing, err = cfg.PresetMissingAliases("fresh")
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	_, err = cfg.PresetMissingAliases("nonexistent")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"stale: gone", "stale: old"}, cfg.UnknownPresetAliases())
+}
+
+func TestManager_With(t *testing.T) {
+	t.Run("no config loaded", func(t *testing.T) {
+		m := NewManager(filepath.Join(t.TempDir(), "missing.yaml"))
+
+		called := false
+		err := m.With(func(*Config) error {
+			called = true
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrNoConfig)
+		assert.False(t, called)
+		assert.PanicsWithValue(t, ErrNoConfig, func() { m.MustGet() })
+	})
+
+	t.Run("loaded config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, CreateDefault(path))
+		m := NewManager(path)
+		require.NoError(t, m.Load())
+
+		err := m.With(func(cfg *Config) error {
+			return cfg.AddGroup("with")
+		})
+		require.NoError(t, err)
+		assert.Contains(t, m.MustGet().GetGroups(), "with")
+
+		sentinel := errors.New("callback failed")
+		assert.Equal(t, sentinel, m.With(func(*Config) error { return sentinel }))
+	})
+}
+
 func TestManager_LoadAndGet(t *testing.T) {
 	// Create temp config file
 	tmpDir := t.TempDir()
@@ -210,6 +364,63 @@ func TestManager_Save(t *testing.T) {
 	assert.True(t, cfg2.Groups[0].Hosts[0].Enabled)
 }
 
+func TestManager_SwitchPath(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "config.yaml")
+	otherPath := filepath.Join(dir, "other.yaml")
+	brokenPath := filepath.Join(dir, "broken.yaml")
+	require.NoError(t, CreateDefault(mainPath))
+	require.NoError(t, os.WriteFile(otherPath, []byte("groups:\n  - name: other\n    hosts: []\n"), 0644))
+	require.NoError(t, os.WriteFile(brokenPath, []byte("groups: ["), 0644))
+
+	m := NewManager(mainPath)
+	require.NoError(t, m.Load())
+
+	assert.Error(t, m.SwitchPath(brokenPath))
+	assert.Equal(t, mainPath, m.Path(), "a bad file leaves the current one in use")
+
+	require.NoError(t, m.SwitchPath(otherPath))
+	assert.Equal(t, otherPath, m.Path())
+	assert.Equal(t, "other", m.Get().Groups[0].Name)
+
+	// Saves go to the new file
+	require.NoError(t, m.Get().AddGroup("extra"))
+	require.NoError(t, m.Save())
+	data, err := os.ReadFile(otherPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "extra")
+}
+
+func TestManager_Save_ChangedOnDisk(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, CreateDefault(configPath))
+
+	manager := NewManager(configPath)
+	require.NoError(t, manager.Load())
+
+	// Saving twice in a row is fine; the daemon's own writes don't count
+	require.NoError(t, manager.Save())
+	require.NoError(t, manager.Save())
+
+	edited := "groups:\n  - name: edited\n    hosts: []\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(edited), 0644))
+
+	manager.Get().Groups[0].Hosts[0].Enabled = true
+	err := manager.Save()
+	require.ErrorIs(t, err, ErrConfigChanged)
+
+	// The hand edit is left alone
+	content, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, edited, string(content))
+
+	// After a reload the daemon can save again
+	require.NoError(t, manager.Reload())
+	assert.Equal(t, "edited", manager.Get().Groups[0].Name)
+	require.NoError(t, manager.Save())
+}
+
 func TestCreateDefault(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "subdir", "config.yaml")
@@ -257,11 +468,14 @@ func TestFlushMethod(t *testing.T) {
 		FlushMethodDscacheutil,
 		FlushMethodKillall,
 		FlushMethodBoth,
+		FlushMethodSystemd,
+		FlushMethodNscd,
 	}
 
 	for _, m := range methods {
 		t.Run(string(m), func(t *testing.T) {
 			assert.NotEmpty(t, string(m))
+			assert.NoError(t, validateSettings(&Settings{FlushMethod: m}))
 		})
 	}
 }
@@ -278,6 +492,26 @@ func TestDefaultConfigPath(t *testing.T) {
 	assert.Contains(t, path, "config.yaml")
 }
 
+func TestNormalizeAlias(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"api-local", "api-local"},
+		{"My App", "my-app"},
+		{"api.example.com", "api-example-com"},
+		{"snake_case_name", "snake-case-name"},
+		{"  spaced   out  ", "spaced-out"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NormalizeAlias(tt.input))
+		})
+	}
+}
+
 func TestConfig_GenerateAlias(t *testing.T) {
 	cfg := &Config{
 		Groups: []Group{
@@ -317,6 +551,7 @@ func TestConfig_AddHost(t *testing.T) {
 		require.NoError(t, err)
 		assert.Len(t, cfg.Groups[0].Hosts, 1)
 		assert.Equal(t, "test.local", cfg.Groups[0].Hosts[0].Domain)
+		assert.NotZero(t, cfg.Groups[0].Hosts[0].CreatedAt)
 	})
 
 	t.Run("add to new group", func(t *testing.T) {
@@ -346,6 +581,353 @@ func TestConfig_AddHost(t *testing.T) {
 	})
 }
 
+func TestConfig_MoveHost(t *testing.T) {
+	newCfg := func() *Config {
+		return &Config{
+			Groups: []Group{
+				{
+					Name: "dev",
+					Hosts: []Host{
+						{Domain: "a.com", IP: "127.0.0.1", Alias: "a", Enabled: true},
+						{Domain: "b.com", IP: "127.0.0.1", Alias: "b", Enabled: false},
+					},
+				},
+				{Name: "staging", Hosts: []Host{}},
+			},
+		}
+	}
+
+	t.Run("move to existing group", func(t *testing.T) {
+		cfg := newCfg()
+		err := cfg.MoveHost("a", "staging")
+		require.NoError(t, err)
+
+		host, group := cfg.FindHostByAlias("a")
+		require.NotNil(t, host)
+		assert.Equal(t, "staging", group.Name)
+		assert.Equal(t, "a.com", host.Domain)
+		assert.True(t, host.Enabled)
+		assert.Len(t, cfg.Groups[0].Hosts, 1)
+	})
+
+	t.Run("move creates group", func(t *testing.T) {
+		cfg := newCfg()
+		err := cfg.MoveHost("b", "prod")
+		require.NoError(t, err)
+
+		_, group := cfg.FindHostByAlias("b")
+		require.NotNil(t, group)
+		assert.Equal(t, "prod", group.Name)
+		assert.Len(t, cfg.Groups, 3)
+	})
+
+	t.Run("same group is a no-op", func(t *testing.T) {
+		cfg := newCfg()
+		err := cfg.MoveHost("a", "dev")
+		require.NoError(t, err)
+		assert.Len(t, cfg.Groups[0].Hosts, 2)
+	})
+
+	t.Run("alias not found", func(t *testing.T) {
+		cfg := newCfg()
+		err := cfg.MoveHost("missing", "dev")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "alias not found")
+	})
+}
+
+func TestConfig_DomainConflicts(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{
+				Name: "dev",
+				Hosts: []Host{
+					{Domain: "api.com", IP: "127.0.0.1", Alias: "api-local", Enabled: true},
+					{Domain: "web.com", IP: "127.0.0.1", Alias: "web-local", Enabled: true},
+				},
+			},
+			{
+				Name: "staging",
+				Hosts: []Host{
+					{Domain: "api.com", IP: "10.0.0.1", Alias: "api-staging", Enabled: true},
+					{Domain: "web.com", IP: "10.0.0.1", Alias: "web-staging", Enabled: false},
+					{Domain: "db.com", IP: "10.0.0.2", Alias: "db-a", Enabled: true},
+					{Domain: "db.com", IP: "10.0.0.2", Alias: "db-b", Enabled: true},
+				},
+			},
+		},
+	}
+
+	conflicts := cfg.DomainConflicts()
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "api.com", conflicts[0].Domain)
+	assert.Equal(t, []string{"api-local", "api-staging"}, conflicts[0].Aliases)
+	assert.Equal(t, []string{"127.0.0.1", "10.0.0.1"}, conflicts[0].IPs)
+}
+
+func TestConfig_Merge(t *testing.T) {
+	newCfg := func() *Config {
+		return &Config{
+			Groups: []Group{{Name: "dev", Hosts: []Host{
+				{Domain: "a.local", IP: "127.0.0.1", Alias: "a", Enabled: true, CreatedAt: 1},
+			}}},
+			Presets: []Preset{{Name: "work", Enable: []string{"a"}}},
+		}
+	}
+
+	t.Run("adds new entries and keeps identical ones", func(t *testing.T) {
+		cfg := newCfg()
+		other := &Config{
+			Groups: []Group{
+				{Name: "dev", Hosts: []Host{
+					{Domain: "a.local", IP: "127.0.0.1", Alias: "a", Enabled: true, CreatedAt: 2},
+					{Domain: "b.local", IP: "127.0.0.1", Alias: "b"},
+				}},
+				{Name: "team", Color: "blue", Hosts: []Host{{Domain: "c.local", IP: "10.0.0.1", Alias: "c"}}},
+			},
+			Presets: []Preset{{Name: "work", Enable: []string{"a"}}, {Name: "team", Enable: []string{"c"}}},
+		}
+
+		assert.Empty(t, cfg.Merge(other))
+		require.Len(t, cfg.Groups, 2)
+		assert.Len(t, cfg.Groups[0].Hosts, 2)
+		assert.Equal(t, int64(1), cfg.Groups[0].Hosts[0].CreatedAt)
+		assert.Equal(t, "blue", cfg.Groups[1].Color)
+		assert.Len(t, cfg.Presets, 2)
+	})
+
+	t.Run("reports conflicts", func(t *testing.T) {
+		cfg := newCfg()
+		other := &Config{
+			Groups: []Group{
+				{Name: "dev", Hosts: []Host{{Domain: "a.local", IP: "10.0.0.9", Alias: "a", Enabled: true}}},
+				{Name: "team", Hosts: []Host{{Domain: "a.local", IP: "127.0.0.1", Alias: "a", Enabled: true}}},
+			},
+			Presets: []Preset{{Name: "work", Disable: []string{"a"}}},
+		}
+
+		assert.Equal(t, []string{
+			"host a differs from the current one",
+			"host a is in group dev, not team",
+			"preset work differs from the current one",
+		}, cfg.Merge(other))
+		assert.Equal(t, "127.0.0.1", cfg.Groups[0].Hosts[0].IP)
+	})
+}
+
+func TestConfig_Export(t *testing.T) {
+	cfg := Default()
+
+	yamlOut, err := cfg.Export(ExportYAML)
+	require.NoError(t, err)
+	parsed, err := Parse(yamlOut)
+	require.NoError(t, err)
+	assert.True(t, cfg.Diff(parsed).IsEmpty())
+
+	jsonOut, err := cfg.Export(ExportJSON)
+	require.NoError(t, err)
+	assert.Contains(t, string(jsonOut), `"flushMethod": "auto"`)
+	parsed, err = Parse(jsonOut)
+	require.NoError(t, err)
+	assert.True(t, cfg.Diff(parsed).IsEmpty())
+	assert.Equal(t, cfg.Settings, parsed.Settings)
+
+	_, err = cfg.Export("toml")
+	assert.Error(t, err)
+}
+
+func TestConfig_Diff(t *testing.T) {
+	current := &Config{
+		Groups: []Group{
+			{
+				Name: "dev",
+				Hosts: []Host{
+					{Domain: "a.com", IP: "127.0.0.1", Alias: "a", Enabled: true},
+					{Domain: "b.com", IP: "127.0.0.1", Alias: "b", Enabled: false},
+					{Domain: "c.com", IP: "127.0.0.1", Alias: "c", Enabled: false},
+				},
+			},
+			{Name: "old"},
+		},
+		Presets: []Preset{
+			{Name: "keep", Enable: []string{"a"}},
+			{Name: "change", Enable: []string{"a"}},
+			{Name: "drop", Enable: []string{"b"}},
+		},
+	}
+
+	incoming := current.Clone()
+	incoming.Groups[0].Hosts[1].IP = "10.0.0.1"
+	incoming.Groups[0].Hosts = incoming.Groups[0].Hosts[:2]
+	incoming.Groups[1] = Group{Name: "new", Hosts: []Host{{Domain: "d.com", IP: "127.0.0.1", Alias: "d"}}}
+	incoming.Presets = []Preset{
+		{Name: "keep", Enable: []string{"a"}},
+		{Name: "change", Enable: []string{"a", "b"}},
+		{Name: "fresh", Disable: []string{"a"}},
+	}
+
+	diff := current.Diff(incoming)
+	assert.Equal(t, []string{"d"}, diff.HostsAdded)
+	assert.Equal(t, []string{"c"}, diff.HostsRemoved)
+	assert.Equal(t, []string{"b"}, diff.HostsChanged)
+	assert.Equal(t, []string{"new"}, diff.GroupsAdded)
+	assert.Equal(t, []string{"old"}, diff.GroupsRemoved)
+	assert.Equal(t, []string{"fresh"}, diff.PresetsAdded)
+	assert.Equal(t, []string{"drop"}, diff.PresetsRemoved)
+	assert.Equal(t, []string{"change"}, diff.PresetsChanged)
+	assert.False(t, diff.IsEmpty())
+
+	assert.True(t, current.Diff(current.Clone()).IsEmpty())
+
+	t.Run("metadata", func(t *testing.T) {
+		tagged := current.Clone()
+		require.NoError(t, tagged.SetHostMetadata("a", map[string]string{"owner": "alice"}))
+		assert.Equal(t, []string{"a"}, current.Diff(tagged).HostsChanged)
+
+		clone := tagged.Clone()
+		clone.Groups[0].Hosts[0].Metadata["owner"] = "bob"
+		assert.Equal(t, "alice", tagged.Groups[0].Hosts[0].Metadata["owner"], "clone must not share metadata")
+
+		require.NoError(t, tagged.SetHostMetadata("a", nil))
+		assert.Nil(t, tagged.Groups[0].Hosts[0].Metadata)
+		assert.Error(t, tagged.SetHostMetadata("missing", nil))
+	})
+
+	t.Run("clone keeps group color", func(t *testing.T) {
+		colored := &Config{Groups: []Group{{Name: "dev", Color: "blue"}}}
+		assert.Equal(t, "blue", colored.Clone().Groups[0].Color)
+	})
+
+	t.Run("ignores creation time", func(t *testing.T) {
+		stamped := current.Clone()
+		stamped.Groups[0].Hosts[0].CreatedAt = 1700000000
+		assert.True(t, current.Diff(stamped).IsEmpty())
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		cfg, err := Parse([]byte("groups:\n  - name: dev\n    hosts:\n      - domain: a.local\n        ip: 127.0.0.1\n        alias: a-local\n"))
+		require.NoError(t, err)
+		assert.Len(t, cfg.GetAllHosts(), 1)
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		_, err := Parse([]byte("groups: ["))
+		assert.Error(t, err)
+	})
+
+	t.Run("fails validation", func(t *testing.T) {
+		_, err := Parse([]byte("groups:\n  - name: dev\n    hosts:\n      - domain: a.local\n        ip: not-an-ip\n        alias: a-local\n"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid config")
+	})
+
+	t.Run("moves hosts from blank-named groups to default", func(t *testing.T) {
+		data := "groups:\n" +
+			"  - name: dev\n    hosts:\n      - {domain: a.local, ip: 127.0.0.1, alias: a-local}\n" +
+			"  - name: \"  \"\n    hosts:\n      - {domain: b.local, ip: 127.0.0.1, alias: b-local}\n" +
+			"  - hosts: []\n"
+		cfg, err := Parse([]byte(data))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dev", "default"}, cfg.GetGroups())
+		_, group := cfg.FindHostByAlias("b-local")
+		require.NotNil(t, group)
+		assert.Equal(t, "default", group.Name)
+	})
+}
+
+func TestParse_MultipleIPs(t *testing.T) {
+	t.Run("ips fills ip", func(t *testing.T) {
+		data := "groups:\n  - name: dev\n    hosts:\n      - {domain: a.local, ips: [127.0.0.1, \"::1\"], alias: a-local}\n"
+		cfg, err := Parse([]byte(data))
+		require.NoError(t, err)
+
+		host, _ := cfg.FindHostByAlias("a-local")
+		require.NotNil(t, host)
+		assert.Equal(t, "127.0.0.1", host.IP)
+		assert.Equal(t, []string{"127.0.0.1", "::1"}, host.Addresses())
+	})
+
+	t.Run("single entry in ips collapses to ip", func(t *testing.T) {
+		data := "groups:\n  - name: dev\n    hosts:\n      - {domain: a.local, ips: [\"::1\"], alias: a-local}\n"
+		cfg, err := Parse([]byte(data))
+		require.NoError(t, err)
+
+		host, _ := cfg.FindHostByAlias("a-local")
+		require.NotNil(t, host)
+		assert.Equal(t, "::1", host.IP)
+		assert.Nil(t, host.IPs)
+	})
+
+	t.Run("invalid address in ips", func(t *testing.T) {
+		data := "groups:\n  - name: dev\n    hosts:\n      - {domain: a.local, ips: [127.0.0.1, nope], alias: a-local}\n"
+		_, err := Parse([]byte(data))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid IP address: nope")
+	})
+}
+
+func TestConfig_MultipleIPs(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.AddHost("a.local", "127.0.0.1, ::1", "a", "dev", true))
+
+	host, _ := cfg.FindHostByAlias("a")
+	require.NotNil(t, host)
+	assert.Equal(t, "127.0.0.1", host.IP)
+	assert.Equal(t, []string{"127.0.0.1", "::1"}, host.IPs)
+
+	clone := cfg.Clone()
+	clone.Groups[0].Hosts[0].IPs[1] = "::2"
+	assert.Equal(t, "::1", host.IPs[1], "clones don't share addresses")
+
+	require.NoError(t, cfg.UpdateHost("a", "a.local", "10.0.0.1", "a", "dev"))
+	host, _ = cfg.FindHostByAlias("a")
+	assert.Equal(t, "10.0.0.1", host.IP)
+	assert.Nil(t, host.IPs)
+
+	require.NoError(t, cfg.UpdateHost("a", "a.local", "10.0.0.1,10.0.0.2", "a", "dev"))
+	updated, err := cfg.SetGroupIP("dev", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated, "a host with extra addresses is pointed at the single IP")
+	host, _ = cfg.FindHostByAlias("a")
+	assert.Equal(t, []string{"10.0.0.1"}, host.Addresses())
+}
+
+func TestConfig_UpdateHost_KeepsDescription(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, cfg.AddHost("a.local", "127.0.0.1", "a", "dev", true))
+	require.NoError(t, cfg.SetHostDescription("a", "staging API"))
+
+	require.NoError(t, cfg.UpdateHost("a", "b.local", "127.0.0.2", "b", "dev"))
+	host, _ := cfg.FindHostByAlias("b")
+	require.NotNil(t, host)
+	assert.Equal(t, "staging API", host.Description)
+
+	require.NoError(t, cfg.UpdateHost("b", "b.local", "127.0.0.2", "b", "other"))
+	host, _ = cfg.FindHostByAlias("b")
+	require.NotNil(t, host)
+	assert.Equal(t, "staging API", host.Description)
+
+	assert.Error(t, cfg.SetHostDescription("missing", "x"))
+}
+
+func TestConfig_NormalizeGroups(t *testing.T) {
+	cfg := &Config{Groups: []Group{
+		{Name: "default", Hosts: []Host{{Alias: "a"}}},
+		{Name: "", Hosts: []Host{{Alias: "b"}, {Alias: "c"}}},
+		{Name: "dev"},
+	}}
+
+	fixes := cfg.normalizeGroups()
+	require.Len(t, fixes, 1)
+	assert.Contains(t, fixes[0], "moved 2 host(s)")
+	assert.Equal(t, []string{"default", "dev"}, cfg.GetGroups())
+	assert.Len(t, cfg.Groups[0].Hosts, 3)
+
+	assert.Empty(t, cfg.normalizeGroups())
+}
+
 func TestConfig_AddGroup(t *testing.T) {
 	t.Run("add new group", func(t *testing.T) {
 		cfg := &Config{Groups: []Group{}}
@@ -361,6 +943,15 @@ func TestConfig_AddGroup(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "group already exists")
 	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		cfg := &Config{Groups: []Group{}}
+		for _, name := range []string{"dev team", "dev.local", "dev\x00"} {
+			err := cfg.AddGroup(name)
+			assert.Error(t, err, name)
+		}
+		assert.Empty(t, cfg.Groups)
+	})
 }
 
 func TestConfig_DeleteGroup(t *testing.T) {
@@ -403,6 +994,111 @@ func TestConfig_RenameGroup(t *testing.T) {
 	})
 }
 
+func TestConfig_SetGroupEnabled(t *testing.T) {
+	cfg := &Config{Groups: []Group{
+		{Name: "dev", Hosts: []Host{
+			{Domain: "a.local", IP: "127.0.0.1", Alias: "a", Enabled: true},
+			{Domain: "b.local", IP: "127.0.0.1", Alias: "b"},
+		}},
+		{Name: "other", Hosts: []Host{{Domain: "c.local", IP: "127.0.0.1", Alias: "c"}}},
+	}}
+
+	updated, err := cfg.SetGroupEnabled("dev", true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	assert.True(t, cfg.Groups[0].Hosts[0].Enabled)
+	assert.True(t, cfg.Groups[0].Hosts[1].Enabled)
+	assert.False(t, cfg.Groups[1].Hosts[0].Enabled)
+
+	updated, err = cfg.SetGroupEnabled("dev", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+
+	_, err = cfg.SetGroupEnabled("missing", true)
+	assert.Error(t, err)
+}
+
+func TestConfig_SetGroupIP(t *testing.T) {
+	newCfg := func() *Config {
+		return &Config{Groups: []Group{
+			{Name: "dev", Hosts: []Host{
+				{Domain: "a.local", IP: "127.0.0.1", Alias: "a"},
+				{Domain: "b.local", IP: "10.0.0.5", Alias: "b"},
+			}},
+			{Name: "other", Hosts: []Host{{Domain: "c.local", IP: "127.0.0.1", Alias: "c"}}},
+		}}
+	}
+
+	t.Run("updates every host in the group", func(t *testing.T) {
+		cfg := newCfg()
+		updated, err := cfg.SetGroupIP("dev", "10.0.0.5")
+		require.NoError(t, err)
+		assert.Equal(t, 1, updated)
+		assert.Equal(t, "10.0.0.5", cfg.Groups[0].Hosts[0].IP)
+		assert.Equal(t, "10.0.0.5", cfg.Groups[0].Hosts[1].IP)
+		assert.Equal(t, "127.0.0.1", cfg.Groups[1].Hosts[0].IP)
+	})
+
+	t.Run("invalid IP", func(t *testing.T) {
+		cfg := newCfg()
+		_, err := cfg.SetGroupIP("dev", "not-an-ip")
+		assert.Error(t, err)
+		assert.Equal(t, "127.0.0.1", cfg.Groups[0].Hosts[0].IP)
+	})
+
+	t.Run("nonexistent group", func(t *testing.T) {
+		cfg := newCfg()
+		_, err := cfg.SetGroupIP("missing", "10.0.0.1")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "group not found")
+	})
+}
+
+func TestConfig_ReorderGroups(t *testing.T) {
+	newCfg := func() *Config {
+		return &Config{Groups: []Group{
+			{Name: "a", Hosts: []Host{{Alias: "a1"}}},
+			{Name: "b"},
+			{Name: "c"},
+		}}
+	}
+
+	t.Run("reorders groups with their hosts", func(t *testing.T) {
+		cfg := newCfg()
+		require.NoError(t, cfg.ReorderGroups([]string{"c", "a", "b"}))
+		assert.Equal(t, []string{"c", "a", "b"}, cfg.GetGroups())
+		assert.Equal(t, "a1", cfg.Groups[1].Hosts[0].Alias)
+	})
+
+	for name, tc := range map[string]struct {
+		names []string
+		want  string
+	}{
+		"unknown":   {[]string{"a", "b", "c", "d"}, "group not found: d"},
+		"duplicate": {[]string{"a", "a", "b", "c"}, "group listed more than once: a"},
+		"missing":   {[]string{"c", "a"}, "missing groups: b"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := newCfg()
+			err := cfg.ReorderGroups(tc.names)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+			assert.Equal(t, []string{"a", "b", "c"}, cfg.GetGroups())
+		})
+	}
+}
+
+func TestConfig_ReorderPresets(t *testing.T) {
+	cfg := &Config{Presets: []Preset{{Name: "work"}, {Name: "home"}}}
+	require.NoError(t, cfg.ReorderPresets([]string{"home", "work"}))
+	assert.Equal(t, "home", cfg.Presets[0].Name)
+	assert.Equal(t, "work", cfg.Presets[1].Name)
+
+	err := cfg.ReorderPresets([]string{"home"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing presets: work")
+}
+
 func TestConfig_GetGroups(t *testing.T) {
 	cfg := &Config{Groups: []Group{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
 	groups := cfg.GetGroups()
@@ -447,6 +1143,46 @@ func TestConfig_AddPreset(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "preset already exists")
 	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		cfg := &Config{Presets: []Preset{}}
+		for _, name := range []string{"work mode", "v1.2", "work\r\n"} {
+			err := cfg.AddPreset(name, nil, nil)
+			assert.Error(t, err, name)
+		}
+		assert.Empty(t, cfg.Presets)
+	})
+}
+
+func TestConfig_PresetConflicts(t *testing.T) {
+	cfg := &Config{
+		Groups: []Group{
+			{
+				Name: "dev",
+				Hosts: []Host{
+					{Domain: "api.local", IP: "127.0.0.1", Alias: "api-dev", Enabled: false},
+					{Domain: "api.local", IP: "10.0.0.1", Alias: "api-staging", Enabled: false},
+					{Domain: "web.local", IP: "127.0.0.1", Alias: "web", Enabled: false},
+				},
+			},
+		},
+	}
+
+	t.Run("no conflicts", func(t *testing.T) {
+		assert.Empty(t, cfg.PresetConflicts([]string{"api-dev", "web"}))
+	})
+
+	t.Run("same domain enabled twice", func(t *testing.T) {
+		warnings := cfg.PresetConflicts([]string{"api-dev", "web", "api-staging"})
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "api-dev")
+		assert.Contains(t, warnings[0], "api-staging")
+		assert.Contains(t, warnings[0], "api.local")
+	})
+
+	t.Run("unknown aliases are ignored", func(t *testing.T) {
+		assert.Empty(t, cfg.PresetConflicts([]string{"missing", "api-dev"}))
+	})
 }
 
 func TestConfig_DeletePreset(t *testing.T) {
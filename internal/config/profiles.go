@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the profile backed by the main config file.
+const DefaultProfile = "default"
+
+// activeProfileFile records which profile the daemon last switched to, so it
+// is restored on restart. It lives next to the main config file.
+const activeProfileFile = "active-profile"
+
+// Profiles manages named configs kept in a profiles directory next to the
+// main config file, e.g. /etc/lolcathost/profiles/work.yaml. The main file
+// itself is the "default" profile.
+type Profiles struct {
+	base string
+}
+
+// NewProfiles returns the profiles that belong to the main config at path.
+func NewProfiles(path string) *Profiles {
+	return &Profiles{base: path}
+}
+
+// Dir returns the directory profile files are kept in.
+func (p *Profiles) Dir() string {
+	return filepath.Join(filepath.Dir(p.base), "profiles")
+}
+
+// Path returns the config file of the named profile.
+func (p *Profiles) Path(name string) (string, error) {
+	if name == DefaultProfile {
+		return p.base, nil
+	}
+	if !ValidateName(name) {
+		return "", fmt.Errorf("invalid profile name: %q", name)
+	}
+	return filepath.Join(p.Dir(), name+".yaml"), nil
+}
+
+// Exists reports whether the named profile has a config file.
+func (p *Profiles) Exists(name string) bool {
+	path, err := p.Path(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// List returns the default profile followed by the others, sorted by name.
+func (p *Profiles) List() ([]string, error) {
+	names := []string{DefaultProfile}
+
+	entries, err := os.ReadDir(p.Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	var others []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if !ok || entry.IsDir() || name == DefaultProfile || !ValidateName(name) {
+			continue
+		}
+		others = append(others, name)
+	}
+	sort.Strings(others)
+
+	return append(names, others...), nil
+}
+
+// Create writes a default config for a new profile. It fails if the profile
+// already exists.
+func (p *Profiles) Create(name string) error {
+	path, err := p.Path(name)
+	if err != nil {
+		return err
+	}
+	if p.Exists(name) {
+		return fmt.Errorf("profile already exists: %s", name)
+	}
+	return CreateDefault(path)
+}
+
+// Active returns the recorded active profile, falling back to the default
+// when none is recorded or the recorded one no longer exists.
+func (p *Profiles) Active() string {
+	data, err := os.ReadFile(p.statePath())
+	if err != nil {
+		return DefaultProfile
+	}
+	name := strings.TrimSpace(string(data))
+	if name == "" || !p.Exists(name) {
+		return DefaultProfile
+	}
+	return name
+}
+
+// SetActive records name as the active profile.
+func (p *Profiles) SetActive(name string) error {
+	if name == DefaultProfile {
+		if err := os.Remove(p.statePath()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to record active profile: %w", err)
+		}
+		return nil
+	}
+
+	// #nosec G306 - The profile name is not sensitive
+	if err := os.WriteFile(p.statePath(), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record active profile: %w", err)
+	}
+	return nil
+}
+
+func (p *Profiles) statePath() string {
+	return filepath.Join(filepath.Dir(p.base), activeProfileFile)
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiles_Path(t *testing.T) {
+	p := NewProfiles("/etc/lolcathost/config.yaml")
+
+	path, err := p.Path(DefaultProfile)
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/lolcathost/config.yaml", path)
+
+	path, err = p.Path("work")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/lolcathost/profiles/work.yaml", path)
+
+	for _, name := range []string{"", "../work", "Work Stuff"} {
+		_, err := p.Path(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestProfiles_CreateAndList(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProfiles(filepath.Join(dir, "config.yaml"))
+
+	names, err := p.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile}, names)
+
+	require.NoError(t, p.Create("work"))
+	require.NoError(t, p.Create("home"))
+	assert.Error(t, p.Create("work"), "existing profiles aren't overwritten")
+	require.NoError(t, os.WriteFile(filepath.Join(p.Dir(), "notes.txt"), nil, 0644))
+
+	names, err = p.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultProfile, "home", "work"}, names)
+
+	path, _ := p.Path("work")
+	assert.NoError(t, NewManager(path).Load(), "new profiles hold a valid default config")
+}
+
+func TestProfiles_Active(t *testing.T) {
+	dir := t.TempDir()
+	p := NewProfiles(filepath.Join(dir, "config.yaml"))
+
+	assert.Equal(t, DefaultProfile, p.Active())
+
+	require.NoError(t, p.Create("work"))
+	require.NoError(t, p.SetActive("work"))
+	assert.Equal(t, "work", p.Active())
+
+	// A recorded profile that was deleted falls back to the default
+	path, _ := p.Path("work")
+	require.NoError(t, os.Remove(path))
+	assert.Equal(t, DefaultProfile, p.Active())
+
+	require.NoError(t, p.SetActive(DefaultProfile))
+	_, err := os.Stat(filepath.Join(dir, activeProfileFile))
+	assert.True(t, os.IsNotExist(err))
+}
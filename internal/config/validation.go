@@ -2,10 +2,17 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // domainRegex validates domain names.
@@ -14,6 +21,12 @@ var domainRegex = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-
 // aliasRegex validates alias names.
 var aliasRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,62}$`)
 
+// metadataKeyRegex validates host metadata keys.
+var metadataKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,62}$`)
+
+// zoneRegex validates IPv6 zone identifiers (interface names like en0 or eth0).
+var zoneRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
 // blockedDomains contains domains that cannot be modified.
 var blockedDomains = map[string]bool{
 	"apple.com":          true,
@@ -26,6 +39,42 @@ var blockedDomains = map[string]bool{
 	"updates.apple.com":  true,
 }
 
+// customBlockedDomains holds the user's settings.blockedDomains, which
+// IsBlockedDomain checks along with blockedDomains.
+var (
+	customBlockedMu      sync.RWMutex
+	customBlockedDomains map[string]bool
+)
+
+// namedColors maps the color names accepted for groups to ANSI color codes.
+var namedColors = map[string]int{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+	"gray":    8,
+	"grey":    8,
+}
+
+// MinIdleTimeout is the shortest idleTimeout setting accepted.
+const MinIdleTimeout = time.Minute
+
+// MinRateLimitWindow is the shortest rateLimitWindow setting accepted.
+const MinRateLimitWindow = time.Second
+
+// MinAuditMaxSize is the smallest auditMaxSize setting accepted, so a typo
+// can't rotate the audit log on every entry.
+const MinAuditMaxSize = 64 << 10
+
+// MinAutoBackupInterval is the shortest autoBackupInterval setting accepted.
+// Periodic backups share the rolling retention with write backups, so a
+// shorter interval would soon push those out.
+const MinAutoBackupInterval = time.Hour
+
 // ValidationError represents a configuration validation error.
 type ValidationError struct {
 	Field   string
@@ -46,11 +95,15 @@ func ValidateConfig(cfg *Config) error {
 		return err
 	}
 
+	// Check hosts against this config's blocked domains rather than those
+	// currently in effect, which may belong to the config being replaced
+	custom := blockedSet(cfg.Settings.BlockedDomains)
+
 	// Track aliases for uniqueness
 	aliases := make(map[string]bool)
 
 	for i, g := range cfg.Groups {
-		if err := validateGroup(&g, i, aliases); err != nil {
+		if err := validateGroup(&g, i, aliases, custom); err != nil {
 			return err
 		}
 	}
@@ -66,7 +119,8 @@ func ValidateConfig(cfg *Config) error {
 
 func validateSettings(s *Settings) error {
 	switch s.FlushMethod {
-	case FlushMethodAuto, FlushMethodDscacheutil, FlushMethodKillall, FlushMethodBoth, "":
+	case FlushMethodAuto, FlushMethodDscacheutil, FlushMethodKillall, FlushMethodBoth,
+		FlushMethodSystemd, FlushMethodNscd, "":
 		// Valid
 	default:
 		return &ValidationError{
@@ -74,10 +128,77 @@ func validateSettings(s *Settings) error {
 			Message: fmt.Sprintf("invalid flush method: %s", s.FlushMethod),
 		}
 	}
+
+	switch s.LogFormat {
+	case LogFormatJSON, LogFormatText, "":
+		// Valid
+	default:
+		return &ValidationError{
+			Field:   "settings.logFormat",
+			Message: fmt.Sprintf("invalid log format: %s (use json or text)", s.LogFormat),
+		}
+	}
+
+	// The TUI pings every 30s to keep its connection open
+	if s.IdleTimeout != 0 && s.IdleTimeout < MinIdleTimeout {
+		return &ValidationError{
+			Field:   "settings.idleTimeout",
+			Message: fmt.Sprintf("idle timeout must be at least %s", MinIdleTimeout),
+		}
+	}
+
+	if s.RateLimit < 0 {
+		return &ValidationError{
+			Field:   "settings.rateLimit",
+			Message: "rate limit must be positive",
+		}
+	}
+	if s.RateLimitWindow != 0 && s.RateLimitWindow < MinRateLimitWindow {
+		return &ValidationError{
+			Field:   "settings.rateLimitWindow",
+			Message: fmt.Sprintf("rate limit window must be at least %s", MinRateLimitWindow),
+		}
+	}
+
+	for i, d := range s.BlockedDomains {
+		if !ValidateDomain(normalizeBlockedDomain(d)) {
+			return &ValidationError{
+				Field:   fmt.Sprintf("settings.blockedDomains[%d]", i),
+				Message: fmt.Sprintf("invalid domain: %s", d),
+			}
+		}
+	}
+
+	if s.MaxBackups < 0 {
+		return &ValidationError{
+			Field:   "settings.maxBackups",
+			Message: "max backups must be at least 1",
+		}
+	}
+
+	if s.AuditMaxSize != 0 && s.AuditMaxSize < MinAuditMaxSize {
+		return &ValidationError{
+			Field:   "settings.auditMaxSize",
+			Message: fmt.Sprintf("audit max size must be at least %d bytes", MinAuditMaxSize),
+		}
+	}
+	if s.AuditMaxFiles < 0 {
+		return &ValidationError{
+			Field:   "settings.auditMaxFiles",
+			Message: "audit max files must be at least 1",
+		}
+	}
+
+	if s.AutoBackupInterval != 0 && s.AutoBackupInterval < MinAutoBackupInterval {
+		return &ValidationError{
+			Field:   "settings.autoBackupInterval",
+			Message: fmt.Sprintf("auto backup interval must be at least %s", MinAutoBackupInterval),
+		}
+	}
 	return nil
 }
 
-func validateGroup(g *Group, index int, aliases map[string]bool) error {
+func validateGroup(g *Group, index int, aliases, blocked map[string]bool) error {
 	if strings.TrimSpace(g.Name) == "" {
 		return &ValidationError{
 			Field:   fmt.Sprintf("groups[%d].name", index),
@@ -85,8 +206,22 @@ func validateGroup(g *Group, index int, aliases map[string]bool) error {
 		}
 	}
 
+	if !ValidateName(g.Name) {
+		return &ValidationError{
+			Field:   fmt.Sprintf("groups[%d].name", index),
+			Message: fmt.Sprintf("invalid group name: %q", g.Name),
+		}
+	}
+
+	if g.Color != "" && !ValidateColor(g.Color) {
+		return &ValidationError{
+			Field:   fmt.Sprintf("groups[%d].color", index),
+			Message: fmt.Sprintf("invalid color: %s (use a color name or a number from 0 to 255)", g.Color),
+		}
+	}
+
 	for i, h := range g.Hosts {
-		if err := validateHost(&h, index, i, aliases); err != nil {
+		if err := validateHost(&h, index, i, aliases, blocked); err != nil {
 			return err
 		}
 	}
@@ -94,7 +229,7 @@ func validateGroup(g *Group, index int, aliases map[string]bool) error {
 	return nil
 }
 
-func validateHost(h *Host, groupIndex, hostIndex int, aliases map[string]bool) error {
+func validateHost(h *Host, groupIndex, hostIndex int, aliases, blocked map[string]bool) error {
 	fieldPrefix := fmt.Sprintf("groups[%d].hosts[%d]", groupIndex, hostIndex)
 
 	// Validate domain
@@ -105,19 +240,23 @@ func validateHost(h *Host, groupIndex, hostIndex int, aliases map[string]bool) e
 		}
 	}
 
-	// Check blocked domains
-	if IsBlockedDomain(h.Domain) {
+	// Check blocked domains, unless root deliberately overrode the check
+	if !h.AllowBlocked && isBlockedBy(h.Domain, blocked) {
 		return &ValidationError{
 			Field:   fieldPrefix + ".domain",
 			Message: fmt.Sprintf("domain is blocked: %s", h.Domain),
 		}
 	}
 
-	// Validate IP
-	if !ValidateIP(h.IP) {
+	// Validate IPs
+	if err := CheckIPs(h.Addresses()); err != nil {
+		field := fieldPrefix + ".ip"
+		if len(h.IPs) > 0 {
+			field = fieldPrefix + ".ips"
+		}
 		return &ValidationError{
-			Field:   fieldPrefix + ".ip",
-			Message: fmt.Sprintf("invalid IP address: %s", h.IP),
+			Field:   field,
+			Message: err.Error(),
 		}
 	}
 
@@ -129,6 +268,22 @@ func validateHost(h *Host, groupIndex, hostIndex int, aliases map[string]bool) e
 		}
 	}
 
+	for key := range h.Metadata {
+		if !ValidateMetadataKey(key) {
+			return &ValidationError{
+				Field:   fieldPrefix + ".metadata",
+				Message: fmt.Sprintf("invalid metadata key: %s", key),
+			}
+		}
+	}
+
+	if !ValidateDescription(h.Description) {
+		return &ValidationError{
+			Field:   fieldPrefix + ".description",
+			Message: fmt.Sprintf("description must be a single line of at most %d characters", MaxDescriptionLength),
+		}
+	}
+
 	// Check alias uniqueness
 	if aliases[h.Alias] {
 		return &ValidationError{
@@ -151,6 +306,13 @@ func validatePreset(p *Preset, index int, aliases map[string]bool) error {
 		}
 	}
 
+	if !ValidateName(p.Name) {
+		return &ValidationError{
+			Field:   fieldPrefix + ".name",
+			Message: fmt.Sprintf("invalid preset name: %q", p.Name),
+		}
+	}
+
 	// Note: We don't validate preset aliases strictly anymore.
 	// Unknown aliases in presets will simply be skipped when applying the preset.
 	// This allows presets to survive when hosts are removed from the config.
@@ -167,13 +329,90 @@ func ValidateDomain(domain string) bool {
 }
 
 // ValidateIP checks if an IP address is valid (IPv4 or IPv6).
+// Link-local IPv6 addresses may carry a zone, e.g. fe80::1%en0.
 func ValidateIP(ip string) bool {
 	if ip == "" {
 		return false
 	}
+	if addr, zone, found := strings.Cut(ip, "%"); found {
+		parsed := net.ParseIP(addr)
+		if parsed == nil || parsed.To4() != nil {
+			return false
+		}
+		return (parsed.IsLinkLocalUnicast() || parsed.IsLinkLocalMulticast()) && zoneRegex.MatchString(zone)
+	}
 	return net.ParseIP(ip) != nil
 }
 
+// SplitIPs splits a comma-separated list of IP addresses, trimming spaces
+// and dropping empty items.
+func SplitIPs(s string) []string {
+	var ips []string
+	for _, ip := range strings.Split(s, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// CheckIPs returns an error naming the first address in ips that is invalid
+// or listed twice, or when ips is empty.
+func CheckIPs(ips []string) error {
+	if len(ips) == 0 {
+		return errors.New("IP address is required")
+	}
+	seen := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		if !ValidateIP(ip) {
+			return fmt.Errorf("invalid IP address: %s", ip)
+		}
+		if seen[ip] {
+			return fmt.Errorf("duplicate IP address: %s", ip)
+		}
+		seen[ip] = true
+	}
+	return nil
+}
+
+// ValidateMetadataKey checks if a host metadata key is valid.
+func ValidateMetadataKey(key string) bool {
+	return metadataKeyRegex.MatchString(key)
+}
+
+// MaxDescriptionLength caps host descriptions, which the TUI shows on a
+// single line under the domain.
+const MaxDescriptionLength = 200
+
+// ValidateDescription checks that a host description is a single line of at
+// most MaxDescriptionLength characters.
+func ValidateDescription(description string) bool {
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return false
+	}
+	return !strings.ContainsFunc(description, unicode.IsControl)
+}
+
+// ColorCode returns the ANSI 256-color code for a group color, which is either
+// a color name or a number from 0 to 255.
+func ColorCode(color string) (int, bool) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if code, ok := namedColors[color]; ok {
+		return code, true
+	}
+	code, err := strconv.Atoi(color)
+	if err != nil || code < 0 || code > 255 {
+		return 0, false
+	}
+	return code, true
+}
+
+// ValidateColor checks if a group color is a known name or a 256-color code.
+func ValidateColor(color string) bool {
+	_, ok := ColorCode(color)
+	return ok
+}
+
 // ValidateAlias checks if an alias is valid.
 func ValidateAlias(alias string) bool {
 	if alias == "" {
@@ -182,18 +421,38 @@ func ValidateAlias(alias string) bool {
 	return aliasRegex.MatchString(alias)
 }
 
-// IsBlockedDomain checks if a domain is in the blocklist.
+// ValidateName checks if a group or preset name is valid. Names follow the
+// alias rules so they stay readable in the config and safe in hosts comments.
+func ValidateName(name string) bool {
+	return ValidateAlias(name)
+}
+
+// IsBlockedDomain checks if a domain is in the built-in blocklist or among
+// the user-defined blocked domains.
 func IsBlockedDomain(domain string) bool {
+	customBlockedMu.RLock()
+	defer customBlockedMu.RUnlock()
+
+	return isBlockedBy(domain, customBlockedDomains)
+}
+
+// isBlockedBy checks if a domain is in the built-in blocklist or custom.
+func isBlockedBy(domain string, custom map[string]bool) bool {
 	domain = strings.ToLower(domain)
+	return matchesBlocked(domain, blockedDomains) || matchesBlocked(domain, custom)
+}
 
+// matchesBlocked reports whether domain is in blocked or a subdomain of an
+// entry in it.
+func matchesBlocked(domain string, blocked map[string]bool) bool {
 	// Check exact match
-	if blockedDomains[domain] {
+	if blocked[domain] {
 		return true
 	}
 
 	// Check if it's a subdomain of a blocked domain
-	for blocked := range blockedDomains {
-		if strings.HasSuffix(domain, "."+blocked) {
+	for b := range blocked {
+		if strings.HasSuffix(domain, "."+b) {
 			return true
 		}
 	}
@@ -201,11 +460,48 @@ func IsBlockedDomain(domain string) bool {
 	return false
 }
 
-// GetBlockedDomains returns a copy of the blocked domains list.
+// SetCustomBlockedDomains replaces the user-defined blocked domains that
+// IsBlockedDomain and GetBlockedDomains merge with the built-in list.
+func SetCustomBlockedDomains(domains []string) {
+	custom := blockedSet(domains)
+
+	customBlockedMu.Lock()
+	defer customBlockedMu.Unlock()
+	customBlockedDomains = custom
+}
+
+// GetBlockedDomains returns the built-in and user-defined blocked domains,
+// sorted.
 func GetBlockedDomains() []string {
-	domains := make([]string, 0, len(blockedDomains))
+	customBlockedMu.RLock()
+	defer customBlockedMu.RUnlock()
+
+	domains := make([]string, 0, len(blockedDomains)+len(customBlockedDomains))
 	for d := range blockedDomains {
 		domains = append(domains, d)
 	}
+	for d := range customBlockedDomains {
+		if !blockedDomains[d] {
+			domains = append(domains, d)
+		}
+	}
+	slices.Sort(domains)
 	return domains
 }
+
+// blockedSet normalizes user-defined blocked domains into a set.
+func blockedSet(domains []string) map[string]bool {
+	set := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		if d = normalizeBlockedDomain(d); d != "" {
+			set[d] = true
+		}
+	}
+	return set
+}
+
+// normalizeBlockedDomain lowercases a blocked domain and drops surrounding
+// space and a trailing dot, so it matches the way hosts are compared.
+func normalizeBlockedDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -53,6 +56,16 @@ func TestValidateIP(t *testing.T) {
 		{"fe80::1", true},
 		{"::ffff:192.168.1.1", true},
 
+		// Zoned IPv6 (link-local only)
+		{"fe80::1%eth0", true},
+		{"fe80::1%en0", true},
+		{"ff02::1%eth0", true},
+		{"2001:db8::1%eth0", false},
+		{"127.0.0.1%eth0", false},
+		{"fe80::1%", false},
+		{"fe80::1%eth 0", false},
+		{"fe80::1%eth0#x", false},
+
 		// Invalid
 		{"", false},
 		{"256.0.0.1", false},
@@ -96,6 +109,59 @@ func TestValidateAlias(t *testing.T) {
 	}
 }
 
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"development", true},
+		{"team-a_2", true},
+
+		{"", false},
+		{"has spaces", false},
+		{"has.dot", false},
+		{"new\nline", false},
+		{"tab\there", false},
+		{"# END LOLCATHOST", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.valid, ValidateName(tt.name), "name: %q", tt.name)
+		})
+	}
+}
+
+func TestColorCode(t *testing.T) {
+	tests := []struct {
+		color string
+		code  int
+		valid bool
+	}{
+		{"blue", 4, true},
+		{"Magenta", 5, true},
+		{"grey", 8, true},
+		{"0", 0, true},
+		{"33", 33, true},
+		{"255", 255, true},
+
+		{"", 0, false},
+		{"256", 0, false},
+		{"-1", 0, false},
+		{"#ff0000", 0, false},
+		{"chartreuse", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.color, func(t *testing.T) {
+			code, ok := ColorCode(tt.color)
+			assert.Equal(t, tt.valid, ok)
+			assert.Equal(t, tt.code, code)
+			assert.Equal(t, tt.valid, ValidateColor(tt.color))
+		})
+	}
+}
+
 func TestIsBlockedDomain(t *testing.T) {
 	tests := []struct {
 		domain  string
@@ -130,6 +196,26 @@ func TestGetBlockedDomains(t *testing.T) {
 	assert.Contains(t, domains, "icloud.com")
 }
 
+func TestSetCustomBlockedDomains(t *testing.T) {
+	t.Cleanup(func() { SetCustomBlockedDomains(nil) })
+
+	SetCustomBlockedDomains([]string{" SSO.Example.com. ", "apple.com"})
+	assert.True(t, IsBlockedDomain("sso.example.com"))
+	assert.True(t, IsBlockedDomain("login.sso.example.com"))
+	assert.True(t, IsBlockedDomain("apple.com"))
+	assert.False(t, IsBlockedDomain("example.com"))
+
+	domains := GetBlockedDomains()
+	assert.Contains(t, domains, "sso.example.com")
+	assert.Contains(t, domains, "apple.com")
+	assert.True(t, slices.IsSorted(domains))
+	assert.Len(t, domains, len(blockedDomains)+1)
+
+	SetCustomBlockedDomains(nil)
+	assert.False(t, IsBlockedDomain("sso.example.com"))
+	assert.NotContains(t, GetBlockedDomains(), "sso.example.com")
+}
+
 func TestValidateConfig(t *testing.T) {
 	t.Run("valid config", func(t *testing.T) {
 		cfg := &Config{
@@ -175,6 +261,142 @@ func TestValidateConfig(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("invalid group name", func(t *testing.T) {
+		cfg := &Config{
+			Groups: []Group{{Name: "dev team", Hosts: []Host{}}},
+		}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "groups[0].name")
+	})
+
+	t.Run("invalid preset name", func(t *testing.T) {
+		cfg := &Config{
+			Presets: []Preset{{Name: "work\nhome"}},
+		}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "presets[0].name")
+	})
+
+	t.Run("idle timeout too short", func(t *testing.T) {
+		cfg := &Config{Settings: Settings{IdleTimeout: time.Second}}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.idleTimeout")
+
+		cfg.Settings.IdleTimeout = 10 * time.Minute
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		cfg := &Config{Settings: Settings{RateLimit: -1}}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.rateLimit")
+
+		cfg.Settings = Settings{RateLimitWindow: time.Millisecond}
+		err = ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.rateLimitWindow")
+
+		cfg.Settings = Settings{RateLimit: 500, RateLimitWindow: 10 * time.Second}
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("blocked domains", func(t *testing.T) {
+		cfg := &Config{Settings: Settings{BlockedDomains: []string{"not a domain"}}}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.blockedDomains[0]")
+
+		// Hosts are checked against the config's own list
+		cfg = &Config{
+			Settings: Settings{BlockedDomains: []string{"sso.example.com"}},
+			Groups: []Group{{Name: "dev", Hosts: []Host{
+				{Domain: "login.sso.example.com", IP: "127.0.0.1", Alias: "login"},
+			}}},
+		}
+		err = ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "domain is blocked")
+
+		// unless root deliberately overrode the check
+		cfg.Groups[0].Hosts[0].AllowBlocked = true
+		assert.NoError(t, ValidateConfig(cfg))
+
+		cfg.Groups[0].Hosts[0].AllowBlocked = false
+		cfg.Settings.BlockedDomains = nil
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("log format", func(t *testing.T) {
+		cfg := &Config{Settings: Settings{LogFormat: "xml"}}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.logFormat")
+
+		for _, format := range []LogFormat{LogFormatJSON, LogFormatText, ""} {
+			cfg.Settings.LogFormat = format
+			assert.NoError(t, ValidateConfig(cfg))
+		}
+	})
+
+	t.Run("max backups", func(t *testing.T) {
+		cfg := &Config{Settings: Settings{MaxBackups: -1}}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.maxBackups")
+
+		cfg.Settings.MaxBackups = 50
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("audit rotation", func(t *testing.T) {
+		cfg := &Config{Settings: Settings{AuditMaxSize: 100}}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.auditMaxSize")
+
+		cfg.Settings = Settings{AuditMaxFiles: -1}
+		err = ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.auditMaxFiles")
+
+		cfg.Settings = Settings{AuditMaxSize: 50 << 20, AuditMaxFiles: 20}
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("auto backup interval too short", func(t *testing.T) {
+		cfg := &Config{Settings: Settings{AutoBackupInterval: time.Minute}}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "settings.autoBackupInterval")
+
+		cfg.Settings.AutoBackupInterval = 24 * time.Hour
+		assert.NoError(t, ValidateConfig(cfg))
+	})
+
+	t.Run("invalid metadata key", func(t *testing.T) {
+		cfg := &Config{
+			Groups: []Group{{Name: "dev", Hosts: []Host{
+				{Domain: "a.local", IP: "127.0.0.1", Alias: "a", Metadata: map[string]string{"owner": "x", "=bad": "y"}},
+			}}},
+		}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid metadata key")
+	})
+
+	t.Run("invalid group color", func(t *testing.T) {
+		cfg := &Config{
+			Groups: []Group{{Name: "dev", Color: "chartreuse", Hosts: []Host{}}},
+		}
+		err := ValidateConfig(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "groups[0].color")
+	})
+
 	t.Run("invalid domain", func(t *testing.T) {
 		cfg := &Config{
 			Groups: []Group{
@@ -342,6 +564,28 @@ func TestValidateDomain_Matrix(t *testing.T) {
 }
 
 // Matrix testing for IP validation
+func TestValidateDescription(t *testing.T) {
+	assert.True(t, ValidateDescription(""))
+	assert.True(t, ValidateDescription("Points at Bob's laptop – ask before changing"))
+	assert.True(t, ValidateDescription(strings.Repeat("é", MaxDescriptionLength)))
+	assert.False(t, ValidateDescription(strings.Repeat("a", MaxDescriptionLength+1)))
+	assert.False(t, ValidateDescription("two\nlines"))
+	assert.False(t, ValidateDescription("tab\there"))
+}
+
+func TestSplitIPs(t *testing.T) {
+	assert.Equal(t, []string{"127.0.0.1", "::1"}, SplitIPs(" 127.0.0.1, ::1 ,"))
+	assert.Equal(t, []string{"10.0.0.1"}, SplitIPs("10.0.0.1"))
+	assert.Empty(t, SplitIPs(" , "))
+}
+
+func TestCheckIPs(t *testing.T) {
+	assert.NoError(t, CheckIPs([]string{"127.0.0.1", "::1"}))
+	assert.EqualError(t, CheckIPs(nil), "IP address is required")
+	assert.EqualError(t, CheckIPs([]string{"127.0.0.1", "999.1.1.1"}), "invalid IP address: 999.1.1.1")
+	assert.EqualError(t, CheckIPs([]string{"::1", "::1"}), "duplicate IP address: ::1")
+}
+
 func TestValidateIP_Matrix(t *testing.T) {
 	octets := []string{"0", "127", "192", "255"}
 
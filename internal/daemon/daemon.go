@@ -5,13 +5,22 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/lukaszraczylo/lolcathost/internal/config"
-	"github.com/lukaszraczylo/lolcathost/internal/protocol"
 )
 
+// autoBackupCheckInterval is how often the daemon checks whether a periodic
+// backup is due.
+const autoBackupCheckInterval = time.Minute
+
+// expiryCheckInterval is how often the daemon disables hosts whose expiry
+// has passed, and so how late a host may go off.
+const expiryCheckInterval = 15 * time.Second
+
 // Daemon represents the lolcathost daemon.
 type Daemon struct {
 	server    *Server
@@ -20,8 +29,15 @@ type Daemon struct {
 	cleanupCh chan struct{}
 }
 
-// New creates a new daemon instance.
-func New(configPath string) (*Daemon, error) {
+// New creates a new daemon instance listening on socketPath. It loads the
+// active profile, which is the config at configPath unless another profile
+// was switched to.
+func New(configPath, socketPath string) (*Daemon, error) {
+	profiles := config.NewProfiles(configPath)
+	if path, err := profiles.Path(profiles.Active()); err == nil {
+		configPath = path
+	}
+
 	cfgManager := config.NewManager(configPath)
 
 	// Try to load config, create default if it doesn't exist
@@ -39,16 +55,15 @@ func New(configPath string) (*Daemon, error) {
 	}
 
 	// Ensure at least one group exists
-	cfg := cfgManager.Get()
-	if cfg != nil {
-		cfg.EnsureDefaultGroup()
-		// Save if we added a default group
-		if len(cfg.Groups) == 1 && cfg.Groups[0].Name == "default" && len(cfg.Groups[0].Hosts) == 0 {
-			_ = cfgManager.Save()
-		}
+	cfg := cfgManager.MustGet()
+	cfg.EnsureDefaultGroup()
+	// Save if we added a default group
+	if len(cfg.Groups) == 1 && cfg.Groups[0].Name == "default" && len(cfg.Groups[0].Hosts) == 0 {
+		_ = cfgManager.Save()
 	}
 
-	server := NewServer(protocol.SocketPath, cfgManager)
+	server := NewServer(socketPath, cfgManager)
+	server.profiles = profiles
 
 	return &Daemon{
 		server:    server,
@@ -58,6 +73,56 @@ func New(configPath string) (*Daemon, error) {
 	}, nil
 }
 
+// SyncWithoutDaemon rewrites the hosts file from the active profile of the
+// config at configPath in the calling process, for recovery when the daemon
+// can't run. Nothing checks who asked: it must run as root, and it relies on
+// sudo for authorization in place of the daemon's group check, rate limit and
+// request serialization. The hosts file is backed up first and the sync is
+// audit-logged under the UID that invoked sudo.
+func SyncWithoutDaemon(configPath string) error {
+	// Sync the profile the daemon would load
+	profiles := config.NewProfiles(configPath)
+	if path, err := profiles.Path(profiles.Active()); err == nil {
+		configPath = path
+	}
+
+	cfgManager := config.NewManager(configPath)
+	if err := cfgManager.Load(); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// The server never listens, so it needs no socket
+	s := NewServer("", cfgManager)
+	if logger, err := NewAuditLogger(AuditLogPath); err == nil {
+		s.auditLogger = logger
+		s.applySettings() // for the log format
+		defer func() { _ = logger.Close() }()
+	}
+
+	err := s.syncHostsFile()
+
+	if s.auditLogger != nil {
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		// #nosec G115 - PIDs fit in 32 bits on supported platforms
+		s.auditLogger.Log(invokingUID(), int32(os.Getpid()), "sync_no_daemon",
+			map[string]string{"config": configPath}, err == nil, errMsg)
+	}
+	return err
+}
+
+// invokingUID returns the UID of the user who ran sudo, or this process's
+// own UID when it wasn't started through sudo.
+func invokingUID() uint32 {
+	if uid, err := strconv.ParseUint(os.Getenv("SUDO_UID"), 10, 32); err == nil {
+		return uint32(uid)
+	}
+	// #nosec G115 - UIDs fit in 32 bits on supported platforms
+	return uint32(os.Getuid())
+}
+
 // Run starts the daemon and blocks until stopped.
 func (d *Daemon) Run() error {
 	// Verify we're running as root
@@ -70,6 +135,9 @@ func (d *Daemon) Run() error {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
+	// Check the hosts file survived since the last run
+	d.server.Reconcile()
+
 	// Watch config for changes
 	if err := d.config.Watch(d.onConfigChange); err != nil {
 		fmt.Fprintf(os.Stderr, "warning: failed to watch config: %v\n", err)
@@ -77,19 +145,38 @@ func (d *Daemon) Run() error {
 
 	// Start cleanup goroutine
 	go d.cleanupLoop()
+	go d.autoBackupLoop()
+	go d.expiryLoop()
 
-	// Wait for shutdown signal
+	// Wait for shutdown signal, reloading on SIGHUP
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
 
-	select {
-	case <-sigCh:
-		fmt.Println("Received shutdown signal")
-	case <-d.stopCh:
-		fmt.Println("Shutdown requested")
+	for {
+		select {
+		case <-hupCh:
+			d.reload()
+			continue
+		case <-sigCh:
+			fmt.Println("Received shutdown signal")
+		case <-d.stopCh:
+			fmt.Println("Shutdown requested")
+		}
+		return d.shutdown()
 	}
+}
 
-	return d.shutdown()
+// reload handles SIGHUP, which is what "systemctl reload" sends.
+func (d *Daemon) reload() {
+	fmt.Println("Received SIGHUP, reloading config...")
+	if err := d.server.ReloadOnSignal(); err != nil {
+		fmt.Fprintf(os.Stderr, "reload: %v\n", err)
+		return
+	}
+	fmt.Println("Config reloaded and hosts file synced")
 }
 
 func (d *Daemon) shutdown() error {
@@ -105,6 +192,7 @@ func (d *Daemon) shutdown() error {
 
 func (d *Daemon) onConfigChange(cfg *config.Config) {
 	fmt.Println("Config changed, syncing hosts file...")
+	d.server.ConfigChanged()
 	// The server will use the updated config on next request
 	// We could trigger a sync here if autoApply is enabled
 	if cfg != nil && cfg.Settings.AutoApply {
@@ -113,6 +201,44 @@ func (d *Daemon) onConfigChange(cfg *config.Config) {
 	}
 }
 
+// autoBackupLoop takes periodic backups while settings.autoBackupInterval is
+// set. Checking every minute lets interval changes apply without a restart.
+func (d *Daemon) autoBackupLoop() {
+	ticker := time.NewTicker(autoBackupCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if _, err := d.server.AutoBackup(now); err != nil {
+				fmt.Fprintf(os.Stderr, "auto backup: %v\n", err)
+			}
+		case <-d.cleanupCh:
+			return
+		}
+	}
+}
+
+// expiryLoop disables hosts enabled with a TTL once it runs out.
+func (d *Daemon) expiryLoop() {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			expired, err := d.server.ExpireHosts(now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "expire hosts: %v\n", err)
+			} else if len(expired) > 0 {
+				fmt.Printf("Disabled expired hosts: %s\n", strings.Join(expired, ", "))
+			}
+		case <-d.cleanupCh:
+			return
+		}
+	}
+}
+
 func (d *Daemon) cleanupLoop() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
@@ -121,6 +247,7 @@ func (d *Daemon) cleanupLoop() {
 		select {
 		case <-ticker.C:
 			d.server.rateLimiter.Cleanup()
+			d.server.authCache.Cleanup()
 		case <-d.cleanupCh:
 			return
 		}
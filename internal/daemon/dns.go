@@ -2,6 +2,7 @@
 package daemon
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
 	"runtime"
@@ -10,6 +11,7 @@ import (
 // DNSFlusher handles DNS cache flushing.
 type DNSFlusher struct {
 	method FlushMethod
+	run    func(name string, args ...string) error
 }
 
 // FlushMethod defines the DNS flush method to use.
@@ -24,18 +26,85 @@ const (
 	FlushMethodNscd        FlushMethod = "nscd"
 )
 
+// flushTools lists, per platform, the commands the flush methods run.
+var flushTools = map[string][]string{
+	"darwin": {"dscacheutil", "killall"},
+	"linux":  {"resolvectl", "systemd-resolve", "nscd"},
+}
+
+// fallbackMethods lists, per platform, the methods auto falls back to when
+// the detected one fails.
+var fallbackMethods = map[string][]FlushMethod{
+	"darwin": {FlushMethodDscacheutil, FlushMethodKillall},
+	"linux":  {FlushMethodSystemd, FlushMethodNscd},
+}
+
 // NewDNSFlusher creates a new DNS flusher.
 func NewDNSFlusher(method FlushMethod) *DNSFlusher {
-	return &DNSFlusher{method: method}
+	return &DNSFlusher{method: method, run: runCommand}
+}
+
+// Method returns the configured flush method.
+func (f *DNSFlusher) Method() FlushMethod {
+	if f.method == "" {
+		return FlushMethodAuto
+	}
+	return f.method
+}
+
+// SetMethod changes the flush method; an empty method means auto.
+func (f *DNSFlusher) SetMethod(method FlushMethod) {
+	f.method = method
+}
+
+// AutoMethod returns the method auto resolves to on this system. On Linux
+// it stays auto when no flush tool is installed.
+func (f *DNSFlusher) AutoMethod() FlushMethod {
+	return f.detectMethod()
+}
+
+// AvailableTools returns the flush commands for this platform found on PATH.
+func (f *DNSFlusher) AvailableTools() []string {
+	var tools []string
+	for _, tool := range flushTools[runtime.GOOS] {
+		if _, err := exec.LookPath(tool); err == nil {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
 }
 
-// Flush flushes the DNS cache using the configured method.
+// Flush flushes the DNS cache using the configured method. Under auto, the
+// platform's other methods are tried before giving up.
 func (f *DNSFlusher) Flush() error {
-	method := f.method
-	if method == FlushMethodAuto || method == "" {
-		method = f.detectMethod()
+	if f.method != FlushMethodAuto && f.method != "" {
+		return f.flushWith(f.method)
 	}
 
+	var errs []error
+	for _, method := range flushCandidates(f.detectMethod(), runtime.GOOS) {
+		err := f.flushWith(method)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// flushCandidates returns the detected method followed by the platform's
+// fallbacks, without repeats.
+func flushCandidates(detected FlushMethod, goos string) []FlushMethod {
+	methods := []FlushMethod{detected}
+	for _, method := range fallbackMethods[goos] {
+		if method != detected {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+func (f *DNSFlusher) flushWith(method FlushMethod) error {
 	switch runtime.GOOS {
 	case "darwin":
 		return f.flushDarwin(method)
@@ -73,18 +142,18 @@ func (f *DNSFlusher) flushDarwin(method FlushMethod) error {
 
 	switch method {
 	case FlushMethodDscacheutil:
-		if err := runCommand("dscacheutil", "-flushcache"); err != nil {
+		if err := f.run("dscacheutil", "-flushcache"); err != nil {
 			return fmt.Errorf("dscacheutil failed: %w", err)
 		}
 	case FlushMethodKillall:
-		if err := runCommand("killall", "-HUP", "mDNSResponder"); err != nil {
+		if err := f.run("killall", "-HUP", "mDNSResponder"); err != nil {
 			return fmt.Errorf("killall mDNSResponder failed: %w", err)
 		}
 	case FlushMethodBoth:
-		if err := runCommand("dscacheutil", "-flushcache"); err != nil {
+		if err := f.run("dscacheutil", "-flushcache"); err != nil {
 			errs = append(errs, fmt.Errorf("dscacheutil failed: %w", err))
 		}
-		if err := runCommand("killall", "-HUP", "mDNSResponder"); err != nil {
+		if err := f.run("killall", "-HUP", "mDNSResponder"); err != nil {
 			errs = append(errs, fmt.Errorf("killall mDNSResponder failed: %w", err))
 		}
 		if len(errs) == 2 {
@@ -92,8 +161,8 @@ func (f *DNSFlusher) flushDarwin(method FlushMethod) error {
 		}
 	default:
 		// Auto - try both
-		_ = runCommand("dscacheutil", "-flushcache")
-		_ = runCommand("killall", "-HUP", "mDNSResponder")
+		_ = f.run("dscacheutil", "-flushcache")
+		_ = f.run("killall", "-HUP", "mDNSResponder")
 	}
 
 	return nil
@@ -103,30 +172,30 @@ func (f *DNSFlusher) flushLinux(method FlushMethod) error {
 	switch method {
 	case FlushMethodSystemd:
 		// Try resolvectl first (newer), then systemd-resolve (older)
-		if err := runCommand("resolvectl", "flush-caches"); err != nil {
-			if err := runCommand("systemd-resolve", "--flush-caches"); err != nil {
+		if err := f.run("resolvectl", "flush-caches"); err != nil {
+			if err := f.run("systemd-resolve", "--flush-caches"); err != nil {
 				return fmt.Errorf("systemd DNS flush failed: %w", err)
 			}
 		}
 	case FlushMethodNscd:
 		// Try to restart nscd
-		if err := runCommand("nscd", "-i", "hosts"); err != nil {
+		if err := f.run("nscd", "-i", "hosts"); err != nil {
 			// Try service restart as fallback
-			if err := runCommand("service", "nscd", "restart"); err != nil {
+			if err := f.run("service", "nscd", "restart"); err != nil {
 				return fmt.Errorf("nscd flush failed: %w", err)
 			}
 		}
 	default:
 		// Auto - try all methods
 		// Try systemd first
-		if err := runCommand("resolvectl", "flush-caches"); err == nil {
+		if err := f.run("resolvectl", "flush-caches"); err == nil {
 			return nil
 		}
-		if err := runCommand("systemd-resolve", "--flush-caches"); err == nil {
+		if err := f.run("systemd-resolve", "--flush-caches"); err == nil {
 			return nil
 		}
 		// Try nscd
-		if err := runCommand("nscd", "-i", "hosts"); err == nil {
+		if err := f.run("nscd", "-i", "hosts"); err == nil {
 			return nil
 		}
 		// On many Linux systems, no explicit flush is needed as /etc/hosts is read directly
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"errors"
 	"runtime"
 	"testing"
 
@@ -40,6 +41,23 @@ func TestDNSFlusher_DetectMethod(t *testing.T) {
 	}
 }
 
+func TestDNSFlusher_Method(t *testing.T) {
+	assert.Equal(t, FlushMethodAuto, NewDNSFlusher("").Method())
+	assert.Equal(t, FlushMethodNscd, NewDNSFlusher(FlushMethodNscd).Method())
+}
+
+func TestDNSFlusher_AvailableTools(t *testing.T) {
+	flusher := NewDNSFlusher(FlushMethodAuto)
+
+	for _, tool := range flusher.AvailableTools() {
+		assert.Contains(t, flushTools[runtime.GOOS], tool)
+	}
+	// Auto only picks a method whose tool is installed
+	if runtime.GOOS == "linux" && flusher.AutoMethod() == FlushMethodNscd {
+		assert.Contains(t, flusher.AvailableTools(), "nscd")
+	}
+}
+
 func TestFlushMethod_String(t *testing.T) {
 	methods := map[FlushMethod]string{
 		FlushMethodAuto:        "auto",
@@ -72,6 +90,42 @@ func TestDNSFlusher_Flush_UnsupportedOS(t *testing.T) {
 	assert.Contains(t, err.Error(), "unsupported operating system")
 }
 
+func TestFlushCandidates(t *testing.T) {
+	assert.Equal(t, []FlushMethod{FlushMethodSystemd, FlushMethodNscd}, flushCandidates(FlushMethodSystemd, "linux"))
+	assert.Equal(t, []FlushMethod{FlushMethodNscd, FlushMethodSystemd}, flushCandidates(FlushMethodNscd, "linux"))
+	assert.Equal(t, []FlushMethod{FlushMethodBoth, FlushMethodDscacheutil, FlushMethodKillall}, flushCandidates(FlushMethodBoth, "darwin"))
+	assert.Equal(t, []FlushMethod{FlushMethodAuto}, flushCandidates(FlushMethodAuto, "windows"))
+}
+
+// failingFlushMethod returns a method that reports failure on this platform
+// when every command fails.
+func failingFlushMethod(t *testing.T) FlushMethod {
+	switch runtime.GOOS {
+	case "darwin":
+		return FlushMethodDscacheutil
+	case "linux":
+		return FlushMethodSystemd
+	default:
+		t.Skip("flush is unsupported on this platform")
+		return ""
+	}
+}
+
+func TestDNSFlusher_Flush_CommandFails(t *testing.T) {
+	var ran []string
+	flusher := &DNSFlusher{
+		method: failingFlushMethod(t),
+		run: func(name string, args ...string) error {
+			ran = append(ran, name)
+			return errors.New("exit status 1")
+		},
+	}
+
+	err := flusher.Flush()
+	assert.Error(t, err)
+	assert.NotEmpty(t, ran)
+}
+
 // Matrix test for flush methods
 func TestFlushMethod_Matrix(t *testing.T) {
 	methods := []FlushMethod{
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -16,8 +17,12 @@ const (
 	HostsPath = "/etc/hosts"
 	// BackupDir is the directory for hosts file backups.
 	BackupDir = "/var/backups/lolcathost"
-	// MaxBackups is the maximum number of backups to keep.
+	// MaxBackups is the number of backups kept unless settings.maxBackups
+	// says otherwise.
 	MaxBackups = 10
+	// maxWriteAttempts is how many times the managed section is written when
+	// another program rewrites the hosts file before it can be verified.
+	maxWriteAttempts = 2
 
 	// Markers for the managed section.
 	markerStart = "# ========== LOLCATHOST MANAGED - DO NOT EDIT =========="
@@ -28,18 +33,96 @@ const (
 // Compiled once at package init for efficiency.
 var entryRegex = regexp.MustCompile(`^(\S+)\s+(\S+)\s+#\s*lolcathost:(\S+)$`)
 
-// HostEntry represents a single entry in the hosts file.
+// backupNameRegex matches backup file names: hosts.<timestamp>.bak with an
+// optional label segment before the extension.
+var backupNameRegex = regexp.MustCompile(`^hosts\.\d{8}-\d{6}(?:\.([a-z0-9-]{1,40}))?\.bak$`)
+
+// backupLabelInvalidChars matches runs of characters not allowed in labels.
+var backupLabelInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// maxBackupLabelLength caps the label segment of a backup file name.
+const maxBackupLabelLength = 40
+
+// HostEntry represents a single entry in the hosts file. An entry with
+// several addresses is written as one line per address, all carrying the
+// same alias.
 type HostEntry struct {
 	IP      string
 	Domain  string
 	Alias   string
 	Enabled bool
+
+	// IPs lists every address when there is more than one; IP then holds
+	// the first of them, as in config.Host.
+	IPs []string
+}
+
+// Addresses returns every IP the entry maps to.
+func (e HostEntry) Addresses() []string {
+	if len(e.IPs) > 0 {
+		return e.IPs
+	}
+	return []string{e.IP}
+}
+
+// SyncDiff lists how a sync changed the managed section. Entries are
+// formatted as "domain → ip".
+type SyncDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// IsEmpty reports whether the sync left the managed section unchanged.
+func (d SyncDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// diffEntries compares the enabled entries of two managed sections. An entry
+// whose IP changed shows up as removed with the old IP and added with the new.
+func diffEntries(before, after []HostEntry) SyncDiff {
+	enabled := func(entries []HostEntry) []string {
+		var keys []string
+		seen := make(map[string]bool)
+		for _, e := range entries {
+			for _, ip := range e.Addresses() {
+				key := e.Domain + " → " + ip
+				if e.Enabled && !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+		return keys
+	}
+
+	oldKeys, newKeys := enabled(before), enabled(after)
+
+	var diff SyncDiff
+	for _, key := range newKeys {
+		if !slices.Contains(oldKeys, key) {
+			diff.Added = append(diff.Added, key)
+		}
+	}
+	for _, key := range oldKeys {
+		if !slices.Contains(newKeys, key) {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	return diff
 }
 
 // HostsManager handles reading and writing the hosts file.
 type HostsManager struct {
 	hostsPath string
 	backupDir string
+
+	// maxBackups is how many backups cleanupBackups keeps. Zero keeps
+	// MaxBackups.
+	maxBackups int
+
+	// afterWrite, if set, runs between writing the hosts file and reading it
+	// back. Tests use it to simulate another program rewriting the file.
+	afterWrite func()
 }
 
 // NewHostsManager creates a new hosts manager.
@@ -53,10 +136,49 @@ func NewHostsManager() *HostsManager {
 // WriteManagedEntries writes the managed entries to the hosts file.
 func (m *HostsManager) WriteManagedEntries(entries []HostEntry) error {
 	// Create backup first
-	if err := m.CreateBackup(); err != nil {
+	if _, err := m.CreateBackup(""); err != nil {
 		return fmt.Errorf("failed to create backup: %w", err)
 	}
 
+	return m.writeManagedEntries(entries)
+}
+
+// writeManagedEntries rewrites the managed section without taking a backup.
+// It is reserved for daemon-internal repairs where the managed section is
+// regenerated from config; user operations go through WriteManagedEntries.
+//
+// The file is read back after writing. Docker, VPN clients and other hosts
+// file managers may rewrite it at the same time, so a section that didn't
+// stick is written once more before giving up.
+func (m *HostsManager) writeManagedEntries(entries []HostEntry) error {
+	managedSection := m.buildManagedSection(entries)
+
+	for attempt := 1; ; attempt++ {
+		if err := m.writeManagedSection(managedSection); err != nil {
+			return err
+		}
+		if m.afterWrite != nil {
+			m.afterWrite()
+		}
+
+		content, err := os.ReadFile(m.hostsPath)
+		if err != nil {
+			return fmt.Errorf("failed to verify hosts file: %w", err)
+		}
+		if extractManagedSection(string(content)) == managedSection {
+			return nil
+		}
+
+		if attempt == maxWriteAttempts {
+			return fmt.Errorf("hosts file verification failed: managed section was changed by another program after %d writes", attempt)
+		}
+		fmt.Fprintf(os.Stderr, "warning: hosts file changed while writing, retrying\n")
+	}
+}
+
+// writeManagedSection replaces the managed section of the hosts file with
+// managedSection, keeping everything outside it.
+func (m *HostsManager) writeManagedSection(managedSection string) error {
 	// Read existing content
 	content, err := os.ReadFile(m.hostsPath)
 	if err != nil {
@@ -66,9 +188,6 @@ func (m *HostsManager) WriteManagedEntries(entries []HostEntry) error {
 	// Remove existing managed section
 	newContent := m.removeManagedSection(string(content))
 
-	// Build new managed section
-	managedSection := m.buildManagedSection(entries)
-
 	// Append managed section
 	newContent = strings.TrimRight(newContent, "\n") + "\n\n" + managedSection
 
@@ -80,6 +199,223 @@ func (m *HostsManager) WriteManagedEntries(entries []HostEntry) error {
 	return nil
 }
 
+// extractManagedSection returns the first managed section of content,
+// markers included, in the form buildManagedSection produces. It returns ""
+// when there is no complete section.
+func extractManagedSection(content string) string {
+	var sb strings.Builder
+	inManagedSection := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == markerStart {
+			inManagedSection = true
+		}
+		if !inManagedSection {
+			continue
+		}
+		sb.WriteString(trimmed)
+		sb.WriteString("\n")
+		if trimmed == markerEnd {
+			return sb.String()
+		}
+	}
+
+	return ""
+}
+
+// readManagedEntries reads the lolcathost-managed entries from the hosts file.
+// Lines in the managed section that can't be parsed are logged, not returned.
+func (m *HostsManager) readManagedEntries() ([]HostEntry, error) {
+	entries, unparseable, err := m.readManagedSection()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range unparseable {
+		fmt.Fprintf(os.Stderr, "warning: unparseable line in managed section: %q\n", line)
+	}
+
+	return entries, nil
+}
+
+// readManagedSection reads the managed section of the hosts file, returning
+// the parsed entries and any non-comment lines that don't match entryRegex,
+// such as entries whose alias trailer was edited by hand. Consecutive lines
+// for the same alias and domain are merged into one entry with several IPs.
+func (m *HostsManager) readManagedSection() (entries []HostEntry, unparseable []string, err error) {
+	content, err := os.ReadFile(m.hostsPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	inManagedSection := false
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == markerStart {
+			inManagedSection = true
+			continue
+		}
+		if line == markerEnd {
+			inManagedSection = false
+			continue
+		}
+
+		if inManagedSection && !strings.HasPrefix(line, "#") && line != "" {
+			matches := entryRegex.FindStringSubmatch(line)
+			if len(matches) != 4 {
+				unparseable = append(unparseable, line)
+				continue
+			}
+			if n := len(entries); n > 0 && entries[n-1].Alias == matches[3] && entries[n-1].Domain == matches[2] {
+				last := &entries[n-1]
+				last.IPs = append(last.Addresses(), matches[1])
+				continue
+			}
+			entries = append(entries, HostEntry{
+				IP:      matches[1],
+				Domain:  matches[2],
+				Alias:   matches[3],
+				Enabled: true,
+			})
+		}
+	}
+
+	return entries, unparseable, nil
+}
+
+// externalLines returns the hosts file lines outside the managed section that
+// map domain, such as entries added by hand or by another hosts manager.
+func (m *HostsManager) externalLines(domain string) ([]string, error) {
+	content, err := os.ReadFile(m.hostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var lines []string
+	inManagedSection := false
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == markerStart:
+			inManagedSection = true
+			continue
+		case line == markerEnd:
+			inManagedSection = false
+			continue
+		case inManagedSection:
+			continue
+		}
+
+		fields, _, _ := strings.Cut(line, "#")
+		names := strings.Fields(fields)
+		if len(names) < 2 {
+			continue
+		}
+		for _, name := range names[1:] {
+			if strings.EqualFold(name, domain) {
+				lines = append(lines, line)
+				break
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// systemHostnames are names the OS maps itself, which ImportUnmanaged leaves
+// alone.
+var systemHostnames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+}
+
+// ImportUnmanaged parses the hosts file lines outside the managed section
+// into entries, one per domain in file order, so existing hand-written
+// mappings can be brought under management. A domain listed on several lines
+// gets each address. System names such as localhost are left out. Entries
+// aren't validated; that is up to the caller.
+func (m *HostsManager) ImportUnmanaged() ([]HostEntry, error) {
+	content, err := os.ReadFile(m.hostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var entries []HostEntry
+	index := make(map[string]int)
+	inManagedSection := false
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == markerStart:
+			inManagedSection = true
+			continue
+		case line == markerEnd:
+			inManagedSection = false
+			continue
+		case inManagedSection:
+			continue
+		}
+
+		fields, _, _ := strings.Cut(line, "#")
+		names := strings.Fields(fields)
+		if len(names) < 2 {
+			continue
+		}
+		ip := names[0]
+		for _, domain := range names[1:] {
+			domain = strings.ToLower(domain)
+			if systemHostnames[domain] {
+				continue
+			}
+			i, exists := index[domain]
+			if !exists {
+				index[domain] = len(entries)
+				entries = append(entries, HostEntry{IP: ip, Domain: domain})
+				continue
+			}
+			if addresses := entries[i].Addresses(); !slices.Contains(addresses, ip) {
+				entries[i].IPs = append(addresses, ip)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// hasManagedSection reports whether the hosts file contains the managed markers.
+func (m *HostsManager) hasManagedSection() (bool, error) {
+	content, err := os.ReadFile(m.hostsPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	var start, end bool
+	for _, line := range strings.Split(string(content), "\n") {
+		switch strings.TrimSpace(line) {
+		case markerStart:
+			start = true
+		case markerEnd:
+			end = start
+		}
+	}
+	return start && end, nil
+}
+
 func (m *HostsManager) removeManagedSection(content string) string {
 	lines := strings.Split(content, "\n")
 	var result []string
@@ -114,8 +450,11 @@ func (m *HostsManager) buildManagedSection(entries []HostEntry) string {
 	sb.WriteString("\n")
 
 	for _, entry := range entries {
-		if entry.Enabled {
-			sb.WriteString(fmt.Sprintf("%s\t%s\t# lolcathost:%s\n", entry.IP, entry.Domain, entry.Alias))
+		if !entry.Enabled {
+			continue
+		}
+		for _, ip := range entry.Addresses() {
+			sb.WriteString(fmt.Sprintf("%s\t%s\t# lolcathost:%s\n", ip, entry.Domain, entry.Alias))
 		}
 	}
 
@@ -142,24 +481,31 @@ func (m *HostsManager) writeAtomic(content string) error {
 	return nil
 }
 
-// CreateBackup creates a backup of the current hosts file.
-func (m *HostsManager) CreateBackup() error {
+// CreateBackup creates a backup of the current hosts file. A non-empty label
+// is sanitized and added to the file name, e.g.
+// hosts.20240101-120000.before-import.bak. It returns the new backup.
+func (m *HostsManager) CreateBackup(label string) (BackupInfo, error) {
 	// #nosec G301 - Backup directory permissions are intentionally 0755
 	if err := os.MkdirAll(m.backupDir, 0755); err != nil {
-		return fmt.Errorf("failed to create backup directory: %w", err)
+		return BackupInfo{}, fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
 	content, err := os.ReadFile(m.hostsPath) // #nosec G304 - Path is controlled by daemon, not user input
 	if err != nil {
-		return fmt.Errorf("failed to read hosts file: %w", err)
+		return BackupInfo{}, fmt.Errorf("failed to read hosts file: %w", err)
 	}
 
-	timestamp := time.Now().Format("20060102-150405")
-	backupPath := filepath.Join(m.backupDir, fmt.Sprintf("hosts.%s.bak", timestamp))
+	now := time.Now()
+	timestamp := now.Format("20060102-150405")
+	name := fmt.Sprintf("hosts.%s.bak", timestamp)
+	if label = sanitizeBackupLabel(label); label != "" {
+		name = fmt.Sprintf("hosts.%s.%s.bak", timestamp, label)
+	}
+	backupPath := filepath.Join(m.backupDir, name)
 
 	// #nosec G306 - Backup file permissions are intentionally 0644
 	if err := os.WriteFile(backupPath, content, 0644); err != nil {
-		return fmt.Errorf("failed to write backup: %w", err)
+		return BackupInfo{}, fmt.Errorf("failed to write backup: %w", err)
 	}
 
 	// Cleanup old backups
@@ -168,9 +514,22 @@ func (m *HostsManager) CreateBackup() error {
 		fmt.Fprintf(os.Stderr, "warning: failed to cleanup backups: %v\n", err)
 	}
 
-	return nil
+	return BackupInfo{
+		Name:      name,
+		Label:     label,
+		Timestamp: now.Unix(),
+		Size:      int64(len(content)),
+	}, nil
+}
+
+// SetMaxBackups sets how many backups are kept. Zero or less restores the
+// MaxBackups default. Excess backups are removed on the next backup.
+func (m *HostsManager) SetMaxBackups(n int) {
+	m.maxBackups = max(n, 0)
 }
 
+// cleanupBackups removes all but the newest backups. Labeled backups count
+// like any other, so a label only names a backup; it doesn't pin it.
 func (m *HostsManager) cleanupBackups() error {
 	entries, err := os.ReadDir(m.backupDir)
 	if err != nil {
@@ -179,12 +538,16 @@ func (m *HostsManager) cleanupBackups() error {
 
 	var backups []os.DirEntry
 	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasPrefix(entry.Name(), "hosts.") && strings.HasSuffix(entry.Name(), ".bak") {
+		if _, ok := parseBackupName(entry.Name()); ok && !entry.IsDir() {
 			backups = append(backups, entry)
 		}
 	}
 
-	if len(backups) <= MaxBackups {
+	keep := MaxBackups
+	if m.maxBackups > 0 {
+		keep = m.maxBackups
+	}
+	if len(backups) <= keep {
 		return nil
 	}
 
@@ -194,7 +557,7 @@ func (m *HostsManager) cleanupBackups() error {
 	})
 
 	// Remove oldest backups
-	for i := MaxBackups; i < len(backups); i++ {
+	for i := keep; i < len(backups); i++ {
 		path := filepath.Join(m.backupDir, backups[i].Name())
 		_ = os.Remove(path)
 	}
@@ -214,7 +577,8 @@ func (m *HostsManager) ListBackups() ([]BackupInfo, error) {
 
 	var backups []BackupInfo
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "hosts.") || !strings.HasSuffix(entry.Name(), ".bak") {
+		label, ok := parseBackupName(entry.Name())
+		if entry.IsDir() || !ok {
 			continue
 		}
 
@@ -225,6 +589,7 @@ func (m *HostsManager) ListBackups() ([]BackupInfo, error) {
 
 		backups = append(backups, BackupInfo{
 			Name:      entry.Name(),
+			Label:     label,
 			Timestamp: info.ModTime().Unix(),
 			Size:      info.Size(),
 		})
@@ -241,14 +606,36 @@ func (m *HostsManager) ListBackups() ([]BackupInfo, error) {
 // BackupInfo holds information about a backup file.
 type BackupInfo struct {
 	Name      string
+	Label     string
 	Timestamp int64
 	Size      int64
 }
 
+// sanitizeBackupLabel lowercases label and replaces anything other than
+// letters, digits and dashes with a dash, so it is safe as a file name
+// segment. It returns "" when nothing usable remains.
+func sanitizeBackupLabel(label string) string {
+	label = backupLabelInvalidChars.ReplaceAllString(strings.ToLower(label), "-")
+	if len(label) > maxBackupLabelLength {
+		label = label[:maxBackupLabelLength]
+	}
+	return strings.Trim(label, "-")
+}
+
+// parseBackupName reports whether name is a backup file name and returns its
+// label, if any. Anything else, including paths, is rejected.
+func parseBackupName(name string) (string, bool) {
+	match := backupNameRegex.FindStringSubmatch(name)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
 // GetBackupContent returns the content of a backup file.
 func (m *HostsManager) GetBackupContent(name string) (string, error) {
 	// Validate backup name to prevent path traversal
-	if filepath.Base(name) != name || !strings.HasPrefix(name, "hosts.") || !strings.HasSuffix(name, ".bak") {
+	if _, ok := parseBackupName(name); !ok {
 		return "", fmt.Errorf("invalid backup name")
 	}
 
@@ -265,7 +652,7 @@ func (m *HostsManager) GetBackupContent(name string) (string, error) {
 // RestoreBackup restores a backup by name.
 func (m *HostsManager) RestoreBackup(name string) error {
 	// Validate backup name to prevent path traversal
-	if filepath.Base(name) != name || !strings.HasPrefix(name, "hosts.") || !strings.HasSuffix(name, ".bak") {
+	if _, ok := parseBackupName(name); !ok {
 		return fmt.Errorf("invalid backup name")
 	}
 
@@ -277,7 +664,7 @@ func (m *HostsManager) RestoreBackup(name string) error {
 	}
 
 	// Create a backup of current state before restoring
-	if err := m.CreateBackup(); err != nil {
+	if _, err := m.CreateBackup("before-rollback"); err != nil {
 		return fmt.Errorf("failed to create backup before restore: %w", err)
 	}
 
@@ -287,3 +674,117 @@ func (m *HostsManager) RestoreBackup(name string) error {
 
 	return nil
 }
+
+// DeleteBackup removes a backup by name.
+func (m *HostsManager) DeleteBackup(name string) error {
+	// Validate backup name to prevent path traversal
+	if _, ok := parseBackupName(name); !ok {
+		return fmt.Errorf("invalid backup name")
+	}
+
+	if err := os.Remove(filepath.Join(m.backupDir, name)); err != nil {
+		return fmt.Errorf("failed to delete backup: %w", err)
+	}
+	return nil
+}
+
+// BackupDiff returns the line-level changes from a backup to the current
+// hosts file.
+func (m *HostsManager) BackupDiff(name string) ([]DiffLine, error) {
+	backup, err := m.GetBackupContent(name)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := os.ReadFile(m.hostsPath) // #nosec G304 - Path is controlled by daemon, not user input
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hosts file: %w", err)
+	}
+
+	return diffLines(splitLines(backup), splitLines(string(current))), nil
+}
+
+// DiffLine is one line of a line-level diff. Op is "+" for a line only in the
+// new text, "-" for one only in the old text and " " for one in both.
+type DiffLine struct {
+	Op   string
+	Text string
+}
+
+// maxDiffCells caps the size of the table diffLines builds, so diffing two
+// large, unrelated files can't exhaust memory.
+const maxDiffCells = 1 << 20
+
+// diffLines returns the changes that turn before into after. Lines common to
+// the start and end are matched directly and the rest by longest common
+// subsequence; if that part is too large it is reported as replaced wholesale.
+func diffLines(before, after []string) []DiffLine {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]DiffLine, 0, len(before)+len(after)-prefix-suffix)
+	for _, line := range before[:prefix] {
+		diff = append(diff, DiffLine{Op: " ", Text: line})
+	}
+
+	a, b := before[prefix:len(before)-suffix], after[prefix:len(after)-suffix]
+	i, j := 0, 0
+	if len(a)*len(b) <= maxDiffCells {
+		// lcs[i][j] is the length of the longest common subsequence of a[i:]
+		// and b[j:].
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		for i < len(a) && j < len(b) {
+			switch {
+			case a[i] == b[j]:
+				diff = append(diff, DiffLine{Op: " ", Text: a[i]})
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				diff = append(diff, DiffLine{Op: "-", Text: a[i]})
+				i++
+			default:
+				diff = append(diff, DiffLine{Op: "+", Text: b[j]})
+				j++
+			}
+		}
+	}
+	for _, line := range a[i:] {
+		diff = append(diff, DiffLine{Op: "-", Text: line})
+	}
+	for _, line := range b[j:] {
+		diff = append(diff, DiffLine{Op: "+", Text: line})
+	}
+
+	for _, line := range before[len(before)-suffix:] {
+		diff = append(diff, DiffLine{Op: " ", Text: line})
+	}
+	return diff
+}
+
+// splitLines splits content into lines, ignoring a final newline.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -1,12 +1,14 @@
 package daemon
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,44 +21,6 @@ func newHostsManagerWithPaths(hostsPath, backupDir string) *HostsManager {
 	}
 }
 
-// readManagedEntries reads the lolcathost-managed entries from the hosts file (for testing).
-func (m *HostsManager) readManagedEntries() ([]HostEntry, error) {
-	content, err := os.ReadFile(m.hostsPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read hosts file: %w", err)
-	}
-
-	var entries []HostEntry
-	inManagedSection := false
-
-	for _, line := range strings.Split(string(content), "\n") {
-		line = strings.TrimSpace(line)
-
-		if line == markerStart {
-			inManagedSection = true
-			continue
-		}
-		if line == markerEnd {
-			inManagedSection = false
-			continue
-		}
-
-		if inManagedSection && !strings.HasPrefix(line, "#") && line != "" {
-			matches := entryRegex.FindStringSubmatch(line)
-			if len(matches) == 4 {
-				entries = append(entries, HostEntry{
-					IP:      matches[1],
-					Domain:  matches[2],
-					Alias:   matches[3],
-					Enabled: true,
-				})
-			}
-		}
-	}
-
-	return entries, nil
-}
-
 func TestHostsManager_readManagedEntries(t *testing.T) {
 	tmpDir := t.TempDir()
 	hostsPath := filepath.Join(tmpDir, "hosts")
@@ -103,6 +67,82 @@ func TestHostsManager_readManagedEntries_NoSection(t *testing.T) {
 	assert.Empty(t, entries)
 }
 
+func TestHostsManager_readManagedSection_Unparseable(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+
+	hostsContent := `127.0.0.1	localhost
+
+# ========== LOLCATHOST MANAGED - DO NOT EDIT ==========
+127.0.0.1	example.com	# lolcathost:example-local
+127.0.0.1	edited.com	# lolcathost: my alias
+# a comment
+# ========== END LOLCATHOST ==========
+`
+	err := os.WriteFile(hostsPath, []byte(hostsContent), 0644)
+	require.NoError(t, err)
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+	entries, unparseable, err := manager.readManagedSection()
+	require.NoError(t, err)
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "example-local", entries[0].Alias)
+	assert.Equal(t, []string{"127.0.0.1\tedited.com\t# lolcathost: my alias"}, unparseable)
+}
+
+func TestHostsManager_externalLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+
+	hostsContent := `127.0.0.1	localhost
+10.0.0.2	api.local www.local
+# 10.0.0.3	api.local
+10.0.0.4	API.local	# uppercase
+
+# ========== LOLCATHOST MANAGED - DO NOT EDIT ==========
+127.0.0.1	api.local	# lolcathost:api
+# ========== END LOLCATHOST ==========
+`
+	err := os.WriteFile(hostsPath, []byte(hostsContent), 0644)
+	require.NoError(t, err)
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+	lines, err := manager.externalLines("api.local")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"10.0.0.2\tapi.local www.local",
+		"10.0.0.4\tAPI.local\t# uppercase",
+	}, lines)
+}
+
+func TestHostsManager_ImportUnmanaged(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+
+	hostsContent := `127.0.0.1	localhost
+::1	localhost ip6-localhost
+10.0.0.2	api.local www.local	# staging
+# 10.0.0.3	commented.local
+fd00::2	API.local
+
+# ========== LOLCATHOST MANAGED - DO NOT EDIT ==========
+127.0.0.1	managed.local	# lolcathost:managed
+# ========== END LOLCATHOST ==========
+`
+	require.NoError(t, os.WriteFile(hostsPath, []byte(hostsContent), 0644))
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+	entries, err := manager.ImportUnmanaged()
+	require.NoError(t, err)
+
+	assert.Equal(t, []HostEntry{
+		{IP: "10.0.0.2", IPs: []string{"10.0.0.2", "fd00::2"}, Domain: "api.local"},
+		{IP: "10.0.0.2", Domain: "www.local"},
+	}, entries)
+}
+
 func TestHostsManager_WriteManagedEntries(t *testing.T) {
 	tmpDir := t.TempDir()
 	hostsPath := filepath.Join(tmpDir, "hosts")
@@ -139,6 +179,38 @@ func TestHostsManager_WriteManagedEntries(t *testing.T) {
 	assert.Contains(t, contentStr, "# ========== END LOLCATHOST ==========")
 }
 
+func TestHostsManager_WriteManagedEntries_MultipleIPs(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+
+	err := os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n"), 0644)
+	require.NoError(t, err)
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+
+	entries := []HostEntry{
+		{IP: "127.0.0.1", IPs: []string{"127.0.0.1", "::1"}, Domain: "dual.local", Alias: "dual", Enabled: true},
+		{IP: "127.0.0.1", Domain: "single.local", Alias: "single", Enabled: true},
+	}
+	require.NoError(t, manager.WriteManagedEntries(entries))
+
+	content, err := os.ReadFile(hostsPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "127.0.0.1\tdual.local\t# lolcathost:dual\n::1\tdual.local\t# lolcathost:dual\n")
+
+	read, err := manager.readManagedEntries()
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	assert.Equal(t, []string{"127.0.0.1", "::1"}, read[0].Addresses())
+	assert.Equal(t, "127.0.0.1", read[0].IP)
+	assert.Equal(t, []string{"127.0.0.1"}, read[1].Addresses())
+	assert.Nil(t, read[1].IPs)
+
+	diff := diffEntries(read[:1], []HostEntry{{IP: "::1", Domain: "dual.local", Alias: "dual", Enabled: true}})
+	assert.Equal(t, []string{"dual.local → 127.0.0.1"}, diff.Removed)
+	assert.Empty(t, diff.Added)
+}
+
 func TestHostsManager_WriteManagedEntries_UpdatesExisting(t *testing.T) {
 	tmpDir := t.TempDir()
 	hostsPath := filepath.Join(tmpDir, "hosts")
@@ -172,6 +244,67 @@ func TestHostsManager_WriteManagedEntries_UpdatesExisting(t *testing.T) {
 	assert.NotContains(t, contentStr, "old.com")
 }
 
+func TestHostsManager_WriteManagedEntries_ExternalClobber(t *testing.T) {
+	entries := []HostEntry{
+		{IP: "127.0.0.1", Domain: "new.com", Alias: "new", Enabled: true},
+	}
+
+	setup := func(t *testing.T) (*HostsManager, string) {
+		tmpDir := t.TempDir()
+		hostsPath := filepath.Join(tmpDir, "hosts")
+		require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n"), 0644))
+		return newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups")), hostsPath
+	}
+	// clobber replaces the file the way another hosts file manager would,
+	// dropping the managed section
+	clobber := func(t *testing.T, hostsPath string) {
+		require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n10.0.0.1\tvpn.corp\n"), 0644))
+	}
+
+	t.Run("retries once", func(t *testing.T) {
+		manager, hostsPath := setup(t)
+		writes := 0
+		manager.afterWrite = func() {
+			writes++
+			if writes == 1 {
+				clobber(t, hostsPath)
+			}
+		}
+
+		require.NoError(t, manager.writeManagedEntries(entries))
+		assert.Equal(t, 2, writes)
+
+		content, err := os.ReadFile(hostsPath)
+		require.NoError(t, err)
+		// The other program's entry survives the retry
+		assert.Contains(t, string(content), "vpn.corp")
+		assert.Contains(t, string(content), "new.com\t# lolcathost:new")
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		manager, hostsPath := setup(t)
+		writes := 0
+		manager.afterWrite = func() {
+			writes++
+			clobber(t, hostsPath)
+		}
+
+		err := manager.writeManagedEntries(entries)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "verification failed")
+		assert.Equal(t, maxWriteAttempts, writes)
+	})
+}
+
+func TestExtractManagedSection(t *testing.T) {
+	section := markerStart + "\n127.0.0.1\tnew.com\t# lolcathost:new\n" + markerEnd + "\n"
+
+	assert.Equal(t, section, extractManagedSection("127.0.0.1\tlocalhost\n\n"+section))
+	assert.Equal(t, section, extractManagedSection("  "+strings.ReplaceAll(section, "\n", "  \n")))
+	assert.Empty(t, extractManagedSection("127.0.0.1\tlocalhost\n"))
+	assert.Empty(t, extractManagedSection(markerStart+"\n127.0.0.1\tnew.com\n"))
+}
+
 func TestHostsManager_CreateBackup(t *testing.T) {
 	tmpDir := t.TempDir()
 	hostsPath := filepath.Join(tmpDir, "hosts")
@@ -183,7 +316,7 @@ func TestHostsManager_CreateBackup(t *testing.T) {
 
 	manager := newHostsManagerWithPaths(hostsPath, backupDir)
 
-	err = manager.CreateBackup()
+	_, err = manager.CreateBackup("")
 	require.NoError(t, err)
 
 	// Verify backup exists
@@ -199,6 +332,72 @@ func TestHostsManager_CreateBackup(t *testing.T) {
 	assert.Equal(t, hostsContent, string(backupContent))
 }
 
+func TestHostsManager_CreateBackup_Label(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	backupDir := filepath.Join(tmpDir, "backups")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n"), 0644))
+
+	manager := newHostsManagerWithPaths(hostsPath, backupDir)
+	backup, err := manager.CreateBackup("Before Import/../x")
+	require.NoError(t, err)
+	assert.Equal(t, "before-import-x", backup.Label)
+	assert.Equal(t, int64(len("127.0.0.1\tlocalhost\n")), backup.Size)
+
+	backups, err := manager.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "before-import-x", backups[0].Label)
+	assert.True(t, strings.HasSuffix(backups[0].Name, ".before-import-x.bak"))
+
+	content, err := manager.GetBackupContent(backups[0].Name)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1\tlocalhost\n", content)
+
+	require.NoError(t, os.WriteFile(hostsPath, []byte("modified\n"), 0644))
+	require.NoError(t, manager.RestoreBackup(backup.Name))
+	restored, err := os.ReadFile(hostsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1\tlocalhost\n", string(restored))
+}
+
+func TestSanitizeBackupLabel(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"before-import":         "before-import",
+		"Before Import":         "before-import",
+		"../../etc/passwd":      "etc-passwd",
+		"---":                   "",
+		"risky change #1!":      "risky-change-1",
+		strings.Repeat("a", 50): strings.Repeat("a", maxBackupLabelLength),
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, sanitizeBackupLabel(in), "label %q", in)
+	}
+}
+
+func TestParseBackupName(t *testing.T) {
+	tests := []struct {
+		name  string
+		label string
+		ok    bool
+	}{
+		{"hosts.20231201-120000.bak", "", true},
+		{"hosts.20231201-120000.before-import.bak", "before-import", true},
+		{"hosts.20231201-120000..bak", "", false},
+		{"hosts.20231201-120000.Before.bak", "", false},
+		{"hosts.20231201-120000.a.b.bak", "", false},
+		{"hosts.20231201-120000.../x.bak", "", false},
+		{"../hosts.20231201-120000.bak", "", false},
+		{"hosts.bak", "", false},
+	}
+	for _, tt := range tests {
+		label, ok := parseBackupName(tt.name)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.label, label, tt.name)
+	}
+}
+
 func TestHostsManager_ListBackups(t *testing.T) {
 	tmpDir := t.TempDir()
 	hostsPath := filepath.Join(tmpDir, "hosts")
@@ -254,7 +453,7 @@ func TestHostsManager_RestoreBackup(t *testing.T) {
 	manager := newHostsManagerWithPaths(hostsPath, backupDir)
 
 	// Create backup
-	err = manager.CreateBackup()
+	_, err = manager.CreateBackup("")
 	require.NoError(t, err)
 
 	// Modify hosts file
@@ -274,6 +473,15 @@ func TestHostsManager_RestoreBackup(t *testing.T) {
 	content, err := os.ReadFile(hostsPath)
 	require.NoError(t, err)
 	assert.Equal(t, initialContent, string(content))
+
+	// The pre-restore state is kept under a label
+	backups, err = manager.ListBackups()
+	require.NoError(t, err)
+	var labels []string
+	for _, b := range backups {
+		labels = append(labels, b.Label)
+	}
+	assert.Contains(t, labels, "before-rollback")
 }
 
 func TestHostsManager_RestoreBackup_InvalidName(t *testing.T) {
@@ -287,6 +495,8 @@ func TestHostsManager_RestoreBackup_InvalidName(t *testing.T) {
 		"../../../etc/passwd",
 		"hosts.bak",        // Missing timestamp
 		"notahosts.backup", // Wrong format
+		"hosts.20231201-120000.../../etc/passwd.bak",
+		"hosts.20231201-120000.Bad Label.bak",
 		"",
 	}
 
@@ -298,6 +508,107 @@ func TestHostsManager_RestoreBackup_InvalidName(t *testing.T) {
 	}
 }
 
+func TestHostsManager_DeleteBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n"), 0644))
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+	backup, err := manager.CreateBackup("old")
+	require.NoError(t, err)
+
+	require.NoError(t, manager.DeleteBackup(backup.Name))
+	backups, err := manager.ListBackups()
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	err = manager.DeleteBackup(backup.Name)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestHostsManager_DeleteBackup_InvalidName(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("keep me"), 0644))
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+
+	tests := []string{
+		"../hosts",
+		"hosts.bak",
+		"notahosts.backup",
+		"hosts.20231201-120000.../../hosts.bak",
+		"",
+	}
+
+	for _, name := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, manager.DeleteBackup(name))
+		})
+	}
+
+	_, err := os.Stat(hostsPath)
+	assert.NoError(t, err)
+}
+
+func TestDiffLines(t *testing.T) {
+	render := func(diff []DiffLine) []string {
+		var out []string
+		for _, d := range diff {
+			out = append(out, d.Op+d.Text)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name          string
+		before, after []string
+		want          []string
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}, []string{" a", " b"}},
+		{"both empty", nil, nil, nil},
+		{"added", []string{"a", "c"}, []string{"a", "b", "c"}, []string{" a", "+b", " c"}},
+		{"removed", []string{"a", "b", "c"}, []string{"a", "c"}, []string{" a", "-b", " c"}},
+		{"changed", []string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{" a", "-b", "+x", " c"}},
+		{"from empty", nil, []string{"a"}, []string{"+a"}},
+		{
+			"interleaved",
+			[]string{"a", "b", "c", "d"},
+			[]string{"b", "x", "d", "e"},
+			[]string{"-a", " b", "-c", "+x", " d", "+e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, render(diffLines(tt.before, tt.after)))
+		})
+	}
+}
+
+func TestHostsManager_BackupDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n10.0.0.1\told.local\n"), 0644))
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+	backup, err := manager.CreateBackup("")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n10.0.0.2\tnew.local\n"), 0644))
+
+	diff, err := manager.BackupDiff(backup.Name)
+	require.NoError(t, err)
+	assert.Equal(t, []DiffLine{
+		{Op: " ", Text: "127.0.0.1\tlocalhost"},
+		{Op: "-", Text: "10.0.0.1\told.local"},
+		{Op: "+", Text: "10.0.0.2\tnew.local"},
+	}, diff)
+
+	_, err = manager.BackupDiff("../hosts")
+	assert.Error(t, err)
+}
+
 func TestHostsManager_CleanupBackups(t *testing.T) {
 	tmpDir := t.TempDir()
 	hostsPath := filepath.Join(tmpDir, "hosts")
@@ -310,7 +621,7 @@ func TestHostsManager_CleanupBackups(t *testing.T) {
 
 	// Create more than MaxBackups
 	for i := 0; i < MaxBackups+5; i++ {
-		err = manager.CreateBackup()
+		_, err = manager.CreateBackup("")
 		require.NoError(t, err)
 	}
 
@@ -320,6 +631,60 @@ func TestHostsManager_CleanupBackups(t *testing.T) {
 	assert.LessOrEqual(t, len(backups), MaxBackups)
 }
 
+func TestHostsManager_CleanupBackups_MaxBackups(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("localhost"), 0644))
+
+	manager := newHostsManagerWithPaths(hostsPath, filepath.Join(tmpDir, "backups"))
+	manager.SetMaxBackups(3)
+
+	// Labels keep backups taken within the same second apart
+	for i := 0; i < 5; i++ {
+		_, err := manager.CreateBackup(fmt.Sprintf("b%d", i))
+		require.NoError(t, err)
+	}
+
+	backups, err := manager.ListBackups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 3)
+
+	manager.SetMaxBackups(0)
+	for i := 5; i < 15; i++ {
+		_, err := manager.CreateBackup(fmt.Sprintf("b%d", i))
+		require.NoError(t, err)
+	}
+
+	backups, err = manager.ListBackups()
+	require.NoError(t, err)
+	assert.Len(t, backups, MaxBackups)
+}
+
+func TestHostsManager_CleanupBackups_RotatesLabeled(t *testing.T) {
+	tmpDir := t.TempDir()
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	backupDir := filepath.Join(tmpDir, "backups")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("localhost"), 0644))
+	require.NoError(t, os.MkdirAll(backupDir, 0755))
+
+	labeled := filepath.Join(backupDir, "hosts.20200101-120000.before-refactor.bak")
+	require.NoError(t, os.WriteFile(labeled, []byte("localhost"), 0644))
+
+	manager := newHostsManagerWithPaths(hostsPath, backupDir)
+	manager.SetMaxBackups(2)
+	for _, label := range []string{"a", "b"} {
+		_, err := manager.CreateBackup(label)
+		require.NoError(t, err)
+	}
+
+	// A label doesn't protect a backup from the rolling retention
+	_, err := os.Stat(labeled)
+	assert.True(t, os.IsNotExist(err))
+	backups, err := manager.ListBackups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 2)
+}
+
 func TestHostsManager_RemoveManagedSection(t *testing.T) {
 	manager := &HostsManager{}
 
@@ -384,9 +749,78 @@ func TestHostsManager_BuildManagedSection(t *testing.T) {
 	assert.Contains(t, result, "# ========== END LOLCATHOST ==========")
 }
 
+func TestDiffEntries(t *testing.T) {
+	before := []HostEntry{
+		{IP: "127.0.0.1", Domain: "a.com", Alias: "a", Enabled: true},
+		{IP: "127.0.0.1", Domain: "b.com", Alias: "b", Enabled: true},
+		{IP: "127.0.0.1", Domain: "c.com", Alias: "c", Enabled: true},
+	}
+	after := []HostEntry{
+		{IP: "127.0.0.1", Domain: "a.com", Alias: "a", Enabled: true},
+		{IP: "10.0.0.1", Domain: "b.com", Alias: "b", Enabled: true},
+		{IP: "127.0.0.1", Domain: "c.com", Alias: "c", Enabled: false},
+		{IP: "127.0.0.1", Domain: "d.com", Alias: "d", Enabled: true},
+	}
+
+	diff := diffEntries(before, after)
+	assert.Equal(t, []string{"b.com → 10.0.0.1", "d.com → 127.0.0.1"}, diff.Added)
+	assert.Equal(t, []string{"b.com → 127.0.0.1", "c.com → 127.0.0.1"}, diff.Removed)
+
+	assert.True(t, diffEntries(before, before).IsEmpty())
+}
+
+func TestServer_LogSyncDiff(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	logPath := filepath.Join(tmpDir, "audit.log")
+	logger, err := NewAuditLogger(logPath)
+	require.NoError(t, err)
+	defer func() { _ = logger.Close() }()
+	server.auditLogger = logger
+
+	readEntries := func() []AuditEntry {
+		content, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		var entries []AuditEntry
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var entry AuditEntry
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	// A client's change is attributed to that client
+	req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{Domain: "diff.local", IP: "127.0.0.1", Group: "development", Enabled: true})
+	require.True(t, server.handleRequest(req, &PeerCredentials{UID: 501, PID: 42}).IsOK())
+
+	var diffs []AuditEntry
+	for _, e := range readEntries() {
+		if e.Action == "sync_diff" {
+			diffs = append(diffs, e)
+		}
+	}
+	require.Len(t, diffs, 1)
+	assert.Equal(t, uint32(501), diffs[0].UID)
+	assert.Equal(t, int32(42), diffs[0].PID)
+	details := diffs[0].Details.(map[string]any)
+	assert.Equal(t, "client", details["source"])
+	assert.Contains(t, details["added"], "diff.local → 127.0.0.1")
+
+	// The daemon's own syncs are marked as such
+	server.peer = nil
+	server.logSyncDiff(SyncDiff{Added: []string{"x.local → 127.0.0.1"}})
+	entries := readEntries()
+	last := entries[len(entries)-1]
+	assert.Equal(t, "sync_diff", last.Action)
+	assert.Equal(t, "daemon", last.Details.(map[string]any)["source"])
+	assert.Equal(t, uint32(0), last.UID)
+}
+
 // Matrix tests for hosts file parsing
 func TestHostsManager_readManagedEntries_Matrix(t *testing.T) {
-	ips := []string{"127.0.0.1", "192.168.1.1", "::1"}
+	ips := []string{"127.0.0.1", "192.168.1.1", "::1", "fe80::1%eth0"}
 	domains := []string{"example.com", "sub.example.com", "my-app.test"}
 	aliases := []string{"test", "my-alias", "app-1"}
 
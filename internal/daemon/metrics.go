@@ -0,0 +1,151 @@
+package daemon
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lukaszraczylo/lolcathost/internal/config"
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
+)
+
+// metrics is a point-in-time snapshot of daemon counters.
+type metrics struct {
+	HostsTotal  int
+	HostsActive int
+	Requests    int64
+	Uptime      int64
+	Backups     int
+	ByType      map[protocol.RequestType]int64
+	Errors      map[protocol.ErrorCode]int64
+	RateLimited int64
+	Reloads     int64
+}
+
+// data converts m to its wire form.
+func (m metrics) data() protocol.MetricsData {
+	return protocol.MetricsData{
+		HostsTotal:     m.HostsTotal,
+		HostsActive:    m.HostsActive,
+		Backups:        m.Backups,
+		Uptime:         m.Uptime,
+		Requests:       m.Requests,
+		RequestsByType: m.ByType,
+		ErrorsByCode:   m.Errors,
+		RateLimited:    m.RateLimited,
+		ConfigReloads:  m.Reloads,
+	}
+}
+
+// countRequest records a request that passed rate limiting.
+func (s *Server) countRequest(reqType protocol.RequestType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requestCount++
+	if s.requestsByType == nil {
+		s.requestsByType = make(map[protocol.RequestType]int64)
+	}
+	s.requestsByType[reqType]++
+}
+
+// countResponse records the error code of a failed response.
+func (s *Server) countResponse(resp *protocol.Response) {
+	if resp.IsOK() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.errorsByCode == nil {
+		s.errorsByCode = make(map[protocol.ErrorCode]int64)
+	}
+	s.errorsByCode[resp.Code]++
+}
+
+// countReload records a config reload, whichever way it was triggered.
+func (s *Server) countReload() {
+	s.mu.Lock()
+	s.reloads++
+	s.mu.Unlock()
+}
+
+// collectMetrics gathers the current counters. Backups are counted on a
+// best-effort basis; a failure to list them reports zero.
+func (s *Server) collectMetrics() metrics {
+	s.mu.RLock()
+	m := metrics{
+		Requests:    s.requestCount,
+		Uptime:      nowUnix() - s.startTime,
+		ByType:      maps.Clone(s.requestsByType),
+		Errors:      maps.Clone(s.errorsByCode),
+		RateLimited: s.rateLimited,
+		Reloads:     s.reloads,
+	}
+	if m.ByType == nil {
+		m.ByType = make(map[protocol.RequestType]int64)
+	}
+	if m.Errors == nil {
+		m.Errors = make(map[protocol.ErrorCode]int64)
+	}
+	s.mu.RUnlock()
+
+	// Without a config there are simply no hosts to count
+	_ = s.config.With(func(cfg *config.Config) error {
+		for _, h := range cfg.GetAllHosts() {
+			m.HostsTotal++
+			if h.Enabled {
+				m.HostsActive++
+			}
+		}
+		return nil
+	})
+
+	if backups, err := s.hosts.ListBackups(); err == nil {
+		m.Backups = len(backups)
+	}
+
+	return m
+}
+
+// formatPrometheus renders m in the Prometheus text exposition format.
+func formatPrometheus(m metrics) string {
+	var b strings.Builder
+	gauge := func(name, help string, value int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&b, "%s %d\n", name, value)
+	}
+	counter := func(name, help string, value int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&b, "# TYPE %s counter\n", name)
+		fmt.Fprintf(&b, "%s %d\n", name, value)
+	}
+
+	gauge("lolcathost_hosts_total", "Number of configured host entries.", int64(m.HostsTotal))
+	gauge("lolcathost_hosts_active", "Number of enabled host entries.", int64(m.HostsActive))
+	counter("lolcathost_requests_total", "Number of requests handled since the daemon started.", m.Requests)
+	gauge("lolcathost_uptime_seconds", "Seconds since the daemon started.", m.Uptime)
+	gauge("lolcathost_backups", "Number of hosts file backups on disk.", int64(m.Backups))
+	counter("lolcathost_rate_limited_total", "Number of requests rejected by the rate limiter.", m.RateLimited)
+	counter("lolcathost_config_reloads_total", "Number of times the config was reloaded.", m.Reloads)
+
+	if len(m.ByType) > 0 {
+		b.WriteString("# HELP lolcathost_requests_by_type_total Number of requests handled, by request type.\n")
+		b.WriteString("# TYPE lolcathost_requests_by_type_total counter\n")
+		for _, t := range slices.Sorted(maps.Keys(m.ByType)) {
+			fmt.Fprintf(&b, "lolcathost_requests_by_type_total{type=%q} %d\n", t, m.ByType[t])
+		}
+	}
+	if len(m.Errors) > 0 {
+		b.WriteString("# HELP lolcathost_errors_total Number of error responses, by error code.\n")
+		b.WriteString("# TYPE lolcathost_errors_total counter\n")
+		for _, c := range slices.Sorted(maps.Keys(m.Errors)) {
+			fmt.Fprintf(&b, "lolcathost_errors_total{code=%q} %d\n", c, m.Errors[c])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatPrometheus(t *testing.T) {
+	text := formatPrometheus(metrics{
+		HostsTotal:  3,
+		HostsActive: 2,
+		Requests:    42,
+		Uptime:      120,
+		Backups:     5,
+	})
+
+	assert.Contains(t, text, "# TYPE lolcathost_hosts_total gauge\nlolcathost_hosts_total 3\n")
+	assert.Contains(t, text, "lolcathost_hosts_active 2\n")
+	assert.Contains(t, text, "# TYPE lolcathost_requests_total counter\nlolcathost_requests_total 42\n")
+	assert.Contains(t, text, "lolcathost_uptime_seconds 120\n")
+	assert.Contains(t, text, "lolcathost_backups 5\n")
+	assert.NotContains(t, text, "lolcathost_requests_by_type_total")
+	assert.NotContains(t, text, "lolcathost_errors_total")
+}
+
+func TestFormatPrometheus_Labeled(t *testing.T) {
+	text := formatPrometheus(metrics{
+		ByType:      map[protocol.RequestType]int64{protocol.RequestPing: 4, protocol.RequestAdd: 1},
+		Errors:      map[protocol.ErrorCode]int64{protocol.ErrCodeRateLimited: 2},
+		RateLimited: 2,
+		Reloads:     3,
+	})
+
+	assert.Contains(t, text, "lolcathost_rate_limited_total 2\n")
+	assert.Contains(t, text, "lolcathost_config_reloads_total 3\n")
+	assert.Contains(t, text, "# TYPE lolcathost_requests_by_type_total counter\n"+
+		`lolcathost_requests_by_type_total{type="add"} 1`+"\n"+
+		`lolcathost_requests_by_type_total{type="ping"} 4`+"\n")
+	assert.Contains(t, text, `lolcathost_errors_total{code="RATE_LIMITED"} 2`+"\n")
+}
+
+func TestServer_HandleMetrics(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.rateLimiter = NewRateLimiter(1, time.Minute)
+
+	send := func(line string, creds *PeerCredentials) *protocol.Response {
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		go func() { _ = server.handleLine(serverConn, []byte(line), creds) }()
+
+		var resp protocol.Response
+		require.NoError(t, json.NewDecoder(clientConn).Decode(&resp))
+		return &resp
+	}
+
+	root := &PeerCredentials{UID: 0, PID: 100}
+	user := &PeerCredentials{UID: 501, PID: 200}
+	assert.True(t, send(`{"type":"ping"}`, root).IsOK())
+	assert.True(t, send(`{"type":"ping"}`, user).IsOK())
+	assert.Equal(t, protocol.ErrCodeRateLimited, send(`{"type":"ping"}`, user).Code)
+	assert.Equal(t, protocol.ErrCodeNotFound, send(`{"type":"delete","payload":{"alias":"missing"}}`, root).Code)
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, send(`not json`, root).Code)
+	require.True(t, server.handleReload().IsOK())
+
+	resp := server.handleMetrics()
+	require.Equal(t, "ok", resp.Status)
+
+	var data protocol.MetricsData
+	require.NoError(t, resp.ParseData(&data))
+
+	// Rejected and unparseable requests count as errors, not requests
+	assert.Equal(t, int64(3), data.Requests)
+	assert.Equal(t, map[protocol.RequestType]int64{
+		protocol.RequestPing:   2,
+		protocol.RequestDelete: 1,
+	}, data.RequestsByType)
+	assert.Equal(t, map[protocol.ErrorCode]int64{
+		protocol.ErrCodeRateLimited:    1,
+		protocol.ErrCodeNotFound:       1,
+		protocol.ErrCodeInvalidRequest: 1,
+	}, data.ErrorsByCode)
+	assert.Equal(t, int64(1), data.RateLimited)
+	assert.Equal(t, int64(1), data.ConfigReloads)
+	assert.Equal(t, 1, data.HostsTotal)
+}
+
+func TestServer_HandlePrometheus(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	resp := server.handlePrometheus()
+	require.Equal(t, "ok", resp.Status)
+
+	var data protocol.PrometheusData
+	require.NoError(t, resp.ParseData(&data))
+
+	// The default test config has one disabled host.
+	assert.Contains(t, data.Text, "lolcathost_hosts_total 1\n")
+	assert.Contains(t, data.Text, "lolcathost_hosts_active 0\n")
+}
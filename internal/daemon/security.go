@@ -7,17 +7,28 @@ import (
 	"os"
 	"os/user"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/lukaszraczylo/lolcathost/internal/config"
 )
 
 const (
 	// AuditLogPath is the path to the audit log file.
 	AuditLogPath = "/var/log/lolcathost/audit.log"
-	// RateLimit is the maximum requests per minute per PID.
+	// MaxAuditSize is the size at which the audit log is rotated unless
+	// settings.auditMaxSize says otherwise.
+	MaxAuditSize = 10 << 20
+	// MaxAuditFiles is how many rotated audit logs (audit.log.1 to .N) are
+	// kept unless settings.auditMaxFiles says otherwise.
+	MaxAuditFiles = 5
+	// RateLimit is the default maximum of requests per window per PID.
 	RateLimit = 100
-	// RateLimitWindow is the time window for rate limiting.
+	// RateLimitWindow is the default time window for rate limiting.
 	RateLimitWindow = time.Minute
+	// AuthCacheTTL is how long a group membership decision is cached per UID.
+	AuthCacheTTL = 30 * time.Second
 )
 
 // pidRateBucket holds rate limiting data for a single PID using a ring buffer.
@@ -44,6 +55,20 @@ func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
 	}
 }
 
+// SetLimit changes the limit and window. Changing either starts every PID
+// afresh, since the buckets are sized by the limit.
+func (r *RateLimiter) SetLimit(limit int, window time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limit == r.limit && window == r.window {
+		return
+	}
+	r.limit = limit
+	r.window = window
+	clear(r.buckets)
+}
+
 // Allow checks if a request from the given PID should be allowed.
 func (r *RateLimiter) Allow(pid int32) bool {
 	r.mu.Lock()
@@ -111,12 +136,84 @@ func (r *RateLimiter) Cleanup() {
 	}
 }
 
-// AuditLogger handles audit logging.
-type AuditLogger struct {
+// authCacheKey identifies a cached group membership decision.
+type authCacheKey struct {
+	uid uint32
+	gid uint32
+}
+
+// authCacheEntry holds a cached group membership decision.
+type authCacheEntry struct {
+	member  bool
+	expires time.Time
+}
+
+// AuthCache caches supplementary group membership lookups per UID, so clients
+// that reconnect often don't trigger a system user lookup on every connection.
+type AuthCache struct {
 	mu      sync.Mutex
-	file    *os.File
-	path    string
-	encoder *json.Encoder
+	entries map[authCacheKey]authCacheEntry
+	ttl     time.Duration
+	lookup  func(uid, gid uint32) bool
+}
+
+// NewAuthCache creates a new auth cache.
+func NewAuthCache(ttl time.Duration) *AuthCache {
+	return &AuthCache{
+		entries: make(map[authCacheKey]authCacheEntry),
+		ttl:     ttl,
+		lookup:  isUserInGroup,
+	}
+}
+
+// IsUserInGroup reports whether the user is a member of the group, using a
+// cached decision when one hasn't expired yet.
+func (a *AuthCache) IsUserInGroup(uid, gid uint32) bool {
+	key := authCacheKey{uid: uid, gid: gid}
+	now := time.Now()
+
+	a.mu.Lock()
+	entry, exists := a.entries[key]
+	a.mu.Unlock()
+
+	if exists && now.Before(entry.expires) {
+		return entry.member
+	}
+
+	// Look up outside the lock; a concurrent miss only costs a duplicate lookup
+	member := a.lookup(uid, gid)
+
+	a.mu.Lock()
+	a.entries[key] = authCacheEntry{member: member, expires: now.Add(a.ttl)}
+	a.mu.Unlock()
+
+	return member
+}
+
+// Cleanup removes expired entries from the cache.
+func (a *AuthCache) Cleanup() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range a.entries {
+		if !now.Before(entry.expires) {
+			delete(a.entries, key)
+		}
+	}
+}
+
+// AuditLogger handles audit logging. Once the log would grow past maxSize it
+// is renamed to path.1, older logs shift up to path.maxFiles and the oldest is
+// dropped.
+type AuditLogger struct {
+	mu       sync.Mutex
+	file     *os.File
+	path     string
+	size     int64
+	maxSize  int64
+	maxFiles int
+	format   config.LogFormat
 }
 
 // AuditEntry represents a single audit log entry.
@@ -139,17 +236,94 @@ func NewAuditLogger(path string) (*AuditLogger, error) {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
 	}
 
+	a := &AuditLogger{
+		path:     path,
+		maxSize:  MaxAuditSize,
+		maxFiles: MaxAuditFiles,
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// open opens the log for appending and records its current size. Caller must
+// hold a.mu, except during construction.
+func (a *AuditLogger) open() error {
 	// #nosec G302,G304,G306 - Path is constant, permissions are intentional for audit log
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open audit log: %w", err)
+		_ = file.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated logs up by one, moves the current log to path.1
+// and starts a fresh one. Caller must hold a.mu.
+func (a *AuditLogger) rotate() error {
+	_ = a.file.Close()
+	a.file = nil
+
+	_ = os.Remove(fmt.Sprintf("%s.%d", a.path, a.maxFiles))
+	for i := a.maxFiles - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1))
+	}
+	renameErr := os.Rename(a.path, a.path+".1")
+
+	// Reopen even if the rename failed, so logging carries on in place
+	if err := a.open(); err != nil {
+		return err
+	}
+	return renameErr
+}
+
+// SetFormat sets how entries are written from now on. config.LogFormatText
+// writes logfmt-style lines; any other format, including empty, writes JSON.
+func (a *AuditLogger) SetFormat(format config.LogFormat) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.format = format
+}
+
+// SetRotation sets the size at which the log is rotated and how many rotated
+// logs are kept. Zero or less restores the MaxAuditSize and MaxAuditFiles
+// defaults.
+func (a *AuditLogger) SetRotation(maxSize int64, maxFiles int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if maxSize <= 0 {
+		maxSize = MaxAuditSize
 	}
+	if maxFiles <= 0 {
+		maxFiles = MaxAuditFiles
+	}
+	a.maxSize = maxSize
+	a.maxFiles = maxFiles
+}
 
-	return &AuditLogger{
-		file:    file,
-		path:    path,
-		encoder: json.NewEncoder(file),
-	}, nil
+// text formats the entry as a single logfmt-style line, e.g.
+// 2024-01-01T12:00:00Z uid=501 pid=42 action=add success=false message="domain x is blocked".
+func (e AuditEntry) text() ([]byte, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s uid=%d pid=%d action=%s success=%t", e.Timestamp, e.UID, e.PID, e.Action, e.Success)
+	if e.Error != "" {
+		fmt.Fprintf(&sb, " message=%s", strconv.Quote(e.Error))
+	}
+	if e.Details != nil {
+		details, err := json.Marshal(e.Details)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&sb, " details=%s", details)
+	}
+	return []byte(sb.String()), nil
 }
 
 // Log writes an audit entry.
@@ -167,8 +341,34 @@ func (a *AuditLogger) Log(uid uint32, pid int32, action string, details any, suc
 		Error:     errMsg,
 	}
 
-	// Ignore encoding errors - audit logging should not fail the operation
-	_ = a.encoder.Encode(entry)
+	if a.file == nil {
+		return
+	}
+
+	// Ignore encoding and write errors - audit logging should not fail the operation
+	var line []byte
+	var err error
+	if a.format == config.LogFormatText {
+		line, err = entry.text()
+	} else {
+		line, err = json.Marshal(entry)
+	}
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	if a.size > 0 && a.size+int64(len(line)) > a.maxSize && a.maxFiles > 0 {
+		if err := a.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: audit log rotation failed: %v\n", err)
+			if a.file == nil {
+				return
+			}
+		}
+	}
+
+	n, _ := a.file.Write(line)
+	a.size += int64(n)
 }
 
 // Close closes the audit logger.
@@ -220,6 +420,16 @@ func isUserInGroup(uid uint32, targetGID uint32) bool {
 	return false
 }
 
+// resolveGroupGID returns the GID of the lolcathost group on this system,
+// falling back to LolcathostGID if the group can't be looked up.
+func resolveGroupGID() uint32 {
+	gid, err := lookupGroupGID("lolcathost")
+	if err != nil {
+		return LolcathostGID
+	}
+	return uint32(gid) // #nosec G115 - GIDs fit in uint32
+}
+
 // lookupGroupGID looks up a group by name and returns its GID.
 func lookupGroupGID(name string) (int, error) {
 	group, err := user.LookupGroup(name)
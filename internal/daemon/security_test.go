@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/lukaszraczylo/lolcathost/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -60,6 +63,19 @@ func TestRateLimiter_Allow(t *testing.T) {
 	})
 }
 
+func TestRateLimiter_SetLimit(t *testing.T) {
+	rl := NewRateLimiter(1, time.Minute)
+	assert.True(t, rl.Allow(1))
+	assert.False(t, rl.Allow(1))
+
+	// Raising the limit lets the same PID through again
+	rl.SetLimit(3, time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.True(t, rl.Allow(1))
+	}
+	assert.False(t, rl.Allow(1))
+}
+
 func TestRateLimiter_Cleanup(t *testing.T) {
 	rl := NewRateLimiter(10, 10*time.Millisecond)
 
@@ -79,6 +95,52 @@ func TestRateLimiter_Cleanup(t *testing.T) {
 	assert.Empty(t, rl.buckets)
 }
 
+// newCountingAuthCache returns an auth cache whose lookups are counted.
+func newCountingAuthCache(ttl time.Duration, lookups *int) *AuthCache {
+	cache := NewAuthCache(ttl)
+	cache.lookup = func(uid, gid uint32) bool {
+		*lookups++
+		return uid == 1000
+	}
+	return cache
+}
+
+func TestAuthCache_IsUserInGroup(t *testing.T) {
+	t.Run("caches decisions per uid", func(t *testing.T) {
+		lookups := 0
+		cache := newCountingAuthCache(time.Minute, &lookups)
+
+		assert.True(t, cache.IsUserInGroup(1000, LolcathostGID))
+		assert.True(t, cache.IsUserInGroup(1000, LolcathostGID))
+		assert.False(t, cache.IsUserInGroup(1001, LolcathostGID))
+		assert.False(t, cache.IsUserInGroup(1001, LolcathostGID))
+		assert.Equal(t, 2, lookups)
+	})
+
+	t.Run("expired decisions are looked up again", func(t *testing.T) {
+		lookups := 0
+		cache := newCountingAuthCache(10*time.Millisecond, &lookups)
+
+		cache.IsUserInGroup(1000, LolcathostGID)
+		time.Sleep(20 * time.Millisecond)
+		cache.IsUserInGroup(1000, LolcathostGID)
+		assert.Equal(t, 2, lookups)
+	})
+}
+
+func TestAuthCache_Cleanup(t *testing.T) {
+	lookups := 0
+	cache := newCountingAuthCache(10*time.Millisecond, &lookups)
+
+	cache.IsUserInGroup(1000, LolcathostGID)
+	cache.IsUserInGroup(1001, LolcathostGID)
+	assert.Len(t, cache.entries, 2)
+
+	time.Sleep(20 * time.Millisecond)
+	cache.Cleanup()
+	assert.Empty(t, cache.entries)
+}
+
 func TestAuditLogger_Log(t *testing.T) {
 	tmpDir := t.TempDir()
 	logPath := filepath.Join(tmpDir, "audit.log")
@@ -104,6 +166,114 @@ func TestAuditLogger_Log(t *testing.T) {
 	assert.Contains(t, contentStr, `"error":"sync failed"`)
 }
 
+func TestAuditLogger_TextFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.log")
+
+	logger, err := NewAuditLogger(logPath)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	logger.SetFormat(config.LogFormatText)
+	logger.Log(1000, 12345, "set", map[string]string{"alias": "test"}, true, "")
+	logger.Log(1000, 12345, "sync", nil, false, `sync "failed"`)
+
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}T\S+Z uid=1000 pid=12345 action=set success=true details=\{"alias":"test"\}$`, lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ` action=sync success=false message="sync \"failed\""`), lines[1])
+
+	logger.SetFormat(config.LogFormatJSON)
+	logger.Log(1000, 12345, "ping", nil, true, "")
+	content, err = os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"action":"ping"`)
+}
+
+func TestAuditLogger_Rotate(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.log")
+
+	logger, err := NewAuditLogger(logPath)
+	require.NoError(t, err)
+	defer logger.Close()
+	logger.maxSize = 1024
+	logger.maxFiles = 2
+
+	// Roughly 100 bytes per entry, so several rotations
+	for i := 0; i < 60; i++ {
+		logger.Log(1000, int32(i), "set", map[string]string{"alias": "test"}, true, "")
+	}
+
+	for _, path := range []string{logPath, logPath + ".1", logPath + ".2"} {
+		info, err := os.Stat(path)
+		require.NoError(t, err, path)
+		assert.LessOrEqual(t, info.Size(), int64(1024), path)
+	}
+	_, err = os.Stat(logPath + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	// The newest entry is in the live log, older ones were rotated out
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"pid":59`)
+	rotated, err := os.ReadFile(logPath + ".1")
+	require.NoError(t, err)
+	assert.NotContains(t, string(rotated), `"pid":59`)
+}
+
+func TestAuditLogger_SetRotation(t *testing.T) {
+	logger, err := NewAuditLogger(filepath.Join(t.TempDir(), "audit.log"))
+	require.NoError(t, err)
+	defer logger.Close()
+
+	logger.SetRotation(1<<20, 3)
+	assert.Equal(t, int64(1<<20), logger.maxSize)
+	assert.Equal(t, 3, logger.maxFiles)
+
+	logger.SetRotation(0, 0)
+	assert.Equal(t, int64(MaxAuditSize), logger.maxSize)
+	assert.Equal(t, MaxAuditFiles, logger.maxFiles)
+}
+
+func TestAuditLogger_RotateConcurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "audit.log")
+
+	logger, err := NewAuditLogger(logPath)
+	require.NoError(t, err)
+	defer logger.Close()
+	logger.maxSize = 2048
+	logger.maxFiles = 100
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				logger.Log(1000, int32(i), "set", nil, true, "")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every entry survives rotation exactly once
+	files, err := filepath.Glob(logPath + "*")
+	require.NoError(t, err)
+	require.Greater(t, len(files), 1)
+	lines := 0
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines += strings.Count(string(content), "\n")
+	}
+	assert.Equal(t, 400, lines)
+}
+
 func TestAuditLogger_CreatesDirectory(t *testing.T) {
 	tmpDir := t.TempDir()
 	logPath := filepath.Join(tmpDir, "subdir", "audit.log")
@@ -197,6 +367,35 @@ func BenchmarkRateLimiter_Cleanup(b *testing.B) {
 	}
 }
 
+func BenchmarkAuthCache_IsUserInGroup(b *testing.B) {
+	uid := uint32(os.Getuid())
+
+	// Both variants perform real user lookups and count how many were needed
+	b.Run("uncached", func(b *testing.B) {
+		lookups := 0
+		for i := 0; i < b.N; i++ {
+			lookups++
+			isUserInGroup(uid, LolcathostGID)
+		}
+		b.ReportMetric(float64(lookups)/float64(b.N), "lookups/op")
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		lookups := 0
+		cache := NewAuthCache(AuthCacheTTL)
+		cache.lookup = func(uid, gid uint32) bool {
+			lookups++
+			return isUserInGroup(uid, gid)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			cache.IsUserInGroup(uid, LolcathostGID)
+		}
+		b.ReportMetric(float64(lookups)/float64(b.N), "lookups/op")
+	})
+}
+
 func BenchmarkAuditLogger_Log(b *testing.B) {
 	tmpDir := b.TempDir()
 	logPath := filepath.Join(tmpDir, "audit.log")
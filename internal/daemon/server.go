@@ -3,11 +3,19 @@ package daemon
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -23,27 +31,83 @@ type Server struct {
 	socketPath   string
 	listener     net.Listener
 	config       *config.Manager
+	profiles     *config.Profiles // nil when profiles aren't set up
 	hosts        *HostsManager
 	flusher      *DNSFlusher
 	rateLimiter  *RateLimiter
+	authCache    *AuthCache
+	groupGID     uint32
 	auditLogger  *AuditLogger
 	mu           sync.RWMutex
+	opMu         sync.Mutex           // serializes config changes with hosts file syncs
+	flushErr     error                // last DNS flush failure of the current request, guarded by opMu
+	peer         *PeerCredentials     // client of the current request, nil for daemon-internal work, guarded by opMu
+	lastSync     *protocol.SyncRecord // most recent hosts file write, guarded by opMu
+	batch        *batchState          // set while a batch request runs, guarded by opMu
+	limitRoot    atomic.Bool          // rate limit root too (settings.rateLimitExemptRoot: false)
+	verbose      atomic.Bool          // print hosts file changes (settings.verbose)
 	running      bool
 	stopCh       chan struct{}
 	requestCount int64
 	startTime    int64
+	reconcile    *protocol.ReconcileData
+	// Counters reported by the metrics request, guarded by mu
+	requestsByType map[protocol.RequestType]int64
+	errorsByCode   map[protocol.ErrorCode]int64
+	rateLimited    int64
+	reloads        int64
 }
 
 // NewServer creates a new daemon server.
 func NewServer(socketPath string, cfgManager *config.Manager) *Server {
-	return &Server{
+	s := &Server{
 		socketPath:  socketPath,
 		config:      cfgManager,
 		hosts:       NewHostsManager(),
 		flusher:     NewDNSFlusher(FlushMethodAuto),
 		rateLimiter: NewRateLimiter(RateLimit, RateLimitWindow),
+		authCache:   NewAuthCache(AuthCacheTTL),
+		groupGID:    resolveGroupGID(),
 		stopCh:      make(chan struct{}),
 	}
+	s.applySettings()
+	return s
+}
+
+// applySettings applies the config's daemon settings that are kept outside
+// the config, such as the DNS flush method, the rate limit and the audit log
+// format. It runs whenever the config is loaded from disk or replaced.
+func (s *Server) applySettings() {
+	_ = s.config.With(func(cfg *config.Config) error {
+		s.flusher.SetMethod(FlushMethod(cfg.Settings.FlushMethod))
+
+		limit, window := RateLimit, RateLimitWindow
+		if cfg.Settings.RateLimit > 0 {
+			limit = cfg.Settings.RateLimit
+		}
+		if cfg.Settings.RateLimitWindow > 0 {
+			window = cfg.Settings.RateLimitWindow
+		}
+		s.rateLimiter.SetLimit(limit, window)
+		s.limitRoot.Store(!cfg.Settings.ExemptsRoot())
+		s.verbose.Store(cfg.Settings.Verbose)
+		s.hosts.SetMaxBackups(cfg.Settings.MaxBackups)
+		config.SetCustomBlockedDomains(cfg.Settings.BlockedDomains)
+		if s.auditLogger != nil {
+			s.auditLogger.SetFormat(cfg.Settings.LogFormat)
+			s.auditLogger.SetRotation(cfg.Settings.AuditMaxSize, cfg.Settings.AuditMaxFiles)
+		}
+		return nil
+	})
+}
+
+// ConfigChanged applies the settings of a config the file watcher reloaded.
+func (s *Server) ConfigChanged() {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+
+	s.countReload()
+	s.applySettings()
 }
 
 // Start starts the server.
@@ -64,15 +128,8 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to listen on socket: %w", err)
 	}
 
-	// Look up the lolcathost group GID dynamically
-	gid, err := lookupGroupGID("lolcathost")
-	if err != nil {
-		// Fall back to default GID if group lookup fails
-		gid = LolcathostGID
-	}
-
 	// Set socket group to lolcathost
-	if err := os.Chown(s.socketPath, 0, gid); err != nil {
+	if err := os.Chown(s.socketPath, 0, int(s.groupGID)); err != nil {
 		_ = listener.Close()
 		return fmt.Errorf("failed to set socket ownership: %w", err)
 	}
@@ -84,6 +141,7 @@ func (s *Server) Start() error {
 	// Try to create audit logger, but don't fail if it doesn't work
 	if logger, err := NewAuditLogger(AuditLogPath); err == nil {
 		s.auditLogger = logger
+		s.applySettings() // for the log format
 	}
 
 	go s.acceptLoop()
@@ -132,11 +190,91 @@ func (s *Server) acceptLoop() {
 	}
 }
 
-// LolcathostGID is the group ID for the lolcathost group.
+// LolcathostGID is the fallback group ID used when the lolcathost group
+// can't be looked up. Linux installs may assign a different GID.
 const LolcathostGID = 850
 
-// connectionReadTimeout is the maximum time to wait for a client to send data.
-const connectionReadTimeout = 30 * time.Second
+// DefaultIdleTimeout is how long a connection may go without sending a
+// request before it is closed, unless the idleTimeout setting overrides it.
+const DefaultIdleTimeout = 5 * time.Minute
+
+// idleTimeout returns the configured connection idle timeout.
+func (s *Server) idleTimeout() time.Duration {
+	timeout := DefaultIdleTimeout
+	_ = s.config.With(func(cfg *config.Config) error {
+		if cfg.Settings.IdleTimeout > 0 {
+			timeout = cfg.Settings.IdleTimeout
+		}
+		return nil
+	})
+	return timeout
+}
+
+// autoBackupLabel labels the backups taken on the autoBackupInterval.
+const autoBackupLabel = "auto"
+
+// AutoBackup backs up the hosts file when settings.autoBackupInterval is set
+// and no periodic backup newer than the interval exists. Going by the backups
+// on disk keeps the cadence across daemon restarts. It reports whether a
+// backup was taken.
+func (s *Server) AutoBackup(now time.Time) (bool, error) {
+	var interval time.Duration
+	_ = s.config.With(func(cfg *config.Config) error {
+		interval = cfg.Settings.AutoBackupInterval
+		return nil
+	})
+	if interval <= 0 {
+		return false, nil
+	}
+
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+
+	backups, err := s.hosts.ListBackups()
+	if err != nil {
+		return false, fmt.Errorf("failed to list backups: %w", err)
+	}
+	for _, b := range backups {
+		if b.Label == autoBackupLabel && now.Sub(time.Unix(b.Timestamp, 0)) < interval {
+			return false, nil
+		}
+	}
+
+	if _, err := s.hosts.CreateBackup(autoBackupLabel); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExpireHosts disables the hosts whose expiry has passed and syncs the hosts
+// file. It returns the aliases it disabled.
+func (s *Server) ExpireHosts(now time.Time) ([]string, error) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+
+	var expired []string
+	_ = s.config.With(func(cfg *config.Config) error {
+		expired = cfg.ExpireHosts(now.Unix())
+		return nil
+	})
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	s.flushErr = nil
+	err := s.saveAndSync()
+
+	if s.auditLogger != nil {
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		// #nosec G115 - UIDs and PIDs fit in 32 bits on supported platforms
+		s.auditLogger.Log(uint32(os.Getuid()), int32(os.Getpid()), "expire",
+			map[string]string{"aliases": strings.Join(expired, ",")}, err == nil, errMsg)
+	}
+	return expired, err
+}
 
 func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
@@ -159,47 +297,63 @@ func (s *Server) handleConnection(conn net.Conn) {
 		return
 	}
 
+	idleTimeout := s.idleTimeout()
 	reader := bufio.NewReader(conn)
 	for {
-		// Set read deadline to prevent clients from hanging indefinitely
-		if err := conn.SetReadDeadline(time.Now().Add(connectionReadTimeout)); err != nil {
+		// Reset the idle deadline for each request so abandoned clients don't
+		// hold a goroutine forever
+		if err := conn.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
 			return
 		}
 
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			return
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return // Read deadline or broken connection
 		}
 
-		var req protocol.Request
-		if err := json.Unmarshal(line, &req); err != nil {
-			if err := s.writeResponse(conn, protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid JSON")); err != nil {
+		// A client that half-closes its write side right after its last
+		// request (possibly without a trailing newline) still gets a response.
+		if len(bytes.TrimSpace(line)) > 0 {
+			if err := s.handleLine(conn, line, creds); err != nil {
 				return // Connection error, stop handling
 			}
-			continue
 		}
 
-		// Rate limiting
-		if creds != nil && !s.rateLimiter.Allow(creds.PID) {
-			if err := s.writeResponse(conn, protocol.NewErrorResponse(protocol.ErrCodeRateLimited, "rate limit exceeded")); err != nil {
-				return // Connection error, stop handling
-			}
-			continue
+		if readErr != nil {
+			return // Clean EOF, client is done sending
 		}
+	}
+}
+
+// handleLine processes a single request line and writes the response.
+// It only returns an error when the response could not be written.
+func (s *Server) handleLine(conn net.Conn, line []byte, creds *PeerCredentials) error {
+	var req protocol.Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp := protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid JSON")
+		s.countResponse(resp)
+		return s.writeResponse(conn, resp)
+	}
 
+	// Rate limiting; root already bypasses authorization, so bulk scripts
+	// running as root aren't throttled either unless the config says so
+	if creds != nil && (creds.UID != 0 || s.limitRoot.Load()) && !s.rateLimiter.Allow(creds.PID) {
 		s.mu.Lock()
-		s.requestCount++
+		s.rateLimited++
 		s.mu.Unlock()
-
-		resp := s.handleRequest(&req, creds)
-		if err := s.writeResponse(conn, resp); err != nil {
-			return // Connection error, stop handling
-		}
+		resp := protocol.NewErrorResponse(protocol.ErrCodeRateLimited, "rate limit exceeded")
+		s.countResponse(resp)
+		return s.writeResponse(conn, resp)
 	}
+
+	s.countRequest(req.Type)
+	resp := s.handleRequest(&req, creds)
+	s.countResponse(resp)
+	return s.writeResponse(conn, resp)
 }
 
 // isAuthorized checks if the peer is authorized to access the daemon.
-// Authorized users are: root (UID 0) or members of the lolcathost group (GID 850).
+// Authorized users are: root (UID 0) or members of the lolcathost group.
 func (s *Server) isAuthorized(creds *PeerCredentials) bool {
 	if creds == nil {
 		// Can't verify credentials - deny by default
@@ -212,16 +366,21 @@ func (s *Server) isAuthorized(creds *PeerCredentials) bool {
 	}
 
 	// Check if user's primary GID is lolcathost
-	if creds.GID == LolcathostGID {
+	if creds.GID == s.groupGID {
 		return true
 	}
 
 	// Check supplementary groups (user might be in lolcathost as secondary group)
-	// This requires looking up the user's groups from the system
-	return isUserInGroup(creds.UID, LolcathostGID)
+	// This requires looking up the user's groups from the system, so the
+	// decision is cached briefly to spare clients that reconnect often
+	if s.authCache != nil {
+		return s.authCache.IsUserInGroup(creds.UID, s.groupGID)
+	}
+	return isUserInGroup(creds.UID, s.groupGID)
 }
 
 func (s *Server) writeResponse(conn net.Conn, resp *protocol.Response) error {
+	resp.Version = protocol.Version
 	data, err := json.Marshal(resp)
 	if err != nil {
 		return fmt.Errorf("failed to marshal response: %w", err)
@@ -234,6 +393,33 @@ func (s *Server) writeResponse(conn net.Conn, resp *protocol.Response) error {
 }
 
 func (s *Server) handleRequest(req *protocol.Request, creds *PeerCredentials) *protocol.Response {
+	if req.Type == protocol.RequestPing {
+		return s.dispatch(req, creds)
+	}
+	if req.Version < protocol.MinVersion {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf(
+			"client speaks protocol %d, daemon needs at least %d; please upgrade the client", req.Version, protocol.MinVersion))
+	}
+
+	// Handlers share one config and each sync rewrites the whole hosts file
+	// from it, so requests run one at a time to keep read→write→flush atomic.
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+
+	// The hosts file write is what makes a change take effect, so a failed
+	// DNS flush is reported as a warning on the successful response.
+	s.flushErr = nil
+	s.peer = creds
+	defer func() { s.peer = nil }()
+	resp := s.dispatch(req, creds)
+	if s.flushErr != nil && resp.IsOK() {
+		resp.Message = fmt.Sprintf("synced; DNS flush failed: %v", s.flushErr)
+	}
+	return resp
+}
+
+// dispatch routes a request to its handler and audit-logs mutations.
+func (s *Server) dispatch(req *protocol.Request, creds *PeerCredentials) *protocol.Response {
 	var uid uint32
 	var pid int32
 	if creds != nil {
@@ -249,7 +435,10 @@ func (s *Server) handleRequest(req *protocol.Request, creds *PeerCredentials) *p
 		return s.handleStatus()
 
 	case protocol.RequestList:
-		return s.handleList()
+		return s.handleList(req)
+
+	case protocol.RequestGet:
+		return s.handleGet(req)
 
 	case protocol.RequestSet:
 		resp := s.handleSet(req)
@@ -288,15 +477,51 @@ func (s *Server) handleRequest(req *protocol.Request, creds *PeerCredentials) *p
 	case protocol.RequestBackups:
 		return s.handleBackups()
 
+	case protocol.RequestCreateBackup:
+		resp := s.handleCreateBackup(req)
+		if s.auditLogger != nil {
+			var payload protocol.CreateBackupPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "create_backup", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestDeleteBackup:
+		resp := s.handleDeleteBackup(req)
+		if s.auditLogger != nil {
+			var payload protocol.DeleteBackupPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "delete_backup", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
 	case protocol.RequestBackupContent:
 		return s.handleBackupContent(req)
 
+	case protocol.RequestBackupDiff:
+		return s.handleBackupDiff(req)
+
 	case protocol.RequestAdd:
-		resp := s.handleAdd(req)
+		resp := s.handleAdd(req, creds)
+		var payload protocol.AddPayload
+		_ = req.ParsePayload(&payload)
+		action := "add"
+		if payload.Force && resp.IsOK() && config.IsBlockedDomain(payload.Domain) {
+			// Stand out from routine adds in both the audit and daemon logs
+			action = "add_blocked_override"
+			fmt.Fprintf(os.Stderr, "WARNING: uid %d (pid %d) mapped blocked domain %s to %s\n", uid, pid, payload.Domain, payload.IP)
+		}
+		if s.auditLogger != nil {
+			s.auditLogger.Log(uid, pid, action, payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestAddBatch:
+		resp := s.handleAddBatch(req)
 		if s.auditLogger != nil {
-			var payload protocol.AddPayload
+			var payload protocol.AddBatchPayload
 			_ = req.ParsePayload(&payload)
-			s.auditLogger.Log(uid, pid, "add", payload, resp.IsOK(), resp.Message)
+			s.auditLogger.Log(uid, pid, "add_batch", payload, resp.IsOK(), resp.Message)
 		}
 		return resp
 
@@ -309,6 +534,33 @@ func (s *Server) handleRequest(req *protocol.Request, creds *PeerCredentials) *p
 		}
 		return resp
 
+	case protocol.RequestUpdate:
+		resp := s.handleUpdate(req)
+		if s.auditLogger != nil {
+			var payload protocol.UpdatePayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "update", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestSetSticky:
+		resp := s.handleSetSticky(req)
+		if s.auditLogger != nil {
+			var payload protocol.SetStickyPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "set_sticky", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestMoveHost:
+		resp := s.handleMoveHost(req)
+		if s.auditLogger != nil {
+			var payload protocol.MoveHostPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "move_host", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
 	case protocol.RequestAddGroup:
 		resp := s.handleAddGroup(req)
 		if s.auditLogger != nil {
@@ -339,6 +591,74 @@ func (s *Server) handleRequest(req *protocol.Request, creds *PeerCredentials) *p
 		}
 		return resp
 
+	case protocol.RequestSetGroupIP:
+		resp := s.handleSetGroupIP(req)
+		if s.auditLogger != nil {
+			var payload protocol.SetGroupIPPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "set_group_ip", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestSetGroup:
+		resp := s.handleSetGroup(req)
+		if s.auditLogger != nil {
+			var payload protocol.SetGroupPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "set_group", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestBatch:
+		return s.handleBatch(req, creds)
+
+	case protocol.RequestExport:
+		return s.handleExport(req)
+
+	case protocol.RequestImport:
+		resp := s.handleImport(req)
+		if s.auditLogger != nil {
+			var payload protocol.ImportPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "import", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestFlush:
+		resp := s.handleFlush()
+		if s.auditLogger != nil {
+			s.auditLogger.Log(uid, pid, "flush", nil, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestReset:
+		resp := s.handleReset(req, creds)
+		if s.auditLogger != nil {
+			s.auditLogger.Log(uid, pid, "reset", nil, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestListProfiles:
+		return s.handleListProfiles()
+
+	case protocol.RequestSwitchProfile:
+		resp := s.handleSwitchProfile(req)
+		if s.auditLogger != nil {
+			var payload protocol.SwitchProfilePayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "switch_profile", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestReorderGroups:
+		resp := s.handleReorderGroups(req)
+		if s.auditLogger != nil {
+			var payload protocol.ReorderPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "reorder_groups", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
 	case protocol.RequestAddPreset:
 		resp := s.handleAddPreset(req)
 		if s.auditLogger != nil {
@@ -357,68 +677,159 @@ func (s *Server) handleRequest(req *protocol.Request, creds *PeerCredentials) *p
 		}
 		return resp
 
+	case protocol.RequestReorderPresets:
+		resp := s.handleReorderPresets(req)
+		if s.auditLogger != nil {
+			var payload protocol.ReorderPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "reorder_presets", payload, resp.IsOK(), resp.Message)
+		}
+		return resp
+
 	case protocol.RequestListPresets:
 		return s.handleListPresets()
 
+	case protocol.RequestVerify:
+		return s.handleVerify()
+
+	case protocol.RequestPreviewHosts:
+		return s.handlePreviewHosts()
+
+	case protocol.RequestExplain:
+		return s.handleExplain(req)
+
+	case protocol.RequestPrometheus:
+		return s.handlePrometheus()
+
+	case protocol.RequestMetrics:
+		return s.handleMetrics()
+
+	case protocol.RequestReload:
+		resp := s.handleReload()
+		if s.auditLogger != nil {
+			s.auditLogger.Log(uid, pid, "reload", nil, resp.IsOK(), resp.Message)
+		}
+		return resp
+
+	case protocol.RequestImportConfig:
+		resp := s.handleImportConfig(req)
+		if s.auditLogger != nil {
+			var payload protocol.ImportConfigPayload
+			_ = req.ParsePayload(&payload)
+			s.auditLogger.Log(uid, pid, "import_config", map[string]bool{"dry_run": payload.DryRun, "merge": payload.Merge}, resp.IsOK(), resp.Message)
+		}
+		return resp
+
 	default:
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("unknown request type: %s", req.Type))
+		msg := fmt.Sprintf("unknown request type: %s", req.Type)
+		if req.Version > protocol.Version {
+			msg += fmt.Sprintf(" (client speaks protocol %d, daemon %d; please upgrade the daemon)", req.Version, protocol.Version)
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, msg)
 	}
 }
 
 func (s *Server) handlePing() *protocol.Response {
-	resp, _ := protocol.NewOKResponse(map[string]string{"pong": "ok"})
+	resp, _ := protocol.NewOKResponse(protocol.PingData{
+		Pong:       "ok",
+		Version:    protocol.Version,
+		MinVersion: protocol.MinVersion,
+	})
 	return resp
 }
 
 func (s *Server) handleStatus() *protocol.Response {
 	s.mu.RLock()
-	reqCount := s.requestCount
-	startTime := s.startTime
+	reconcile := s.reconcile
 	s.mu.RUnlock()
 
-	cfg := s.config.Get()
-	var activeCount int
-	if cfg != nil {
-		for _, h := range cfg.GetAllHosts() {
-			if h.Enabled {
-				activeCount++
-			}
-		}
+	m := s.collectMetrics()
+
+	var profile string
+	if s.profiles != nil {
+		profile = s.profiles.Active()
 	}
 
+	var blocked []string
+	_ = s.config.With(func(cfg *config.Config) error {
+		blocked = slices.Clone(cfg.Settings.BlockedDomains)
+		return nil
+	})
+
 	data := protocol.StatusData{
 		Running:      true,
 		Version:      Version,
-		Uptime:       nowUnix() - startTime,
-		ActiveCount:  activeCount,
-		RequestCount: reqCount,
+		Uptime:       m.Uptime,
+		ActiveCount:  m.HostsActive,
+		RequestCount: m.Requests,
+		ConfigPath:   s.config.Path(),
+		Profile:      profile,
+		Reconcile:    reconcile,
+
+		BlockedDomains: blocked,
+		Flush: &protocol.FlushInfo{
+			Platform:   runtime.GOOS,
+			Method:     string(s.flusher.Method()),
+			AutoMethod: string(s.flusher.AutoMethod()),
+			Tools:      s.flusher.AvailableTools(),
+		},
 	}
 
 	resp, _ := protocol.NewOKResponse(data)
 	return resp
 }
 
+func (s *Server) handlePrometheus() *protocol.Response {
+	resp, _ := protocol.NewOKResponse(protocol.PrometheusData{
+		Text: formatPrometheus(s.collectMetrics()),
+	})
+	return resp
+}
+
+func (s *Server) handleMetrics() *protocol.Response {
+	resp, _ := protocol.NewOKResponse(s.collectMetrics().data())
+	return resp
+}
+
 func nowUnix() int64 {
 	return time.Now().Unix()
 }
 
-func (s *Server) handleList() *protocol.Response {
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+// handleList returns entries in the same order as config.GetAllHosts, so
+// repeated listings can be diffed line by line.
+func (s *Server) handleList(req *protocol.Request) *protocol.Response {
+	// The payload is optional; without one every entry is listed
+	var payload protocol.ListPayload
+	if req.Payload != nil {
+		if err := req.ParsePayload(&payload); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+		}
+	}
+
+	switch payload.State {
+	case "", protocol.ListStateAll, protocol.ListStateEnabled, protocol.ListStateDisabled:
+		// Valid
+	default:
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid state filter: %s", payload.State))
 	}
 
+	inFile := s.managedByAlias()
+
 	var entries []protocol.HostEntry
-	for _, g := range cfg.Groups {
-		for _, h := range g.Hosts {
-			entries = append(entries, protocol.HostEntry{
-				Domain:  h.Domain,
-				IP:      h.IP,
-				Alias:   h.Alias,
-				Enabled: h.Enabled,
-				Group:   g.Name,
-			})
+	err := s.config.With(func(cfg *config.Config) error {
+		for _, g := range cfg.Groups {
+			for _, h := range g.Hosts {
+				if (payload.State == protocol.ListStateEnabled && !h.Enabled) ||
+					(payload.State == protocol.ListStateDisabled && h.Enabled) {
+					continue
+				}
+				entries = append(entries, hostEntry(h, g, inFile))
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
 	resp, _ := protocol.NewOKResponse(protocol.ListData{Entries: entries})
@@ -430,31 +841,38 @@ func (s *Server) handleSet(req *protocol.Request) *protocol.Response {
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
-
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+	if payload.ExpiresAt != 0 && (!payload.Enabled || payload.ExpiresAt <= time.Now().Unix()) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "expiry must be in the future and only applies when enabling")
 	}
 
-	host, _ := cfg.FindHostByAlias(payload.Alias)
-	if host == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, fmt.Sprintf("alias not found: %s", payload.Alias))
-	}
+	var domain string
+	err := s.config.With(func(cfg *config.Config) error {
+		host, _ := cfg.FindHostByAlias(payload.Alias)
+		if host == nil {
+			return requestErrorf(protocol.ErrCodeNotFound, "alias not found: %s", payload.Alias)
+		}
 
-	// Check for conflicts if enabling
-	if payload.Enabled && !payload.Force {
-		for _, g := range cfg.Groups {
-			for _, h := range g.Hosts {
-				if h.Alias != payload.Alias && h.Domain == host.Domain && h.Enabled {
-					return protocol.NewErrorResponse(protocol.ErrCodeConflict,
-						fmt.Sprintf("domain %s already mapped by alias %s (use force to override)", host.Domain, h.Alias))
+		// Check for conflicts if enabling
+		if payload.Enabled && !payload.Force {
+			for _, g := range cfg.Groups {
+				for _, h := range g.Hosts {
+					if h.Alias != payload.Alias && h.Domain == host.Domain && h.Enabled {
+						return requestErrorf(protocol.ErrCodeConflict,
+							"domain %s already mapped by alias %s (use force to override)", host.Domain, h.Alias)
+					}
 				}
 			}
 		}
-	}
 
-	// Update config
-	cfg.SetHostEnabled(payload.Alias, payload.Enabled)
+		// Update config
+		cfg.SetHostEnabled(payload.Alias, payload.Enabled)
+		cfg.SetHostExpiry(payload.Alias, payload.ExpiresAt)
+		domain = host.Domain
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
 
 	// Save and sync with rollback on failure
 	if err := s.saveAndSync(); err != nil {
@@ -462,12 +880,22 @@ func (s *Server) handleSet(req *protocol.Request) *protocol.Response {
 	}
 
 	resp, _ := protocol.NewOKResponse(protocol.SetData{
-		Domain:  host.Domain,
+		Domain:  domain,
 		Applied: true,
 	})
 	return resp
 }
 
+// handleFlush flushes the DNS cache without touching the hosts file.
+func (s *Server) handleFlush() *protocol.Response {
+	if err := s.flushDNS(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("DNS flush failed: %v", err))
+	}
+
+	resp, _ := protocol.NewOKResponse(map[string]string{"method": string(s.flusher.Method())})
+	return resp
+}
+
 func (s *Server) handleSync() *protocol.Response {
 	if err := s.syncHostsFile(); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to sync: %v", err))
@@ -483,21 +911,35 @@ func (s *Server) handlePreset(req *protocol.Request) *protocol.Response {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
-	}
-
-	if err := cfg.ApplyPreset(payload.Name); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, err.Error())
+	data := protocol.PresetData{Preset: payload.Name}
+	err := s.config.With(func(cfg *config.Config) error {
+		var err error
+		data.Enable, data.Disable, err = cfg.PresetChanges(payload.Name)
+		if err != nil {
+			return codeError(protocol.ErrCodeNotFound, err)
+		}
+		data.Skipped, _ = cfg.PresetMissingAliases(payload.Name)
+		if payload.Strict && len(data.Skipped) > 0 {
+			return requestErrorf(protocol.ErrCodeNotFound, "preset %s references missing aliases: %s", payload.Name, strings.Join(data.Skipped, ", "))
+		}
+		if payload.DryRun {
+			return nil
+		}
+		return codeError(protocol.ErrCodeNotFound, cfg.ApplyPreset(payload.Name))
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	// Save and sync with rollback on failure
-	if err := s.saveAndSync(); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	if !payload.DryRun {
+		// Save and sync with rollback on failure
+		if err := s.saveAndSync(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+		}
+		data.Applied = true
 	}
 
-	resp, _ := protocol.NewOKResponse(map[string]string{"preset": payload.Name, "applied": "true"})
+	resp, _ := protocol.NewOKResponse(data)
 	return resp
 }
 
@@ -512,12 +954,59 @@ func (s *Server) handleRollback(req *protocol.Request) *protocol.Response {
 	}
 
 	// Flush DNS after restore
-	_ = s.flusher.Flush()
+	s.flushDNS()
 
 	resp, _ := protocol.NewOKResponse(map[string]string{"restored": payload.BackupName})
 	return resp
 }
 
+// handleCreateBackup backs up the hosts file on demand, with an optional label
+// in the file name.
+func (s *Server) handleCreateBackup(req *protocol.Request) *protocol.Response {
+	var payload protocol.CreateBackupPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.Label != "" && sanitizeBackupLabel(payload.Label) == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "label must contain a letter or digit")
+	}
+
+	backup, err := s.hosts.CreateBackup(payload.Label)
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to create backup: %v", err))
+	}
+
+	resp, _ := protocol.NewOKResponse(protocol.BackupInfo{
+		Name:      backup.Name,
+		Label:     backup.Label,
+		Timestamp: backup.Timestamp,
+		Size:      backup.Size,
+	})
+	return resp
+}
+
+func (s *Server) handleDeleteBackup(req *protocol.Request) *protocol.Response {
+	var payload protocol.DeleteBackupPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if _, ok := parseBackupName(payload.BackupName); !ok {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid backup name")
+	}
+
+	if err := s.hosts.DeleteBackup(payload.BackupName); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return protocol.NewErrorResponse(protocol.ErrCodeNotFound, fmt.Sprintf("backup '%s' not found", payload.BackupName))
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+
+	resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.BackupName})
+	return resp
+}
+
 func (s *Server) handleBackups() *protocol.Response {
 	backups, err := s.hosts.ListBackups()
 	if err != nil {
@@ -528,6 +1017,7 @@ func (s *Server) handleBackups() *protocol.Response {
 	for _, b := range backups {
 		infos = append(infos, protocol.BackupInfo{
 			Name:      b.Name,
+			Label:     b.Label,
 			Timestamp: b.Timestamp,
 			Size:      b.Size,
 		})
@@ -556,56 +1046,87 @@ func (s *Server) handleBackupContent(req *protocol.Request) *protocol.Response {
 	return resp
 }
 
-func (s *Server) handleAdd(req *protocol.Request) *protocol.Response {
-	var payload protocol.AddPayload
+func (s *Server) handleBackupDiff(req *protocol.Request) *protocol.Response {
+	var payload protocol.BackupDiffPayload
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
-	// Validate domain
-	if payload.Domain == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidDomain, "domain is required")
+	if payload.BackupName == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "backup name is required")
 	}
 
-	// Validate IP
-	if payload.IP == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidIP, "IP address is required")
+	diff, err := s.hosts.BackupDiff(payload.BackupName)
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, fmt.Sprintf("failed to diff backup: %v", err))
 	}
 
-	// Validate group
-	if payload.Group == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group is required")
+	lines := make([]protocol.DiffLine, len(diff))
+	for i, d := range diff {
+		lines[i] = protocol.DiffLine{Op: d.Op, Text: d.Text}
 	}
 
-	// Check blocked domains
-	if config.IsBlockedDomain(payload.Domain) {
-		return protocol.NewErrorResponse(protocol.ErrCodeBlockedDomain, fmt.Sprintf("domain %s is blocked", payload.Domain))
+	resp, _ := protocol.NewOKResponse(protocol.BackupDiffData{Lines: lines})
+	return resp
+}
+
+func (s *Server) handleAdd(req *protocol.Request, creds *PeerCredentials) *protocol.Response {
+	var payload protocol.AddPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+	// Mapping a blocked domain is never up to an unprivileged user
+	if payload.Force && (creds == nil || creds.UID != 0) {
+		return protocol.NewErrorResponse(protocol.ErrCodeUnauthorized, "only root can override a blocked domain")
 	}
 
-	// Add to config (alias will be auto-generated if empty)
-	if err := cfg.AddHost(payload.Domain, payload.IP, payload.Alias, payload.Group, payload.Enabled); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeConflict, err.Error())
+	// Aliases are stored normalized; the response reports what was stored
+	payload.Alias = config.NormalizeAlias(payload.Alias)
+
+	if errResp := validateAddPayload(payload, payload.Force); errResp != nil {
+		return errResp
 	}
+	allowBlocked := payload.Force && config.IsBlockedDomain(payload.Domain)
 
-	// Save and sync with rollback on failure
-	if err := s.saveAndSync(); err != nil {
+	// Add to config, generating the alias from the domain if none was given
+	err := s.config.With(func(cfg *config.Config) error {
+		if payload.Alias == "" {
+			payload.Alias = cfg.GenerateAlias(payload.Domain)
+		}
+		if err := cfg.AddHost(payload.Domain, payload.IP, payload.Alias, payload.Group, payload.Enabled); err != nil {
+			return codeError(protocol.ErrCodeConflict, err)
+		}
+		if err := cfg.SetHostMetadata(payload.Alias, payload.Metadata); err != nil {
+			return err
+		}
+		if err := cfg.SetHostAllowBlocked(payload.Alias, allowBlocked); err != nil {
+			return err
+		}
+		return cfg.SetHostDescription(payload.Alias, payload.Description)
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// Save and sync with rollback on failure
+	if err := s.saveAndSync(); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
 	}
 
 	resp, _ := protocol.NewOKResponse(protocol.SetData{
 		Domain:  payload.Domain,
+		Alias:   payload.Alias,
 		Applied: true,
 	})
 	return resp
 }
 
-func (s *Server) handleDelete(req *protocol.Request) *protocol.Response {
-	var payload protocol.DeletePayload
+// handleUpdate changes the fields of a host the payload sets and keeps the
+// rest, so the host stays in the hosts file throughout. The result is
+// validated as a whole, the same way add validates a new host.
+func (s *Server) handleUpdate(req *protocol.Request) *protocol.Response {
+	var payload protocol.UpdatePayload
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
@@ -614,70 +1135,280 @@ func (s *Server) handleDelete(req *protocol.Request) *protocol.Response {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "alias is required")
 	}
 
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
-	}
+	var updated protocol.AddPayload
+	err := s.config.With(func(cfg *config.Config) error {
+		host, group := cfg.FindHostByAlias(payload.Alias)
+		if host == nil {
+			return requestErrorf(protocol.ErrCodeNotFound, "alias not found: %s", payload.Alias)
+		}
 
-	// Delete from config
-	if !cfg.DeleteHost(payload.Alias) {
-		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, fmt.Sprintf("alias not found: %s", payload.Alias))
+		updated = protocol.AddPayload{
+			Domain:      host.Domain,
+			IP:          strings.Join(host.Addresses(), ","),
+			Alias:       host.Alias,
+			Group:       group.Name,
+			Metadata:    host.Metadata,
+			Description: host.Description,
+		}
+		if payload.Domain != nil {
+			updated.Domain = *payload.Domain
+		}
+		if payload.IP != nil {
+			updated.IP = *payload.IP
+		}
+		if payload.NewAlias != nil && *payload.NewAlias != "" {
+			updated.Alias = config.NormalizeAlias(*payload.NewAlias)
+		}
+		if payload.Group != nil {
+			updated.Group = *payload.Group
+		}
+		if payload.Metadata != nil {
+			updated.Metadata = payload.Metadata
+		}
+		if payload.Description != nil {
+			updated.Description = *payload.Description
+		}
+
+		// A blocked domain root added on purpose may keep its override, but
+		// only for as long as the domain stays the same
+		allowBlocked := host.AllowBlocked && strings.EqualFold(updated.Domain, host.Domain)
+
+		if errResp := validateAddPayload(updated, allowBlocked); errResp != nil {
+			return requestErrorf(errResp.Code, "%s", errResp.Message)
+		}
+		if err := cfg.UpdateHost(payload.Alias, updated.Domain, updated.IP, updated.Alias, updated.Group); err != nil {
+			return codeError(protocol.ErrCodeConflict, err)
+		}
+		if err := cfg.SetHostMetadata(updated.Alias, updated.Metadata); err != nil {
+			return err
+		}
+		if err := cfg.SetHostAllowBlocked(updated.Alias, allowBlocked); err != nil {
+			return err
+		}
+		return cfg.SetHostDescription(updated.Alias, updated.Description)
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	// Save and sync with rollback on failure
 	if err := s.saveAndSync(); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
 	}
 
-	resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Alias})
+	resp, _ := protocol.NewOKResponse(protocol.SetData{
+		Domain:  updated.Domain,
+		Alias:   updated.Alias,
+		Applied: true,
+	})
 	return resp
 }
 
-func (s *Server) handleAddGroup(req *protocol.Request) *protocol.Response {
-	var payload protocol.GroupPayload
+// handleAddBatch adds several hosts with a single save and sync. Entries are
+// handled independently: one bad line doesn't stop the others.
+func (s *Server) handleAddBatch(req *protocol.Request) *protocol.Response {
+	var payload protocol.AddBatchPayload
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
-	if payload.Name == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group name is required")
+	if len(payload.Hosts) == 0 {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "no hosts to add")
 	}
 
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
-	}
+	var data protocol.AddBatchData
+	err := s.config.With(func(cfg *config.Config) error {
+		for _, h := range payload.Hosts {
+			h.Alias = config.NormalizeAlias(h.Alias)
+			if errResp := validateAddPayload(h, false); errResp != nil {
+				data.Failed = append(data.Failed, protocol.BatchFailure{Domain: h.Domain, Error: errResp.Message})
+				continue
+			}
+
+			if hostExists(cfg, h.Domain, h.IP) {
+				data.Skipped = append(data.Skipped, h.Domain)
+				continue
+			}
 
-	if err := cfg.AddGroup(payload.Name); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeConflict, err.Error())
+			if h.Alias == "" {
+				h.Alias = cfg.GenerateAlias(h.Domain)
+			}
+			if err := cfg.AddHost(h.Domain, h.IP, h.Alias, h.Group, h.Enabled); err != nil {
+				data.Failed = append(data.Failed, protocol.BatchFailure{Domain: h.Domain, Error: err.Error()})
+				continue
+			}
+			_ = cfg.SetHostMetadata(h.Alias, h.Metadata)
+			_ = cfg.SetHostDescription(h.Alias, h.Description)
+			data.Added = append(data.Added, h.Domain)
+		}
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	// Save config
-	if err := s.config.Save(); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
+	if len(data.Added) > 0 {
+		// Save and sync with rollback on failure
+		if err := s.saveAndSync(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+		}
 	}
 
-	resp, _ := protocol.NewOKResponse(map[string]string{"added": payload.Name})
+	resp, _ := protocol.NewOKResponse(data)
 	return resp
 }
 
-func (s *Server) handleDeleteGroup(req *protocol.Request) *protocol.Response {
-	var payload protocol.GroupPayload
+// hostExists reports whether cfg already maps domain to ip, which may list
+// several comma-separated addresses, in any group.
+func hostExists(cfg *config.Config, domain, ip string) bool {
+	ips := config.SplitIPs(ip)
+	for _, h := range cfg.GetAllHosts() {
+		if h.Domain == domain && slices.Equal(h.Addresses(), ips) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAddPayload checks a new host entry before it touches the config,
+// returning an error response or nil if the entry is acceptable. allowBlocked
+// skips the blocked-domain check, for overrides root asked for.
+func validateAddPayload(payload protocol.AddPayload, allowBlocked bool) *protocol.Response {
+	// Validate domain
+	if payload.Domain == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidDomain, "domain is required")
+	}
+	if !config.ValidateDomain(payload.Domain) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidDomain, fmt.Sprintf("invalid domain: %s", payload.Domain))
+	}
+
+	// Validate IPs; several may be given comma-separated
+	if err := config.CheckIPs(config.SplitIPs(payload.IP)); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidIP, err.Error())
+	}
+
+	// Validate group; an unknown name creates the group
+	if payload.Group == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group is required")
+	}
+	if !config.ValidateName(payload.Group) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid group name: %q", payload.Group))
+	}
+
+	// Validate alias; an empty alias is auto-generated. Anything the alias
+	// regex rejects could break the "# lolcathost:<alias>" trailer on write.
+	if payload.Alias != "" && !config.ValidateAlias(payload.Alias) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid alias: %s", payload.Alias))
+	}
+
+	for key := range payload.Metadata {
+		if !config.ValidateMetadataKey(key) {
+			return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid metadata key: %s", key))
+		}
+	}
+
+	if !config.ValidateDescription(payload.Description) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest,
+			fmt.Sprintf("description must be a single line of at most %d characters", config.MaxDescriptionLength))
+	}
+
+	// Check blocked domains
+	if !allowBlocked && config.IsBlockedDomain(payload.Domain) {
+		return protocol.NewErrorResponse(protocol.ErrCodeBlockedDomain, fmt.Sprintf("domain %s is blocked", payload.Domain))
+	}
+
+	return nil
+}
+
+// handleImport adds the hosts file entries outside the managed section to
+// the config as disabled hosts. Each entry is handled independently, as in
+// add_batch. Since nothing is enabled the hosts file stays as it is until the
+// user enables an imported host and removes the original line.
+func (s *Server) handleImport(req *protocol.Request) *protocol.Response {
+	var payload protocol.ImportPayload
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
-	if payload.Name == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group name is required")
+	if payload.Group == "" {
+		payload.Group = protocol.DefaultImportGroup
+	}
+	if !config.ValidateName(payload.Group) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid group name: %q", payload.Group))
+	}
+
+	entries, err := s.hosts.ImportUnmanaged()
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+
+	data := protocol.ImportData{Group: payload.Group}
+	err = s.config.With(func(cfg *config.Config) error {
+		configured := make(map[string]bool)
+		for _, h := range cfg.GetAllHosts() {
+			configured[h.Domain] = true
+		}
+
+		for _, e := range entries {
+			ipErr := config.CheckIPs(e.Addresses())
+			switch {
+			case !config.ValidateDomain(e.Domain):
+				data.Failed = append(data.Failed, protocol.BatchFailure{Domain: e.Domain, Error: "invalid domain"})
+			case ipErr != nil:
+				data.Failed = append(data.Failed, protocol.BatchFailure{Domain: e.Domain, Error: ipErr.Error()})
+			case config.IsBlockedDomain(e.Domain):
+				data.Failed = append(data.Failed, protocol.BatchFailure{Domain: e.Domain, Error: "domain is blocked"})
+			case configured[e.Domain]:
+				data.Skipped = append(data.Skipped, e.Domain)
+			default:
+				if err := cfg.AddHost(e.Domain, strings.Join(e.Addresses(), ","), "", payload.Group, false); err != nil {
+					data.Failed = append(data.Failed, protocol.BatchFailure{Domain: e.Domain, Error: err.Error()})
+					continue
+				}
+				configured[e.Domain] = true
+				data.Added = append(data.Added, e.Domain)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	if len(data.Added) > 0 {
+		if err := s.saveConfig(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
+		}
+	}
+
+	resp, _ := protocol.NewOKResponse(data)
+	return resp
+}
+
+func (s *Server) handleDelete(req *protocol.Request) *protocol.Response {
+	var payload protocol.DeletePayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+	if payload.Alias == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "alias is required")
 	}
 
-	if err := cfg.DeleteGroup(payload.Name); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, err.Error())
+	// Delete from config
+	var deleted bool
+	err := s.config.With(func(cfg *config.Config) error {
+		deleted = cfg.DeleteHost(payload.Alias)
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+	if !deleted {
+		if payload.IfExists {
+			resp, _ := protocol.NewOKResponse(map[string]string{"deleted": ""})
+			return resp
+		}
+		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, fmt.Sprintf("alias not found: %s", payload.Alias))
 	}
 
 	// Save and sync with rollback on failure
@@ -685,69 +1416,90 @@ func (s *Server) handleDeleteGroup(req *protocol.Request) *protocol.Response {
 		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
 	}
 
-	resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Name})
+	resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Alias})
 	return resp
 }
 
-func (s *Server) handleListGroups() *protocol.Response {
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+func (s *Server) handleSetSticky(req *protocol.Request) *protocol.Response {
+	var payload protocol.SetStickyPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	err := s.config.With(func(cfg *config.Config) error {
+		if !cfg.SetHostSticky(payload.Alias, payload.Sticky) {
+			return requestErrorf(protocol.ErrCodeNotFound, "alias not found: %s", payload.Alias)
+		}
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// Stickiness only matters to presets, so only save config
+	if err := s.saveConfig(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
 	}
 
-	resp, _ := protocol.NewOKResponse(protocol.GroupsData{Groups: cfg.GetGroups()})
+	resp, _ := protocol.NewOKResponse(map[string]any{"alias": payload.Alias, "sticky": payload.Sticky})
 	return resp
 }
 
-func (s *Server) handleRenameGroup(req *protocol.Request) *protocol.Response {
-	var payload protocol.RenameGroupPayload
+func (s *Server) handleMoveHost(req *protocol.Request) *protocol.Response {
+	var payload protocol.MoveHostPayload
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
-	if payload.OldName == "" || payload.NewName == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "old_name and new_name are required")
+	if payload.Alias == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "alias is required")
 	}
 
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+	if payload.Group == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group is required")
+	}
+	if !config.ValidateName(payload.Group) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid group name: %q", payload.Group))
 	}
 
-	if err := cfg.RenameGroup(payload.OldName, payload.NewName); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, err.Error())
+	err := s.config.With(func(cfg *config.Config) error {
+		return codeError(protocol.ErrCodeNotFound, cfg.MoveHost(payload.Alias, payload.Group))
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	// Save config
-	if err := s.config.Save(); err != nil {
+	// Group membership doesn't affect the hosts file, so only save config
+	if err := s.saveConfig(); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
 	}
 
-	resp, _ := protocol.NewOKResponse(map[string]string{"renamed": payload.NewName})
+	resp, _ := protocol.NewOKResponse(map[string]string{"moved": payload.Alias, "group": payload.Group})
 	return resp
 }
 
-func (s *Server) handleAddPreset(req *protocol.Request) *protocol.Response {
-	var payload protocol.AddPresetPayload
+func (s *Server) handleAddGroup(req *protocol.Request) *protocol.Response {
+	var payload protocol.GroupPayload
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
 	if payload.Name == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "preset name is required")
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group name is required")
 	}
-
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+	if !config.ValidateName(payload.Name) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid group name: %q", payload.Name))
 	}
 
-	if err := cfg.AddPreset(payload.Name, payload.Enable, payload.Disable); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeConflict, err.Error())
+	err := s.config.With(func(cfg *config.Config) error {
+		return codeError(protocol.ErrCodeConflict, cfg.AddGroup(payload.Name))
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
 	// Save config
-	if err := s.config.Save(); err != nil {
+	if err := s.saveConfig(); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
 	}
 
@@ -755,83 +1507,1205 @@ func (s *Server) handleAddPreset(req *protocol.Request) *protocol.Response {
 	return resp
 }
 
-func (s *Server) handleDeletePreset(req *protocol.Request) *protocol.Response {
-	var payload protocol.PresetPayload
+func (s *Server) handleDeleteGroup(req *protocol.Request) *protocol.Response {
+	var payload protocol.GroupPayload
 	if err := req.ParsePayload(&payload); err != nil {
 		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
 	}
 
 	if payload.Name == "" {
-		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "preset name is required")
-	}
-
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group name is required")
 	}
 
-	if err := cfg.DeletePreset(payload.Name); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, err.Error())
+	err := s.config.With(func(cfg *config.Config) error {
+		return codeError(protocol.ErrCodeNotFound, cfg.DeleteGroup(payload.Name))
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	// Save config
-	if err := s.config.Save(); err != nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
+	// Save and sync with rollback on failure
+	if err := s.saveAndSync(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
 	}
 
 	resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Name})
 	return resp
 }
 
-func (s *Server) handleListPresets() *protocol.Response {
-	cfg := s.config.Get()
-	if cfg == nil {
-		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "no configuration loaded")
+func (s *Server) handleGet(req *protocol.Request) *protocol.Response {
+	var payload protocol.GetPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.Alias == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "alias is required")
 	}
 
-	presets := cfg.GetPresets()
-	infos := make([]protocol.PresetInfo, len(presets))
-	for i, p := range presets {
-		infos[i] = protocol.PresetInfo{
-			Name:    p.Name,
-			Enable:  p.Enable,
-			Disable: p.Disable,
+	inFile := s.managedByAlias()
+
+	var entry protocol.HostEntry
+	err := s.config.With(func(cfg *config.Config) error {
+		host, group := cfg.FindHostByAlias(payload.Alias)
+		if host == nil {
+			return requestErrorf(protocol.ErrCodeNotFound, "alias not found: %s", payload.Alias)
 		}
+		entry = hostEntry(*host, *group, inFile)
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	resp, _ := protocol.NewOKResponse(protocol.PresetsData{Presets: infos})
+	resp, _ := protocol.NewOKResponse(entry)
 	return resp
 }
 
-func (s *Server) syncHostsFile() error {
-	cfg := s.config.Get()
-	if cfg == nil {
-		return fmt.Errorf("no configuration loaded")
+// managedByAlias returns the managed hosts file entries keyed by alias. An
+// unreadable hosts file yields no entries, so every enabled host is reported
+// as unsynced.
+func (s *Server) managedByAlias() map[string]HostEntry {
+	managed, _ := s.hosts.readManagedEntries()
+	inFile := make(map[string]HostEntry, len(managed))
+	for _, e := range managed {
+		inFile[e.Alias] = e
 	}
+	return inFile
+}
 
-	var entries []HostEntry
-	for _, g := range cfg.Groups {
-		for _, h := range g.Hosts {
-			entries = append(entries, HostEntry{
-				IP:      h.IP,
-				Domain:  h.Domain,
-				Alias:   h.Alias,
-				Enabled: h.Enabled,
-			})
-		}
+// hostEntry converts a configured host in group g into its protocol form.
+func hostEntry(h config.Host, g config.Group, inFile map[string]HostEntry) protocol.HostEntry {
+	return protocol.HostEntry{
+		Domain:      h.Domain,
+		IP:          h.IP,
+		IPs:         slices.Clone(h.IPs),
+		Alias:       h.Alias,
+		Enabled:     h.Enabled,
+		Group:       g.Name,
+		CreatedAt:   h.CreatedAt,
+		GroupColor:  g.Color,
+		ExpiresAt:   h.ExpiresAt,
+		Metadata:    maps.Clone(h.Metadata),
+		Sticky:      h.Sticky,
+		Description: h.Description,
+		Synced:      hostSynced(h, inFile),
 	}
+}
 
-	if err := s.hosts.WriteManagedEntries(entries); err != nil {
-		return err
+func (s *Server) handleListGroups() *protocol.Response {
+	var groups []string
+	err := s.config.With(func(cfg *config.Config) error {
+		groups = cfg.GetGroups()
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	// Flush DNS cache
-	return s.flusher.Flush()
+	resp, _ := protocol.NewOKResponse(protocol.GroupsData{Groups: groups})
+	return resp
 }
 
-// saveAndSync saves the configuration and syncs to /etc/hosts atomically.
-// If sync fails, it attempts to reload the previous config from disk.
-func (s *Server) saveAndSync() error {
+func (s *Server) handleRenameGroup(req *protocol.Request) *protocol.Response {
+	var payload protocol.RenameGroupPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.OldName == "" || payload.NewName == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "old_name and new_name are required")
+	}
+	if !config.ValidateName(payload.NewName) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid group name: %q", payload.NewName))
+	}
+
+	err := s.config.With(func(cfg *config.Config) error {
+		return codeError(protocol.ErrCodeNotFound, cfg.RenameGroup(payload.OldName, payload.NewName))
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// Save config
+	if err := s.saveConfig(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
+	}
+
+	resp, _ := protocol.NewOKResponse(map[string]string{"renamed": payload.NewName})
+	return resp
+}
+
+// handleSetGroupIP points every host in a group at a new IP with a single
+// hosts file sync.
+func (s *Server) handleSetGroupIP(req *protocol.Request) *protocol.Response {
+	var payload protocol.SetGroupIPPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.Group == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group is required")
+	}
+	if payload.IP == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidIP, "IP address is required")
+	}
+	if !config.ValidateIP(payload.IP) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidIP, fmt.Sprintf("invalid IP address: %s", payload.IP))
+	}
+
+	data := protocol.SetGroupIPData{Group: payload.Group, IP: payload.IP}
+	err := s.config.With(func(cfg *config.Config) error {
+		var err error
+		data.Updated, err = cfg.SetGroupIP(payload.Group, payload.IP)
+		return codeError(protocol.ErrCodeNotFound, err)
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	if data.Updated > 0 {
+		// Save and sync with rollback on failure
+		if err := s.saveAndSync(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+		}
+	}
+
+	resp, _ := protocol.NewOKResponse(data)
+	return resp
+}
+
+// handleSetGroup enables or disables every host in a group with a single
+// hosts file sync. Enabling checks for domain conflicts as set does, both
+// with hosts already enabled and between hosts of the group.
+func (s *Server) handleSetGroup(req *protocol.Request) *protocol.Response {
+	var payload protocol.SetGroupPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.Group == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "group is required")
+	}
+
+	data := protocol.SetGroupData{Group: payload.Group, Enabled: payload.Enabled}
+	err := s.config.With(func(cfg *config.Config) error {
+		if payload.Enabled && !payload.Force {
+			if err := groupEnableConflict(cfg, payload.Group); err != nil {
+				return err
+			}
+		}
+		var err error
+		data.Updated, err = cfg.SetGroupEnabled(payload.Group, payload.Enabled)
+		return codeError(protocol.ErrCodeNotFound, err)
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	if data.Updated > 0 {
+		// Save and sync with rollback on failure
+		if err := s.saveAndSync(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+		}
+	}
+
+	resp, _ := protocol.NewOKResponse(data)
+	return resp
+}
+
+// groupEnableConflict returns a conflict error if enabling every host in the
+// group would map a domain through two aliases.
+func groupEnableConflict(cfg *config.Config, group string) error {
+	for _, g := range cfg.Groups {
+		if g.Name != group {
+			continue
+		}
+		for _, host := range g.Hosts {
+			if host.Enabled {
+				continue
+			}
+			for _, other := range cfg.Groups {
+				for _, h := range other.Hosts {
+					if h.Alias != host.Alias && h.Domain == host.Domain && (h.Enabled || other.Name == group) {
+						return requestErrorf(protocol.ErrCodeConflict,
+							"domain %s already mapped by alias %s (use force to override)", host.Domain, h.Alias)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// batchState records the work batched requests deferred.
+type batchState struct {
+	save bool
+	sync bool
+}
+
+// batchable lists the requests a batch may carry: those that change the
+// config in memory and leave saving and syncing to saveConfig, saveAndSync or
+// syncHostsFile. Requests that replace or reload the whole config aren't
+// included, since the deferred save would be lost or overwrite them.
+var batchable = map[protocol.RequestType]bool{
+	protocol.RequestSet:            true,
+	protocol.RequestAdd:            true,
+	protocol.RequestUpdate:         true,
+	protocol.RequestAddBatch:       true,
+	protocol.RequestDelete:         true,
+	protocol.RequestSetSticky:      true,
+	protocol.RequestMoveHost:       true,
+	protocol.RequestPreset:         true,
+	protocol.RequestAddGroup:       true,
+	protocol.RequestDeleteGroup:    true,
+	protocol.RequestRenameGroup:    true,
+	protocol.RequestSetGroup:       true,
+	protocol.RequestSetGroupIP:     true,
+	protocol.RequestReorderGroups:  true,
+	protocol.RequestReorderPresets: true,
+	protocol.RequestAddPreset:      true,
+	protocol.RequestDeletePreset:   true,
+	protocol.RequestSync:           true,
+}
+
+// handleBatch runs several requests in order and then saves the config and
+// syncs the hosts file once, so a multi-host change costs one write and one
+// DNS flush. Each request is audit-logged as if sent on its own. If the final
+// sync fails the config is rolled back and the whole batch reported failed.
+func (s *Server) handleBatch(req *protocol.Request, creds *PeerCredentials) *protocol.Response {
+	var payload protocol.BatchPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if len(payload.Requests) == 0 {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "no requests in batch")
+	}
+
+	batch := &batchState{}
+	s.batch = batch
+	data := protocol.BatchData{Results: make([]*protocol.Response, len(payload.Requests))}
+	for i, item := range payload.Requests {
+		switch {
+		case item == nil:
+			data.Results[i] = protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "missing request")
+		case !batchable[item.Type]:
+			data.Results[i] = protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest,
+				fmt.Sprintf("request type %s can't be batched", item.Type))
+		default:
+			data.Results[i] = s.dispatch(item, creds)
+		}
+	}
+	s.batch = nil
+
+	if batch.save {
+		if err := s.saveAndSync(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+		}
+	} else if batch.sync {
+		if err := s.syncHostsFile(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to sync: %v", err))
+		}
+	}
+
+	resp, _ := protocol.NewOKResponse(data)
+	return resp
+}
+
+func (s *Server) handleReorderGroups(req *protocol.Request) *protocol.Response {
+	var payload protocol.ReorderPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	var groups []string
+	err := s.config.With(func(cfg *config.Config) error {
+		if err := cfg.ReorderGroups(payload.Names); err != nil {
+			return codeError(protocol.ErrCodeInvalidRequest, err)
+		}
+		groups = cfg.GetGroups()
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// Group order determines the order of entries in the hosts file
+	if err := s.saveAndSync(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+
+	resp, _ := protocol.NewOKResponse(protocol.GroupsData{Groups: groups})
+	return resp
+}
+
+func (s *Server) handleReorderPresets(req *protocol.Request) *protocol.Response {
+	var payload protocol.ReorderPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	err := s.config.With(func(cfg *config.Config) error {
+		return codeError(protocol.ErrCodeInvalidRequest, cfg.ReorderPresets(payload.Names))
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// Save config
+	if err := s.saveConfig(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
+	}
+
+	resp, _ := protocol.NewOKResponse(map[string][]string{"reordered": payload.Names})
+	return resp
+}
+
+func (s *Server) handleAddPreset(req *protocol.Request) *protocol.Response {
+	var payload protocol.AddPresetPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.Name == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "preset name is required")
+	}
+	if !config.ValidateName(payload.Name) {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("invalid preset name: %q", payload.Name))
+	}
+
+	var warnings []string
+	err := s.config.With(func(cfg *config.Config) error {
+		if err := cfg.AddPreset(payload.Name, payload.Enable, payload.Disable); err != nil {
+			return codeError(protocol.ErrCodeConflict, err)
+		}
+		warnings = cfg.PresetConflicts(payload.Enable)
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// Save config
+	if err := s.saveConfig(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
+	}
+
+	resp, _ := protocol.NewOKResponse(map[string]string{"added": payload.Name})
+	// Domain collisions in the enable list are not fatal, but applying the
+	// preset will always conflict, so let the caller know early.
+	if len(warnings) > 0 {
+		resp.Message = strings.Join(warnings, "; ")
+	}
+	return resp
+}
+
+func (s *Server) handleDeletePreset(req *protocol.Request) *protocol.Response {
+	var payload protocol.PresetPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.Name == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "preset name is required")
+	}
+
+	err := s.config.With(func(cfg *config.Config) error {
+		return codeError(protocol.ErrCodeNotFound, cfg.DeletePreset(payload.Name))
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// Save config
+	if err := s.saveConfig(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to save config: %v", err))
+	}
+
+	resp, _ := protocol.NewOKResponse(map[string]string{"deleted": payload.Name})
+	return resp
+}
+
+func (s *Server) handleListPresets() *protocol.Response {
+	var infos []protocol.PresetInfo
+	err := s.config.With(func(cfg *config.Config) error {
+		presets := cfg.GetPresets()
+		infos = make([]protocol.PresetInfo, len(presets))
+		for i, p := range presets {
+			infos[i] = protocol.PresetInfo{
+				Name:    p.Name,
+				Enable:  p.Enable,
+				Disable: p.Disable,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	resp, _ := protocol.NewOKResponse(protocol.PresetsData{Presets: infos})
+	return resp
+}
+
+// handleReload re-reads the config file after it was edited by hand and, when
+// autoApply is set, syncs the hosts file to match.
+func (s *Server) handleReload() *protocol.Response {
+	if err := s.config.Reload(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, err.Error())
+	}
+	s.countReload()
+	s.applySettings()
+
+	var autoApply bool
+	_ = s.config.With(func(cfg *config.Config) error {
+		autoApply = cfg.Settings.AutoApply
+		return nil
+	})
+	if autoApply {
+		if err := s.syncHostsFile(); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to sync hosts: %v", err))
+		}
+	}
+
+	resp, _ := protocol.NewOKResponse(map[string]string{"reloaded": s.config.Path()})
+	return resp
+}
+
+// ReloadOnSignal re-reads the config file and syncs the hosts file from it,
+// as the daemon does on SIGHUP. A config that fails to load or validate
+// leaves the current one in memory. The sender of a signal is unknown, so the
+// audit entry carries the daemon's own UID and PID.
+func (s *Server) ReloadOnSignal() error {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+
+	s.flushErr = nil
+	err := s.config.Reload()
+	if err != nil {
+		err = fmt.Errorf("keeping the current config: %w", err)
+	} else {
+		s.countReload()
+		s.applySettings()
+		err = s.syncHostsFile()
+	}
+
+	if s.auditLogger != nil {
+		var errMsg string
+		if err != nil {
+			errMsg = err.Error()
+		}
+		// #nosec G115 - UIDs and PIDs fit in 32 bits on supported platforms
+		s.auditLogger.Log(uint32(os.Getuid()), int32(os.Getpid()), "reload_signal",
+			map[string]string{"path": s.config.Path()}, err == nil, errMsg)
+	}
+	return err
+}
+
+// handleImportConfig replaces the groups and presets with those of the given
+// configuration. Settings are kept. With dry_run set it only reports the diff.
+func (s *Server) handleImportConfig(req *protocol.Request) *protocol.Response {
+	var payload protocol.ImportConfigPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	imported, err := config.Parse([]byte(payload.Content))
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, err.Error())
+	}
+	if unknown := imported.UnknownPresetAliases(); payload.Strict && len(unknown) > 0 {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("presets reference unknown aliases: %s", strings.Join(unknown, "; ")))
+	}
+
+	var data protocol.ImportConfigData
+	var replaced bool
+	err = s.config.With(func(cfg *config.Config) error {
+		if payload.Merge {
+			merged := cfg.Clone()
+			data.Conflicts = merged.Merge(imported)
+			if err := config.ValidateConfig(merged); err != nil {
+				return codeError(protocol.ErrCodeInvalidRequest, fmt.Errorf("merged config is invalid: %w", err))
+			}
+			imported = merged
+		}
+
+		diff := cfg.Diff(imported)
+		data = protocol.ImportConfigData{
+			Conflicts:      data.Conflicts,
+			HostsAdded:     diff.HostsAdded,
+			HostsRemoved:   diff.HostsRemoved,
+			HostsChanged:   diff.HostsChanged,
+			GroupsAdded:    diff.GroupsAdded,
+			GroupsRemoved:  diff.GroupsRemoved,
+			PresetsAdded:   diff.PresetsAdded,
+			PresetsRemoved: diff.PresetsRemoved,
+			PresetsChanged: diff.PresetsChanged,
+		}
+
+		if payload.DryRun {
+			return nil
+		}
+		if len(data.Conflicts) > 0 {
+			return requestErrorf(protocol.ErrCodeConflict, "merge conflicts, nothing imported: %s", strings.Join(data.Conflicts, "; "))
+		}
+		if diff.IsEmpty() {
+			return nil
+		}
+
+		cfg.Groups = imported.Groups
+		cfg.Presets = imported.Presets
+		cfg.EnsureDefaultGroup()
+		replaced = true
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	if !replaced {
+		resp, _ := protocol.NewOKResponse(data)
+		return resp
+	}
+
+	// Save and sync with rollback on failure
+	if err := s.saveAndSync(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+
+	data.Applied = true
+	resp, _ := protocol.NewOKResponse(data)
+	return resp
+}
+
+// handleExport returns the whole config for sharing or backup.
+func (s *Server) handleExport(req *protocol.Request) *protocol.Response {
+	var payload protocol.ExportPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+	if payload.Format == "" {
+		payload.Format = config.ExportYAML
+	}
+
+	var content []byte
+	err := s.config.With(func(cfg *config.Config) error {
+		var err error
+		content, err = cfg.Export(payload.Format)
+		return codeError(protocol.ErrCodeInvalidRequest, err)
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	resp, _ := protocol.NewOKResponse(protocol.ExportData{Format: payload.Format, Content: string(content)})
+	return resp
+}
+
+func (s *Server) handleListProfiles() *protocol.Response {
+	if s.profiles == nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "profiles are not available")
+	}
+
+	names, err := s.profiles.List()
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+
+	resp, _ := protocol.NewOKResponse(protocol.ProfilesData{
+		Active:   s.profiles.Active(),
+		Profiles: names,
+	})
+	return resp
+}
+
+// handleSwitchProfile makes another profile the active config and syncs the
+// hosts file to it. If the sync fails, the previous profile is restored.
+func (s *Server) handleSwitchProfile(req *protocol.Request) *protocol.Response {
+	if s.profiles == nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, "profiles are not available")
+	}
+
+	var payload protocol.SwitchProfilePayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	path, err := s.profiles.Path(payload.Name)
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, err.Error())
+	}
+
+	if !s.profiles.Exists(payload.Name) {
+		if !payload.Create {
+			return protocol.NewErrorResponse(protocol.ErrCodeNotFound, fmt.Sprintf("profile not found: %s", payload.Name))
+		}
+		if err := s.profiles.Create(payload.Name); err != nil {
+			return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+		}
+	}
+
+	previous, previousPath := s.profiles.Active(), s.config.Path()
+	if err := s.config.SwitchPath(path); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, fmt.Sprintf("failed to load profile %s: %v", payload.Name, err))
+	}
+	s.applySettings()
+
+	if err := s.syncHostsFile(); err != nil {
+		if restoreErr := s.config.SwitchPath(previousPath); restoreErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to restore profile %s: %v\n", previous, restoreErr)
+		}
+		s.applySettings()
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("failed to sync hosts (profile not switched): %v", err))
+	}
+
+	if err := s.profiles.SetActive(payload.Name); err != nil {
+		// The switch took effect; only the choice won't survive a restart
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+
+	return s.handleListProfiles()
+}
+
+// resetBackupLabel labels the backup taken before a reset.
+const resetBackupLabel = "before-reset"
+
+// handleReset replaces the config with the defaults and rewrites the managed
+// section to match, after backing up the hosts file. Only root may reset.
+func (s *Server) handleReset(req *protocol.Request, creds *PeerCredentials) *protocol.Response {
+	if creds == nil || creds.UID != 0 {
+		return protocol.NewErrorResponse(protocol.ErrCodePermissionError, "reset requires root")
+	}
+
+	var payload protocol.ResetPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+	if !payload.Confirm {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "reset must be confirmed")
+	}
+
+	if _, err := s.hosts.CreateBackup(resetBackupLabel); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, fmt.Sprintf("backup failed, nothing was reset: %v", err))
+	}
+
+	var data protocol.ResetData
+	err := s.config.With(func(cfg *config.Config) error {
+		data = protocol.ResetData{
+			HostsRemoved:   len(cfg.GetAllHosts()),
+			GroupsRemoved:  len(cfg.Groups),
+			PresetsRemoved: len(cfg.Presets),
+		}
+		*cfg = *config.Default()
+		return nil
+	})
+	s.applySettings()
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	if err := s.saveAndSync(); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+
+	resp, _ := protocol.NewOKResponse(data)
+	return resp
+}
+
+// handleVerify compares the configuration against the managed section of the
+// hosts file and reports drift as well as ambiguous domain mappings.
+func (s *Server) handleVerify() *protocol.Response {
+	managed, unparseable, err := s.hosts.readManagedSection()
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+
+	var conflicts []config.DomainConflict
+	var drift []protocol.VerifyIssue
+	err = s.config.With(func(cfg *config.Config) error {
+		conflicts = cfg.DomainConflicts()
+		drift = driftIssues(cfg, managed)
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	issues := []protocol.VerifyIssue{}
+
+	for _, line := range unparseable {
+		issues = append(issues, protocol.VerifyIssue{
+			Severity:   protocol.SeverityHigh,
+			Kind:       "unparseable",
+			Message:    fmt.Sprintf("managed section contains unparseable line %q", line),
+			Suggestion: "run sync to rewrite the managed section",
+		})
+	}
+
+	for _, conflict := range conflicts {
+		var mappings []string
+		for i, alias := range conflict.Aliases {
+			mappings = append(mappings, fmt.Sprintf("%s (%s)", alias, conflict.IPs[i]))
+		}
+		issues = append(issues, protocol.VerifyIssue{
+			Severity:   protocol.SeverityHigh,
+			Kind:       "domain_conflict",
+			Domain:     conflict.Domain,
+			Message:    fmt.Sprintf("%s is mapped to different IPs by %s", conflict.Domain, strings.Join(mappings, ", ")),
+			Suggestion: fmt.Sprintf("keep %s and disable %s", conflict.Aliases[0], strings.Join(conflict.Aliases[1:], ", ")),
+		})
+	}
+
+	issues = append(issues, drift...)
+
+	resp, _ := protocol.NewOKResponse(protocol.VerifyData{Issues: issues})
+	return resp
+}
+
+// handleExplain gathers what decides whether an entry resolves: its config
+// state, the managed section, conflicting entries, hosts file lines outside
+// the managed section and the last sync. The issues found are the diagnosis.
+func (s *Server) handleExplain(req *protocol.Request) *protocol.Response {
+	var payload protocol.ExplainPayload
+	if err := req.ParsePayload(&payload); err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid payload")
+	}
+
+	if payload.Alias == "" {
+		return protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "alias is required")
+	}
+
+	managed, _, err := s.hosts.readManagedSection()
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+	inFile := make(map[string]HostEntry, len(managed))
+	for _, e := range managed {
+		inFile[e.Alias] = e
+	}
+
+	var data protocol.ExplainData
+	err = s.config.With(func(cfg *config.Config) error {
+		host, group := cfg.FindHostByAlias(payload.Alias)
+		if host == nil {
+			return requestErrorf(protocol.ErrCodeNotFound, "alias not found: %s", payload.Alias)
+		}
+		data.Entry = hostEntry(*host, *group, inFile)
+
+		for _, other := range cfg.GetAllHosts() {
+			if other.Enabled && other.Alias != host.Alias && other.Domain == host.Domain && !slices.Equal(other.Addresses(), host.Addresses()) {
+				data.Conflicts = append(data.Conflicts, other.Alias)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	_, data.InHostsFile = inFile[payload.Alias]
+	data.External, err = s.hosts.externalLines(data.Entry.Domain)
+	if err != nil {
+		return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+	}
+	data.LastSync = s.lastSync
+	data.Issues = explainIssues(data)
+
+	resp, _ := protocol.NewOKResponse(data)
+	return resp
+}
+
+// explainIssues turns the facts gathered by an explain request into issues,
+// most fundamental first.
+func explainIssues(data protocol.ExplainData) []protocol.VerifyIssue {
+	e := data.Entry
+	issues := []protocol.VerifyIssue{}
+	issue := func(severity, kind, message, suggestion string) {
+		issues = append(issues, protocol.VerifyIssue{
+			Severity:   severity,
+			Kind:       kind,
+			Domain:     e.Domain,
+			Alias:      e.Alias,
+			IP:         e.IP,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	switch {
+	case !e.Enabled:
+		issue(protocol.SeverityHigh, "disabled",
+			fmt.Sprintf("%s is disabled", e.Alias),
+			fmt.Sprintf("enable it with 'lolcathost on %s'", e.Alias))
+		if data.InHostsFile {
+			issue(protocol.SeverityMedium, "stale",
+				fmt.Sprintf("%s is disabled but still in the hosts file", e.Alias),
+				"run 'lolcathost sync'")
+		}
+	case !data.InHostsFile:
+		issue(protocol.SeverityHigh, "missing",
+			fmt.Sprintf("%s is enabled but missing from the hosts file", e.Alias),
+			"run 'lolcathost sync'")
+	case !e.Synced:
+		issue(protocol.SeverityHigh, "mismatch",
+			fmt.Sprintf("the hosts file maps %s differently than the config", e.Alias),
+			"run 'lolcathost sync'")
+	}
+
+	if e.Enabled && len(data.Conflicts) > 0 {
+		issue(protocol.SeverityHigh, "domain_conflict",
+			fmt.Sprintf("%s is also mapped to other IPs by %s", e.Domain, strings.Join(data.Conflicts, ", ")),
+			fmt.Sprintf("disable %s", strings.Join(data.Conflicts, ", ")))
+	}
+
+	for _, line := range data.External {
+		severity := protocol.SeverityHigh
+		if slices.Contains(e.Addresses(), strings.Fields(line)[0]) {
+			severity = protocol.SeverityMedium
+		}
+		issue(severity, "external_entry",
+			fmt.Sprintf("line outside the managed section also maps %s: %q", e.Domain, line),
+			"remove it by hand, or stop the program that manages it")
+	}
+
+	if data.LastSync != nil {
+		if data.LastSync.Error != "" {
+			issue(protocol.SeverityHigh, "sync_failed",
+				fmt.Sprintf("the last sync failed: %s", data.LastSync.Error),
+				"fix the cause and run 'lolcathost sync'")
+		}
+		if data.LastSync.FlushError != "" {
+			issue(protocol.SeverityMedium, "flush_failed",
+				fmt.Sprintf("the DNS flush after the last sync failed: %s", data.LastSync.FlushError),
+				"flush the DNS cache by hand or change flushMethod")
+		}
+	}
+
+	return issues
+}
+
+// hostSynced reports whether the managed section agrees with h.
+func hostSynced(h config.Host, inFile map[string]HostEntry) bool {
+	e, ok := inFile[h.Alias]
+	if !h.Enabled {
+		return !ok
+	}
+	return ok && slices.Equal(e.Addresses(), h.Addresses()) && e.Domain == h.Domain
+}
+
+// handlePreviewHosts returns the managed section a sync would write, without
+// touching the hosts file.
+func (s *Server) handlePreviewHosts() *protocol.Response {
+	entries, err := s.configEntries()
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	resp, _ := protocol.NewOKResponse(protocol.PreviewHostsData{
+		Section: s.hosts.buildManagedSection(entries),
+	})
+	return resp
+}
+
+// driftIssues reports enabled hosts missing from or different in the managed
+// section, and managed entries that the configuration no longer enables.
+func driftIssues(cfg *config.Config, managed []HostEntry) []protocol.VerifyIssue {
+	var issues []protocol.VerifyIssue
+
+	inFile := make(map[string]HostEntry, len(managed))
+	for _, e := range managed {
+		inFile[e.Alias] = e
+	}
+
+	for _, h := range cfg.GetAllHosts() {
+		if !h.Enabled {
+			continue
+		}
+		e, ok := inFile[h.Alias]
+		delete(inFile, h.Alias)
+		if !ok {
+			issues = append(issues, protocol.VerifyIssue{
+				Severity:   protocol.SeverityMedium,
+				Kind:       "missing",
+				Domain:     h.Domain,
+				Alias:      h.Alias,
+				IP:         strings.Join(h.Addresses(), ", "),
+				Message:    fmt.Sprintf("%s is enabled but not in the hosts file", h.Alias),
+				Suggestion: "run sync",
+			})
+			continue
+		}
+		if !slices.Equal(e.Addresses(), h.Addresses()) || e.Domain != h.Domain {
+			wantIP, fileIP := strings.Join(h.Addresses(), ", "), strings.Join(e.Addresses(), ", ")
+			issues = append(issues, protocol.VerifyIssue{
+				Severity:   protocol.SeverityMedium,
+				Kind:       "mismatch",
+				Domain:     h.Domain,
+				Alias:      h.Alias,
+				IP:         wantIP,
+				FileDomain: e.Domain,
+				FileIP:     fileIP,
+				Message:    fmt.Sprintf("%s maps %s to %s in the hosts file, expected %s to %s", h.Alias, e.Domain, fileIP, h.Domain, wantIP),
+				Suggestion: "run sync",
+			})
+		}
+	}
+
+	for _, e := range managed {
+		if _, ok := inFile[e.Alias]; !ok {
+			continue
+		}
+		issues = append(issues, protocol.VerifyIssue{
+			Severity:   protocol.SeverityMedium,
+			Kind:       "extra",
+			Domain:     e.Domain,
+			Alias:      e.Alias,
+			IP:         strings.Join(e.Addresses(), ", "),
+			Message:    fmt.Sprintf("%s is in the hosts file but not enabled in config", e.Alias),
+			Suggestion: "run sync",
+		})
+	}
+
+	return issues
+}
+
+// Reconcile compares the managed section of the hosts file with the
+// configuration, logs any discrepancies and, when reconcileOnStart is set,
+// syncs the hosts file to fix them. The result is reported by status.
+func (s *Server) Reconcile() *protocol.ReconcileData {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+
+	result := &protocol.ReconcileData{Timestamp: nowUnix()}
+	defer func() {
+		s.mu.Lock()
+		s.reconcile = result
+		s.mu.Unlock()
+	}()
+
+	hasSection, err := s.hosts.hasManagedSection()
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "reconcile: %v\n", err)
+		return result
+	}
+
+	managed, err := s.hosts.readManagedEntries()
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "reconcile: %v\n", err)
+		return result
+	}
+
+	var issues []protocol.VerifyIssue
+	var reconcileOnStart bool
+	err = s.config.With(func(cfg *config.Config) error {
+		issues = driftIssues(cfg, managed)
+
+		// Markers only matter when config expects entries to be written
+		for _, h := range cfg.GetAllHosts() {
+			if h.Enabled && !hasSection {
+				result.MarkersMissing = true
+				fmt.Fprintf(os.Stderr, "reconcile: hosts file is missing the lolcathost managed section\n")
+				break
+			}
+		}
+
+		reconcileOnStart = cfg.Settings.ReconcileOnStart
+		return nil
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Drift = len(issues)
+
+	for _, issue := range issues {
+		fmt.Fprintf(os.Stderr, "reconcile: %s\n", issue.Message)
+	}
+
+	if result.Drift == 0 && !result.MarkersMissing {
+		return result
+	}
+
+	if !reconcileOnStart {
+		fmt.Fprintf(os.Stderr, "reconcile: reconcileOnStart is disabled, run sync to fix\n")
+		return result
+	}
+
+	// Self-healing runs on every boot, so skip the backup rather than rotating
+	// out the user's history with copies of a drifted file.
+	if err := s.writeHostsFile(false); err != nil {
+		result.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "reconcile: sync failed: %v\n", err)
+		return result
+	}
+	result.Synced = true
+	return result
+}
+
+func (s *Server) syncHostsFile() error {
+	if s.batch != nil {
+		s.batch.sync = true
+		return nil
+	}
+	return s.writeHostsFile(true)
+}
+
+// writeHostsFile writes the managed section from config, backing up the hosts
+// file first when backup is set.
+func (s *Server) writeHostsFile(backup bool) error {
+	entries, err := s.configEntries()
+	if err != nil {
+		return err
+	}
+
+	// A missing or unreadable section just means everything shows as added
+	before, _ := s.hosts.readManagedEntries()
+
+	write := s.hosts.WriteManagedEntries
+	if !backup {
+		write = s.hosts.writeManagedEntries
+	}
+	if err := write(entries); err != nil {
+		s.lastSync = &protocol.SyncRecord{Timestamp: nowUnix(), Error: err.Error()}
+		return err
+	}
+
+	s.logSyncDiff(diffEntries(before, entries))
+
+	record := &protocol.SyncRecord{Timestamp: nowUnix()}
+	if err := s.flushDNS(); err != nil {
+		record.FlushError = err.Error()
+	}
+	s.lastSync = record
+	return nil
+}
+
+// flushDNS flushes the DNS cache after the hosts file was written. A failure
+// only delays when lookups see the change, so it is logged and recorded for
+// the response instead of failing the request.
+func (s *Server) flushDNS() error {
+	err := s.flusher.Flush()
+	if err != nil {
+		s.flushErr = err
+		fmt.Fprintf(os.Stderr, "warning: DNS flush failed: %v\n", err)
+	}
+	return err
+}
+
+// configEntries returns the configured hosts, in config order, as hosts file
+// entries.
+func (s *Server) configEntries() ([]HostEntry, error) {
+	var entries []HostEntry
+	err := s.config.With(func(cfg *config.Config) error {
+		for _, g := range cfg.Groups {
+			for _, h := range g.Hosts {
+				entries = append(entries, HostEntry{
+					IP:      h.IP,
+					IPs:     h.IPs,
+					Domain:  h.Domain,
+					Alias:   h.Alias,
+					Enabled: h.Enabled,
+				})
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// requestError is an error that maps to a specific response code.
+type requestError struct {
+	code    protocol.ErrorCode
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// requestErrorf returns a requestError with a formatted message.
+func requestErrorf(code protocol.ErrorCode, format string, args ...any) error {
+	return &requestError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// codeError tags err with a response code, passing nil through.
+func codeError(code protocol.ErrorCode, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &requestError{code: code, message: err.Error()}
+}
+
+// errorResponse turns an error from a config callback into a response. Errors
+// without a code, including config.ErrNoConfig, are internal errors.
+func errorResponse(err error) *protocol.Response {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return protocol.NewErrorResponse(reqErr.code, reqErr.message)
+	}
+	return protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error())
+}
+
+// syncDiffDetails is the audit record of a sync. Source is "client" when a
+// request caused the sync and "daemon" for the daemon's own syncs, such as
+// reconciling at startup or reloading an edited config, whose uid and pid are
+// then left at zero.
+type syncDiffDetails struct {
+	SyncDiff
+	Source string `json:"source"`
+}
+
+// logSyncDiff records what a sync changed in the managed section, attributed
+// to the client of the current request if there is one. The changes are only
+// printed with settings.verbose.
+func (s *Server) logSyncDiff(diff SyncDiff) {
+	if diff.IsEmpty() {
+		return
+	}
+
+	if s.verbose.Load() {
+		for _, e := range diff.Added {
+			fmt.Printf("sync: + %s\n", e)
+		}
+		for _, e := range diff.Removed {
+			fmt.Printf("sync: - %s\n", e)
+		}
+	}
+
+	if s.auditLogger != nil {
+		details := syncDiffDetails{SyncDiff: diff, Source: "daemon"}
+		var uid uint32
+		var pid int32
+		if s.peer != nil {
+			details.Source = "client"
+			uid, pid = s.peer.UID, s.peer.PID
+		}
+		s.auditLogger.Log(uid, pid, "sync_diff", details, true, "")
+	}
+}
+
+// saveConfig saves the configuration, or defers the save to the end of the
+// running batch.
+func (s *Server) saveConfig() error {
+	if s.batch != nil {
+		s.batch.save = true
+		return nil
+	}
+	return s.config.Save()
+}
+
+// saveAndSync saves the configuration and syncs to /etc/hosts atomically.
+// If sync fails, it attempts to reload the previous config from disk.
+func (s *Server) saveAndSync() error {
+	if s.batch != nil {
+		s.batch.save = true
+		s.batch.sync = true
+		return nil
+	}
+
 	// Save config
 	if err := s.config.Save(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
@@ -844,6 +2718,7 @@ func (s *Server) saveAndSync() error {
 			// Log reload failure but return original sync error
 			fmt.Fprintf(os.Stderr, "warning: failed to reload config after sync failure: %v\n", reloadErr)
 		}
+		s.applySettings()
 		return fmt.Errorf("failed to sync hosts (config rolled back): %w", err)
 	}
 
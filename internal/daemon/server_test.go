@@ -2,9 +2,16 @@ package daemon
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -36,9 +43,12 @@ func setupTestServer(t *testing.T) (*Server, string, func()) {
 	server := &Server{
 		socketPath:  socketPath,
 		config:      cfgManager,
+		profiles:    config.NewProfiles(configPath),
 		hosts:       newHostsManagerWithPaths(hostsPath, backupDir),
 		flusher:     NewDNSFlusher(FlushMethodAuto),
 		rateLimiter: NewRateLimiter(100, time.Minute),
+		authCache:   NewAuthCache(AuthCacheTTL),
+		groupGID:    LolcathostGID,
 		stopCh:      make(chan struct{}),
 	}
 
@@ -49,12 +59,173 @@ func setupTestServer(t *testing.T) (*Server, string, func()) {
 	return server, tmpDir, cleanup
 }
 
+func TestServer_NoConfigLoaded(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// A manager that was never loaded has no config
+	server.config = config.NewManager(filepath.Join(tmpDir, "unloaded.yaml"))
+
+	requests := []*protocol.Request{
+		{Type: protocol.RequestList},
+		{Type: protocol.RequestListGroups},
+		{Type: protocol.RequestListPresets},
+		{Type: protocol.RequestVerify},
+		{Type: protocol.RequestSync},
+	}
+	for _, tc := range []struct {
+		reqType protocol.RequestType
+		payload any
+	}{
+		{protocol.RequestSet, protocol.SetPayload{Alias: "a", Enabled: true}},
+		{protocol.RequestAdd, protocol.AddPayload{Domain: "a.local", IP: "127.0.0.1", Group: "dev"}},
+		{protocol.RequestDelete, protocol.DeletePayload{Alias: "a"}},
+		{protocol.RequestPreset, protocol.PresetPayload{Name: "p"}},
+		{protocol.RequestAddGroup, protocol.GroupPayload{Name: "g"}},
+		{protocol.RequestAddPreset, protocol.AddPresetPayload{Name: "p"}},
+	} {
+		req, _ := protocol.NewRequest(tc.reqType, tc.payload)
+		requests = append(requests, req)
+	}
+
+	for _, req := range requests {
+		t.Run(string(req.Type), func(t *testing.T) {
+			resp := server.handleRequest(req, nil)
+			assert.Equal(t, "error", resp.Status)
+			assert.Equal(t, protocol.ErrCodeInternalError, resp.Code)
+			assert.Contains(t, resp.Message, "no configuration loaded")
+		})
+	}
+}
+
+func TestServer_RateLimitExemptsRoot(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.rateLimiter = NewRateLimiter(1, time.Minute)
+
+	ping := []byte(`{"type":"ping"}`)
+	send := func(creds *PeerCredentials) *protocol.Response {
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		go func() { _ = server.handleLine(serverConn, ping, creds) }()
+
+		var resp protocol.Response
+		require.NoError(t, json.NewDecoder(clientConn).Decode(&resp))
+		return &resp
+	}
+
+	root := &PeerCredentials{UID: 0, PID: 100}
+	for i := 0; i < 5; i++ {
+		assert.True(t, send(root).IsOK(), "root request %d was rate limited", i)
+	}
+
+	user := &PeerCredentials{UID: 501, PID: 200}
+	assert.True(t, send(user).IsOK())
+	resp := send(user)
+	assert.Equal(t, protocol.ErrCodeRateLimited, resp.Code)
+}
+
+func TestServer_RateLimitExemptsRootBulk(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.rateLimiter = NewRateLimiter(RateLimit, RateLimitWindow)
+
+	ping := []byte(`{"type":"ping"}`)
+	send := func(creds *PeerCredentials) *protocol.Response {
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		go func() { _ = server.handleLine(serverConn, ping, creds) }()
+
+		var resp protocol.Response
+		require.NoError(t, json.NewDecoder(clientConn).Decode(&resp))
+		return &resp
+	}
+
+	// Twice the default limit from one root process, as a provisioning
+	// script would
+	root := &PeerCredentials{UID: 0, PID: 100}
+	rejected := 0
+	for i := 0; i < 2*RateLimit; i++ {
+		if send(root).Code == protocol.ErrCodeRateLimited {
+			rejected++
+		}
+	}
+	assert.Zero(t, rejected)
+
+	// A group member is still held to the limit
+	member := &PeerCredentials{UID: 501, PID: 200}
+	for i := 0; i < RateLimit; i++ {
+		require.True(t, send(member).IsOK(), "member request %d", i)
+	}
+	assert.Equal(t, protocol.ErrCodeRateLimited, send(member).Code)
+}
+
+func TestServer_RateLimitSettings(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	exempt := false
+	server.config.Get().Settings.RateLimit = 2
+	server.config.Get().Settings.RateLimitExemptRoot = &exempt
+	server.applySettings()
+
+	ping := []byte(`{"type":"ping"}`)
+	send := func(creds *PeerCredentials) *protocol.Response {
+		serverConn, clientConn := net.Pipe()
+		defer clientConn.Close()
+		defer serverConn.Close()
+
+		go func() { _ = server.handleLine(serverConn, ping, creds) }()
+
+		var resp protocol.Response
+		require.NoError(t, json.NewDecoder(clientConn).Decode(&resp))
+		return &resp
+	}
+
+	root := &PeerCredentials{UID: 0, PID: 100}
+	assert.True(t, send(root).IsOK())
+	assert.True(t, send(root).IsOK())
+	assert.Equal(t, protocol.ErrCodeRateLimited, send(root).Code)
+
+	// Back to the default: root is exempt again
+	server.config.Get().Settings.RateLimitExemptRoot = nil
+	server.applySettings()
+	assert.True(t, send(root).IsOK())
+}
+
 func TestServer_HandlePing(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
 
 	resp := server.handlePing()
 	assert.Equal(t, "ok", resp.Status)
+
+	var data protocol.PingData
+	require.NoError(t, resp.ParseData(&data))
+	assert.Equal(t, "ok", data.Pong)
+	assert.Equal(t, protocol.Version, data.Version)
+	assert.Equal(t, protocol.MinVersion, data.MinVersion)
+}
+
+func TestServer_ResponseVersion(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	creds := &PeerCredentials{UID: 0, PID: 100}
+	go func() { _ = server.handleLine(serverConn, []byte(`{"type":"status"}`), creds) }()
+
+	var resp protocol.Response
+	require.NoError(t, json.NewDecoder(clientConn).Decode(&resp))
+	assert.True(t, resp.IsOK())
+	assert.Equal(t, protocol.Version, resp.Version)
 }
 
 func TestServer_HandleStatus(t *testing.T) {
@@ -69,13 +240,147 @@ func TestServer_HandleStatus(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.True(t, data.Running)
+	assert.Equal(t, server.config.Path(), data.ConfigPath)
+
+	require.NotNil(t, data.Flush)
+	assert.Equal(t, runtime.GOOS, data.Flush.Platform)
+	assert.Equal(t, "auto", data.Flush.Method)
+	assert.Equal(t, string(server.flusher.AutoMethod()), data.Flush.AutoMethod)
+}
+
+func TestServer_HandleReload(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	t.Run("picks up edited config", func(t *testing.T) {
+		content := "settings:\n  autoApply: true\ngroups:\n  - name: edited\n    hosts:\n      - domain: edited.local\n        ip: 127.0.0.1\n        alias: edited-local\n        enabled: true\n"
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+		resp := server.handleReload()
+		require.Equal(t, "ok", resp.Status)
+
+		host, _ := server.config.Get().FindHostByAlias("edited-local")
+		require.NotNil(t, host)
+
+		entries, err := server.hosts.readManagedEntries()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "edited-local", entries[0].Alias)
+	})
+
+	t.Run("invalid config keeps the previous one", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(configPath, []byte("groups: ["), 0644))
+
+		resp := server.handleReload()
+		assert.Equal(t, "error", resp.Status)
+
+		host, _ := server.config.Get().FindHostByAlias("edited-local")
+		assert.NotNil(t, host)
+	})
+}
+
+func TestServer_ReloadOnSignal(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	logPath := filepath.Join(tmpDir, "audit.log")
+	logger, err := NewAuditLogger(logPath)
+	require.NoError(t, err)
+	defer func() { _ = logger.Close() }()
+	server.auditLogger = logger
+
+	t.Run("reloads and syncs", func(t *testing.T) {
+		content := "groups:\n  - name: edited\n    hosts:\n      - domain: edited.local\n        ip: 127.0.0.1\n        alias: edited-local\n        enabled: true\n"
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+		require.NoError(t, server.ReloadOnSignal())
+
+		entries, err := server.hosts.readManagedEntries()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "edited-local", entries[0].Alias)
+	})
+
+	t.Run("invalid config keeps the previous one", func(t *testing.T) {
+		content := "groups:\n  - name: broken\n    hosts:\n      - domain: broken.local\n        ip: not-an-ip\n        alias: broken-local\n        enabled: true\n"
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+		assert.Error(t, server.ReloadOnSignal())
+
+		host, _ := server.config.Get().FindHostByAlias("edited-local")
+		assert.NotNil(t, host)
+		entries, err := server.hosts.readManagedEntries()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "edited-local", entries[0].Alias)
+	})
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), `"action":"reload_signal"`))
+}
+
+func TestServer_Reconcile(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	cfg := server.config.Get()
+	cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", true)
+
+	t.Run("reports missing markers without syncing", func(t *testing.T) {
+		result := server.Reconcile()
+		assert.True(t, result.MarkersMissing)
+		assert.Equal(t, 1, result.Drift)
+		assert.False(t, result.Synced)
+
+		content, err := os.ReadFile(hostsPath)
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), markerStart)
+	})
+
+	t.Run("syncs when reconcileOnStart is set", func(t *testing.T) {
+		cfg.Settings.ReconcileOnStart = true
+		defer func() { cfg.Settings.ReconcileOnStart = false }()
+
+		result := server.Reconcile()
+		assert.True(t, result.Synced)
+		assert.Empty(t, result.Error)
+
+		entries, err := server.hosts.readManagedEntries()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "api-local", entries[0].Alias)
+
+		backups, err := server.hosts.ListBackups()
+		require.NoError(t, err)
+		assert.Empty(t, backups, "self-healing should not create backups")
+	})
+
+	t.Run("clean file", func(t *testing.T) {
+		result := server.Reconcile()
+		assert.False(t, result.MarkersMissing)
+		assert.Zero(t, result.Drift)
+		assert.False(t, result.Synced)
+	})
+
+	t.Run("exposed in status", func(t *testing.T) {
+		resp := server.handleStatus()
+		var data protocol.StatusData
+		require.NoError(t, resp.ParseData(&data))
+		require.NotNil(t, data.Reconcile)
+		assert.Zero(t, data.Reconcile.Drift)
+	})
 }
 
 func TestServer_HandleList(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
 
-	resp := server.handleList()
+	resp := server.handleList(&protocol.Request{Type: protocol.RequestList})
 	assert.Equal(t, "ok", resp.Status)
 
 	var data protocol.ListData
@@ -85,6 +390,467 @@ func TestServer_HandleList(t *testing.T) {
 	assert.NotNil(t, data.Entries)
 }
 
+func TestServer_HandleList_Order(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddGroup("alpha"))
+	require.NoError(t, cfg.AddHost("z.local", "127.0.0.1", "z-local", "development", true))
+	require.NoError(t, cfg.AddHost("b.local", "127.0.0.1", "b-local", "alpha", false))
+	require.NoError(t, cfg.AddHost("a.local", "127.0.0.1", "a-local", "alpha", true))
+	require.NoError(t, server.config.Save())
+
+	aliases := func(t *testing.T) []string {
+		resp := server.handleList(&protocol.Request{Type: protocol.RequestList})
+		require.Equal(t, "ok", resp.Status)
+		var data protocol.ListData
+		require.NoError(t, resp.ParseData(&data))
+		result := make([]string, 0, len(data.Entries))
+		for _, e := range data.Entries {
+			result = append(result, e.Alias)
+		}
+		return result
+	}
+
+	// Group order first, then insertion order within each group
+	want := []string{"example-local", "z-local", "b-local", "a-local"}
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, want, aliases(t))
+	}
+
+	require.NoError(t, server.config.Load())
+	assert.Equal(t, want, aliases(t), "order survives a reload")
+}
+
+func TestServer_HandleList_Synced(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	synced := func(t *testing.T) map[string]bool {
+		resp := server.handleList(&protocol.Request{Type: protocol.RequestList})
+		require.Equal(t, "ok", resp.Status)
+		var data protocol.ListData
+		require.NoError(t, resp.ParseData(&data))
+		result := make(map[string]bool)
+		for _, e := range data.Entries {
+			result[e.Alias] = e.Synced
+		}
+		return result
+	}
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddHost("on.local", "127.0.0.1", "on-local", "development", true))
+
+	// Enabled in config but never written out
+	assert.Equal(t, map[string]bool{"example-local": true, "on-local": false}, synced(t))
+
+	require.NoError(t, server.syncHostsFile())
+	assert.Equal(t, map[string]bool{"example-local": true, "on-local": true}, synced(t))
+
+	// Changing the IP without syncing leaves the file stale
+	cfg.Groups[0].Hosts[len(cfg.Groups[0].Hosts)-1].IP = "10.0.0.1"
+	assert.False(t, synced(t)["on-local"])
+}
+
+func TestServer_HandleSetGroupIP(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", true))
+
+	t.Run("updates the group", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestSetGroupIP, protocol.SetGroupIPPayload{Group: "development", IP: "192.168.1.20"})
+		resp := server.handleSetGroupIP(req)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.SetGroupIPData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, 2, data.Updated)
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "hosts"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "192.168.1.20\tapi.local")
+	})
+
+	t.Run("invalid IP", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestSetGroupIP, protocol.SetGroupIPPayload{Group: "development", IP: "999.1.1.1"})
+		resp := server.handleSetGroupIP(req)
+		assert.Equal(t, protocol.ErrCodeInvalidIP, resp.Code)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestSetGroupIP, protocol.SetGroupIPPayload{Group: "missing", IP: "10.0.0.1"})
+		resp := server.handleSetGroupIP(req)
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+}
+
+func TestServer_HandleSetGroup(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", false))
+	require.NoError(t, cfg.AddGroup("staging"))
+	require.NoError(t, cfg.AddHost("api.local", "10.0.0.1", "api-staging", "staging", true))
+
+	setGroup := func(payload protocol.SetGroupPayload) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestSetGroup, payload)
+		return server.handleSetGroup(req)
+	}
+
+	t.Run("conflicting domain", func(t *testing.T) {
+		resp := setGroup(protocol.SetGroupPayload{Group: "development", Enabled: true})
+		assert.Equal(t, protocol.ErrCodeConflict, resp.Code)
+		host, _ := server.config.Get().FindHostByAlias("example-local")
+		assert.False(t, host.Enabled)
+	})
+
+	t.Run("disables the group", func(t *testing.T) {
+		resp := setGroup(protocol.SetGroupPayload{Group: "staging", Enabled: false})
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.SetGroupData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, 1, data.Updated)
+	})
+
+	t.Run("enables the group", func(t *testing.T) {
+		resp := setGroup(protocol.SetGroupPayload{Group: "development", Enabled: true})
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.SetGroupData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, 2, data.Updated)
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "hosts"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "127.0.0.1\tapi.local")
+		assert.Contains(t, string(content), "example.local")
+	})
+
+	t.Run("force skips the conflict check", func(t *testing.T) {
+		resp := setGroup(protocol.SetGroupPayload{Group: "staging", Enabled: true, Force: true})
+		require.Equal(t, "ok", resp.Status, resp.Message)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		resp := setGroup(protocol.SetGroupPayload{Group: "missing", Enabled: false})
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+}
+
+func TestServer_HandleBatch(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	flushes := 0
+	server.flusher = &DNSFlusher{
+		method: failingFlushMethod(t),
+		run: func(string, ...string) error {
+			flushes++
+			return nil
+		},
+	}
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", false))
+
+	// Count the commands one flush runs on this platform
+	require.NoError(t, server.syncHostsFile())
+	perFlush := flushes
+	require.Positive(t, perFlush)
+
+	batch := func(reqs ...*protocol.Request) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestBatch, protocol.BatchPayload{Requests: reqs})
+		return server.handleRequest(req, &PeerCredentials{UID: 0, PID: 1})
+	}
+	set := func(alias string, enabled bool) *protocol.Request {
+		req, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{Alias: alias, Enabled: enabled})
+		return req
+	}
+
+	t.Run("applies every request with one sync", func(t *testing.T) {
+		flushes = 0
+		status, _ := protocol.NewRequest(protocol.RequestStatus, nil)
+		nested, _ := protocol.NewRequest(protocol.RequestBatch, protocol.BatchPayload{})
+		resp := batch(set("example-local", true), set("missing", true), status, nested, set("api-local", true))
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.BatchData
+		require.NoError(t, resp.ParseData(&data))
+		require.Len(t, data.Results, 5)
+		assert.True(t, data.Results[0].IsOK())
+		assert.Equal(t, protocol.ErrCodeNotFound, data.Results[1].Code)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, data.Results[2].Code)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, data.Results[3].Code)
+		assert.True(t, data.Results[4].IsOK())
+
+		assert.Equal(t, perFlush, flushes)
+		content, err := os.ReadFile(filepath.Join(tmpDir, "hosts"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "example.local")
+		assert.Contains(t, string(content), "api.local")
+
+		saved := config.NewManager(filepath.Join(tmpDir, "config.yaml"))
+		require.NoError(t, saved.Load())
+		host, _ := saved.Get().FindHostByAlias("api-local")
+		require.NotNil(t, host)
+		assert.True(t, host.Enabled)
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		flushes = 0
+		resp := batch(set("missing", true))
+		require.Equal(t, "ok", resp.Status, resp.Message)
+		assert.Zero(t, flushes)
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		resp := batch()
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+}
+
+func TestServer_HandleFlush(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	var ran []string
+	var flushErr error
+	server.flusher = &DNSFlusher{
+		method: failingFlushMethod(t),
+		run: func(name string, _ ...string) error {
+			ran = append(ran, name)
+			return flushErr
+		},
+	}
+
+	req, _ := protocol.NewRequest(protocol.RequestFlush, nil)
+	resp := server.handleRequest(req, &PeerCredentials{UID: 0, PID: 1})
+	require.Equal(t, "ok", resp.Status, resp.Message)
+	assert.NotEmpty(t, ran)
+
+	flushErr = errors.New("exit status 1")
+	resp = server.handleRequest(req, &PeerCredentials{UID: 0, PID: 1})
+	assert.Equal(t, protocol.ErrCodeInternalError, resp.Code)
+	assert.Contains(t, resp.Message, "DNS flush failed")
+}
+
+func TestServer_ApplySettings(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	assert.Equal(t, FlushMethodAuto, server.flusher.Method())
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := "settings:\n  flushMethod: nscd\n  maxBackups: 3\ngroups:\n  - name: default\n    hosts: []\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+	resp := server.handleReload()
+	require.Equal(t, "ok", resp.Status, resp.Message)
+	assert.Equal(t, FlushMethodNscd, server.flusher.Method())
+	assert.Equal(t, 3, server.hosts.maxBackups)
+
+	server.flusher = NewDNSFlusher(FlushMethodAuto)
+	server.ConfigChanged()
+	assert.Equal(t, FlushMethodNscd, server.flusher.Method())
+
+	logger, err := NewAuditLogger(filepath.Join(tmpDir, "audit.log"))
+	require.NoError(t, err)
+	defer func() { _ = logger.Close() }()
+	server.auditLogger = logger
+
+	content = "settings:\n  logFormat: text\n  auditMaxSize: 1048576\n  auditMaxFiles: 2\ngroups:\n  - name: default\n    hosts: []\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+	resp = server.handleReload()
+	require.Equal(t, "ok", resp.Status, resp.Message)
+	assert.Equal(t, config.LogFormatText, logger.format)
+	assert.Equal(t, int64(1<<20), logger.maxSize)
+	assert.Equal(t, 2, logger.maxFiles)
+}
+
+func TestServer_CustomBlockedDomains(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+	t.Cleanup(func() { config.SetCustomBlockedDomains(nil) })
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := "settings:\n  blockedDomains: [sso.example.com]\ngroups:\n  - name: default\n    hosts: []\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+	resp := server.handleReload()
+	require.Equal(t, "ok", resp.Status, resp.Message)
+
+	req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+		Domain: "login.sso.example.com",
+		IP:     "127.0.0.1",
+		Alias:  "login",
+		Group:  "default",
+	})
+	resp = server.handleAdd(req, nil)
+	assert.Equal(t, protocol.ErrCodeBlockedDomain, resp.Code)
+
+	var status protocol.StatusData
+	require.NoError(t, server.handleStatus().ParseData(&status))
+	assert.Equal(t, []string{"sso.example.com"}, status.BlockedDomains)
+}
+
+func TestServer_AddForceBlocked(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	logPath := filepath.Join(tmpDir, "audit.log")
+	logger, err := NewAuditLogger(logPath)
+	require.NoError(t, err)
+	defer func() { _ = logger.Close() }()
+	server.auditLogger = logger
+
+	root := &PeerCredentials{UID: 0, PID: 1}
+	member := &PeerCredentials{UID: 501, PID: 2}
+	add := func(payload protocol.AddPayload, creds *PeerCredentials) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, payload)
+		return server.handleRequest(req, creds)
+	}
+	icloud := protocol.AddPayload{Domain: "icloud.com", IP: "127.0.0.1", Group: "development", Enabled: true}
+
+	resp := add(icloud, root)
+	assert.Equal(t, protocol.ErrCodeBlockedDomain, resp.Code)
+
+	icloud.Force = true
+	resp = add(icloud, member)
+	assert.Equal(t, protocol.ErrCodeUnauthorized, resp.Code)
+
+	resp = add(icloud, root)
+	require.True(t, resp.IsOK(), resp.Message)
+
+	host, _ := server.config.Get().FindHostByAlias("icloud-com")
+	require.NotNil(t, host)
+	assert.True(t, host.AllowBlocked)
+
+	// The saved config still loads
+	resp = server.handleReload()
+	require.True(t, resp.IsOK(), resp.Message)
+
+	// The override survives updates that keep the domain, and no further
+	update := func(payload protocol.UpdatePayload) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestUpdate, payload)
+		return server.handleRequest(req, member)
+	}
+	ip, domain := "127.0.0.2", "mzstatic.com"
+	resp = update(protocol.UpdatePayload{Alias: "icloud-com", IP: &ip})
+	assert.True(t, resp.IsOK(), resp.Message)
+	resp = update(protocol.UpdatePayload{Alias: "icloud-com", Domain: &domain})
+	assert.Equal(t, protocol.ErrCodeBlockedDomain, resp.Code)
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), `"action":"add_blocked_override"`))
+}
+
+func TestServer_HandleImport(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	hostsContent := "127.0.0.1\tlocalhost\n" +
+		"10.0.0.2\tapi.local\n" +
+		"10.0.0.3\texample.local\n" +
+		"10.0.0.4\tapple.com\n" +
+		"999.0.0.1\tbroken.local\n" +
+		"10.0.0.5\tbad_domain!\n"
+	require.NoError(t, os.WriteFile(server.hosts.hostsPath, []byte(hostsContent), 0644))
+
+	req, _ := protocol.NewRequest(protocol.RequestImport, protocol.ImportPayload{})
+	resp := server.handleImport(req)
+	require.Equal(t, "ok", resp.Status, resp.Message)
+
+	var data protocol.ImportData
+	require.NoError(t, resp.ParseData(&data))
+	assert.Equal(t, protocol.DefaultImportGroup, data.Group)
+	assert.Equal(t, []string{"api.local"}, data.Added)
+	assert.Equal(t, []string{"example.local"}, data.Skipped)
+	require.Len(t, data.Failed, 3)
+	assert.Equal(t, "apple.com", data.Failed[0].Domain)
+	assert.Equal(t, "broken.local", data.Failed[1].Domain)
+	assert.Equal(t, "bad_domain!", data.Failed[2].Domain)
+
+	host, group := server.config.Get().FindHostByAlias("api-local")
+	require.NotNil(t, host)
+	assert.Equal(t, protocol.DefaultImportGroup, group.Name)
+	assert.Equal(t, "10.0.0.2", host.IP)
+	assert.False(t, host.Enabled)
+
+	t.Run("invalid group", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestImport, protocol.ImportPayload{Group: "bad group"})
+		resp := server.handleImport(req)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+}
+
+func TestServer_HandleGet(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	t.Run("found", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestGet, protocol.GetPayload{Alias: "example-local"})
+		resp := server.handleGet(req)
+		require.Equal(t, "ok", resp.Status)
+
+		var entry protocol.HostEntry
+		require.NoError(t, resp.ParseData(&entry))
+		assert.Equal(t, "example-local", entry.Alias)
+		assert.Equal(t, "example.local", entry.Domain)
+		assert.Equal(t, "development", entry.Group)
+		assert.False(t, entry.Enabled)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestGet, protocol.GetPayload{Alias: "missing"})
+		resp := server.handleGet(req)
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+
+	t.Run("missing alias", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestGet, protocol.GetPayload{})
+		resp := server.handleGet(req)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+}
+
+func TestServer_HandleList_StateFilter(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	cfg.AddHost("on.local", "127.0.0.1", "on-local", "development", true)
+
+	list := func(t *testing.T, state string) []protocol.HostEntry {
+		req, _ := protocol.NewRequest(protocol.RequestList, protocol.ListPayload{State: state})
+		resp := server.handleList(req)
+		require.Equal(t, "ok", resp.Status)
+		var data protocol.ListData
+		require.NoError(t, resp.ParseData(&data))
+		return data.Entries
+	}
+
+	assert.Len(t, list(t, protocol.ListStateAll), 2)
+
+	enabled := list(t, protocol.ListStateEnabled)
+	require.Len(t, enabled, 1)
+	assert.Equal(t, "on-local", enabled[0].Alias)
+
+	disabled := list(t, protocol.ListStateDisabled)
+	require.Len(t, disabled, 1)
+	assert.Equal(t, "example-local", disabled[0].Alias)
+
+	req, _ := protocol.NewRequest(protocol.RequestList, protocol.ListPayload{State: "bogus"})
+	resp := server.handleList(req)
+	assert.Equal(t, "error", resp.Status)
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+}
+
 func TestServer_HandleSet(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -132,6 +898,156 @@ func TestServer_HandleSet(t *testing.T) {
 	})
 }
 
+func TestServer_HandleSetExpiry(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	set := func(enabled bool, expiresAt int64) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{
+			Alias:     "example-local",
+			Enabled:   enabled,
+			ExpiresAt: expiresAt,
+		})
+		return server.handleSet(req)
+	}
+
+	t.Run("rejects a past expiry", func(t *testing.T) {
+		resp := set(true, time.Now().Add(-time.Minute).Unix())
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("rejects an expiry when disabling", func(t *testing.T) {
+		resp := set(false, time.Now().Add(time.Minute).Unix())
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("records the expiry", func(t *testing.T) {
+		expiresAt := time.Now().Add(30 * time.Minute).Unix()
+		resp := set(true, expiresAt)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		host, _ := server.config.Get().FindHostByAlias("example-local")
+		assert.True(t, host.Enabled)
+		assert.Equal(t, expiresAt, host.ExpiresAt)
+
+		getReq, _ := protocol.NewRequest(protocol.RequestGet, protocol.GetPayload{Alias: "example-local"})
+		entry := server.handleGet(getReq)
+		var got protocol.HostEntry
+		require.NoError(t, entry.ParseData(&got))
+		assert.Equal(t, expiresAt, got.ExpiresAt)
+	})
+}
+
+func TestServer_ExpireHosts(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	expiresAt := time.Now().Add(time.Minute)
+	req, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{
+		Alias:     "example-local",
+		Enabled:   true,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	require.Equal(t, "ok", server.handleSet(req).Status)
+
+	expired, err := server.ExpireHosts(expiresAt.Add(-time.Second))
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	expired, err = server.ExpireHosts(expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example-local"}, expired)
+
+	host, _ := server.config.Get().FindHostByAlias("example-local")
+	assert.False(t, host.Enabled)
+	assert.Zero(t, host.ExpiresAt)
+
+	content, err := os.ReadFile(server.hosts.hostsPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "example.local")
+}
+
+func TestServer_HandleAddBatch(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddHost("existing.local", "127.0.0.1", "existing-local", "dev", true))
+	require.NoError(t, server.config.Save())
+
+	req, _ := protocol.NewRequest(protocol.RequestAddBatch, protocol.AddBatchPayload{
+		Hosts: []protocol.AddPayload{
+			{Domain: "api.dev.local", IP: "127.0.0.1", Group: "dev", Enabled: true},
+			{Domain: "web.dev.local", IP: "127.0.0.1", Group: "dev", Enabled: true},
+			{Domain: "existing.local", IP: "127.0.0.1", Group: "dev", Enabled: true},
+			{Domain: "not a domain", IP: "127.0.0.1", Group: "dev", Enabled: true},
+			{Domain: "icloud.com", IP: "127.0.0.1", Group: "dev", Enabled: true},
+		},
+	})
+	resp := server.handleAddBatch(req)
+	require.Equal(t, "ok", resp.Status, resp.Message)
+
+	var data protocol.AddBatchData
+	require.NoError(t, resp.ParseData(&data))
+	assert.Equal(t, []string{"api.dev.local", "web.dev.local"}, data.Added)
+	assert.Equal(t, []string{"existing.local"}, data.Skipped)
+	require.Len(t, data.Failed, 2)
+	assert.Equal(t, "not a domain", data.Failed[0].Domain)
+	assert.Equal(t, "icloud.com", data.Failed[1].Domain)
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "hosts"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "api.dev.local")
+	assert.Contains(t, string(content), "web.dev.local")
+
+	t.Run("empty batch", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAddBatch, protocol.AddBatchPayload{})
+		resp := server.handleAddBatch(req)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+}
+
+func TestServer_ConcurrentSetsDontTearHostsFile(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	const hosts = 8
+	cfg := server.config.Get()
+	for i := 0; i < hosts; i++ {
+		require.NoError(t, cfg.AddHost(fmt.Sprintf("race%d.local", i), "127.0.0.1", fmt.Sprintf("race-%d", i), "default", false))
+	}
+	require.NoError(t, server.config.Save())
+
+	var wg sync.WaitGroup
+	for i := 0; i < hosts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{
+				Alias:   fmt.Sprintf("race-%d", i),
+				Enabled: true,
+			})
+			resp := server.handleRequest(req, nil)
+			assert.Equal(t, "ok", resp.Status, resp.Message)
+		}(i)
+	}
+	wg.Wait()
+
+	managed, err := server.hosts.readManagedEntries()
+	require.NoError(t, err)
+	byAlias := make(map[string]HostEntry)
+	for _, e := range managed {
+		byAlias[e.Alias] = e
+	}
+	for i := 0; i < hosts; i++ {
+		alias := fmt.Sprintf("race-%d", i)
+		entry, ok := byAlias[alias]
+		require.True(t, ok, "missing %s", alias)
+		assert.Equal(t, fmt.Sprintf("race%d.local", i), entry.Domain)
+		assert.True(t, entry.Enabled, "%s not enabled", alias)
+	}
+}
+
 func TestServer_HandleAdd(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -142,21 +1058,134 @@ func TestServer_HandleAdd(t *testing.T) {
 			IP:     "127.0.0.1",
 			Group:  "default",
 		})
-		resp := server.handleAdd(req)
+		resp := server.handleAdd(req, nil)
 		assert.Equal(t, "ok", resp.Status)
 	})
 
-	t.Run("duplicate alias", func(t *testing.T) {
-		// When alias is explicitly provided, duplicates are rejected
+	t.Run("stores metadata", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain:   "owned.local",
+			IP:       "127.0.0.1",
+			Group:    "default",
+			Metadata: map[string]string{"owner": "alice", "ticket": "OPS-1"},
+		})
+		resp := server.handleAdd(req, nil)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		resp = server.handleList(&protocol.Request{Type: protocol.RequestList})
+		var data protocol.ListData
+		require.NoError(t, resp.ParseData(&data))
+		var found bool
+		for _, e := range data.Entries {
+			if e.Alias == "owned-local" {
+				found = true
+				assert.Equal(t, map[string]string{"owner": "alice", "ticket": "OPS-1"}, e.Metadata)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("stores description outside the hosts file", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain:      "described.local",
+			IP:          "127.0.0.1",
+			Group:       "default",
+			Enabled:     true,
+			Description: "points at the staging API",
+		})
+		resp := server.handleAdd(req, nil)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		req, _ = protocol.NewRequest(protocol.RequestGet, protocol.GetPayload{Alias: "described-local"})
+		resp = server.handleGet(req)
+		var entry protocol.HostEntry
+		require.NoError(t, resp.ParseData(&entry))
+		assert.Equal(t, "points at the staging API", entry.Description)
+
+		content, err := os.ReadFile(server.hosts.hostsPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "described.local")
+		assert.NotContains(t, string(content), "staging API")
+	})
+
+	t.Run("multi-line description", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain:      "badnote.local",
+			IP:          "127.0.0.1",
+			Group:       "default",
+			Description: "line one\nline two",
+		})
+		resp := server.handleAdd(req, nil)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("invalid metadata key", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain:   "badmeta.local",
+			IP:       "127.0.0.1",
+			Group:    "default",
+			Metadata: map[string]string{"has space": "x"},
+		})
+		resp := server.handleAdd(req, nil)
+		assert.Equal(t, "error", resp.Status)
+		assert.Contains(t, resp.Message, "invalid metadata key")
+	})
+
+	t.Run("duplicate alias", func(t *testing.T) {
+		// When alias is explicitly provided, duplicates are rejected
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain: "another.local",
+			IP:     "127.0.0.1",
+			Alias:  "newhost-local", // Same alias as auto-generated for newhost.local
+			Group:  "default",
+		})
+		resp := server.handleAdd(req, nil)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeConflict, resp.Code)
+	})
+
+	t.Run("invalid alias", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain: "hashed.local",
+			IP:     "127.0.0.1",
+			Alias:  "my#alias",
+			Group:  "default",
+		})
+		resp := server.handleAdd(req, nil)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("normalizes alias", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain: "spaced.local",
+			IP:     "127.0.0.1",
+			Alias:  "My Spaced.Alias",
+			Group:  "default",
+		})
+		resp := server.handleAdd(req, nil)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.SetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, "my-spaced-alias", data.Alias)
+
+		host, _ := server.config.Get().FindHostByAlias("my-spaced-alias")
+		assert.NotNil(t, host)
+	})
+
+	t.Run("reports generated alias", func(t *testing.T) {
 		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
-			Domain: "another.local",
+			Domain: "generated.local",
 			IP:     "127.0.0.1",
-			Alias:  "newhost-local", // Same alias as auto-generated for newhost.local
 			Group:  "default",
 		})
-		resp := server.handleAdd(req)
-		assert.Equal(t, "error", resp.Status)
-		assert.Equal(t, protocol.ErrCodeConflict, resp.Code)
+		resp := server.handleAdd(req, nil)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.SetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, "generated-local", data.Alias)
 	})
 
 	t.Run("blocked domain", func(t *testing.T) {
@@ -165,7 +1194,7 @@ func TestServer_HandleAdd(t *testing.T) {
 			IP:     "127.0.0.1",
 			Group:  "default",
 		})
-		resp := server.handleAdd(req)
+		resp := server.handleAdd(req, nil)
 		assert.Equal(t, "error", resp.Status)
 		assert.Equal(t, protocol.ErrCodeBlockedDomain, resp.Code)
 	})
@@ -176,28 +1205,83 @@ func TestServer_HandleAdd(t *testing.T) {
 			IP:     "127.0.0.1",
 			Group:  "default",
 		})
-		resp := server.handleAdd(req)
+		resp := server.handleAdd(req, nil)
 		assert.Equal(t, "error", resp.Status)
 	})
 
 	t.Run("empty IP", func(t *testing.T) {
-		// Only empty IP is rejected, format is not validated
 		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
 			Domain: "valid.local",
 			IP:     "",
 			Group:  "default",
 		})
-		resp := server.handleAdd(req)
+		resp := server.handleAdd(req, nil)
 		assert.Equal(t, "error", resp.Status)
 		assert.Equal(t, protocol.ErrCodeInvalidIP, resp.Code)
 	})
 
+	t.Run("several IPs", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain:  "dual.local",
+			IP:      "127.0.0.1, ::1",
+			Group:   "default",
+			Enabled: true,
+		})
+		resp := server.handleAdd(req, nil)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		req, _ = protocol.NewRequest(protocol.RequestGet, protocol.GetPayload{Alias: "dual-local"})
+		resp = server.handleGet(req)
+		var entry protocol.HostEntry
+		require.NoError(t, resp.ParseData(&entry))
+		assert.Equal(t, "127.0.0.1", entry.IP)
+		assert.Equal(t, []string{"127.0.0.1", "::1"}, entry.IPs)
+		assert.True(t, entry.Synced)
+
+		req, _ = protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+			Domain: "dual2.local",
+			IP:     "127.0.0.1,foo",
+			Group:  "default",
+		})
+		resp = server.handleAdd(req, nil)
+		assert.Equal(t, protocol.ErrCodeInvalidIP, resp.Code)
+	})
+
+	t.Run("malformed IP", func(t *testing.T) {
+		for _, ip := range []string{"999.1.1.1", "foo", "not-an-ip"} {
+			req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+				Domain: "valid.local",
+				IP:     ip,
+				Group:  "default",
+			})
+			resp := server.handleAdd(req, nil)
+			assert.Equal(t, "error", resp.Status, ip)
+			assert.Equal(t, protocol.ErrCodeInvalidIP, resp.Code, ip)
+		}
+	})
+
+	t.Run("malformed domain", func(t *testing.T) {
+		for _, domain := range []string{"foo", "bad..local", "-bad.local"} {
+			req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+				Domain: domain,
+				IP:     "127.0.0.1",
+				Group:  "default",
+			})
+			resp := server.handleAdd(req, nil)
+			assert.Equal(t, "error", resp.Status, domain)
+			assert.Equal(t, protocol.ErrCodeInvalidDomain, resp.Code, domain)
+		}
+
+		host, _ := server.config.Get().FindHostByAlias("foo")
+		assert.Nil(t, host, "rejected entries must not be stored")
+	})
+
 	t.Run("invalid payload", func(t *testing.T) {
 		req := &protocol.Request{
 			Type:    protocol.RequestAdd,
 			Payload: json.RawMessage(`{invalid`),
 		}
-		resp := server.handleAdd(req)
+		resp := server.handleAdd(req, nil)
 		assert.Equal(t, "error", resp.Status)
 	})
 }
@@ -221,7 +1305,7 @@ func TestServer_HandleDelete(t *testing.T) {
 
 	t.Run("verify deleted entry not in list", func(t *testing.T) {
 		// After delete, list should not contain the deleted entry
-		resp := server.handleList()
+		resp := server.handleList(&protocol.Request{Type: protocol.RequestList})
 		assert.Equal(t, "ok", resp.Status)
 
 		var data protocol.ListData
@@ -243,6 +1327,15 @@ func TestServer_HandleDelete(t *testing.T) {
 		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
 	})
 
+	t.Run("delete nonexistent with if_exists", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestDelete, protocol.DeletePayload{
+			Alias:    "nonexistent",
+			IfExists: true,
+		})
+		resp := server.handleDelete(req)
+		assert.Equal(t, "ok", resp.Status)
+	})
+
 	t.Run("invalid payload", func(t *testing.T) {
 		req := &protocol.Request{
 			Type:    protocol.RequestDelete,
@@ -261,12 +1354,58 @@ func TestServer_HandleSync(t *testing.T) {
 	assert.Equal(t, "ok", resp.Status)
 }
 
+func TestServer_HandleSync_ExternalClobber(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	server.hosts.afterWrite = func() {
+		_ = os.WriteFile(hostsPath, []byte("127.0.0.1\tlocalhost\n"), 0644)
+	}
+
+	resp := server.handleSync()
+	assert.Equal(t, protocol.ErrCodeInternalError, resp.Code)
+	assert.Contains(t, resp.Message, "verification failed")
+}
+
+func TestServer_AutoBackup(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	// Disabled by default
+	taken, err := server.AutoBackup(now)
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	server.config.Get().Settings.AutoBackupInterval = 24 * time.Hour
+
+	taken, err = server.AutoBackup(now)
+	require.NoError(t, err)
+	assert.True(t, taken)
+
+	// Not due again until the interval has passed
+	taken, err = server.AutoBackup(now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	taken, err = server.AutoBackup(now.Add(25 * time.Hour))
+	require.NoError(t, err)
+	assert.True(t, taken)
+
+	backups, err := server.hosts.ListBackups()
+	require.NoError(t, err)
+	require.NotEmpty(t, backups)
+	assert.Equal(t, autoBackupLabel, backups[0].Label)
+}
+
 func TestServer_HandleBackups(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
 
 	// Create a backup first
-	server.hosts.CreateBackup()
+	_, _ = server.hosts.CreateBackup("")
 
 	resp := server.handleBackups()
 	assert.Equal(t, "ok", resp.Status)
@@ -277,6 +1416,247 @@ func TestServer_HandleBackups(t *testing.T) {
 	assert.NotNil(t, data.Backups)
 }
 
+func TestServer_HandleCreateBackup(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	createBackup := func(label string) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestCreateBackup, protocol.CreateBackupPayload{Label: label})
+		return server.handleRequest(req, nil)
+	}
+
+	resp := createBackup("Before Refactor")
+	require.True(t, resp.IsOK(), resp.Message)
+
+	var data protocol.BackupInfo
+	require.NoError(t, resp.ParseData(&data))
+	assert.Equal(t, "before-refactor", data.Label)
+	assert.True(t, strings.HasSuffix(data.Name, ".before-refactor.bak"), data.Name)
+
+	backups, err := server.hosts.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, data.Name, backups[0].Name)
+
+	// A labeled backup can be rolled back to like any other
+	req, _ := protocol.NewRequest(protocol.RequestRollback, protocol.RollbackPayload{BackupName: data.Name})
+	resp = server.handleRequest(req, nil)
+	assert.True(t, resp.IsOK(), resp.Message)
+
+	resp = createBackup("")
+	require.True(t, resp.IsOK(), resp.Message)
+	var unlabeled protocol.BackupInfo
+	require.NoError(t, resp.ParseData(&unlabeled))
+	assert.Empty(t, unlabeled.Label)
+
+	resp = createBackup("///")
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+}
+
+func TestServer_HandleDeleteBackup(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	backup, err := server.hosts.CreateBackup("")
+	require.NoError(t, err)
+
+	deleteBackup := func(name string) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestDeleteBackup, protocol.DeleteBackupPayload{BackupName: name})
+		return server.handleRequest(req, nil)
+	}
+
+	resp := deleteBackup(backup.Name)
+	require.True(t, resp.IsOK(), resp.Message)
+
+	backups, err := server.hosts.ListBackups()
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	resp = deleteBackup(backup.Name)
+	assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+
+	resp = deleteBackup("../hosts")
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+}
+
+func TestServer_HandleBackupDiff(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	backup, err := server.hosts.CreateBackup("")
+	require.NoError(t, err)
+
+	cfg := server.config.Get()
+	require.True(t, cfg.SetHostEnabled("example-local", true))
+	require.NoError(t, server.syncHostsFile())
+
+	backupDiff := func(name string) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestBackupDiff, protocol.BackupDiffPayload{BackupName: name})
+		return server.handleRequest(req, nil)
+	}
+
+	resp := backupDiff(backup.Name)
+	require.True(t, resp.IsOK(), resp.Message)
+
+	var data protocol.BackupDiffData
+	require.NoError(t, resp.ParseData(&data))
+	var added []string
+	for _, line := range data.Lines {
+		if line.Op == protocol.DiffAdded {
+			added = append(added, line.Text)
+		}
+	}
+	require.NotEmpty(t, added)
+	assert.Contains(t, strings.Join(added, "\n"), "example.local")
+
+	resp = backupDiff("")
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+
+	resp = backupDiff("../hosts")
+	assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+}
+
+func TestServer_HandleUpdate(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", true))
+	require.NoError(t, cfg.SetHostMetadata("api-local", map[string]string{"owner": "alice"}))
+	require.NoError(t, server.syncHostsFile())
+
+	update := func(payload protocol.UpdatePayload) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestUpdate, payload)
+		return server.handleUpdate(req)
+	}
+	ptr := func(s string) *string { return &s }
+
+	t.Run("changes only the given fields", func(t *testing.T) {
+		resp := update(protocol.UpdatePayload{Alias: "api-local", IP: ptr("10.0.0.5")})
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		host, group := server.config.Get().FindHostByAlias("api-local")
+		require.NotNil(t, host)
+		assert.Equal(t, "10.0.0.5", host.IP)
+		assert.Equal(t, "api.local", host.Domain)
+		assert.Equal(t, "development", group.Name)
+		assert.True(t, host.Enabled)
+		assert.Equal(t, "alice", host.Metadata["owner"])
+
+		content, err := os.ReadFile(server.hosts.hostsPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "10.0.0.5")
+	})
+
+	t.Run("renames, moves and clears metadata", func(t *testing.T) {
+		resp := update(protocol.UpdatePayload{
+			Alias:       "api-local",
+			NewAlias:    ptr("api"),
+			Group:       ptr("team"),
+			Metadata:    map[string]string{},
+			Description: ptr("team API"),
+		})
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.SetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, "api", data.Alias)
+
+		host, group := server.config.Get().FindHostByAlias("api")
+		require.NotNil(t, host)
+		assert.Equal(t, "team", group.Name)
+		assert.Empty(t, host.Metadata)
+		assert.Equal(t, "team API", host.Description)
+		assert.True(t, host.Enabled)
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		resp := update(protocol.UpdatePayload{Alias: "api", IP: ptr("not-an-ip")})
+		assert.Equal(t, protocol.ErrCodeInvalidIP, resp.Code)
+
+		resp = update(protocol.UpdatePayload{Alias: "api", Domain: ptr("apple.com")})
+		assert.Equal(t, protocol.ErrCodeBlockedDomain, resp.Code)
+
+		resp = update(protocol.UpdatePayload{Alias: "api", NewAlias: ptr("example-local")})
+		assert.Equal(t, protocol.ErrCodeConflict, resp.Code)
+
+		host, _ := server.config.Get().FindHostByAlias("api")
+		require.NotNil(t, host)
+		assert.Equal(t, "10.0.0.5", host.IP)
+	})
+
+	t.Run("nonexistent alias", func(t *testing.T) {
+		resp := update(protocol.UpdatePayload{Alias: "nonexistent", IP: ptr("10.0.0.1")})
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+
+	t.Run("missing alias", func(t *testing.T) {
+		resp := update(protocol.UpdatePayload{IP: ptr("10.0.0.1")})
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+		assert.Equal(t, "alias is required", resp.Message)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		req := &protocol.Request{
+			Type:    protocol.RequestUpdate,
+			Payload: json.RawMessage(`{invalid`),
+		}
+		resp := server.handleUpdate(req)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+}
+
+func TestServer_HandleMoveHost(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	cfg.AddHost("move.local", "127.0.0.1", "move-local", "development", true)
+	server.config.Save()
+
+	t.Run("move host", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestMoveHost, protocol.MoveHostPayload{
+			Alias: "move-local",
+			Group: "moved",
+		})
+		resp := server.handleMoveHost(req)
+		assert.Equal(t, "ok", resp.Status)
+
+		host, group := server.config.Get().FindHostByAlias("move-local")
+		require.NotNil(t, host)
+		assert.Equal(t, "moved", group.Name)
+		assert.True(t, host.Enabled)
+	})
+
+	t.Run("nonexistent alias", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestMoveHost, protocol.MoveHostPayload{
+			Alias: "nonexistent",
+			Group: "moved",
+		})
+		resp := server.handleMoveHost(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+
+	t.Run("missing group", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestMoveHost, protocol.MoveHostPayload{
+			Alias: "move-local",
+		})
+		resp := server.handleMoveHost(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		req := &protocol.Request{
+			Type:    protocol.RequestMoveHost,
+			Payload: json.RawMessage(`{invalid`),
+		}
+		resp := server.handleMoveHost(req)
+		assert.Equal(t, "error", resp.Status)
+	})
+}
+
 func TestServer_HandleAddGroup(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -306,6 +1686,14 @@ func TestServer_HandleAddGroup(t *testing.T) {
 		assert.Equal(t, "error", resp.Status)
 	})
 
+	t.Run("invalid name", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestAddGroup, protocol.GroupPayload{
+			Name: "new group",
+		})
+		resp := server.handleAddGroup(req)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
 	t.Run("invalid payload", func(t *testing.T) {
 		req := &protocol.Request{
 			Type:    protocol.RequestAddGroup,
@@ -316,6 +1704,23 @@ func TestServer_HandleAddGroup(t *testing.T) {
 	})
 }
 
+func TestServer_SaveRefusesExternalEdit(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("groups:\n  - name: edited\n    hosts: []\n"), 0644))
+
+	req, _ := protocol.NewRequest(protocol.RequestAddGroup, protocol.GroupPayload{Name: "newgroup"})
+	resp := server.handleAddGroup(req)
+	assert.Equal(t, protocol.ErrCodeInternalError, resp.Code)
+	assert.Contains(t, resp.Message, "changed on disk")
+
+	content, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "newgroup")
+}
+
 func TestServer_HandleDeleteGroup(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -365,6 +1770,61 @@ func TestServer_HandleListGroups(t *testing.T) {
 	assert.NotNil(t, data.Groups)
 }
 
+func TestServer_HandleReorderGroups(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddGroup("staging"))
+	require.NoError(t, cfg.AddHost("a.local", "127.0.0.1", "a-local", "development", true))
+	require.NoError(t, cfg.AddHost("b.local", "127.0.0.1", "b-local", "staging", true))
+
+	t.Run("reorders and syncs", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestReorderGroups, protocol.ReorderPayload{
+			Names: []string{"staging", "development"},
+		})
+		resp := server.handleReorderGroups(req)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.GroupsData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, []string{"staging", "development"}, data.Groups)
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "hosts"))
+		require.NoError(t, err)
+		assert.Less(t, strings.Index(string(content), "b.local"), strings.Index(string(content), "a.local"))
+	})
+
+	t.Run("rejects incomplete list", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestReorderGroups, protocol.ReorderPayload{
+			Names: []string{"development"},
+		})
+		resp := server.handleReorderGroups(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+		assert.Contains(t, resp.Message, "missing groups: staging")
+	})
+}
+
+func TestServer_HandleReorderPresets(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req, _ := protocol.NewRequest(protocol.RequestReorderPresets, protocol.ReorderPayload{
+		Names: []string{"clear", "local"},
+	})
+	resp := server.handleReorderPresets(req)
+	require.Equal(t, "ok", resp.Status, resp.Message)
+	assert.Equal(t, "clear", server.config.Get().Presets[0].Name)
+
+	req, _ = protocol.NewRequest(protocol.RequestReorderPresets, protocol.ReorderPayload{
+		Names: []string{"clear", "local", "travel"},
+	})
+	resp = server.handleReorderPresets(req)
+	assert.Equal(t, "error", resp.Status)
+	assert.Contains(t, resp.Message, "preset not found: travel")
+}
+
 func TestServer_HandleRenameGroup(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -427,6 +1887,20 @@ func TestServer_HandleAddPreset(t *testing.T) {
 		assert.Equal(t, protocol.ErrCodeConflict, resp.Code)
 	})
 
+	t.Run("conflicting enable set warns", func(t *testing.T) {
+		cfg := server.config.Get()
+		cfg.AddHost("dup.local", "127.0.0.1", "dup-one", "default", false)
+		cfg.AddHost("dup.local", "10.0.0.1", "dup-two", "default", false)
+
+		req, _ := protocol.NewRequest(protocol.RequestAddPreset, protocol.AddPresetPayload{
+			Name:   "conflicting",
+			Enable: []string{"dup-one", "dup-two"},
+		})
+		resp := server.handleAddPreset(req)
+		assert.Equal(t, "ok", resp.Status)
+		assert.Contains(t, resp.Message, "dup.local")
+	})
+
 	t.Run("empty name", func(t *testing.T) {
 		req, _ := protocol.NewRequest(protocol.RequestAddPreset, protocol.AddPresetPayload{
 			Name: "",
@@ -494,6 +1968,61 @@ func TestServer_HandleListPresets(t *testing.T) {
 	assert.NotNil(t, data.Presets)
 }
 
+func TestServer_HandleSetSticky(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	cfg.AddHost("pinned.local", "127.0.0.1", "pinned", "default", true)
+	cfg.AddPreset("off", nil, []string{"pinned"})
+	server.config.Save()
+
+	t.Run("set sticky", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestSetSticky, protocol.SetStickyPayload{
+			Alias:  "pinned",
+			Sticky: true,
+		})
+		resp := server.handleSetSticky(req)
+		require.Equal(t, "ok", resp.Status)
+
+		host, _ := server.config.Get().FindHostByAlias("pinned")
+		require.NotNil(t, host)
+		assert.True(t, host.Sticky)
+	})
+
+	t.Run("preset leaves sticky host enabled", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestPreset, protocol.PresetPayload{Name: "off"})
+		resp := server.handlePreset(req)
+		require.Equal(t, "ok", resp.Status)
+
+		var data protocol.PresetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Empty(t, data.Disable)
+
+		host, _ := server.config.Get().FindHostByAlias("pinned")
+		assert.True(t, host.Enabled)
+	})
+
+	t.Run("nonexistent alias", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestSetSticky, protocol.SetStickyPayload{
+			Alias:  "nonexistent",
+			Sticky: true,
+		})
+		resp := server.handleSetSticky(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		req := &protocol.Request{
+			Type:    protocol.RequestSetSticky,
+			Payload: json.RawMessage(`{invalid`),
+		}
+		resp := server.handleSetSticky(req)
+		assert.Equal(t, "error", resp.Status)
+	})
+}
+
 func TestServer_HandlePreset(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -505,12 +2034,70 @@ func TestServer_HandlePreset(t *testing.T) {
 	cfg.AddPreset("testpreset", []string{"host1"}, []string{"host2"})
 	server.config.Save()
 
+	t.Run("dry run reports changes without applying", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestPreset, protocol.PresetPayload{
+			Name:   "testpreset",
+			DryRun: true,
+		})
+		resp := server.handlePreset(req)
+		require.Equal(t, "ok", resp.Status)
+
+		var data protocol.PresetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.False(t, data.Applied)
+		assert.Equal(t, []string{"host1"}, data.Enable)
+		assert.Empty(t, data.Disable)
+
+		host, _ := server.config.Get().FindHostByAlias("host1")
+		assert.False(t, host.Enabled)
+	})
+
 	t.Run("apply existing preset", func(t *testing.T) {
 		req, _ := protocol.NewRequest(protocol.RequestPreset, protocol.PresetPayload{
 			Name: "testpreset",
 		})
 		resp := server.handlePreset(req)
 		assert.Equal(t, "ok", resp.Status)
+
+		var data protocol.PresetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.True(t, data.Applied)
+	})
+
+	t.Run("reports skipped aliases", func(t *testing.T) {
+		cfg := server.config.Get()
+		require.NoError(t, cfg.AddPreset("stale", []string{"host1", "gone"}, nil))
+		require.NoError(t, server.config.Save())
+
+		req, _ := protocol.NewRequest(protocol.RequestPreset, protocol.PresetPayload{
+			Name: "stale",
+		})
+		resp := server.handlePreset(req)
+		require.Equal(t, "ok", resp.Status)
+
+		var data protocol.PresetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.True(t, data.Applied)
+		assert.Equal(t, []string{"gone"}, data.Skipped)
+	})
+
+	t.Run("strict fails on skipped aliases", func(t *testing.T) {
+		require.NoError(t, server.config.With(func(cfg *config.Config) error {
+			cfg.SetHostEnabled("host1", false)
+			return nil
+		}))
+
+		req, _ := protocol.NewRequest(protocol.RequestPreset, protocol.PresetPayload{
+			Name:   "stale",
+			Strict: true,
+		})
+		resp := server.handlePreset(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+		assert.Contains(t, resp.Message, "gone")
+
+		host, _ := server.config.Get().FindHostByAlias("host1")
+		assert.False(t, host.Enabled, "strict failure must not apply the preset")
 	})
 
 	t.Run("apply nonexistent preset", func(t *testing.T) {
@@ -537,7 +2124,7 @@ func TestServer_HandleRollback(t *testing.T) {
 	defer cleanup()
 
 	// Create a backup first
-	server.hosts.CreateBackup()
+	_, _ = server.hosts.CreateBackup("")
 	backups, _ := server.hosts.ListBackups()
 	require.NotEmpty(t, backups)
 
@@ -572,7 +2159,7 @@ func TestServer_HandleBackupContent(t *testing.T) {
 	defer cleanup()
 
 	// Create a backup first
-	server.hosts.CreateBackup()
+	_, _ = server.hosts.CreateBackup("")
 	backups, _ := server.hosts.ListBackups()
 	require.NotEmpty(t, backups)
 
@@ -607,6 +2194,528 @@ func TestServer_HandleBackupContent(t *testing.T) {
 	})
 }
 
+func TestServer_FlushFailureIsWarning(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	server.flusher = &DNSFlusher{
+		method: failingFlushMethod(t),
+		run:    func(string, ...string) error { return errors.New("exit status 1") },
+	}
+
+	req, _ := protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{Alias: "example-local", Enabled: true})
+	resp := server.handleRequest(req, &PeerCredentials{UID: 0, PID: 1})
+	require.Equal(t, "ok", resp.Status, resp.Message)
+	assert.Contains(t, resp.Message, "synced; DNS flush failed")
+
+	// The write stands and the config isn't rolled back
+	host, _ := server.config.Get().FindHostByAlias("example-local")
+	require.NotNil(t, host)
+	assert.True(t, host.Enabled)
+	entries, err := server.hosts.readManagedEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "example-local", entries[0].Alias)
+
+	// The warning belongs to that request only
+	resp = server.handleRequest(&protocol.Request{Type: protocol.RequestList}, &PeerCredentials{UID: 0, PID: 1})
+	require.Equal(t, "ok", resp.Status)
+	assert.Empty(t, resp.Message)
+}
+
+func TestServer_HandleExplain(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	server.flusher = &DNSFlusher{
+		method: failingFlushMethod(t),
+		run:    func(string, ...string) error { return nil },
+	}
+
+	explain := func(t *testing.T, alias string) protocol.ExplainData {
+		t.Helper()
+		req, _ := protocol.NewRequest(protocol.RequestExplain, protocol.ExplainPayload{Alias: alias})
+		resp := server.handleExplain(req)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.ExplainData
+		require.NoError(t, resp.ParseData(&data))
+		return data
+	}
+	kinds := func(issues []protocol.VerifyIssue) []string {
+		var out []string
+		for _, issue := range issues {
+			out = append(out, issue.Kind)
+		}
+		return out
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		data := explain(t, "example-local")
+		assert.False(t, data.Entry.Enabled)
+		assert.False(t, data.InHostsFile)
+		assert.Equal(t, []string{"disabled"}, kinds(data.Issues))
+	})
+
+	t.Run("enabled but not synced", func(t *testing.T) {
+		require.NoError(t, server.config.With(func(cfg *config.Config) error {
+			cfg.SetHostEnabled("example-local", true)
+			return nil
+		}))
+
+		data := explain(t, "example-local")
+		assert.Equal(t, []string{"missing"}, kinds(data.Issues))
+		assert.Nil(t, data.LastSync)
+	})
+
+	t.Run("nothing in the way", func(t *testing.T) {
+		require.NoError(t, server.syncHostsFile())
+
+		data := explain(t, "example-local")
+		assert.True(t, data.InHostsFile)
+		assert.True(t, data.Entry.Synced)
+		require.NotNil(t, data.LastSync)
+		assert.Empty(t, data.LastSync.Error)
+		assert.Empty(t, data.Issues)
+	})
+
+	t.Run("conflicts and external lines", func(t *testing.T) {
+		require.NoError(t, server.config.With(func(cfg *config.Config) error {
+			return cfg.AddHost("example.local", "10.0.0.1", "example-remote", "development", true)
+		}))
+
+		content, err := os.ReadFile(server.hosts.hostsPath)
+		require.NoError(t, err)
+		content = append([]byte("10.0.0.2\texample.local other.local # by hand\n"), content...)
+		require.NoError(t, os.WriteFile(server.hosts.hostsPath, content, 0644))
+
+		data := explain(t, "example-local")
+		assert.Equal(t, []string{"example-remote"}, data.Conflicts)
+		assert.Equal(t, []string{"10.0.0.2\texample.local other.local # by hand"}, data.External)
+		assert.Equal(t, []string{"domain_conflict", "external_entry"}, kinds(data.Issues))
+	})
+
+	t.Run("failed flush", func(t *testing.T) {
+		server.flusher.run = func(string, ...string) error { return errors.New("exit status 1") }
+		require.NoError(t, server.syncHostsFile())
+
+		data := explain(t, "example-local")
+		require.NotNil(t, data.LastSync)
+		assert.NotEmpty(t, data.LastSync.FlushError)
+		assert.Contains(t, kinds(data.Issues), "flush_failed")
+	})
+
+	t.Run("nonexistent alias", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestExplain, protocol.ExplainPayload{Alias: "nonexistent"})
+		resp := server.handleExplain(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+
+	t.Run("missing alias", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestExplain, protocol.ExplainPayload{})
+		resp := server.handleExplain(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+}
+
+func TestServer_HandleSwitchProfile(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	require.NoError(t, server.config.With(func(cfg *config.Config) error {
+		return cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", true)
+	}))
+	require.NoError(t, server.saveAndSync())
+
+	switchTo := func(name string, create bool) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestSwitchProfile, protocol.SwitchProfilePayload{Name: name, Create: create})
+		return server.handleSwitchProfile(req)
+	}
+	managedAliases := func(t *testing.T) []string {
+		entries, err := server.hosts.readManagedEntries()
+		require.NoError(t, err)
+		var aliases []string
+		for _, e := range entries {
+			aliases = append(aliases, e.Alias)
+		}
+		return aliases
+	}
+
+	t.Run("missing profile", func(t *testing.T) {
+		resp := switchTo("work", false)
+		assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		resp := switchTo("../work", true)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("create and switch", func(t *testing.T) {
+		resp := switchTo("work", true)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.ProfilesData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, "work", data.Active)
+		assert.Equal(t, []string{"default", "work"}, data.Profiles)
+
+		assert.Equal(t, filepath.Join(tmpDir, "profiles", "work.yaml"), server.config.Path())
+		host, _ := server.config.Get().FindHostByAlias("api-local")
+		assert.Nil(t, host, "the new profile starts from the defaults")
+		assert.Empty(t, managedAliases(t), "hosts file follows the active profile")
+	})
+
+	t.Run("switch back", func(t *testing.T) {
+		resp := switchTo("default", false)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		assert.Equal(t, filepath.Join(tmpDir, "config.yaml"), server.config.Path())
+		assert.Equal(t, []string{"api-local"}, managedAliases(t))
+		assert.Equal(t, "default", server.profiles.Active())
+	})
+
+	t.Run("status reports the profile", func(t *testing.T) {
+		require.Equal(t, "ok", switchTo("work", false).Status)
+
+		var data protocol.StatusData
+		require.NoError(t, server.handleStatus().ParseData(&data))
+		assert.Equal(t, "work", data.Profile)
+	})
+}
+
+func TestServer_HandleReset(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddGroup("work"))
+	require.NoError(t, cfg.AddHost("api.work.local", "127.0.0.1", "work-api", "work", true))
+	require.NoError(t, cfg.AddPreset("work", []string{"work-api"}, nil))
+	require.NoError(t, server.saveAndSync())
+
+	root := &PeerCredentials{UID: 0, PID: 1}
+	confirm, _ := protocol.NewRequest(protocol.RequestReset, protocol.ResetPayload{Confirm: true})
+
+	t.Run("requires root", func(t *testing.T) {
+		resp := server.handleReset(confirm, &PeerCredentials{UID: 501, PID: 2})
+		assert.Equal(t, protocol.ErrCodePermissionError, resp.Code)
+		host, _ := server.config.Get().FindHostByAlias("work-api")
+		assert.NotNil(t, host)
+	})
+
+	t.Run("requires confirmation", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestReset, protocol.ResetPayload{})
+		resp := server.handleReset(req, root)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("resets to defaults", func(t *testing.T) {
+		resp := server.handleReset(confirm, root)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.ResetData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, protocol.ResetData{HostsRemoved: 2, GroupsRemoved: 2, PresetsRemoved: 3}, data)
+
+		assert.Equal(t, config.Default(), server.config.Get())
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "config.yaml"))
+		require.NoError(t, err)
+		loaded, err := config.Parse(content)
+		require.NoError(t, err)
+		assert.Equal(t, config.Default().Groups, loaded.Groups)
+
+		entries, err := server.hosts.readManagedEntries()
+		require.NoError(t, err)
+		assert.Empty(t, entries, "default hosts are disabled")
+
+		backups, err := server.hosts.ListBackups()
+		require.NoError(t, err)
+		var labels []string
+		for _, b := range backups {
+			labels = append(labels, b.Label)
+		}
+		assert.Contains(t, labels, "before-reset")
+	})
+}
+
+func TestServer_HandleImportConfigMerge(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	importConfig := func(content string, dryRun bool) *protocol.Response {
+		req, _ := protocol.NewRequest(protocol.RequestImportConfig, protocol.ImportConfigPayload{
+			Content: content,
+			DryRun:  dryRun,
+			Merge:   true,
+		})
+		return server.handleImportConfig(req)
+	}
+
+	t.Run("merges into the current config", func(t *testing.T) {
+		resp := importConfig("groups:\n  - name: team\n    hosts:\n      - domain: api.team.local\n        ip: 127.0.0.1\n        alias: team-api\n        enabled: true\n", false)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.ImportConfigData
+		require.NoError(t, resp.ParseData(&data))
+		assert.True(t, data.Applied)
+		assert.Equal(t, []string{"team-api"}, data.HostsAdded)
+		assert.Empty(t, data.HostsRemoved)
+
+		cfg := server.config.Get()
+		host, _ := cfg.FindHostByAlias("example-local")
+		assert.NotNil(t, host)
+		host, _ = cfg.FindHostByAlias("team-api")
+		assert.NotNil(t, host)
+	})
+
+	conflicting := "groups:\n  - name: development\n    hosts:\n      - domain: example.local\n        ip: 10.0.0.1\n        alias: example-local\n        enabled: true\n"
+
+	t.Run("dry run lists conflicts", func(t *testing.T) {
+		resp := importConfig(conflicting, true)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.ImportConfigData
+		require.NoError(t, resp.ParseData(&data))
+		assert.Equal(t, []string{"host example-local differs from the current one"}, data.Conflicts)
+	})
+
+	t.Run("conflicts refuse the merge", func(t *testing.T) {
+		resp := importConfig(conflicting, false)
+		assert.Equal(t, protocol.ErrCodeConflict, resp.Code)
+		assert.Contains(t, resp.Message, "example-local")
+
+		host, _ := server.config.Get().FindHostByAlias("example-local")
+		assert.Equal(t, "127.0.0.1", host.IP)
+	})
+}
+
+func TestServer_HandleExport(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	for _, format := range []string{"", config.ExportJSON} {
+		req, _ := protocol.NewRequest(protocol.RequestExport, protocol.ExportPayload{Format: format})
+		resp := server.handleExport(req)
+		require.Equal(t, "ok", resp.Status, resp.Message)
+
+		var data protocol.ExportData
+		require.NoError(t, resp.ParseData(&data))
+		parsed, err := config.Parse([]byte(data.Content))
+		require.NoError(t, err)
+		host, _ := parsed.FindHostByAlias("example-local")
+		assert.NotNil(t, host, format)
+	}
+
+	req, _ := protocol.NewRequest(protocol.RequestExport, protocol.ExportPayload{Format: "xml"})
+	resp := server.handleExport(req)
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+}
+
+func TestServer_HandleImportConfig(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	content := `groups:
+  - name: team
+    hosts:
+      - domain: api.team.local
+        ip: 127.0.0.1
+        alias: team-api
+        enabled: true
+presets:
+  - name: team
+    enable: [team-api]
+`
+
+	t.Run("dry run reports diff without applying", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestImportConfig, protocol.ImportConfigPayload{
+			Content: content,
+			DryRun:  true,
+		})
+		resp := server.handleImportConfig(req)
+		require.Equal(t, "ok", resp.Status)
+
+		var data protocol.ImportConfigData
+		require.NoError(t, resp.ParseData(&data))
+		assert.False(t, data.Applied)
+		assert.Equal(t, []string{"team-api"}, data.HostsAdded)
+		assert.Equal(t, []string{"example-local"}, data.HostsRemoved)
+		assert.Equal(t, []string{"team"}, data.GroupsAdded)
+		assert.Equal(t, []string{"team"}, data.PresetsAdded)
+
+		host, _ := server.config.Get().FindHostByAlias("team-api")
+		assert.Nil(t, host)
+	})
+
+	t.Run("applies import", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestImportConfig, protocol.ImportConfigPayload{
+			Content: content,
+		})
+		resp := server.handleImportConfig(req)
+		require.Equal(t, "ok", resp.Status)
+
+		var data protocol.ImportConfigData
+		require.NoError(t, resp.ParseData(&data))
+		assert.True(t, data.Applied)
+
+		host, group := server.config.Get().FindHostByAlias("team-api")
+		require.NotNil(t, host)
+		assert.Equal(t, "team", group.Name)
+
+		entries, err := server.hosts.readManagedEntries()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "team-api", entries[0].Alias)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestImportConfig, protocol.ImportConfigPayload{
+			Content: "groups: [",
+			DryRun:  true,
+		})
+		resp := server.handleImportConfig(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	})
+
+	t.Run("blocked domain", func(t *testing.T) {
+		req, _ := protocol.NewRequest(protocol.RequestImportConfig, protocol.ImportConfigPayload{
+			Content: "groups:\n  - name: bad\n    hosts:\n      - domain: apple.com\n        ip: 127.0.0.1\n        alias: apple\n",
+			DryRun:  true,
+		})
+		resp := server.handleImportConfig(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Contains(t, resp.Message, "blocked")
+	})
+
+	t.Run("strict rejects unknown preset aliases", func(t *testing.T) {
+		stale := content + "  - name: stale\n    enable: [team-api, gone]\n"
+
+		req, _ := protocol.NewRequest(protocol.RequestImportConfig, protocol.ImportConfigPayload{
+			Content: stale,
+			DryRun:  true,
+		})
+		resp := server.handleImportConfig(req)
+		assert.Equal(t, "ok", resp.Status, "lenient by default")
+
+		req, _ = protocol.NewRequest(protocol.RequestImportConfig, protocol.ImportConfigPayload{
+			Content: stale,
+			Strict:  true,
+		})
+		resp = server.handleImportConfig(req)
+		assert.Equal(t, "error", resp.Status)
+		assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+		assert.Contains(t, resp.Message, "stale: gone")
+	})
+}
+
+func TestServer_HandlePreviewHosts(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	before, err := os.ReadFile(hostsPath)
+	require.NoError(t, err)
+
+	cfg := server.config.Get()
+	require.NoError(t, cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", true))
+
+	resp := server.handlePreviewHosts()
+	require.Equal(t, "ok", resp.Status)
+	var data protocol.PreviewHostsData
+	require.NoError(t, resp.ParseData(&data))
+
+	assert.True(t, strings.HasPrefix(data.Section, markerStart+"\n"))
+	assert.Contains(t, data.Section, "127.0.0.1\tapi.local\t# lolcathost:api-local\n")
+	assert.NotContains(t, data.Section, "example.local", "disabled hosts are not written")
+
+	after, err := os.ReadFile(hostsPath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "preview must not write the hosts file")
+}
+
+func TestServer_HandleVerify(t *testing.T) {
+	server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	hostsPath := filepath.Join(tmpDir, "hosts")
+	verify := func(t *testing.T) []protocol.VerifyIssue {
+		resp := server.handleVerify()
+		require.Equal(t, "ok", resp.Status)
+		var data protocol.VerifyData
+		require.NoError(t, resp.ParseData(&data))
+		return data.Issues
+	}
+
+	cfg := server.config.Get()
+	cfg.AddHost("api.local", "127.0.0.1", "api-local", "development", true)
+
+	t.Run("in sync", func(t *testing.T) {
+		require.NoError(t, server.syncHostsFile())
+		assert.Empty(t, verify(t))
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		content := "127.0.0.1\tlocalhost\n\n" + markerStart + "\n" +
+			"10.0.0.1\tapi.local\t# lolcathost:api-local\n" +
+			"127.0.0.1\tstale.local\t# lolcathost:stale-local\n" +
+			markerEnd + "\n"
+		require.NoError(t, os.WriteFile(hostsPath, []byte(content), 0644))
+		cfg.AddHost("web.local", "127.0.0.1", "web-local", "development", true)
+		defer cfg.DeleteHost("web-local")
+
+		kinds := make(map[string]string)
+		byAlias := make(map[string]protocol.VerifyIssue)
+		for _, issue := range verify(t) {
+			kinds[issue.Alias] = issue.Kind
+			byAlias[issue.Alias] = issue
+			assert.Equal(t, protocol.SeverityMedium, issue.Severity)
+		}
+		assert.Equal(t, map[string]string{
+			"api-local":   "mismatch",
+			"stale-local": "extra",
+			"web-local":   "missing",
+		}, kinds)
+
+		mismatch := byAlias["api-local"]
+		assert.Equal(t, "127.0.0.1", mismatch.IP)
+		assert.Equal(t, "10.0.0.1", mismatch.FileIP)
+		assert.Equal(t, "api.local", mismatch.FileDomain)
+		assert.Equal(t, "127.0.0.1", byAlias["stale-local"].IP)
+		assert.Equal(t, "stale.local", byAlias["stale-local"].Domain)
+		assert.Equal(t, "127.0.0.1", byAlias["web-local"].IP)
+	})
+
+	t.Run("unparseable managed line", func(t *testing.T) {
+		content := "127.0.0.1\tlocalhost\n\n" + markerStart + "\n" +
+			"127.0.0.1\tapi.local\t# lolcathost:api-local\n" +
+			"127.0.0.1\tedited.local\t# lolcathost: edited alias\n" +
+			markerEnd + "\n"
+		require.NoError(t, os.WriteFile(hostsPath, []byte(content), 0644))
+
+		issues := verify(t)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "unparseable", issues[0].Kind)
+		assert.Equal(t, protocol.SeverityHigh, issues[0].Severity)
+	})
+
+	t.Run("domain mapped to different IPs", func(t *testing.T) {
+		cfg.AddHost("api.local", "10.0.0.1", "api-remote", "development", true)
+		require.NoError(t, server.syncHostsFile())
+
+		issues := verify(t)
+		require.Len(t, issues, 1)
+		assert.Equal(t, protocol.SeverityHigh, issues[0].Severity)
+		assert.Equal(t, "domain_conflict", issues[0].Kind)
+		assert.Equal(t, "api.local", issues[0].Domain)
+		assert.Contains(t, issues[0].Suggestion, "disable api-remote")
+	})
+}
+
 func TestServer_HandleRequest_UnknownType(t *testing.T) {
 	server, _, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -618,6 +2727,13 @@ func TestServer_HandleRequest_UnknownType(t *testing.T) {
 	resp := server.handleRequest(req, creds)
 	assert.Equal(t, "error", resp.Status)
 	assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	assert.NotContains(t, resp.Message, "upgrade")
+
+	// A newer client likely sent a request type this daemon doesn't know yet
+	req.Version = protocol.Version + 1
+	resp = server.handleRequest(req, creds)
+	assert.Equal(t, protocol.ErrCodeInvalidRequest, resp.Code)
+	assert.Contains(t, resp.Message, "please upgrade the daemon")
 }
 
 func TestServer_IsAuthorized(t *testing.T) {
@@ -632,6 +2748,14 @@ func TestServer_IsAuthorized(t *testing.T) {
 	t.Run("nil credentials", func(t *testing.T) {
 		assert.False(t, server.isAuthorized(nil))
 	})
+
+	t.Run("primary group uses resolved gid", func(t *testing.T) {
+		server.groupGID = 4242
+		defer func() { server.groupGID = LolcathostGID }()
+
+		assert.True(t, server.isAuthorized(&PeerCredentials{UID: 99999, GID: 4242, PID: 1}))
+		assert.False(t, server.isAuthorized(&PeerCredentials{UID: 99999, GID: LolcathostGID, PID: 1}))
+	})
 }
 
 func TestServer_StartStop(t *testing.T) {
@@ -699,6 +2823,181 @@ func TestServer_AcceptConnection(t *testing.T) {
 	assert.Equal(t, "ok", resp.Status)
 }
 
+// sendOverSocket starts server on its socket, sends req as this process and
+// returns the response, so the daemon sees real peer credentials.
+func sendOverSocket(t *testing.T, server *Server, req *protocol.Request) *protocol.Response {
+	t.Helper()
+
+	go server.Start()
+	time.Sleep(100 * time.Millisecond)
+	t.Cleanup(func() { server.Stop() })
+
+	conn, err := net.Dial("unix", server.socketPath)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, json.NewEncoder(conn).Encode(req))
+	var resp protocol.Response
+	require.NoError(t, json.NewDecoder(conn).Decode(&resp))
+	return &resp
+}
+
+func TestServer_ResetAsRootOverSocket(t *testing.T) {
+	// Skip test if not running as root (reset is root only)
+	if os.Getuid() != 0 {
+		t.Skip("Test requires root privileges")
+	}
+
+	server, _, _ := setupTestServer(t)
+
+	req, _ := protocol.NewRequest(protocol.RequestReset, protocol.ResetPayload{Confirm: true})
+	resp := sendOverSocket(t, server, req)
+	require.Equal(t, "ok", resp.Status, resp.Message)
+
+	var data protocol.ResetData
+	require.NoError(t, resp.ParseData(&data))
+	assert.Positive(t, data.GroupsRemoved)
+}
+
+func TestServer_ForceBlockedAsRootOverSocket(t *testing.T) {
+	// Skip test if not running as root (the override is root only)
+	if os.Getuid() != 0 {
+		t.Skip("Test requires root privileges")
+	}
+
+	server, _, _ := setupTestServer(t)
+
+	req, _ := protocol.NewRequest(protocol.RequestAdd, protocol.AddPayload{
+		Domain: "icloud.com", IP: "127.0.0.1", Group: "development", Enabled: true, Force: true,
+	})
+	resp := sendOverSocket(t, server, req)
+	require.Equal(t, "ok", resp.Status, resp.Message)
+
+	entries, err := server.hosts.readManagedEntries()
+	require.NoError(t, err)
+	assert.True(t, slices.ContainsFunc(entries, func(e HostEntry) bool { return e.Domain == "icloud.com" }))
+}
+
+func TestServer_HandleConnection_IdleTimeout(t *testing.T) {
+	// Skip test if not running as root (non-root peers are not authorized)
+	if os.Getuid() != 0 {
+		t.Skip("Test requires root privileges to pass peer authorization")
+	}
+
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.config.Get().Settings.IdleTimeout = 200 * time.Millisecond
+
+	serverConn, clientConn, err := unixSocketPair()
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		server.handleConnection(serverConn)
+		close(done)
+	}()
+
+	// Activity within the timeout keeps the connection open
+	decoder := json.NewDecoder(clientConn)
+	for i := 0; i < 3; i++ {
+		_, err := clientConn.Write([]byte(`{"type":"ping"}` + "\n"))
+		require.NoError(t, err)
+		var resp protocol.Response
+		require.NoError(t, decoder.Decode(&resp))
+		assert.Equal(t, "ok", resp.Status)
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+
+	_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	var extra protocol.Response
+	assert.Error(t, decoder.Decode(&extra))
+}
+
+// unixSocketPair returns both ends of a connected Unix socket, so peer
+// credentials are available to the server side.
+func unixSocketPair() (net.Conn, net.Conn, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	var conns [2]net.Conn
+	for i, fd := range fds {
+		f := os.NewFile(uintptr(fd), "socketpair")
+		conns[i], err = net.FileConn(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return conns[0], conns[1], nil
+}
+
+func TestServer_HandleConnection_HalfClose(t *testing.T) {
+	// Skip test if not running as root (non-root peers are not authorized)
+	if os.Getuid() != 0 {
+		t.Skip("Test requires root privileges to pass peer authorization")
+	}
+
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	// Use /tmp directly to avoid long paths (Unix socket paths have ~104 char limit on macOS)
+	tmpDir, err := os.MkdirTemp("/tmp", "lolcat")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	listener, err := net.Listen("unix", filepath.Join(tmpDir, "s.sock"))
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handleConnection(conn)
+		}
+	}()
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"with trailing newline", `{"type":"ping"}` + "\n"},
+		{"without trailing newline", `{"type":"ping"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("unix", listener.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = conn.Write([]byte(tt.payload))
+			require.NoError(t, err)
+			require.NoError(t, conn.(*net.UnixConn).CloseWrite())
+
+			_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			decoder := json.NewDecoder(conn)
+			var resp protocol.Response
+			require.NoError(t, decoder.Decode(&resp))
+			assert.Equal(t, "ok", resp.Status)
+
+			// Server closes its side once the client is done sending
+			var extra protocol.Response
+			assert.Error(t, decoder.Decode(&extra))
+		})
+	}
+}
+
 // Benchmarks
 
 func BenchmarkServer_HandlePing(b *testing.B) {
@@ -733,7 +3032,7 @@ func BenchmarkServer_HandleList(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		server.handleList()
+		server.handleList(&protocol.Request{Type: protocol.RequestList})
 	}
 }
 
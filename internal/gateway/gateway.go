@@ -0,0 +1,224 @@
+// Package gateway exposes a small REST API that relays requests to the
+// lolcathost daemon over its Unix socket.
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
+)
+
+// maxBodySize caps request bodies; payloads are a few hundred bytes at most.
+const maxBodySize = 1 << 20
+
+// Conn is a connection to the daemon.
+type Conn interface {
+	Do(req *protocol.Request) (*protocol.Response, error)
+	Close() error
+}
+
+// Gateway translates REST calls into daemon requests. Each HTTP request uses
+// its own daemon connection, so the daemon still checks that whoever runs the
+// gateway is in the lolcathost group; the token keeps other local users out.
+type Gateway struct {
+	dial  func() (Conn, error)
+	token string
+	mux   *http.ServeMux
+}
+
+// New creates a gateway that connects to the daemon with dial and accepts
+// requests carrying token as a bearer token.
+func New(dial func() (Conn, error), token string) *Gateway {
+	g := &Gateway{
+		dial:  dial,
+		token: token,
+		mux:   http.NewServeMux(),
+	}
+
+	g.mux.HandleFunc("GET /v1/entries", g.handleList)
+	g.mux.HandleFunc("POST /v1/entries", g.handleAdd)
+	g.mux.HandleFunc("GET /v1/entries/{alias}", g.handleGet)
+	g.mux.HandleFunc("PATCH /v1/entries/{alias}", g.handleSet)
+	g.mux.HandleFunc("DELETE /v1/entries/{alias}", g.handleDelete)
+	g.mux.HandleFunc("GET /v1/presets", g.handleListPresets)
+	g.mux.HandleFunc("POST /v1/presets/{name}/apply", g.handleApplyPreset)
+
+	return g
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.authorized(r) {
+		writeResponse(w, protocol.NewErrorResponse(protocol.ErrCodeUnauthorized, "unauthorized: missing or invalid token"))
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) == 1
+}
+
+func (g *Gateway) handleList(w http.ResponseWriter, r *http.Request) {
+	g.relay(w, protocol.RequestList, protocol.ListPayload{State: r.URL.Query().Get("state")})
+}
+
+func (g *Gateway) handleGet(w http.ResponseWriter, r *http.Request) {
+	g.relay(w, protocol.RequestGet, protocol.GetPayload{Alias: r.PathValue("alias")})
+}
+
+func (g *Gateway) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var payload protocol.AddPayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	g.relay(w, protocol.RequestAdd, payload)
+}
+
+func (g *Gateway) handleSet(w http.ResponseWriter, r *http.Request) {
+	var payload protocol.SetPayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	payload.Alias = r.PathValue("alias")
+	g.relay(w, protocol.RequestSet, payload)
+}
+
+func (g *Gateway) handleDelete(w http.ResponseWriter, r *http.Request) {
+	g.relay(w, protocol.RequestDelete, protocol.DeletePayload{Alias: r.PathValue("alias")})
+}
+
+func (g *Gateway) handleListPresets(w http.ResponseWriter, r *http.Request) {
+	g.relay(w, protocol.RequestListPresets, nil)
+}
+
+func (g *Gateway) handleApplyPreset(w http.ResponseWriter, r *http.Request) {
+	var payload protocol.PresetPayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	payload.Name = r.PathValue("name")
+	g.relay(w, protocol.RequestPreset, payload)
+}
+
+// relay sends one request to the daemon and writes its response.
+func (g *Gateway) relay(w http.ResponseWriter, reqType protocol.RequestType, payload any) {
+	req, err := protocol.NewRequest(reqType, payload)
+	if err != nil {
+		writeResponse(w, protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error()))
+		return
+	}
+
+	conn, err := g.dial()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	defer conn.Close()
+
+	resp, err := conn.Do(req)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeResponse(w, resp)
+}
+
+// decodeBody parses an optional JSON body into v. An empty body leaves v
+// unchanged. It writes an error response and returns false on bad input.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(v)
+	if err != nil && err != io.EOF {
+		writeResponse(w, protocol.NewErrorResponse(protocol.ErrCodeInvalidRequest, "invalid JSON body"))
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(protocol.NewErrorResponse(protocol.ErrCodeInternalError, err.Error()))
+}
+
+func writeResponse(w http.ResponseWriter, resp *protocol.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode(resp))
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// statusCode maps a daemon response onto an HTTP status.
+func statusCode(resp *protocol.Response) int {
+	if resp.IsOK() {
+		return http.StatusOK
+	}
+
+	switch resp.Code {
+	case protocol.ErrCodeInvalidRequest, protocol.ErrCodeInvalidDomain,
+		protocol.ErrCodeInvalidIP, protocol.ErrCodeBlockedDomain:
+		return http.StatusBadRequest
+	case protocol.ErrCodeUnauthorized:
+		return http.StatusUnauthorized
+	case protocol.ErrCodePermissionError:
+		return http.StatusForbidden
+	case protocol.ErrCodeNotFound:
+		return http.StatusNotFound
+	case protocol.ErrCodeConflict:
+		return http.StatusConflict
+	case protocol.ErrCodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListenAddr fills in 127.0.0.1 when addr has no host, so ":8099" stays
+// local. Listening on other interfaces needs the host spelled out.
+func ListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// LoadOrCreateToken returns the token stored at path, creating a random one
+// readable only by the current user if the file doesn't exist.
+func LoadOrCreateToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create token directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write token: %w", err)
+	}
+	return token, nil
+}
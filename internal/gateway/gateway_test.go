@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
+)
+
+type fakeConn struct {
+	requests []*protocol.Request
+	handler  func(req *protocol.Request) *protocol.Response
+	closed   bool
+}
+
+func (f *fakeConn) Do(req *protocol.Request) (*protocol.Response, error) {
+	f.requests = append(f.requests, req)
+	return f.handler(req), nil
+}
+
+func (f *fakeConn) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newTestGateway(conn *fakeConn) *Gateway {
+	return New(func() (Conn, error) { return conn, nil }, "secret")
+}
+
+func doRequest(g *Gateway, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGateway_Unauthorized(t *testing.T) {
+	conn := &fakeConn{}
+	g := newTestGateway(conn)
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/entries", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, header)
+	}
+	assert.Empty(t, conn.requests, "unauthorized calls must not reach the daemon")
+}
+
+func TestGateway_Routes(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		reqType protocol.RequestType
+		payload string
+	}{
+		{"list", http.MethodGet, "/v1/entries?state=enabled", "", protocol.RequestList, `{"state":"enabled"}`},
+		{"get", http.MethodGet, "/v1/entries/api", "", protocol.RequestGet, `{"alias":"api"}`},
+		{"add", http.MethodPost, "/v1/entries", `{"domain":"api.local","ip":"127.0.0.1","alias":"api","group":"dev"}`, protocol.RequestAdd, `{"domain":"api.local","ip":"127.0.0.1","alias":"api","group":"dev","enabled":false}`},
+		{"set", http.MethodPatch, "/v1/entries/api", `{"enabled":true}`, protocol.RequestSet, `{"alias":"api","enabled":true}`},
+		{"delete", http.MethodDelete, "/v1/entries/api", "", protocol.RequestDelete, `{"alias":"api"}`},
+		{"list presets", http.MethodGet, "/v1/presets", "", protocol.RequestListPresets, ""},
+		{"apply preset", http.MethodPost, "/v1/presets/work/apply", "", protocol.RequestPreset, `{"name":"work"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &fakeConn{handler: func(*protocol.Request) *protocol.Response {
+				resp, _ := protocol.NewOKResponse(nil)
+				return resp
+			}}
+			rec := doRequest(newTestGateway(conn), tt.method, tt.path, tt.body)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			require.Len(t, conn.requests, 1)
+			assert.Equal(t, tt.reqType, conn.requests[0].Type)
+			if tt.payload == "" {
+				assert.Empty(t, conn.requests[0].Payload)
+			} else {
+				assert.JSONEq(t, tt.payload, string(conn.requests[0].Payload))
+			}
+			assert.True(t, conn.closed)
+		})
+	}
+}
+
+func TestGateway_ErrorStatus(t *testing.T) {
+	conn := &fakeConn{handler: func(*protocol.Request) *protocol.Response {
+		return protocol.NewErrorResponse(protocol.ErrCodeNotFound, "host not found: api")
+	}}
+	rec := doRequest(newTestGateway(conn), http.MethodGet, "/v1/entries/api", "")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var resp protocol.Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, protocol.ErrCodeNotFound, resp.Code)
+	assert.Equal(t, "host not found: api", resp.Message)
+}
+
+func TestGateway_InvalidBody(t *testing.T) {
+	conn := &fakeConn{}
+	rec := doRequest(newTestGateway(conn), http.MethodPatch, "/v1/entries/api", "{invalid")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, conn.requests)
+}
+
+func TestGateway_DaemonUnavailable(t *testing.T) {
+	g := New(func() (Conn, error) { return nil, errors.New("failed to connect to daemon") }, "secret")
+	rec := doRequest(g, http.MethodGet, "/v1/entries", "")
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Contains(t, rec.Body.String(), "failed to connect to daemon")
+}
+
+func TestListenAddr(t *testing.T) {
+	addr, err := ListenAddr(":8099")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8099", addr)
+
+	addr, err = ListenAddr("0.0.0.0:8099")
+	require.NoError(t, err)
+	assert.Equal(t, "0.0.0.0:8099", addr)
+
+	_, err = ListenAddr("8099")
+	assert.Error(t, err)
+}
+
+func TestLoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lolcathost", "http-token")
+
+	token, err := LoadOrCreateToken(path)
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	again, err := LoadOrCreateToken(path)
+	require.NoError(t, err)
+	assert.Equal(t, token, again, "an existing token is reused")
+}
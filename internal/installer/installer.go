@@ -2,6 +2,7 @@
 package installer
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -24,7 +25,6 @@ const (
 	// Paths
 	LogDir          = "/var/log/lolcathost"
 	BackupDir       = "/var/backups/lolcathost"
-	SocketPath      = "/var/run/lolcathost.sock"
 	LaunchDaemonDir = "/Library/LaunchDaemons"
 	SystemdDir      = "/etc/systemd/system"
 )
@@ -42,6 +42,8 @@ const LaunchDaemonPlist = `<?xml version="1.0" encoding="UTF-8"?>
         <string>--daemon</string>
         <string>--config</string>
         <string>/etc/lolcathost/config.yaml</string>
+        <string>--socket</string>
+        <string>%s</string>
     </array>
     <key>RunAtLoad</key>
     <true/>
@@ -62,7 +64,8 @@ After=network.target
 
 [Service]
 Type=simple
-ExecStart=%s --daemon --config /etc/lolcathost/config.yaml
+ExecStart=%s --daemon --config /etc/lolcathost/config.yaml --socket %s
+ExecReload=/bin/kill -HUP $MAINPID
 Restart=always
 RestartSec=5
 User=root
@@ -75,11 +78,12 @@ WantedBy=multi-user.target
 // Installer handles installation and uninstallation.
 type Installer struct {
 	binaryPath string
+	socketPath string
 	verbose    bool
 }
 
-// New creates a new installer.
-func New() (*Installer, error) {
+// New creates a new installer whose service runs the daemon on socketPath.
+func New(socketPath string) (*Installer, error) {
 	binaryPath, err := os.Executable()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get executable path: %w", err)
@@ -93,6 +97,7 @@ func New() (*Installer, error) {
 
 	return &Installer{
 		binaryPath: binaryPath,
+		socketPath: socketPath,
 		verbose:    true,
 	}, nil
 }
@@ -168,7 +173,7 @@ func (i *Installer) Uninstall() error {
 	}
 
 	// Remove socket
-	_ = os.Remove(SocketPath)
+	_ = os.Remove(i.socketPath)
 
 	// Note: We don't remove the group, logs, or backups
 	// The user may want to keep these
@@ -338,7 +343,7 @@ func (i *Installer) createSystemConfig() error {
 
 func (i *Installer) installLaunchDaemon() error {
 	plistPath := filepath.Join(LaunchDaemonDir, "com.lolcathost.daemon.plist")
-	plistContent := fmt.Sprintf(LaunchDaemonPlist, i.binaryPath)
+	plistContent := fmt.Sprintf(LaunchDaemonPlist, i.binaryPath, i.socketPath)
 
 	// Unload if already loaded (do this before writing plist)
 	i.log("  Stopping existing daemon if running...")
@@ -387,7 +392,7 @@ func (i *Installer) uninstallLaunchDaemon() {
 
 func (i *Installer) installSystemdService() error {
 	unitPath := filepath.Join(SystemdDir, "lolcathost.service")
-	unitContent := fmt.Sprintf(SystemdUnit, i.binaryPath)
+	unitContent := fmt.Sprintf(SystemdUnit, i.binaryPath, i.socketPath)
 
 	i.log("  Writing systemd unit...")
 	// #nosec G306 - Unit file permissions are intentionally 0644
@@ -448,11 +453,24 @@ func (i *Installer) createDefaultConfig() error {
 	return nil
 }
 
-// CheckInstallation checks if the daemon is properly installed.
-func CheckInstallation() error {
+// Installation states reported by CheckInstallation. The returned errors wrap
+// one of these so callers can tell which setup step is outstanding.
+var (
+	ErrNotInstalled  = errors.New("daemon not running (socket not found)")
+	ErrNotInGroup    = errors.New("group membership missing")
+	ErrGroupInactive = errors.New("group membership inactive")
+)
+
+// CheckInstallation checks if the daemon is properly installed and listening
+// on socketPath. Root is never in the group but can always reach the socket,
+// so for root only the socket is checked.
+func CheckInstallation(socketPath string) error {
 	// Check if socket exists
-	if _, err := os.Stat(SocketPath); os.IsNotExist(err) {
-		return fmt.Errorf("daemon not running (socket not found)")
+	if _, err := os.Stat(socketPath); os.IsNotExist(err) {
+		return ErrNotInstalled
+	}
+	if os.Geteuid() == 0 {
+		return nil
 	}
 
 	// Check if user is in group
@@ -479,8 +497,46 @@ func CheckInstallation() error {
 	}
 
 	if !inGroup {
-		return fmt.Errorf("user '%s' is not in group '%s'. Run 'sudo lolcathost --install' and open a new terminal", u.Username, GroupName)
+		return fmt.Errorf("%w: user '%s' is not in group '%s'. Run 'sudo lolcathost --install' and open a new terminal", ErrNotInGroup, u.Username, GroupName)
 	}
 
-	return nil
+	// Membership is recorded, but this process may have been started before
+	// the user was added to the group, in which case the socket is still
+	// inaccessible.
+	g, err := user.LookupGroup(GroupName)
+	if err != nil {
+		return nil
+	}
+	gid, err := strconv.Atoi(g.Gid)
+	if err != nil || processHasGroup(gid) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: user '%s' is in group '%s' but this session predates it. %s", ErrGroupInactive, u.Username, GroupName, groupActivationHint())
+}
+
+// processHasGroup reports whether the current process carries the given GID.
+func processHasGroup(gid int) bool {
+	if os.Getegid() == gid {
+		return true
+	}
+	groups, err := os.Getgroups()
+	if err != nil {
+		return false
+	}
+	for _, g := range groups {
+		if g == gid {
+			return true
+		}
+	}
+	return false
+}
+
+// groupActivationHint returns platform-specific advice for picking up a new
+// group membership without reinstalling.
+func groupActivationHint() string {
+	if runtime.GOOS == "darwin" {
+		return "Open a new terminal window, or log out and back in if that is not enough"
+	}
+	return fmt.Sprintf("Run 'newgrp %s' in this shell, or log out and back in", GroupName)
 }
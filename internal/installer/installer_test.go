@@ -0,0 +1,28 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckInstallation_NotInstalled(t *testing.T) {
+	err := CheckInstallation(filepath.Join(t.TempDir(), "missing.sock"))
+	assert.ErrorIs(t, err, ErrNotInstalled)
+}
+
+func TestCheckInstallation_RootSkipsGroupCheck(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root")
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	require.NoError(t, os.WriteFile(socketPath, nil, 0600))
+
+	// The installer never adds root to the group, so sudo commands rely on
+	// the exemption rather than membership
+	assert.NoError(t, CheckInstallation(socketPath))
+}
@@ -4,33 +4,72 @@ package protocol
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 )
 
 // SocketPath is the Unix socket path for daemon communication.
 const SocketPath = "/var/run/lolcathost.sock"
 
+// Version is the protocol version this build speaks. Bump it when adding a
+// request type or changing a message in a way an older peer can't handle.
+// Peers that predate versioning send no version, which reads as 0: the
+// protocol as it was before versioning, which version 1 only extends.
+const Version = 1
+
+// MinVersion is the oldest protocol version of a peer this build still
+// works with.
+const MinVersion = 0
+
 // RequestType defines the type of request.
 type RequestType string
 
 const (
-	RequestPing          RequestType = "ping"
-	RequestStatus        RequestType = "status"
-	RequestList          RequestType = "list"
-	RequestSet           RequestType = "set"
-	RequestAdd           RequestType = "add"
-	RequestDelete        RequestType = "delete"
-	RequestSync          RequestType = "sync"
-	RequestPreset        RequestType = "preset"
-	RequestRollback      RequestType = "rollback"
-	RequestBackups       RequestType = "backups"
-	RequestAddGroup      RequestType = "add_group"
-	RequestDeleteGroup   RequestType = "delete_group"
-	RequestRenameGroup   RequestType = "rename_group"
-	RequestListGroups    RequestType = "list_groups"
-	RequestAddPreset     RequestType = "add_preset"
-	RequestDeletePreset  RequestType = "delete_preset"
-	RequestListPresets   RequestType = "list_presets"
-	RequestBackupContent RequestType = "backup_content"
+	RequestPing           RequestType = "ping"
+	RequestStatus         RequestType = "status"
+	RequestList           RequestType = "list"
+	RequestGet            RequestType = "get"
+	RequestSet            RequestType = "set"
+	RequestAdd            RequestType = "add"
+	RequestDelete         RequestType = "delete"
+	RequestSync           RequestType = "sync"
+	RequestPreset         RequestType = "preset"
+	RequestRollback       RequestType = "rollback"
+	RequestBackups        RequestType = "backups"
+	RequestAddGroup       RequestType = "add_group"
+	RequestDeleteGroup    RequestType = "delete_group"
+	RequestRenameGroup    RequestType = "rename_group"
+	RequestListGroups     RequestType = "list_groups"
+	RequestAddPreset      RequestType = "add_preset"
+	RequestDeletePreset   RequestType = "delete_preset"
+	RequestListPresets    RequestType = "list_presets"
+	RequestBackupContent  RequestType = "backup_content"
+	RequestMoveHost       RequestType = "move_host"
+	RequestVerify         RequestType = "verify"
+	RequestImportConfig   RequestType = "import_config"
+	RequestReload         RequestType = "reload"
+	RequestPrometheus     RequestType = "prometheus"
+	RequestMetrics        RequestType = "metrics"
+	RequestAddBatch       RequestType = "add_batch"
+	RequestReorderGroups  RequestType = "reorder_groups"
+	RequestReorderPresets RequestType = "reorder_presets"
+	RequestPreviewHosts   RequestType = "preview_hosts"
+	RequestSetGroupIP     RequestType = "set_group_ip"
+	RequestReset          RequestType = "reset"
+	RequestListProfiles   RequestType = "list_profiles"
+	RequestSwitchProfile  RequestType = "switch_profile"
+	RequestSetSticky      RequestType = "set_sticky"
+	RequestExplain        RequestType = "explain"
+	RequestSetGroup       RequestType = "set_group"
+	RequestBatch          RequestType = "batch"
+	RequestFlush          RequestType = "flush"
+	RequestImport         RequestType = "import"
+	RequestExport         RequestType = "export"
+	RequestUpdate         RequestType = "update"
+	RequestCreateBackup   RequestType = "create_backup"
+	RequestDeleteBackup   RequestType = "delete_backup"
+	RequestBackupDiff     RequestType = "backup_diff"
 )
 
 // ErrorCode defines standard error codes.
@@ -53,6 +92,25 @@ const (
 type Request struct {
 	Type    RequestType     `json:"type"`
 	Payload json.RawMessage `json:"payload,omitempty"`
+	Version int             `json:"version,omitempty"`
+}
+
+// List state filters.
+const (
+	ListStateAll      = "all"
+	ListStateEnabled  = "enabled"
+	ListStateDisabled = "disabled"
+)
+
+// ListPayload is the optional payload for list requests.
+type ListPayload struct {
+	State string `json:"state,omitempty"`
+}
+
+// GetPayload is the payload for get requests. The response data is a
+// HostEntry.
+type GetPayload struct {
+	Alias string `json:"alias"`
 }
 
 // SetPayload is the payload for set requests.
@@ -60,11 +118,30 @@ type SetPayload struct {
 	Alias   string `json:"alias"`
 	Enabled bool   `json:"enabled"`
 	Force   bool   `json:"force,omitempty"`
+
+	// ExpiresAt, when enabling, has the daemon disable the host again at
+	// this Unix time.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
 }
 
 // PresetPayload is the payload for preset requests.
 type PresetPayload struct {
-	Name string `json:"name"`
+	Name   string `json:"name"`
+	DryRun bool   `json:"dry_run,omitempty"`
+	// Strict fails the request instead of skipping aliases that no longer
+	// exist.
+	Strict bool `json:"strict,omitempty"`
+}
+
+// PresetData is the data for preset responses. It lists the aliases whose
+// state the preset changes, or would change when it was a dry run, and the
+// aliases it references that no longer exist and are skipped.
+type PresetData struct {
+	Preset  string   `json:"preset"`
+	Applied bool     `json:"applied"`
+	Enable  []string `json:"enable,omitempty"`
+	Disable []string `json:"disable,omitempty"`
+	Skipped []string `json:"skipped,omitempty"`
 }
 
 // RollbackPayload is the payload for rollback requests.
@@ -72,6 +149,22 @@ type RollbackPayload struct {
 	BackupName string `json:"backup_name"`
 }
 
+// CreateBackupPayload is the payload for create_backup requests. Label is
+// optional and ends up in the backup's file name.
+type CreateBackupPayload struct {
+	Label string `json:"label,omitempty"`
+}
+
+// DeleteBackupPayload is the payload for delete_backup requests.
+type DeleteBackupPayload struct {
+	BackupName string `json:"backup_name"`
+}
+
+// BackupDiffPayload is the payload for backup_diff requests.
+type BackupDiffPayload struct {
+	BackupName string `json:"backup_name"`
+}
+
 // BackupContentPayload is the payload for backup_content requests.
 type BackupContentPayload struct {
 	BackupName string `json:"backup_name"`
@@ -84,11 +177,107 @@ type AddPayload struct {
 	Alias   string `json:"alias"`
 	Group   string `json:"group"`
 	Enabled bool   `json:"enabled"`
+
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Description string            `json:"description,omitempty"`
+
+	// Force adds the host even if its domain is blocked. Only root may set
+	// it, and only add requests honor it.
+	Force bool `json:"force,omitempty"`
+}
+
+// AddBatchPayload is the payload for add_batch requests.
+type AddBatchPayload struct {
+	Hosts []AddPayload `json:"hosts"`
+}
+
+// BatchFailure describes an entry an add_batch request couldn't add.
+type BatchFailure struct {
+	Domain string `json:"domain"`
+	Error  string `json:"error"`
+}
+
+// AddBatchData is the data for add_batch responses. Domains already mapped
+// to the same IP are skipped rather than failed.
+type AddBatchData struct {
+	Added   []string       `json:"added,omitempty"`
+	Skipped []string       `json:"skipped,omitempty"`
+	Failed  []BatchFailure `json:"failed,omitempty"`
+}
+
+// BatchPayload is the payload for batch requests. The daemon runs the
+// requests in order, then saves the config and syncs the hosts file once.
+type BatchPayload struct {
+	Requests []*Request `json:"requests"`
+}
+
+// BatchData is the data for batch responses, one result per request in the
+// same order. A failed request doesn't stop the ones after it.
+type BatchData struct {
+	Results []*Response `json:"results"`
+}
+
+// ImportPayload is the payload for import requests, which copy the hosts
+// file entries lolcathost doesn't manage into the config.
+type ImportPayload struct {
+	// Group receives the imported hosts; empty means DefaultImportGroup.
+	Group string `json:"group,omitempty"`
+}
+
+// DefaultImportGroup is the group import uses when none is given.
+const DefaultImportGroup = "imported"
+
+// ImportData is the data for import responses. Imported hosts are added
+// disabled. Domains the config already maps are skipped; invalid and
+// blocked ones are reported as failed.
+type ImportData struct {
+	Group   string         `json:"group"`
+	Added   []string       `json:"added,omitempty"`
+	Skipped []string       `json:"skipped,omitempty"`
+	Failed  []BatchFailure `json:"failed,omitempty"`
 }
 
 // DeletePayload is the payload for delete requests.
 type DeletePayload struct {
+	Alias    string `json:"alias"`
+	IfExists bool   `json:"if_exists,omitempty"`
+}
+
+// UpdatePayload is the payload for update requests. Alias names the host;
+// only the fields that are set change, and everything else about the host,
+// including whether it is enabled, is kept. A null metadata leaves it as is
+// while an empty object clears it.
+type UpdatePayload struct {
+	Alias       string            `json:"alias"`
+	Domain      *string           `json:"domain,omitempty"`
+	IP          *string           `json:"ip,omitempty"`
+	NewAlias    *string           `json:"new_alias,omitempty"`
+	Group       *string           `json:"group,omitempty"`
+	Metadata    map[string]string `json:"metadata"`
+	Description *string           `json:"description,omitempty"`
+}
+
+// IsEmpty reports whether the update changes nothing.
+func (p UpdatePayload) IsEmpty() bool {
+	return p.Domain == nil && p.IP == nil && p.NewAlias == nil && p.Group == nil &&
+		p.Metadata == nil && p.Description == nil
+}
+
+// SetStickyPayload is the payload for set_sticky requests.
+type SetStickyPayload struct {
+	Alias  string `json:"alias"`
+	Sticky bool   `json:"sticky"`
+}
+
+// ExplainPayload is the payload for explain requests.
+type ExplainPayload struct {
+	Alias string `json:"alias"`
+}
+
+// MoveHostPayload is the payload for move_host requests.
+type MoveHostPayload struct {
 	Alias string `json:"alias"`
+	Group string `json:"group"`
 }
 
 // GroupPayload is the payload for group add/delete requests.
@@ -102,6 +291,40 @@ type RenameGroupPayload struct {
 	NewName string `json:"new_name"`
 }
 
+// SetGroupIPPayload is the payload for set_group_ip requests.
+type SetGroupIPPayload struct {
+	Group string `json:"group"`
+	IP    string `json:"ip"`
+}
+
+// SetGroupIPData is the data for set_group_ip responses.
+type SetGroupIPData struct {
+	Group   string `json:"group"`
+	IP      string `json:"ip"`
+	Updated int    `json:"updated"`
+}
+
+// SetGroupPayload is the payload for set_group requests, which enable or
+// disable every host in a group.
+type SetGroupPayload struct {
+	Group   string `json:"group"`
+	Enabled bool   `json:"enabled"`
+	Force   bool   `json:"force,omitempty"`
+}
+
+// SetGroupData is the data for set_group responses.
+type SetGroupData struct {
+	Group   string `json:"group"`
+	Enabled bool   `json:"enabled"`
+	Updated int    `json:"updated"`
+}
+
+// ReorderPayload is the payload for reorder_groups and reorder_presets
+// requests. Names must list every existing group or preset exactly once.
+type ReorderPayload struct {
+	Names []string `json:"names"`
+}
+
 // GroupsData is the data for list_groups responses.
 type GroupsData struct {
 	Groups []string `json:"groups"`
@@ -132,6 +355,17 @@ type Response struct {
 	Data    json.RawMessage `json:"data,omitempty"`
 	Message string          `json:"message,omitempty"`
 	Code    ErrorCode       `json:"code,omitempty"`
+	Version int             `json:"version,omitempty"`
+}
+
+// PingData is the data for ping responses. Daemons that predate protocol
+// versioning leave both versions 0.
+type PingData struct {
+	Pong string `json:"pong"`
+	// Version is the daemon's protocol version.
+	Version int `json:"version,omitempty"`
+	// MinVersion is the oldest client protocol version the daemon serves.
+	MinVersion int `json:"min_version,omitempty"`
 }
 
 // StatusData is the data for status responses.
@@ -141,6 +375,34 @@ type StatusData struct {
 	Uptime       int64  `json:"uptime_seconds"`
 	ActiveCount  int    `json:"active_count"`
 	RequestCount int64  `json:"request_count"`
+	ConfigPath   string `json:"config_path,omitempty"`
+	Profile      string `json:"profile,omitempty"`
+
+	// BlockedDomains lists the user-defined blocked domains, which clients
+	// merge with the built-in list.
+	BlockedDomains []string `json:"blocked_domains,omitempty"`
+
+	Reconcile *ReconcileData `json:"reconcile,omitempty"`
+	Flush     *FlushInfo     `json:"flush,omitempty"`
+}
+
+// FlushInfo describes how the daemon flushes the DNS cache.
+type FlushInfo struct {
+	Platform string `json:"platform"`
+	// Method is the configured flush method; AutoMethod is what auto
+	// resolves to on this system.
+	Method     string   `json:"method"`
+	AutoMethod string   `json:"auto_method"`
+	Tools      []string `json:"tools"`
+}
+
+// ReconcileData describes the startup check of the hosts file against config.
+type ReconcileData struct {
+	Timestamp      int64  `json:"timestamp"`
+	MarkersMissing bool   `json:"markers_missing"`
+	Drift          int    `json:"drift"`
+	Synced         bool   `json:"synced"`
+	Error          string `json:"error,omitempty"`
 }
 
 // HostEntry represents a single host entry.
@@ -150,9 +412,73 @@ type HostEntry struct {
 	Alias   string `json:"alias"`
 	Enabled bool   `json:"enabled"`
 	Group   string `json:"group"`
+
+	// IPs lists every address when the entry maps to more than one; IP
+	// then holds the first of them.
+	IPs []string `json:"ips,omitempty"`
+
+	CreatedAt  int64  `json:"created_at,omitempty"`
+	GroupColor string `json:"group_color,omitempty"`
+
+	// ExpiresAt is when the daemon disables the entry again, if ever.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Sticky hosts are never disabled by presets.
+	Sticky bool `json:"sticky,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	// Synced reports whether the hosts file agrees with the entry: present
+	// with the same domain and IP when enabled, absent when disabled.
+	Synced bool `json:"synced"`
+}
+
+// Addresses returns every IP the entry maps to.
+func (e HostEntry) Addresses() []string {
+	if len(e.IPs) > 0 {
+		return e.IPs
+	}
+	return []string{e.IP}
+}
+
+// FormatIPs returns the entry's addresses as a comma-separated list, the
+// form add and edit accept.
+func (e HostEntry) FormatIPs() string {
+	return strings.Join(e.Addresses(), ", ")
 }
 
-// ListData is the data for list responses.
+// Matches reports whether term occurs, ignoring case, in the entry's domain,
+// alias, description, addresses, group or any metadata key or value. An empty
+// term matches every entry.
+func (e HostEntry) Matches(term string) bool {
+	term = strings.ToLower(term)
+	for _, field := range []string{e.Domain, e.Alias, e.Description, e.FormatIPs(), e.Group} {
+		if strings.Contains(strings.ToLower(field), term) {
+			return true
+		}
+	}
+	for key, value := range e.Metadata {
+		if strings.Contains(strings.ToLower(key), term) || strings.Contains(strings.ToLower(value), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatMetadata renders host metadata as comma-separated key=value pairs
+// sorted by key.
+func FormatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		pairs = append(pairs, key+"="+metadata[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// ListData is the data for list responses. Entries are in config order:
+// groups as listed, then hosts as listed within each group.
 type ListData struct {
 	Entries []HostEntry `json:"entries"`
 }
@@ -160,6 +486,7 @@ type ListData struct {
 // SetData is the data for set responses.
 type SetData struct {
 	Domain  string `json:"domain"`
+	Alias   string `json:"alias,omitempty"`
 	Applied bool   `json:"applied"`
 }
 
@@ -168,21 +495,184 @@ type BackupsData struct {
 	Backups []BackupInfo `json:"backups"`
 }
 
-// BackupInfo represents a backup file.
+// BackupInfo represents a backup file. It is also the data for create_backup
+// responses.
 type BackupInfo struct {
 	Name      string `json:"name"`
+	Label     string `json:"label,omitempty"`
 	Timestamp int64  `json:"timestamp"`
 	Size      int64  `json:"size"`
 }
 
+// BackupDiffData is the data for backup_diff responses. Lines lead from the
+// backup to the current hosts file.
+type BackupDiffData struct {
+	Lines []DiffLine `json:"lines"`
+}
+
+// DiffLine is one line of a line-level diff. Op is DiffAdded for a line only
+// in the current file, DiffRemoved for one only in the backup and DiffContext
+// for one in both.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff line operations.
+const (
+	DiffAdded   = "+"
+	DiffRemoved = "-"
+	DiffContext = " "
+)
+
 // BackupContentData is the data for backup_content responses.
 type BackupContentData struct {
 	Content string `json:"content"`
 }
 
+// PrometheusData is the data for prometheus responses.
+type PrometheusData struct {
+	Text string `json:"text"`
+}
+
+// MetricsData is the data for metrics responses. Counters start at zero
+// when the daemon starts.
+type MetricsData struct {
+	HostsTotal     int                   `json:"hosts_total"`
+	HostsActive    int                   `json:"hosts_active"`
+	Backups        int                   `json:"backups"`
+	Uptime         int64                 `json:"uptime"`
+	Requests       int64                 `json:"requests"`
+	RequestsByType map[RequestType]int64 `json:"requests_by_type"`
+	ErrorsByCode   map[ErrorCode]int64   `json:"errors_by_code"`
+	RateLimited    int64                 `json:"rate_limited"`
+	ConfigReloads  int64                 `json:"config_reloads"`
+}
+
+// ImportConfigPayload is the payload for import_config requests.
+type ImportConfigPayload struct {
+	Content string `json:"content"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+	// Strict rejects configs whose presets reference aliases no host has.
+	Strict bool `json:"strict,omitempty"`
+	// Merge adds the file's groups, hosts and presets to the current config
+	// instead of replacing them. Entries that exist with different contents
+	// are reported as conflicts and nothing is applied.
+	Merge bool `json:"merge,omitempty"`
+}
+
+// ExportPayload is the payload for export requests.
+type ExportPayload struct {
+	// Format is "yaml" (the default) or "json".
+	Format string `json:"format,omitempty"`
+}
+
+// ExportData is the data for export responses: the whole config, settings
+// included, in a form import_config accepts.
+type ExportData struct {
+	Format  string `json:"format"`
+	Content string `json:"content"`
+}
+
+// SwitchProfilePayload is the payload for switch_profile requests. With
+// Create set, a missing profile is created from the default config.
+type SwitchProfilePayload struct {
+	Name   string `json:"name"`
+	Create bool   `json:"create,omitempty"`
+}
+
+// ProfilesData is the data for list_profiles and switch_profile responses.
+// Profiles starts with "default", the main config file.
+type ProfilesData struct {
+	Active   string   `json:"active"`
+	Profiles []string `json:"profiles"`
+}
+
+// ResetPayload is the payload for reset requests. Confirm must be set, so a
+// stray request can't wipe the config.
+type ResetPayload struct {
+	Confirm bool `json:"confirm"`
+}
+
+// ResetData is the data for reset responses. It counts what the reset
+// removed; the hosts file is backed up with the "before-reset" label first.
+type ResetData struct {
+	HostsRemoved   int `json:"hosts_removed"`
+	GroupsRemoved  int `json:"groups_removed"`
+	PresetsRemoved int `json:"presets_removed"`
+}
+
+// ImportConfigData is the data for import_config responses. It lists what the
+// import changes, or would change when it was a dry run.
+type ImportConfigData struct {
+	Applied        bool     `json:"applied"`
+	HostsAdded     []string `json:"hosts_added,omitempty"`
+	HostsRemoved   []string `json:"hosts_removed,omitempty"`
+	HostsChanged   []string `json:"hosts_changed,omitempty"`
+	GroupsAdded    []string `json:"groups_added,omitempty"`
+	GroupsRemoved  []string `json:"groups_removed,omitempty"`
+	PresetsAdded   []string `json:"presets_added,omitempty"`
+	PresetsRemoved []string `json:"presets_removed,omitempty"`
+	PresetsChanged []string `json:"presets_changed,omitempty"`
+	// Conflicts lists why a merge was refused.
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// Severity levels for verify issues.
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+)
+
+// VerifyIssue describes a single problem found by a verify request.
+type VerifyIssue struct {
+	Severity   string `json:"severity"`
+	Kind       string `json:"kind"`
+	Domain     string `json:"domain,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	FileDomain string `json:"file_domain,omitempty"` // mismatch only: domain found in the hosts file
+	FileIP     string `json:"file_ip,omitempty"`     // mismatch only: IP found in the hosts file
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// PreviewHostsData is the data for preview_hosts responses.
+type PreviewHostsData struct {
+	Section string `json:"section"`
+}
+
+// VerifyData is the data for verify responses.
+type VerifyData struct {
+	Issues []VerifyIssue `json:"issues"`
+}
+
+// SyncRecord describes the daemon's most recent hosts file sync.
+type SyncRecord struct {
+	Timestamp  int64  `json:"timestamp"`
+	Error      string `json:"error,omitempty"`
+	FlushError string `json:"flush_error,omitempty"`
+}
+
+// ExplainData is the data for explain responses. Issues is empty when
+// nothing stands in the way of the entry resolving.
+type ExplainData struct {
+	Entry HostEntry `json:"entry"`
+	// InHostsFile reports whether the managed section has a line for the
+	// alias, whatever its domain and IP.
+	InHostsFile bool `json:"in_hosts_file"`
+	// Conflicts lists other enabled aliases mapping the domain to another IP.
+	Conflicts []string `json:"conflicts,omitempty"`
+	// External lists hosts file lines outside the managed section that
+	// mention the domain.
+	External []string      `json:"external,omitempty"`
+	LastSync *SyncRecord   `json:"last_sync,omitempty"`
+	Issues   []VerifyIssue `json:"issues"`
+}
+
 // NewRequest creates a new request with the given type and payload.
 func NewRequest(reqType RequestType, payload interface{}) (*Request, error) {
-	req := &Request{Type: reqType}
+	req := &Request{Type: reqType, Version: Version}
 	if payload != nil {
 		data, err := json.Marshal(payload)
 		if err != nil {
@@ -44,6 +44,7 @@ func TestNewRequest(t *testing.T) {
 			}
 			require.NoError(t, err)
 			assert.Equal(t, tt.reqType, req.Type)
+			assert.Equal(t, Version, req.Version)
 			if tt.payload != nil {
 				assert.NotNil(t, req.Payload)
 			}
@@ -51,6 +52,18 @@ func TestNewRequest(t *testing.T) {
 	}
 }
 
+func TestRequest_VersionCompat(t *testing.T) {
+	// A request from a client that predates versioning reads as version 0
+	var req Request
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"ping"}`), &req))
+	assert.Zero(t, req.Version)
+
+	// and a version 0 response carries no version field for older clients
+	data, err := json.Marshal(NewErrorResponse(ErrCodeNotFound, "missing"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "version")
+}
+
 func TestRequest_ParsePayload(t *testing.T) {
 	t.Run("valid payload", func(t *testing.T) {
 		payload := SetPayload{Alias: "test-alias", Enabled: true, Force: false}
@@ -225,3 +238,53 @@ func TestBackupInfo(t *testing.T) {
 	assert.Equal(t, info.Timestamp, parsed.Timestamp)
 	assert.Equal(t, info.Size, parsed.Size)
 }
+
+func TestHostEntry_Matches(t *testing.T) {
+	entry := HostEntry{
+		Domain:      "api.example.local",
+		IP:          "10.0.0.1",
+		IPs:         []string{"10.0.0.1", "fd00::1"},
+		Alias:       "api-local",
+		Group:       "Staging",
+		Description: "Payments API",
+		Metadata:    map[string]string{"owner": "alice"},
+	}
+
+	tests := []struct {
+		term string
+		want bool
+	}{
+		{"", true},
+		{"EXAMPLE", true},
+		{"api-local", true},
+		{"fd00", true},
+		{"staging", true},
+		{"payments", true},
+		{"owner", true},
+		{"alice", true},
+		{"prod", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, entry.Matches(tt.term), "term %q", tt.term)
+	}
+}
+
+func TestFormatMetadata(t *testing.T) {
+	assert.Equal(t, "", FormatMetadata(nil))
+	assert.Equal(t, "owner=alice, ticket=OPS-1", FormatMetadata(map[string]string{"ticket": "OPS-1", "owner": "alice"}))
+}
+
+func TestUpdatePayload_IsEmpty(t *testing.T) {
+	assert.True(t, UpdatePayload{Alias: "api"}.IsEmpty())
+
+	ip := "10.0.0.1"
+	assert.False(t, UpdatePayload{Alias: "api", IP: &ip}.IsEmpty())
+	assert.False(t, UpdatePayload{Alias: "api", Metadata: map[string]string{}}.IsEmpty())
+
+	// A null metadata leaves it unchanged, an empty object clears it
+	var decoded UpdatePayload
+	require.NoError(t, json.Unmarshal([]byte(`{"alias":"api","metadata":{}}`), &decoded))
+	assert.NotNil(t, decoded.Metadata)
+	require.NoError(t, json.Unmarshal([]byte(`{"alias":"api","metadata":null}`), &decoded))
+	assert.Nil(t, decoded.Metadata)
+}
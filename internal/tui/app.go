@@ -4,12 +4,16 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/sys/unix"
 
 	"github.com/lukaszraczylo/lolcathost/internal/client"
 	"github.com/lukaszraczylo/lolcathost/internal/config"
@@ -29,6 +33,9 @@ const (
 	ViewHelp
 	ViewSearch
 	ViewConfirmDelete
+	ViewReleaseNotes
+	ViewConfirmQuit
+	ViewConfirmEditConfig
 )
 
 // Model is the main Bubble Tea model.
@@ -36,6 +43,7 @@ type Model struct {
 	// Client
 	client    *client.Client
 	connected bool
+	lastPing  time.Time // when the last keepalive ping was sent
 
 	// Views
 	mode         ViewMode
@@ -45,21 +53,27 @@ type Model struct {
 	groupPicker  *GroupPicker
 	backupPicker *BackupPicker
 	searchInput  textinput.Model
+	spinner      spinner.Model
 
 	// State
 	width              int
 	height             int
 	message            string
-	messageStyle       string // "error" or "success"
+	messageStyle       string // "error", "warning" or "success"
 	messageTime        time.Time
 	searchTerm         string
+	statusFilter       StatusFilter
 	allGroups          []string // All groups including empty ones
 	pendingDeleteAlias string   // Alias of host pending delete confirmation
+	pendingConfigPath  string   // Read-only config pending edit confirmation
+	syncing            int      // Number of in-flight requests that rewrite the hosts file
 
 	// Update notification
 	updateAvailable bool
 	updateVersion   string
 	updateURL       string
+	updateNotes     string
+	notesScroll     int
 
 	// Version info for update checking
 	version     string
@@ -75,24 +89,47 @@ type (
 		err     error
 	}
 	toggleMsg struct {
-		alias string
-		err   error
+		alias   string
+		warning string
+		err     error
+	}
+	bulkToggleMsg struct {
+		aliases []string
+		failed  map[string]string // alias -> error message
+		warning string
+		err     error
+	}
+	stickyMsg struct {
+		alias  string
+		sticky bool
+		err    error
 	}
 	presetMsg struct {
-		name string
-		err  error
+		name    string
+		skipped []string
+		err     error
+	}
+	presetPreviewMsg struct {
+		preview *protocol.PresetData
+		err     error
 	}
 	addMsg struct {
 		domain string
+		alias  string
 		err    error
 	}
 	deleteMsg struct {
 		alias string
 		err   error
 	}
+	updateHostMsg struct {
+		alias string
+		err   error
+	}
 	addPresetMsg struct {
-		name string
-		err  error
+		name    string
+		warning string
+		err     error
 	}
 	deletePresetMsg struct {
 		name string
@@ -118,23 +155,55 @@ type (
 		groups []string
 		err    error
 	}
+	refreshAllMsg struct {
+		entries []protocol.HostEntry
+		presets []protocol.PresetInfo
+		groups  []string
+		err     error
+	}
 	rollbackMsg struct {
 		name string
 		err  error
 	}
+	deleteBackupMsg struct {
+		name string
+		err  error
+	}
 	refreshBackupsMsg struct {
 		backups []protocol.BackupInfo
 		err     error
 	}
-	backupContentMsg struct {
-		content string
+	backupDiffMsg struct {
+		name  string
+		lines []protocol.DiffLine
+		err   error
+	}
+	blockedDomainsMsg struct {
+		domains []string
 		err     error
 	}
+	openConfigMsg struct {
+		path     string
+		writable bool
+		err      error
+	}
+	editorDoneMsg struct {
+		path     string
+		writable bool
+		err      error
+	}
+	reloadMsg struct {
+		err error
+	}
 	clearMsgMsg struct{}
 	tickMsg     struct{}
-	updateMsg   struct {
+	pingMsg     struct {
+		err error
+	}
+	updateMsg struct {
 		version string
 		url     string
+		notes   string
 	}
 )
 
@@ -145,6 +214,10 @@ func NewModel(socketPath string) *Model {
 	searchInput.CharLimit = 100
 	searchInput.Width = 50
 
+	syncSpinner := spinner.New()
+	syncSpinner.Spinner = spinner.Dot
+	syncSpinner.Style = spinnerStyle
+
 	return &Model{
 		client:       client.New(socketPath),
 		list:         NewListView(),
@@ -153,6 +226,7 @@ func NewModel(socketPath string) *Model {
 		groupPicker:  NewGroupPicker(),
 		backupPicker: NewBackupPicker(),
 		searchInput:  searchInput,
+		spinner:      syncSpinner,
 		mode:         ViewList,
 	}
 }
@@ -172,6 +246,9 @@ func (m *Model) connect() tea.Cmd {
 		if err := m.client.Connect(); err != nil {
 			return connectMsg{err: err}
 		}
+		if err := m.client.CheckVersion(); err != nil {
+			return connectMsg{err: err}
+		}
 		return connectMsg{err: nil}
 	}
 }
@@ -186,24 +263,101 @@ func (m *Model) refresh() tea.Cmd {
 	}
 }
 
+// refreshAll fetches entries, presets and groups in one go so every view is
+// brought up to date together, e.g. after the config was edited on disk.
+func (m *Model) refreshAll() tea.Cmd {
+	return func() tea.Msg {
+		entries, err := m.client.List()
+		if err != nil {
+			return refreshAllMsg{err: err}
+		}
+		presets, err := m.client.ListPresets()
+		if err != nil {
+			return refreshAllMsg{err: err}
+		}
+		groups, err := m.client.ListGroups()
+		if err != nil {
+			return refreshAllMsg{err: err}
+		}
+		return refreshAllMsg{entries: entries, presets: presets, groups: groups}
+	}
+}
+
 func (m *Model) toggle(alias string, enabled bool) tea.Cmd {
 	return func() tea.Msg {
 		_, err := m.client.Set(alias, enabled, false)
-		return toggleMsg{alias: alias, err: err}
+		return toggleMsg{alias: alias, warning: m.client.Warning(), err: err}
+	}
+}
+
+// toggleBulk switches several entries in one batch request, so the daemon
+// syncs the hosts file once.
+func (m *Model) toggleBulk(aliases []string, enabled bool) tea.Cmd {
+	return func() tea.Msg {
+		reqs := make([]*protocol.Request, len(aliases))
+		for i, alias := range aliases {
+			reqs[i], _ = protocol.NewRequest(protocol.RequestSet, protocol.SetPayload{Alias: alias, Enabled: enabled})
+		}
+
+		results, err := m.client.Batch(reqs)
+		msg := bulkToggleMsg{aliases: aliases, failed: make(map[string]string), warning: m.client.Warning(), err: err}
+		for i, resp := range results {
+			if !resp.IsOK() {
+				msg.failed[aliases[i]] = resp.Message
+			}
+		}
+		return msg
+	}
+}
+
+func (m *Model) setSticky(alias string, sticky bool) tea.Cmd {
+	return func() tea.Msg {
+		err := m.client.SetSticky(alias, sticky)
+		return stickyMsg{alias: alias, sticky: sticky, err: err}
 	}
 }
 
 func (m *Model) applyPreset(name string) tea.Cmd {
 	return func() tea.Msg {
-		err := m.client.ApplyPreset(name)
-		return presetMsg{name: name, err: err}
+		data, err := m.client.ApplyPresetReport(name, false)
+		if err != nil {
+			return presetMsg{name: name, err: err}
+		}
+		return presetMsg{name: name, skipped: data.Skipped}
+	}
+}
+
+func (m *Model) previewPreset(name string) tea.Cmd {
+	return func() tea.Msg {
+		preview, err := m.client.PreviewPreset(name)
+		return presetPreviewMsg{preview: preview, err: err}
 	}
 }
 
-func (m *Model) addHost(domain, ip, alias, group string) tea.Cmd {
+func (m *Model) addHost(domain, ip, alias, group string, metadata map[string]string, description string) tea.Cmd {
 	return func() tea.Msg {
-		_, err := m.client.Add(domain, ip, alias, group, false)
-		return addMsg{domain: domain, err: err}
+		data, err := m.client.AddEntry(protocol.AddPayload{
+			Domain:      domain,
+			IP:          ip,
+			Alias:       alias,
+			Group:       group,
+			Metadata:    metadata,
+			Description: description,
+		})
+		if err != nil {
+			return addMsg{domain: domain, err: err}
+		}
+		return addMsg{domain: domain, alias: data.Alias}
+	}
+}
+
+func (m *Model) updateHost(update protocol.UpdatePayload) tea.Cmd {
+	return func() tea.Msg {
+		data, err := m.client.Update(update)
+		if err != nil {
+			return updateHostMsg{alias: update.Alias, err: err}
+		}
+		return updateHostMsg{alias: data.Alias}
 	}
 }
 
@@ -216,8 +370,8 @@ func (m *Model) deleteHost(alias string) tea.Cmd {
 
 func (m *Model) addPreset(name string, enable, disable []string) tea.Cmd {
 	return func() tea.Msg {
-		err := m.client.AddPreset(name, enable, disable)
-		return addPresetMsg{name: name, err: err}
+		warning, err := m.client.AddPreset(name, enable, disable)
+		return addPresetMsg{name: name, warning: warning, err: err}
 	}
 }
 
@@ -270,6 +424,13 @@ func (m *Model) rollback(backupName string) tea.Cmd {
 	}
 }
 
+func (m *Model) deleteBackup(backupName string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.client.DeleteBackup(backupName)
+		return deleteBackupMsg{name: backupName, err: err}
+	}
+}
+
 func (m *Model) refreshBackups() tea.Cmd {
 	return func() tea.Msg {
 		backups, err := m.client.ListBackups()
@@ -277,10 +438,62 @@ func (m *Model) refreshBackups() tea.Cmd {
 	}
 }
 
-func (m *Model) fetchBackupContent(backupName string) tea.Cmd {
+func (m *Model) fetchBackupDiff(backupName string) tea.Cmd {
 	return func() tea.Msg {
-		content, err := m.client.GetBackupContent(backupName)
-		return backupContentMsg{content: content, err: err}
+		lines, err := m.client.GetBackupDiff(backupName)
+		return backupDiffMsg{name: backupName, lines: lines, err: err}
+	}
+}
+
+// fetchBlockedDomains loads the user-defined blocked domains from the daemon,
+// so the form and help view use the same blocklist it does.
+func (m *Model) fetchBlockedDomains() tea.Cmd {
+	return func() tea.Msg {
+		status, err := m.client.Status()
+		if err != nil {
+			return blockedDomainsMsg{err: err}
+		}
+		return blockedDomainsMsg{domains: status.BlockedDomains}
+	}
+}
+
+// openConfig looks up the daemon's config path and whether the current user
+// can write to it, so edits made in $EDITOR actually stick.
+func (m *Model) openConfig() tea.Cmd {
+	return func() tea.Msg {
+		status, err := m.client.Status()
+		if err != nil {
+			return openConfigMsg{err: err}
+		}
+		if status.ConfigPath == "" {
+			return openConfigMsg{err: fmt.Errorf("daemon did not report its config path")}
+		}
+		writable := unix.Access(status.ConfigPath, unix.W_OK) == nil
+		return openConfigMsg{path: status.ConfigPath, writable: writable}
+	}
+}
+
+// editConfig suspends the TUI and opens the config file in the user's editor.
+func (m *Model) editConfig(path string, writable bool) tea.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// Split so editors configured with arguments (e.g. "code --wait") work
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...) // #nosec G204 - Editor is chosen by the invoking user
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorDoneMsg{path: path, writable: writable, err: err}
+	})
+}
+
+func (m *Model) reload() tea.Cmd {
+	return func() tea.Msg {
+		return reloadMsg{err: m.client.Reload()}
 	}
 }
 
@@ -290,6 +503,16 @@ func (m *Model) tick() tea.Cmd {
 	})
 }
 
+// keepaliveInterval is how often the TUI pings the daemon so an idle session
+// stays within the daemon's connection idle timeout.
+const keepaliveInterval = 30 * time.Second
+
+func (m *Model) ping() tea.Cmd {
+	return func() tea.Msg {
+		return pingMsg{err: m.client.Ping()}
+	}
+}
+
 func (m *Model) clearMsg() tea.Cmd {
 	return tea.Tick(time.Second*3, func(t time.Time) tea.Msg {
 		return clearMsgMsg{}
@@ -306,7 +529,7 @@ func (m *Model) checkForUpdate() tea.Cmd {
 		defer cancel()
 
 		if update := checker.CheckForUpdate(ctx); update != nil {
-			return updateMsg{version: update.LatestVersion, url: update.ReleaseURL}
+			return updateMsg{version: update.LatestVersion, url: update.ReleaseURL, notes: update.ReleaseNotes}
 		}
 		return nil
 	}
@@ -347,6 +570,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, m.refresh())
 			cmds = append(cmds, m.refreshPresets())
 			cmds = append(cmds, m.refreshGroups())
+			cmds = append(cmds, m.fetchBlockedDomains())
+		}
+
+	case blockedDomainsMsg:
+		// Keep the last known list if the daemon can't be asked
+		if msg.err == nil {
+			config.SetCustomBlockedDomains(msg.domains)
 		}
 
 	case refreshMsg:
@@ -358,16 +588,62 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			// Always update the list, even if entries is nil/empty
 			m.list.SetItems(msg.entries)
+			m.groupPicker.SetCounts(m.list.GroupCounts())
 		}
 
 	case toggleMsg:
+		m.finishSync()
 		if msg.err != nil {
 			m.list.SetError(msg.alias, true)
 			m.setError(fmt.Sprintf("Toggle failed: %v", msg.err))
 		} else {
 			m.list.SetPending(msg.alias, false)
 			cmds = append(cmds, m.refresh())
-			m.setSuccess("Entry toggled")
+			if msg.warning != "" {
+				m.setWarning("Entry toggled; " + msg.warning)
+			} else {
+				m.setSuccess("Entry toggled")
+			}
+		}
+
+	case bulkToggleMsg:
+		m.finishSync()
+		for _, alias := range msg.aliases {
+			_, failed := msg.failed[alias]
+			m.list.SetPending(alias, false)
+			m.list.SetError(alias, msg.err != nil || failed)
+		}
+		switch {
+		case msg.err != nil:
+			m.setError(fmt.Sprintf("Toggle failed: %v", msg.err))
+		case len(msg.failed) > 0:
+			cmds = append(cmds, m.refresh())
+			for _, alias := range msg.aliases {
+				if errMsg, ok := msg.failed[alias]; ok {
+					m.setError(fmt.Sprintf("Toggled %d of %d entries; %s: %s", len(msg.aliases)-len(msg.failed), len(msg.aliases), alias, errMsg))
+					break
+				}
+			}
+		default:
+			m.list.ClearSelection()
+			cmds = append(cmds, m.refresh())
+			if msg.warning != "" {
+				m.setWarning(fmt.Sprintf("Toggled %d entries; %s", len(msg.aliases), msg.warning))
+			} else {
+				m.setSuccess(fmt.Sprintf("Toggled %d entries", len(msg.aliases)))
+			}
+		}
+
+	case stickyMsg:
+		switch {
+		case msg.err != nil:
+			m.setError(fmt.Sprintf("Sticky toggle failed: %v", msg.err))
+		case msg.sticky:
+			cmds = append(cmds, m.refresh())
+			m.setSuccess(fmt.Sprintf("Pinned %s: presets won't disable it", msg.alias))
+		default:
+			cmds = append(cmds, m.refresh())
+			m.setSuccess(fmt.Sprintf("Unpinned %s", msg.alias))
 		}
 
 	case presetMsg:
@@ -375,20 +651,47 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.setError(fmt.Sprintf("Preset failed: %v", msg.err))
 		} else {
 			cmds = append(cmds, m.refresh())
-			m.setSuccess(fmt.Sprintf("Applied preset: %s", msg.name))
+			if len(msg.skipped) > 0 {
+				m.setWarning(fmt.Sprintf("Applied preset: %s; %d alias(es) no longer exist: %s", msg.name, len(msg.skipped), strings.Join(msg.skipped, ", ")))
+			} else {
+				m.setSuccess(fmt.Sprintf("Applied preset: %s", msg.name))
+			}
 		}
+		m.presetPicker.CancelForm()
 		m.mode = ViewList
 
+	case presetPreviewMsg:
+		switch {
+		case msg.err != nil:
+			m.setError(fmt.Sprintf("Preset failed: %v", msg.err))
+		case len(msg.preview.Enable)+len(msg.preview.Disable) > PresetConfirmThreshold:
+			m.presetPicker.InitConfirmApply(msg.preview)
+		default:
+			cmds = append(cmds, m.applyPreset(msg.preview.Preset))
+		}
+
 	case addMsg:
+		m.finishSync()
 		if msg.err != nil {
 			m.setError(fmt.Sprintf("Add failed: %v", msg.err))
 		} else {
 			cmds = append(cmds, m.refresh())
-			m.setSuccess(fmt.Sprintf("Added host: %s", msg.domain))
+			m.setSuccess(fmt.Sprintf("Added host: %s (alias %s)", msg.domain, msg.alias))
+		}
+		m.mode = ViewList
+
+	case updateHostMsg:
+		m.finishSync()
+		if msg.err != nil {
+			m.setError(fmt.Sprintf("Update failed: %v", msg.err))
+		} else {
+			cmds = append(cmds, m.refresh())
+			m.setSuccess(fmt.Sprintf("Updated: %s", msg.alias))
 		}
 		m.mode = ViewList
 
 	case deleteMsg:
+		m.finishSync()
 		// Clear pending state regardless of success/failure
 		m.list.SetPending(msg.alias, false)
 		if msg.err != nil {
@@ -404,7 +707,11 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.setError(fmt.Sprintf("Add preset failed: %v", msg.err))
 		} else {
 			cmds = append(cmds, m.refreshPresets())
-			m.setSuccess(fmt.Sprintf("Added preset: %s", msg.name))
+			if msg.warning != "" {
+				m.setError(fmt.Sprintf("Added preset %s, but it conflicts: %s", msg.name, msg.warning))
+			} else {
+				m.setSuccess(fmt.Sprintf("Added preset: %s", msg.name))
+			}
 		}
 		m.presetPicker.CancelForm()
 
@@ -456,6 +763,21 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err == nil && msg.groups != nil {
 			m.allGroups = msg.groups
 			m.groupPicker.SetGroups(msg.groups)
+			m.groupPicker.SetCounts(m.list.GroupCounts())
+		}
+
+	case refreshAllMsg:
+		if msg.err != nil {
+			m.setError(fmt.Sprintf("Refresh failed: %v", msg.err))
+			m.connected = false
+			_ = m.client.Close()
+		} else {
+			m.list.SetItems(msg.entries)
+			m.presetPicker.SetPresetsWithInfo(msg.presets)
+			m.allGroups = msg.groups
+			m.groupPicker.SetGroups(msg.groups)
+			m.groupPicker.SetCounts(m.list.GroupCounts())
+			m.setSuccess("Refreshed")
 		}
 
 	case rollbackMsg:
@@ -468,20 +790,68 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.backupPicker.Cancel()
 		m.mode = ViewList
 
+	case deleteBackupMsg:
+		if msg.err != nil {
+			m.setError(fmt.Sprintf("Delete failed: %v", msg.err))
+		} else {
+			m.setSuccess("Deleted backup")
+		}
+		m.backupPicker.Cancel()
+		cmds = append(cmds, m.refreshBackups())
+
 	case refreshBackupsMsg:
 		if msg.err == nil && msg.backups != nil {
 			m.backupPicker.SetBackups(msg.backups)
-			// Fetch content for the first backup
-			if len(msg.backups) > 0 {
-				cmds = append(cmds, m.fetchBackupContent(msg.backups[0].Name))
+			// Fetch the diff for the backup under the cursor
+			if backup := m.backupPicker.Selected(); backup != "" {
+				cmds = append(cmds, m.fetchBackupDiff(backup))
 			}
 		}
 
-	case backupContentMsg:
-		if msg.err == nil {
-			m.backupPicker.SetPreviewContent(msg.content)
+	case backupDiffMsg:
+		// Ignore a diff for a backup the cursor has since moved off
+		if msg.name == m.backupPicker.Selected() {
+			m.backupPicker.SetDiff(msg.lines, msg.err)
+		}
+
+	case spinner.TickMsg:
+		// Let the spinner stop ticking once nothing is in flight
+		if m.syncing > 0 {
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
+	case openConfigMsg:
+		switch {
+		case msg.err != nil:
+			m.setError(fmt.Sprintf("Open config failed: %v", msg.err))
+		case !msg.writable:
+			// Warn before the editor starts, not after the edits are lost
+			m.pendingConfigPath = msg.path
+			m.mode = ViewConfirmEditConfig
+		default:
+			cmds = append(cmds, m.editConfig(msg.path, msg.writable))
+		}
+
+	case editorDoneMsg:
+		switch {
+		case msg.err != nil:
+			m.setError(fmt.Sprintf("Editor failed: %v", msg.err))
+		case !msg.writable:
+			// Nothing can have changed, so there's nothing to reload
+		default:
+			cmds = append(cmds, m.reload())
 		}
 
+	case reloadMsg:
+		if msg.err != nil {
+			m.setError(fmt.Sprintf("Reload failed: %v", msg.err))
+		} else {
+			m.setSuccess("Config reloaded")
+		}
+		cmds = append(cmds, m.refresh(), m.refreshPresets(), m.refreshGroups())
+
 	case clearMsgMsg:
 		if time.Since(m.messageTime) >= time.Second*3 {
 			m.message = ""
@@ -491,14 +861,29 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Reconnect if disconnected
 		if !m.connected {
 			cmds = append(cmds, m.connect())
+		} else if time.Since(m.lastPing) >= keepaliveInterval {
+			m.lastPing = time.Now()
+			cmds = append(cmds, m.ping())
+		}
+		// Pick up entries the daemon switched off when their TTL ran out
+		if m.connected && m.list.HasExpired(time.Now()) {
+			cmds = append(cmds, m.refresh())
 		}
 		cmds = append(cmds, m.tick())
 
+	case pingMsg:
+		if msg.err != nil {
+			// Mark as disconnected to trigger reconnect
+			m.connected = false
+			_ = m.client.Close()
+		}
+
 	case updateMsg:
 		if msg.version != "" {
 			m.updateAvailable = true
 			m.updateVersion = msg.version
 			m.updateURL = msg.url
+			m.updateNotes = msg.notes
 		}
 	}
 
@@ -509,7 +894,12 @@ func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
 	// Global keys
 	switch msg.String() {
 	case "ctrl+c":
+		if m.mode == ViewList || m.mode == ViewConfirmQuit {
+			return m.quit()
+		}
 		return tea.Quit
+	case "ctrl+r":
+		return m.refreshAll()
 	}
 
 	// Mode-specific keys
@@ -530,18 +920,36 @@ func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
 		return m.handleSearchKey(msg)
 	case ViewConfirmDelete:
 		return m.handleConfirmDeleteKey(msg)
+	case ViewReleaseNotes:
+		return m.handleReleaseNotesKey(msg)
+	case ViewConfirmQuit:
+		return m.handleConfirmQuitKey(msg)
+	case ViewConfirmEditConfig:
+		return m.handleConfirmEditConfigKey(msg)
 	}
 
 	return nil
 }
 
+// quit exits the TUI, first asking for confirmation while toggles are still
+// being applied so the hosts file isn't left half-synced. Asking again quits.
+func (m *Model) quit() tea.Cmd {
+	if m.mode == ViewConfirmQuit || !m.list.HasPending() {
+		return tea.Quit
+	}
+	m.mode = ViewConfirmQuit
+	return nil
+}
+
 func (m *Model) handleListKey(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
 	case "q":
-		return tea.Quit
+		return m.quit()
 	case "esc":
-		// Clear search if active
-		if m.searchTerm != "" {
+		// Clear the selection first, then the search
+		if m.list.SelectionCount() > 0 {
+			m.list.ClearSelection()
+		} else if m.searchTerm != "" {
 			m.searchTerm = ""
 			m.searchInput.Reset()
 		}
@@ -550,7 +958,30 @@ func (m *Model) handleListKey(msg tea.KeyMsg) tea.Cmd {
 	case "down", "j":
 		m.list.MoveDown()
 	case " ", "enter":
+		if m.list.OnHeader() {
+			m.list.ToggleCollapsed()
+			return nil
+		}
+		if m.list.SelectionCount() > 0 {
+			return m.toggleSelection()
+		}
 		return m.toggleSelected()
+	case "c":
+		m.list.ToggleCollapsed()
+	case "S":
+		m.list.SetSortMode(m.list.SortMode().Next())
+		m.setSuccess("Sorted by " + m.list.SortMode().String())
+		return m.clearMsg()
+	case "x":
+		m.list.ToggleSelection()
+	case "a":
+		m.list.SelectGroup(m.searchTerm, m.statusFilter)
+	case "1":
+		m.statusFilter = FilterAll
+	case "2":
+		m.statusFilter = FilterActive
+	case "3":
+		m.statusFilter = FilterDisabled
 	case "n":
 		m.mode = ViewForm
 		m.form.SetGroups(m.allGroups)
@@ -559,13 +990,17 @@ func (m *Model) handleListKey(msg tea.KeyMsg) tea.Cmd {
 		if item := m.list.Selected(); item != nil {
 			m.mode = ViewForm
 			m.form.SetGroups(m.allGroups)
-			m.form.InitEdit(item.Entry.Domain, item.Entry.IP, item.Entry.Alias, item.Entry.Group)
+			m.form.InitEdit(item.Entry.Domain, item.Entry.FormatIPs(), item.Entry.Alias, item.Entry.Group, item.Entry.Metadata, item.Entry.Description)
 		}
 	case "d":
 		if item := m.list.Selected(); item != nil {
 			m.pendingDeleteAlias = item.Entry.Alias
 			m.mode = ViewConfirmDelete
 		}
+	case "s":
+		if item := m.list.Selected(); item != nil {
+			return m.setSticky(item.Entry.Alias, !item.Entry.Sticky)
+		}
 	case "p":
 		m.mode = ViewPresets
 		// Pass available aliases to preset picker
@@ -581,6 +1016,14 @@ func (m *Model) handleListKey(msg tea.KeyMsg) tea.Cmd {
 		m.searchInput.Focus()
 	case "?":
 		m.mode = ViewHelp
+		return m.fetchBlockedDomains()
+	case "o":
+		return m.openConfig()
+	case "u":
+		if m.updateAvailable {
+			m.notesScroll = 0
+			m.mode = ViewReleaseNotes
+		}
 	case "r":
 		return m.refresh()
 	}
@@ -598,18 +1041,18 @@ func (m *Model) handleFormKey(msg tea.KeyMsg) tea.Cmd {
 			return m.clearMsg()
 		}
 		domain, ip, group := m.form.Values()
+		metadata, _ := m.form.Metadata() // checked by Validate
+		description := m.form.Description()
 		if m.form.IsEdit() {
-			// For edit, delete old and add new (simple approach)
-			oldAlias := m.form.EditAlias()
-			return tea.Sequence(
-				func() tea.Msg {
-					_ = m.client.Delete(oldAlias)
-					return nil
-				},
-				m.addHost(domain, ip, "", group), // Empty alias = auto-generate
-			)
+			update := m.form.Changes()
+			if update.IsEmpty() {
+				m.mode = ViewList
+				m.setSuccess("No changes")
+				return m.clearMsg()
+			}
+			return m.startSync(m.updateHost(update))
 		}
-		return m.addHost(domain, ip, "", group) // Empty alias = auto-generate
+		return m.startSync(m.addHost(domain, ip, "", group, metadata, description)) // Empty alias = auto-generate
 	}
 
 	return m.form.Update(msg)
@@ -626,6 +1069,8 @@ func (m *Model) handlePresetKey(msg tea.KeyMsg) tea.Cmd {
 		return m.handlePresetPickerKey(msg)
 	case PresetModeConfirmDelete:
 		return m.handlePresetDeleteKey(msg)
+	case PresetModeConfirmApply:
+		return m.handlePresetApplyKey(msg)
 	}
 	return nil
 }
@@ -640,7 +1085,7 @@ func (m *Model) handlePresetSelectKey(msg tea.KeyMsg) tea.Cmd {
 		m.presetPicker.MoveDown()
 	case "enter":
 		if preset := m.presetPicker.Selected(); preset != "" {
-			return m.applyPreset(preset)
+			return m.previewPreset(preset)
 		}
 	case "n":
 		m.presetPicker.InitAdd()
@@ -706,6 +1151,19 @@ func (m *Model) handlePresetPickerKey(msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
+func (m *Model) handlePresetApplyKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y":
+		if preset := m.presetPicker.PendingApply(); preset != "" {
+			return m.applyPreset(preset)
+		}
+		m.presetPicker.CancelForm()
+	case "n", "N", "esc":
+		m.presetPicker.CancelForm()
+	}
+	return nil
+}
+
 func (m *Model) handlePresetDeleteKey(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
 	case "y", "Y":
@@ -789,6 +1247,8 @@ func (m *Model) handleBackupKey(msg tea.KeyMsg) tea.Cmd {
 		return m.handleBackupSelectKey(msg)
 	case BackupModeConfirmRestore:
 		return m.handleBackupRestoreKey(msg)
+	case BackupModeConfirmDelete:
+		return m.handleBackupDeleteKey(msg)
 	}
 	return nil
 }
@@ -799,15 +1259,15 @@ func (m *Model) handleBackupSelectKey(msg tea.KeyMsg) tea.Cmd {
 		m.mode = ViewList
 	case "up", "k":
 		m.backupPicker.MoveUp()
-		// Fetch content for newly selected backup
-		if backup := m.backupPicker.Selected(); backup != "" && m.backupPicker.PreviewContent() == "" {
-			return m.fetchBackupContent(backup)
+		// Fetch the diff for the newly selected backup
+		if backup := m.backupPicker.Selected(); backup != "" && !m.backupPicker.DiffLoaded() {
+			return m.fetchBackupDiff(backup)
 		}
 	case "down", "j":
 		m.backupPicker.MoveDown()
-		// Fetch content for newly selected backup
-		if backup := m.backupPicker.Selected(); backup != "" && m.backupPicker.PreviewContent() == "" {
-			return m.fetchBackupContent(backup)
+		// Fetch the diff for the newly selected backup
+		if backup := m.backupPicker.Selected(); backup != "" && !m.backupPicker.DiffLoaded() {
+			return m.fetchBackupDiff(backup)
 		}
 	case "shift+up", "K":
 		m.backupPicker.ScrollPreviewUp()
@@ -815,6 +1275,8 @@ func (m *Model) handleBackupSelectKey(msg tea.KeyMsg) tea.Cmd {
 		m.backupPicker.ScrollPreviewDown()
 	case "enter":
 		m.backupPicker.InitRestore()
+	case "d":
+		m.backupPicker.InitDelete()
 	case "r":
 		return m.refreshBackups()
 	}
@@ -834,6 +1296,19 @@ func (m *Model) handleBackupRestoreKey(msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
+func (m *Model) handleBackupDeleteKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y":
+		if backup := m.backupPicker.Selected(); backup != "" {
+			return m.deleteBackup(backup)
+		}
+		m.backupPicker.Cancel()
+	case "n", "N", "esc":
+		m.backupPicker.Cancel()
+	}
+	return nil
+}
+
 func (m *Model) handleHelpKey(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
 	case "esc", "q", "?":
@@ -842,6 +1317,33 @@ func (m *Model) handleHelpKey(msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
+func (m *Model) handleReleaseNotesKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "q", "u":
+		m.mode = ViewList
+	case "up", "k":
+		if m.notesScroll > 0 {
+			m.notesScroll--
+		}
+	case "down", "j":
+		if m.notesScroll < m.maxNotesScroll() {
+			m.notesScroll++
+		}
+	}
+	return nil
+}
+
+// notesHeight is how many lines of release notes fit in the dialog.
+func (m *Model) notesHeight() int {
+	return max(m.height-12, 5) // Reserve space for title, borders, help
+}
+
+// maxNotesScroll is the scroll position that shows the last page of notes.
+func (m *Model) maxNotesScroll() int {
+	lines := strings.Count(m.updateNotes, "\n") + 1
+	return max(lines-m.notesHeight(), 0)
+}
+
 func (m *Model) handleSearchKey(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
 	case "esc":
@@ -868,7 +1370,7 @@ func (m *Model) handleConfirmDeleteKey(msg tea.KeyMsg) tea.Cmd {
 		m.mode = ViewList
 		// Set pending state for visual feedback
 		m.list.SetPending(alias, true)
-		return m.deleteHost(alias)
+		return m.startSync(m.deleteHost(alias))
 	case "n", "N", "esc":
 		m.pendingDeleteAlias = ""
 		m.mode = ViewList
@@ -877,6 +1379,30 @@ func (m *Model) handleConfirmDeleteKey(msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
+func (m *Model) handleConfirmQuitKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y", "q":
+		return tea.Quit
+	case "n", "N", "esc":
+		m.mode = ViewList
+	}
+	return nil
+}
+
+func (m *Model) handleConfirmEditConfigKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "y", "Y":
+		path := m.pendingConfigPath
+		m.pendingConfigPath = ""
+		m.mode = ViewList
+		return m.editConfig(path, false)
+	case "n", "N", "esc":
+		m.pendingConfigPath = ""
+		m.mode = ViewList
+	}
+	return nil
+}
+
 func (m *Model) toggleSelected() tea.Cmd {
 	item := m.list.Selected()
 	if item == nil {
@@ -884,7 +1410,42 @@ func (m *Model) toggleSelected() tea.Cmd {
 	}
 
 	m.list.SetPending(item.Entry.Alias, true)
-	return m.toggle(item.Entry.Alias, !item.Entry.Enabled)
+	return m.startSync(m.toggle(item.Entry.Alias, !item.Entry.Enabled))
+}
+
+// startSync marks a hosts-file write as in flight and starts the status bar
+// spinner if it isn't already running.
+// toggleSelection switches every selected entry at once: all on unless they
+// already all are, in which case all off.
+func (m *Model) toggleSelection() tea.Cmd {
+	aliases := m.list.SelectedAliases()
+	enabled := false
+	for _, alias := range aliases {
+		if item := m.list.FindByAlias(alias); item != nil && !item.Entry.Enabled {
+			enabled = true
+			break
+		}
+	}
+
+	for _, alias := range aliases {
+		m.list.SetPending(alias, true)
+	}
+	return m.startSync(m.toggleBulk(aliases, enabled))
+}
+
+func (m *Model) startSync(cmd tea.Cmd) tea.Cmd {
+	m.syncing++
+	if m.syncing == 1 {
+		return tea.Batch(cmd, m.spinner.Tick)
+	}
+	return cmd
+}
+
+// finishSync marks an in-flight hosts-file write as done.
+func (m *Model) finishSync() {
+	if m.syncing > 0 {
+		m.syncing--
+	}
 }
 
 func (m *Model) setError(msg string) {
@@ -893,6 +1454,12 @@ func (m *Model) setError(msg string) {
 	m.messageTime = time.Now()
 }
 
+func (m *Model) setWarning(msg string) {
+	m.message = msg
+	m.messageStyle = "warning"
+	m.messageTime = time.Now()
+}
+
 func (m *Model) setSuccess(msg string) {
 	m.message = msg
 	m.messageStyle = "success"
@@ -910,7 +1477,7 @@ func (m *Model) View() string {
 	// Update notification
 	if m.updateAvailable {
 		sb.WriteString("  ")
-		sb.WriteString(updateStyle.Render(fmt.Sprintf("Update available: v%s", m.updateVersion)))
+		sb.WriteString(updateStyle.Render(fmt.Sprintf("Update available: v%s (u: notes)", m.updateVersion)))
 	}
 
 	sb.WriteString("\n\n")
@@ -918,7 +1485,11 @@ func (m *Model) View() string {
 	// Main content based on mode
 	switch m.mode {
 	case ViewList:
-		sb.WriteString(m.list.ViewFiltered(m.searchTerm))
+		sb.WriteString(m.list.ViewFiltered(m.searchTerm, m.statusFilter))
+		if detail := m.list.SelectedDetail(time.Now()); detail != "" {
+			sb.WriteString("\n")
+			sb.WriteString(helpDescStyle.Render(detail))
+		}
 	case ViewForm:
 		sb.WriteString(m.form.View())
 	case ViewPresets:
@@ -933,14 +1504,23 @@ func (m *Model) View() string {
 		sb.WriteString(m.searchView())
 	case ViewConfirmDelete:
 		sb.WriteString(m.confirmDeleteView())
+	case ViewConfirmQuit:
+		sb.WriteString(m.confirmQuitView())
+	case ViewConfirmEditConfig:
+		sb.WriteString(m.confirmEditConfigView())
+	case ViewReleaseNotes:
+		sb.WriteString(m.releaseNotesView())
 	}
 
 	// Message
 	if m.message != "" {
 		sb.WriteString("\n")
-		if m.messageStyle == "error" {
+		switch m.messageStyle {
+		case "error":
 			sb.WriteString(errorMsgStyle.Render(m.message))
-		} else {
+		case "warning":
+			sb.WriteString(warningMsgStyle.Render(m.message))
+		default:
 			sb.WriteString(successMsgStyle.Render(m.message))
 		}
 	}
@@ -985,6 +1565,7 @@ func (m *Model) helpBar() string {
 	items := []helpItem{
 		{"↑↓/jk", "Navigate", 13},
 		{"Space", "Toggle", 13},
+		{"x", "Select", 9},
 		{"n", "New", 6},
 		{"e", "Edit", 7},
 		{"d", "Delete", 9},
@@ -1049,7 +1630,20 @@ func (m *Model) statusBar() string {
 	active := fmt.Sprintf("%d active", m.list.ActiveCount())
 	total := fmt.Sprintf("%d total", m.list.Len())
 
-	return statusBarStyle.Render(fmt.Sprintf("%s  |  %s  |  %s", status, active, total))
+	bar := statusBarStyle.Render(fmt.Sprintf("%s  |  %s  |  %s", status, active, total))
+	if n := m.list.SelectionCount(); n > 0 && m.mode == ViewList {
+		bar += statusBarStyle.Render(fmt.Sprintf("  |  %d selected", n))
+	}
+	if m.statusFilter != FilterAll {
+		bar += statusBarStyle.Render(fmt.Sprintf("  |  showing %s", m.statusFilter))
+	}
+	if sort := m.list.SortMode(); sort != SortConfig {
+		bar += statusBarStyle.Render("  |  sorted by " + sort.String())
+	}
+	if m.syncing > 0 {
+		bar += statusBarStyle.Render("  |  ") + m.spinner.View() + statusBarStyle.Render("syncing…")
+	}
+	return bar
 }
 
 func (m *Model) helpView() string {
@@ -1060,15 +1654,25 @@ func (m *Model) helpView() string {
 
 	help := []struct{ key, desc string }{
 		{"↑/↓ or j/k", "Navigate up/down"},
-		{"Space/Enter", "Toggle entry on/off"},
+		{"Space/Enter", "Toggle entry on/off (every selected entry, if any); expand a collapsed group"},
+		{"c", "Collapse/expand the current group"},
+		{"x", "Select/deselect entry for a bulk toggle"},
+		{"a", "Select every shown entry in the current group"},
+		{"S", "Cycle sort: config order, domain, alias, active first, IP"},
+		{"1/2/3", "Show all, only active or only disabled entries"},
+		{"Esc", "Clear selection, then search"},
 		{"n", "Add new entry"},
 		{"e", "Edit selected entry"},
 		{"d", "Delete selected entry"},
+		{"s", "Pin/unpin entry (presets never disable pinned entries)"},
 		{"p", "Open preset manager"},
 		{"g", "Open group manager"},
 		{"b", "Open backup manager"},
 		{"/", "Search"},
 		{"r", "Refresh list"},
+		{"ctrl+r", "Refresh list, presets and groups"},
+		{"o", "Open config file in $EDITOR"},
+		{"u", "Show release notes (when an update is available)"},
 		{"?", "Toggle this help"},
 		{"q", "Quit"},
 	}
@@ -1093,6 +1697,44 @@ func (m *Model) helpView() string {
 	return dialogStyle.Render(sb.String())
 }
 
+func (m *Model) releaseNotesView() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render(fmt.Sprintf("What's new in v%s", m.updateVersion)))
+	sb.WriteString("\n\n")
+
+	if m.updateNotes == "" {
+		// Notes couldn't be fetched or the release has none
+		sb.WriteString(helpDescStyle.Render("No release notes available."))
+		sb.WriteString("\n")
+	} else {
+		lines := strings.Split(m.updateNotes, "\n")
+		notesHeight := m.notesHeight()
+
+		// The window may have grown since the last scroll
+		scroll := min(m.notesScroll, m.maxNotesScroll())
+		endLine := min(scroll+notesHeight, len(lines))
+
+		for _, line := range lines[scroll:endLine] {
+			sb.WriteString(helpDescStyle.Render(line))
+			sb.WriteString("\n")
+		}
+
+		if len(lines) > notesHeight {
+			sb.WriteString("\n")
+			sb.WriteString(helpDescStyle.Render(fmt.Sprintf("%d-%d of %d (↑↓ scroll)", scroll+1, endLine, len(lines))))
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(helpDescStyle.Render(m.updateURL))
+	sb.WriteString("\n\n")
+	sb.WriteString(helpDescStyle.Render("Press u or Esc to close"))
+
+	return dialogStyle.Render(sb.String())
+}
+
 func (m *Model) searchView() string {
 	var sb strings.Builder
 
@@ -1113,11 +1755,12 @@ func (m *Model) confirmDeleteView() string {
 	sb.WriteString("\n\n")
 
 	// Find the entry details for the pending delete
-	var domain, ip string
+	var domain, ip, description string
 	for _, item := range m.list.items {
 		if item.Entry.Alias == m.pendingDeleteAlias {
 			domain = item.Entry.Domain
-			ip = item.Entry.IP
+			ip = item.Entry.FormatIPs()
+			description = item.Entry.Description
 			break
 		}
 	}
@@ -1129,6 +1772,9 @@ func (m *Model) confirmDeleteView() string {
 	sb.WriteString(fmt.Sprintf("  Alias:  %s\n", helpKeyStyle.Render(m.pendingDeleteAlias)))
 	sb.WriteString(fmt.Sprintf("  Domain: %s\n", helpDescStyle.Render(domain)))
 	sb.WriteString(fmt.Sprintf("  IP:     %s\n", helpDescStyle.Render(ip)))
+	if description != "" {
+		sb.WriteString(fmt.Sprintf("  Note:   %s\n", helpDescStyle.Render(description)))
+	}
 
 	sb.WriteString("\n")
 	sb.WriteString(helpDescStyle.Render("y confirm • n/Esc cancel"))
@@ -1136,6 +1782,36 @@ func (m *Model) confirmDeleteView() string {
 	return dialogStyle.Render(sb.String())
 }
 
+func (m *Model) confirmQuitView() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render("Confirm Quit"))
+	sb.WriteString("\n\n")
+
+	warningStyle := lipgloss.NewStyle().Foreground(colorWarning).Bold(true)
+	sb.WriteString(warningStyle.Render("Operations still in progress, quit anyway? (y/n)"))
+	sb.WriteString("\n\n")
+	sb.WriteString(helpDescStyle.Render("y quit • n/Esc keep waiting"))
+
+	return dialogStyle.Render(sb.String())
+}
+
+func (m *Model) confirmEditConfigView() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render("Config Not Writable"))
+	sb.WriteString("\n\n")
+
+	warningStyle := lipgloss.NewStyle().Foreground(colorWarning).Bold(true)
+	sb.WriteString(warningStyle.Render(fmt.Sprintf("%s is not writable by you, edits can't be saved.", m.pendingConfigPath)))
+	sb.WriteString("\n")
+	sb.WriteString(helpDescStyle.Render("Use sudoedit to change it. Open it read-only anyway? (y/n)"))
+	sb.WriteString("\n\n")
+	sb.WriteString(helpDescStyle.Render("y open • n/Esc cancel"))
+
+	return dialogStyle.Render(sb.String())
+}
+
 // RunWithVersion starts the TUI application with version info for update checking.
 func RunWithVersion(socketPath, version, githubOwner, githubRepo string) error {
 	m := NewModel(socketPath)
@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleReleaseNotesKey_ClampsScroll(t *testing.T) {
+	m := &Model{mode: ViewReleaseNotes, height: 20, updateNotes: strings.Repeat("line\n", 19) + "line"}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+
+	// 20 lines in an 8-line window leave 12 lines to scroll
+	for i := 0; i < 30; i++ {
+		m.handleReleaseNotesKey(down)
+	}
+	assert.Equal(t, 12, m.notesScroll)
+
+	m.handleReleaseNotesKey(tea.KeyMsg{Type: tea.KeyUp})
+	assert.Equal(t, 11, m.notesScroll)
+
+	// Notes that fit don't scroll at all
+	m = &Model{mode: ViewReleaseNotes, height: 40, updateNotes: "one\ntwo"}
+	m.handleReleaseNotesKey(down)
+	assert.Equal(t, 0, m.notesScroll)
+}
+
+func TestOpenConfig_ConfirmsReadOnly(t *testing.T) {
+	m := &Model{mode: ViewList}
+
+	m.Update(openConfigMsg{path: "/etc/lolcathost/config.yaml", writable: false})
+	assert.Equal(t, ViewConfirmEditConfig, m.mode)
+	assert.Equal(t, "/etc/lolcathost/config.yaml", m.pendingConfigPath)
+	assert.Contains(t, m.confirmEditConfigView(), "not writable")
+
+	assert.Nil(t, m.handleConfirmEditConfigKey(tea.KeyMsg{Type: tea.KeyEsc}))
+	assert.Equal(t, ViewList, m.mode)
+	assert.Empty(t, m.pendingConfigPath)
+
+	m.Update(openConfigMsg{path: "/etc/lolcathost/config.yaml", writable: false})
+	assert.NotNil(t, m.handleConfirmEditConfigKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}))
+	assert.Equal(t, ViewList, m.mode)
+}
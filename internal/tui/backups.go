@@ -16,17 +16,20 @@ type BackupMode int
 const (
 	BackupModeSelect BackupMode = iota
 	BackupModeConfirmRestore
+	BackupModeConfirmDelete
 )
 
 // BackupPicker handles the backup selection and restore UI.
 type BackupPicker struct {
-	backups        []protocol.BackupInfo
-	cursor         int
-	width          int
-	height         int
-	mode           BackupMode
-	previewContent string
-	previewScroll  int
+	backups       []protocol.BackupInfo
+	cursor        int
+	width         int
+	height        int
+	mode          BackupMode
+	diff          []protocol.DiffLine
+	diffLoaded    bool
+	diffErr       error
+	previewScroll int
 }
 
 // NewBackupPicker creates a new backup picker.
@@ -36,12 +39,14 @@ func NewBackupPicker() *BackupPicker {
 	}
 }
 
-// SetBackups updates the available backups.
+// SetBackups updates the available backups. The preview is cleared, since the
+// backup under the cursor may have changed.
 func (b *BackupPicker) SetBackups(backups []protocol.BackupInfo) {
 	b.backups = backups
 	if b.cursor >= len(backups) {
 		b.cursor = max(0, len(backups)-1)
 	}
+	b.clearDiff()
 }
 
 // SetSize sets the picker dimensions.
@@ -54,8 +59,7 @@ func (b *BackupPicker) SetSize(width, height int) {
 func (b *BackupPicker) MoveUp() {
 	if b.cursor > 0 {
 		b.cursor--
-		b.previewContent = "" // Clear preview to trigger reload
-		b.previewScroll = 0
+		b.clearDiff()
 	}
 }
 
@@ -63,20 +67,30 @@ func (b *BackupPicker) MoveUp() {
 func (b *BackupPicker) MoveDown() {
 	if b.cursor < len(b.backups)-1 {
 		b.cursor++
-		b.previewContent = "" // Clear preview to trigger reload
-		b.previewScroll = 0
+		b.clearDiff()
 	}
 }
 
-// SetPreviewContent sets the preview content for the current backup.
-func (b *BackupPicker) SetPreviewContent(content string) {
-	b.previewContent = content
+// SetDiff sets the changes from the current backup to the hosts file, or the
+// error fetching them.
+func (b *BackupPicker) SetDiff(lines []protocol.DiffLine, err error) {
+	b.diff = lines
+	b.diffErr = err
+	b.diffLoaded = true
 	b.previewScroll = 0
 }
 
-// PreviewContent returns the current preview content.
-func (b *BackupPicker) PreviewContent() string {
-	return b.previewContent
+// DiffLoaded reports whether the diff for the current backup has been set.
+func (b *BackupPicker) DiffLoaded() bool {
+	return b.diffLoaded
+}
+
+// clearDiff drops the diff so the one for the current backup is fetched.
+func (b *BackupPicker) clearDiff() {
+	b.diff = nil
+	b.diffErr = nil
+	b.diffLoaded = false
+	b.previewScroll = 0
 }
 
 // ScrollPreviewUp scrolls the preview up.
@@ -125,6 +139,14 @@ func (b *BackupPicker) InitRestore() {
 	b.mode = BackupModeConfirmRestore
 }
 
+// InitDelete starts delete confirmation.
+func (b *BackupPicker) InitDelete() {
+	if b.SelectedInfo() == nil {
+		return
+	}
+	b.mode = BackupModeConfirmDelete
+}
+
 // Cancel cancels the current operation.
 func (b *BackupPicker) Cancel() {
 	b.mode = BackupModeSelect
@@ -135,6 +157,8 @@ func (b *BackupPicker) View() string {
 	switch b.mode {
 	case BackupModeConfirmRestore:
 		return b.restoreView()
+	case BackupModeConfirmDelete:
+		return b.deleteView()
 	default:
 		return b.selectView()
 	}
@@ -164,6 +188,9 @@ func (b *BackupPicker) selectView() string {
 		timestamp := time.Unix(backup.Timestamp, 0).Format("2006-01-02 15:04:05")
 		sizeStr := formatSize(backup.Size)
 		line := fmt.Sprintf("%s  (%s)", timestamp, sizeStr)
+		if backup.Label != "" {
+			line += "  " + backup.Label
+		}
 
 		if i == b.cursor {
 			leftSb.WriteString(presetSelectedStyle.Render("▸ " + line))
@@ -174,18 +201,23 @@ func (b *BackupPicker) selectView() string {
 	}
 
 	leftSb.WriteString("\n")
-	leftSb.WriteString(WrapHelpText("↑↓ navigate • Enter restore • Esc cancel", 40))
+	leftSb.WriteString(WrapHelpText("↑↓ navigate • Enter restore • d delete • Esc cancel", 40))
 
-	// Build right panel (preview)
+	// Build right panel (diff)
 	var rightSb strings.Builder
-	rightSb.WriteString(titleStyle.Render("Preview"))
+	rightSb.WriteString(titleStyle.Render("Changes since backup"))
 	rightSb.WriteString("\n\n")
 
-	if b.previewContent == "" {
+	lines := collapseDiff(b.diff, diffContextLines)
+	switch {
+	case !b.diffLoaded:
 		rightSb.WriteString(helpDescStyle.Render("Loading..."))
-	} else {
-		// Show content with scroll support
-		lines := strings.Split(b.previewContent, "\n")
+	case b.diffErr != nil:
+		rightSb.WriteString(errorMsgStyle.Render(fmt.Sprintf("Could not load diff: %v", b.diffErr)))
+	case len(lines) == 0:
+		rightSb.WriteString(helpDescStyle.Render("No changes since this backup."))
+	default:
+		// Show the diff with scroll support
 		previewHeight := b.height - 12 // Reserve space for title, borders, help
 		if previewHeight < 5 {
 			previewHeight = 5
@@ -206,26 +238,21 @@ func (b *BackupPicker) selectView() string {
 			endLine = len(lines)
 		}
 
-		visibleLines := lines[b.previewScroll:endLine]
-		for _, line := range visibleLines {
-			// Truncate long lines
-			if len(line) > 50 {
-				line = line[:47] + "..."
-			}
-			rightSb.WriteString(helpDescStyle.Render(line))
+		for _, line := range lines[b.previewScroll:endLine] {
+			rightSb.WriteString(renderDiffLine(line))
 			rightSb.WriteString("\n")
 		}
 
 		// Show scroll indicator
 		if len(lines) > previewHeight {
 			rightSb.WriteString("\n")
-			scrollText := fmt.Sprintf("%d-%d of %d (↑↓ scroll)", b.previewScroll+1, endLine, len(lines))
+			scrollText := fmt.Sprintf("%d-%d of %d (shift+↑↓ scroll)", b.previewScroll+1, endLine, len(lines))
 			rightSb.WriteString(helpDescStyle.Render(scrollText))
 		}
 	}
 
 	// Style the panels
-	leftWidth := 45
+	leftWidth := 60
 	rightWidth := b.width - leftWidth - 10
 	if rightWidth < 30 {
 		rightWidth = 30
@@ -251,11 +278,7 @@ func (b *BackupPicker) selectView() string {
 func (b *BackupPicker) restoreView() string {
 	var sb strings.Builder
 
-	backup := b.SelectedInfo()
-	timestamp := ""
-	if backup != nil {
-		timestamp = time.Unix(backup.Timestamp, 0).Format("2006-01-02 15:04:05")
-	}
+	timestamp := b.selectedTitle()
 
 	sb.WriteString(titleStyle.Render("Restore Backup"))
 	sb.WriteString("\n\n")
@@ -268,6 +291,89 @@ func (b *BackupPicker) restoreView() string {
 	return dialogStyle.Render(sb.String())
 }
 
+func (b *BackupPicker) deleteView() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render("Delete Backup"))
+	sb.WriteString("\n\n")
+	sb.WriteString(errorMsgStyle.Render(fmt.Sprintf("Delete backup '%s'?", b.selectedTitle())))
+	sb.WriteString("\n\n")
+	sb.WriteString(helpDescStyle.Render("This cannot be undone."))
+	sb.WriteString("\n\n")
+	sb.WriteString(helpDescStyle.Render("y confirm • n/Esc cancel"))
+
+	return dialogStyle.Render(sb.String())
+}
+
+// selectedTitle describes the selected backup by its time and label.
+func (b *BackupPicker) selectedTitle() string {
+	backup := b.SelectedInfo()
+	if backup == nil {
+		return ""
+	}
+	title := time.Unix(backup.Timestamp, 0).Format("2006-01-02 15:04:05")
+	if backup.Label != "" {
+		title += " " + backup.Label
+	}
+	return title
+}
+
+// diffContextLines is how many unchanged lines are shown around each change.
+const diffContextLines = 2
+
+// collapseDiff keeps the changed lines of a diff and up to context unchanged
+// lines around each, replacing longer unchanged runs with a single line with
+// an empty Op. It returns nil when nothing changed.
+func collapseDiff(diff []protocol.DiffLine, context int) []protocol.DiffLine {
+	keep := make([]bool, len(diff))
+	changed := false
+	for i, line := range diff {
+		if line.Op == protocol.DiffContext {
+			continue
+		}
+		changed = true
+		for j := max(0, i-context); j <= min(len(diff)-1, i+context); j++ {
+			keep[j] = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	var lines []protocol.DiffLine
+	for i := 0; i < len(diff); {
+		if keep[i] {
+			lines = append(lines, diff[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(diff) && !keep[i] {
+			i++
+		}
+		lines = append(lines, protocol.DiffLine{Text: fmt.Sprintf("⋯ %d unchanged line(s)", i-start)})
+	}
+	return lines
+}
+
+// renderDiffLine colors a diff line by its operation and truncates it to fit
+// the panel.
+func renderDiffLine(line protocol.DiffLine) string {
+	text := line.Op + line.Text
+	if len(text) > 50 {
+		text = text[:47] + "..."
+	}
+
+	switch line.Op {
+	case protocol.DiffAdded:
+		return diffAddedStyle.Render(text)
+	case protocol.DiffRemoved:
+		return diffRemovedStyle.Render(text)
+	default:
+		return helpDescStyle.Render(text)
+	}
+}
+
 // formatSize formats bytes to human readable format.
 func formatSize(bytes int64) string {
 	const (
@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
+)
+
+func TestCollapseDiff(t *testing.T) {
+	ctx := func(text string) protocol.DiffLine { return protocol.DiffLine{Op: protocol.DiffContext, Text: text} }
+
+	diff := []protocol.DiffLine{
+		ctx("1"), ctx("2"), ctx("3"), ctx("4"),
+		{Op: protocol.DiffRemoved, Text: "5"},
+		{Op: protocol.DiffAdded, Text: "5b"},
+		ctx("6"), ctx("7"), ctx("8"),
+	}
+
+	assert.Equal(t, []protocol.DiffLine{
+		{Text: "⋯ 2 unchanged line(s)"},
+		ctx("3"), ctx("4"),
+		{Op: protocol.DiffRemoved, Text: "5"},
+		{Op: protocol.DiffAdded, Text: "5b"},
+		ctx("6"), ctx("7"),
+		{Text: "⋯ 1 unchanged line(s)"},
+	}, collapseDiff(diff, 2))
+
+	assert.Nil(t, collapseDiff([]protocol.DiffLine{ctx("1"), ctx("2")}, 2))
+	assert.Nil(t, collapseDiff(nil, 2))
+}
+
+func TestBackupPicker_DiffClearedOnMove(t *testing.T) {
+	b := NewBackupPicker()
+	b.SetBackups([]protocol.BackupInfo{{Name: "hosts.20231201-120000.bak"}, {Name: "hosts.20231130-120000.bak"}})
+	assert.False(t, b.DiffLoaded())
+
+	b.SetDiff([]protocol.DiffLine{{Op: protocol.DiffAdded, Text: "x"}}, nil)
+	assert.True(t, b.DiffLoaded())
+
+	b.MoveDown()
+	assert.False(t, b.DiffLoaded())
+	assert.Equal(t, "hosts.20231130-120000.bak", b.Selected())
+}
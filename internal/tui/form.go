@@ -3,11 +3,13 @@ package tui
 
 import (
 	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/lukaszraczylo/lolcathost/internal/config"
+	"github.com/lukaszraczylo/lolcathost/internal/protocol"
 )
 
 // FormMode represents the form mode.
@@ -25,9 +27,17 @@ const (
 	FieldDomain FormField = iota
 	FieldIP
 	FieldGroup
+	FieldMetadata
+	FieldDescription
 	FieldCount
 )
 
+// formValues are an entry's values as the form shows them.
+type formValues struct {
+	domain, ip, group, description string
+	metadata                       map[string]string
+}
+
 // Form handles the add/edit entry form.
 type Form struct {
 	mode      FormMode
@@ -36,6 +46,7 @@ type Form struct {
 	width     int
 	height    int
 	editAlias string // Original alias when editing
+	original  formValues
 
 	// Group dropdown
 	groups       []string
@@ -55,13 +66,23 @@ func NewForm() *Form {
 	// IP field
 	fields[FieldIP] = textinput.New()
 	fields[FieldIP].Placeholder = "127.0.0.1"
-	fields[FieldIP].CharLimit = 45 // IPv6 max
+	fields[FieldIP].CharLimit = 255 // a few comma-separated IPv6 addresses with zones
 
 	// Group field (not used as text input, but kept for compatibility)
 	fields[FieldGroup] = textinput.New()
 	fields[FieldGroup].Placeholder = "development"
 	fields[FieldGroup].CharLimit = 63
 
+	// Metadata field, as comma-separated key=value pairs
+	fields[FieldMetadata] = textinput.New()
+	fields[FieldMetadata].Placeholder = "owner=alice, ticket=OPS-123"
+	fields[FieldMetadata].CharLimit = 512
+
+	// Description field
+	fields[FieldDescription] = textinput.New()
+	fields[FieldDescription].Placeholder = "Why this entry exists"
+	fields[FieldDescription].CharLimit = config.MaxDescriptionLength
+
 	return &Form{
 		fields: fields,
 		focus:  FieldDomain,
@@ -99,12 +120,15 @@ func (f *Form) Init() {
 }
 
 // InitEdit initializes the form for editing an existing entry.
-func (f *Form) InitEdit(domain, ip, alias, group string) {
+func (f *Form) InitEdit(domain, ip, alias, group string, metadata map[string]string, description string) {
 	f.mode = FormModeEdit
 	f.editAlias = alias
+	f.original = formValues{domain: domain, ip: ip, group: group, description: description, metadata: metadata}
 
 	f.fields[FieldDomain].SetValue(domain)
 	f.fields[FieldIP].SetValue(ip)
+	f.fields[FieldMetadata].SetValue(protocol.FormatMetadata(metadata))
+	f.fields[FieldDescription].SetValue(description)
 
 	// Find the group in the list
 	f.groupCursor = 0
@@ -220,11 +244,66 @@ func (f *Form) Values() (domain, ip, group string) {
 		group
 }
 
+// Metadata returns the parsed metadata field.
+func (f *Form) Metadata() (map[string]string, error) {
+	return parseMetadata(f.fields[FieldMetadata].Value())
+}
+
+// parseMetadata parses comma-separated key=value pairs, the format
+// protocol.FormatMetadata writes.
+func parseMetadata(s string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || !config.ValidateMetadataKey(key) {
+			return nil, fmt.Errorf("invalid metadata %q (use key=value)", pair)
+		}
+		metadata[key] = strings.TrimSpace(value)
+	}
+	return metadata, nil
+}
+
+// Description returns the description field.
+func (f *Form) Description() string {
+	return strings.TrimSpace(f.fields[FieldDescription].Value())
+}
+
 // EditAlias returns the original alias when editing.
 func (f *Form) EditAlias() string {
 	return f.editAlias
 }
 
+// Changes returns an update for the entry being edited that sets only the
+// fields that differ from what InitEdit filled in. Call Validate first.
+func (f *Form) Changes() protocol.UpdatePayload {
+	domain, ip, group := f.Values()
+	metadata, _ := f.Metadata()
+	description := f.Description()
+
+	update := protocol.UpdatePayload{Alias: f.editAlias}
+	if domain != f.original.domain {
+		update.Domain = &domain
+	}
+	if ip != f.original.ip {
+		update.IP = &ip
+	}
+	if group != f.original.group {
+		update.Group = &group
+	}
+	if !maps.Equal(metadata, f.original.metadata) {
+		update.Metadata = metadata
+	}
+	if description != f.original.description {
+		update.Description = &description
+	}
+	return update
+}
+
 // IsEdit returns true if in edit mode.
 func (f *Form) IsEdit() bool {
 	return f.mode == FormModeEdit
@@ -237,12 +316,18 @@ func (f *Form) Validate() string {
 	if domain == "" {
 		return "Domain is required"
 	}
-	if ip == "" {
-		return "IP address is required"
+	if err := config.CheckIPs(config.SplitIPs(ip)); err != nil {
+		return err.Error()
 	}
 	if group == "" {
 		return "Group is required"
 	}
+	if _, err := f.Metadata(); err != nil {
+		return err.Error()
+	}
+	if !config.ValidateDescription(f.Description()) {
+		return "Description must be a single line"
+	}
 
 	// Check if domain is blocked
 	if config.IsBlockedDomain(domain) {
@@ -275,7 +360,7 @@ func (f *Form) View() string {
 	sb.WriteString("\n\n")
 
 	// IP field
-	sb.WriteString(inputLabelStyle.Render("IP Address:"))
+	sb.WriteString(inputLabelStyle.Render("IP Address (comma-separate several):"))
 	sb.WriteString("\n")
 	style = inputStyle
 	if f.focus == FieldIP {
@@ -290,6 +375,26 @@ func (f *Form) View() string {
 	sb.WriteString(f.renderGroupDropdown())
 	sb.WriteString("\n\n")
 
+	// Metadata field
+	sb.WriteString(inputLabelStyle.Render("Metadata (key=value, ...):"))
+	sb.WriteString("\n")
+	style = inputStyle
+	if f.focus == FieldMetadata {
+		style = inputFocusStyle
+	}
+	sb.WriteString(style.Render(f.fields[FieldMetadata].View()))
+	sb.WriteString("\n\n")
+
+	// Description field
+	sb.WriteString(inputLabelStyle.Render("Description:"))
+	sb.WriteString("\n")
+	style = inputStyle
+	if f.focus == FieldDescription {
+		style = inputFocusStyle
+	}
+	sb.WriteString(style.Render(f.fields[FieldDescription].View()))
+	sb.WriteString("\n\n")
+
 	sb.WriteString("\n")
 	sb.WriteString(WrapHelpText("Tab/↓ next • Shift+Tab/↑ prev • ←→ select group • Enter save • Esc cancel", f.width-6))
 
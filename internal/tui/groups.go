@@ -2,6 +2,7 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -18,9 +19,16 @@ const (
 	GroupModeConfirmDelete
 )
 
+// GroupCount holds the number of active and total hosts in a group.
+type GroupCount struct {
+	Active int
+	Total  int
+}
+
 // GroupPicker handles the group selection and management UI.
 type GroupPicker struct {
 	groups   []string
+	counts   map[string]GroupCount
 	cursor   int
 	width    int
 	height   int
@@ -49,6 +57,11 @@ func (g *GroupPicker) SetGroups(groups []string) {
 	}
 }
 
+// SetCounts updates the per-group host counts shown next to each name.
+func (g *GroupPicker) SetCounts(counts map[string]GroupCount) {
+	g.counts = counts
+}
+
 // SetSize sets the picker dimensions.
 func (g *GroupPicker) SetSize(width, height int) {
 	g.width = width
@@ -180,10 +193,12 @@ func (g *GroupPicker) selectView() string {
 		sb.WriteString(helpDescStyle.Render("Press 'n' to create one"))
 	} else {
 		for i, group := range g.groups {
+			count := g.counts[group]
+			label := fmt.Sprintf("%s (%d/%d)", group, count.Active, count.Total)
 			if i == g.cursor {
-				sb.WriteString(presetSelectedStyle.Render("▸ " + group))
+				sb.WriteString(presetSelectedStyle.Render("▸ " + label))
 			} else {
-				sb.WriteString(presetItemStyle.Render("  " + group))
+				sb.WriteString(presetItemStyle.Render("  " + label))
 			}
 			sb.WriteString("\n")
 		}
@@ -2,8 +2,12 @@
 package tui
 
 import (
+	"cmp"
 	"fmt"
+	"net/netip"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
@@ -15,22 +19,111 @@ type EntryItem struct {
 	Entry    protocol.HostEntry
 	Pending  bool
 	HasError bool
+
+	order int // position in the config, for SortConfig
+}
+
+// SortMode orders the entries within each group.
+type SortMode int
+
+const (
+	SortConfig SortMode = iota
+	SortDomain
+	SortAlias
+	SortStatus
+	SortIP
+)
+
+// sortModeNames are indexed by SortMode.
+var sortModeNames = []string{"config order", "domain", "alias", "active first", "IP"}
+
+func (s SortMode) String() string {
+	return sortModeNames[s]
+}
+
+// Next returns the mode after s, wrapping around to SortConfig.
+func (s SortMode) Next() SortMode {
+	return (s + 1) % SortMode(len(sortModeNames))
+}
+
+// compare orders two entries by the mode, returning 0 for SortConfig and for
+// entries it considers equal.
+func (s SortMode) compare(a, b protocol.HostEntry) int {
+	switch s {
+	case SortDomain:
+		return strings.Compare(a.Domain, b.Domain)
+	case SortAlias:
+		return strings.Compare(a.Alias, b.Alias)
+	case SortStatus:
+		if a.Enabled != b.Enabled {
+			if a.Enabled {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	case SortIP:
+		ipA, errA := netip.ParseAddr(a.IP)
+		ipB, errB := netip.ParseAddr(b.IP)
+		if errA != nil || errB != nil {
+			return strings.Compare(a.IP, b.IP)
+		}
+		return ipA.Compare(ipB)
+	}
+	return 0
+}
+
+// StatusFilter narrows the list to active or disabled entries.
+type StatusFilter int
+
+const (
+	FilterAll StatusFilter = iota
+	FilterActive
+	FilterDisabled
+)
+
+func (f StatusFilter) String() string {
+	switch f {
+	case FilterActive:
+		return "active"
+	case FilterDisabled:
+		return "disabled"
+	}
+	return "all"
+}
+
+// matches reports whether an entry passes the filter.
+func (f StatusFilter) matches(e protocol.HostEntry) bool {
+	switch f {
+	case FilterActive:
+		return e.Enabled
+	case FilterDisabled:
+		return !e.Enabled
+	}
+	return true
 }
 
 // ListView handles the list of host entries.
 type ListView struct {
-	items      []EntryItem
-	groups     map[string][]int // group name -> indices in items
-	groupOrder []string         // ordered group names
-	cursor     int
-	width      int
-	height     int
+	items        []EntryItem
+	groups       map[string][]int  // group name -> indices in items
+	groupOrder   []string          // ordered group names
+	groupColors  map[string]string // group name -> configured header color
+	selected     map[string]bool   // aliases marked for a bulk toggle
+	collapsed    map[string]bool   // group name -> rows hidden
+	sortMode     SortMode
+	cursor       int
+	headerCursor string // collapsed group whose header the cursor is on
+	width        int
+	height       int
 }
 
 // NewListView creates a new list view.
 func NewListView() *ListView {
 	return &ListView{
-		groups: make(map[string][]int),
+		groups:    make(map[string][]int),
+		selected:  make(map[string]bool),
+		collapsed: make(map[string]bool),
 	}
 }
 
@@ -39,24 +132,82 @@ func (l *ListView) SetItems(entries []protocol.HostEntry) {
 	l.items = make([]EntryItem, len(entries))
 	l.groups = make(map[string][]int)
 	l.groupOrder = nil
+	l.groupColors = make(map[string]string)
 
 	groupSeen := make(map[string]bool)
 
 	for i, e := range entries {
-		l.items[i] = EntryItem{Entry: e}
+		l.items[i] = EntryItem{Entry: e, order: i}
 
 		if !groupSeen[e.Group] {
 			groupSeen[e.Group] = true
 			l.groupOrder = append(l.groupOrder, e.Group)
+			l.groupColors[e.Group] = e.GroupColor
 		}
 
 		l.groups[e.Group] = append(l.groups[e.Group], i)
 	}
+	l.sortItems()
 
 	// Reset cursor if out of bounds
 	if l.cursor >= len(l.items) {
 		l.cursor = max(0, len(l.items)-1)
 	}
+
+	// Keep the selection across refreshes, minus entries that are gone
+	present := make(map[string]bool, len(l.items))
+	for _, item := range l.items {
+		present[item.Entry.Alias] = true
+	}
+	for alias := range l.selected {
+		if !present[alias] {
+			delete(l.selected, alias)
+		}
+	}
+	l.fixCursor()
+}
+
+// SortMode returns how entries are ordered within their groups.
+func (l *ListView) SortMode() SortMode {
+	return l.sortMode
+}
+
+// SetSortMode orders the entries within each group, keeping the cursor on
+// the same entry. The mode sticks across SetItems.
+func (l *ListView) SetSortMode(mode SortMode) {
+	alias := l.SelectedAlias()
+	l.sortMode = mode
+	l.sortItems()
+	for i, item := range l.items {
+		if item.Entry.Alias == alias {
+			l.cursor = i
+			break
+		}
+	}
+	l.fixCursor()
+}
+
+// sortItems orders items by group, then by the sort mode, so moving the
+// cursor follows the rows as rendered.
+func (l *ListView) sortItems() {
+	groupPos := make(map[string]int, len(l.groupOrder))
+	for i, name := range l.groupOrder {
+		groupPos[name] = i
+	}
+	slices.SortStableFunc(l.items, func(a, b EntryItem) int {
+		if c := cmp.Compare(groupPos[a.Entry.Group], groupPos[b.Entry.Group]); c != 0 {
+			return c
+		}
+		if c := l.sortMode.compare(a.Entry, b.Entry); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.order, b.order)
+	})
+
+	l.groups = make(map[string][]int)
+	for i, item := range l.items {
+		l.groups[item.Entry.Group] = append(l.groups[item.Entry.Group], i)
+	}
 }
 
 // SetSize sets the view dimensions.
@@ -67,20 +218,118 @@ func (l *ListView) SetSize(width, height int) {
 
 // MoveUp moves the cursor up.
 func (l *ListView) MoveUp() {
-	if l.cursor > 0 {
-		l.cursor--
-	}
+	l.move(-1)
 }
 
 // MoveDown moves the cursor down.
 func (l *ListView) MoveDown() {
-	if l.cursor < len(l.items)-1 {
-		l.cursor++
+	l.move(1)
+}
+
+// listStop is a place the cursor can rest: an item, or the header of a
+// collapsed group when idx is -1.
+type listStop struct {
+	group string
+	idx   int
+}
+
+// stops lists the cursor positions top to bottom. Expanded groups contribute
+// their rows and collapsed ones just their header.
+func (l *ListView) stops() []listStop {
+	var stops []listStop
+	for _, name := range l.groupOrder {
+		if l.collapsed[name] {
+			stops = append(stops, listStop{group: name, idx: -1})
+			continue
+		}
+		for _, idx := range l.groups[name] {
+			stops = append(stops, listStop{group: name, idx: idx})
+		}
+	}
+	return stops
+}
+
+func (l *ListView) move(delta int) {
+	stops := l.stops()
+	pos := slices.IndexFunc(stops, func(s listStop) bool {
+		if l.headerCursor != "" {
+			return s.idx < 0 && s.group == l.headerCursor
+		}
+		return s.idx == l.cursor
+	})
+	next := pos + delta
+	if pos < 0 || next < 0 || next >= len(stops) {
+		return
+	}
+
+	if stops[next].idx < 0 {
+		l.headerCursor = stops[next].group
+	} else {
+		l.headerCursor = ""
+		l.cursor = stops[next].idx
 	}
 }
 
-// Selected returns the currently selected item.
+// fixCursor moves the cursor onto the header of a collapsed group hiding its
+// item, or off a header whose group is no longer collapsed.
+func (l *ListView) fixCursor() {
+	if l.headerCursor != "" && (!l.collapsed[l.headerCursor] || len(l.groups[l.headerCursor]) == 0) {
+		if indices := l.groups[l.headerCursor]; len(indices) > 0 {
+			l.cursor = indices[0]
+		}
+		l.headerCursor = ""
+	}
+	if item := l.Selected(); item != nil && l.collapsed[item.Entry.Group] {
+		l.headerCursor = item.Entry.Group
+	}
+}
+
+// OnHeader reports whether the cursor is on a collapsed group's header.
+func (l *ListView) OnHeader() bool {
+	return l.headerCursor != ""
+}
+
+// SelectedGroup returns the group of the header or item under the cursor.
+func (l *ListView) SelectedGroup() string {
+	if l.headerCursor != "" {
+		return l.headerCursor
+	}
+	if item := l.Selected(); item != nil {
+		return item.Entry.Group
+	}
+	return ""
+}
+
+// ToggleCollapsed collapses the cursor's group, leaving the cursor on its
+// header, or expands it again. The state is kept across SetItems.
+func (l *ListView) ToggleCollapsed() {
+	name := l.SelectedGroup()
+	if name == "" {
+		return
+	}
+	if l.collapsed[name] {
+		delete(l.collapsed, name)
+		l.headerCursor = ""
+		if indices := l.groups[name]; len(indices) > 0 {
+			l.cursor = indices[0]
+		}
+		return
+	}
+	l.collapsed[name] = true
+	l.headerCursor = name
+}
+
+// IsCollapsed reports whether a group's rows are hidden.
+func (l *ListView) IsCollapsed(group string) bool {
+	return l.collapsed[group]
+}
+
+// Selected returns the currently selected item, or nil when the cursor is on
+// a group header.
 func (l *ListView) Selected() *EntryItem {
+	if l.headerCursor != "" {
+		return nil
+	}
 	if l.cursor >= 0 && l.cursor < len(l.items) {
 		return &l.items[l.cursor]
 	}
@@ -95,6 +344,42 @@ func (l *ListView) SelectedAlias() string {
 	return ""
 }
 
+// SelectedDetail describes the selected entry in one line, or returns an
+// empty string when nothing is selected.
+func (l *ListView) SelectedDetail(now time.Time) string {
+	item := l.Selected()
+	if item == nil {
+		return ""
+	}
+	detail := fmt.Sprintf("%s  ·  group %s  ·  added %s", item.Entry.Alias, item.Entry.Group, formatCreated(item.Entry.CreatedAt, now))
+	if len(item.Entry.Metadata) > 0 {
+		detail += "  ·  " + protocol.FormatMetadata(item.Entry.Metadata)
+	}
+	return detail
+}
+
+// formatCreated renders a creation timestamp with its age, or "unknown" for
+// entries that predate creation times being recorded.
+func formatCreated(createdAt int64, now time.Time) string {
+	if createdAt == 0 {
+		return "unknown"
+	}
+
+	created := time.Unix(createdAt, 0)
+	age := now.Sub(created)
+
+	var ago string
+	switch {
+	case age < time.Hour:
+		ago = "just now"
+	case age < 24*time.Hour:
+		ago = fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		ago = fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+	return fmt.Sprintf("%s (%s)", created.Format("2006-01-02"), ago)
+}
+
 // GetAliases returns all available aliases.
 func (l *ListView) GetAliases() []string {
 	aliases := make([]string, len(l.items))
@@ -104,6 +389,61 @@ func (l *ListView) GetAliases() []string {
 	return aliases
 }
 
+// ToggleSelection adds the item under the cursor to the selection, or removes
+// it if it is already selected.
+func (l *ListView) ToggleSelection() {
+	item := l.Selected()
+	if item == nil {
+		return
+	}
+	if l.selected[item.Entry.Alias] {
+		delete(l.selected, item.Entry.Alias)
+	} else {
+		l.selected[item.Entry.Alias] = true
+	}
+}
+
+// SelectGroup selects every item in the cursor's group that matches term and
+// status, which is what the list shows of that group.
+func (l *ListView) SelectGroup(term string, status StatusFilter) {
+	for _, idx := range l.groups[l.SelectedGroup()] {
+		if (term == "" || l.items[idx].Entry.Matches(term)) && status.matches(l.items[idx].Entry) {
+			l.selected[l.items[idx].Entry.Alias] = true
+		}
+	}
+}
+
+// ClearSelection deselects everything.
+func (l *ListView) ClearSelection() {
+	clear(l.selected)
+}
+
+// SelectionCount returns how many items are selected.
+func (l *ListView) SelectionCount() int {
+	return len(l.selected)
+}
+
+// SelectedAliases returns the selected aliases in list order.
+func (l *ListView) SelectedAliases() []string {
+	var aliases []string
+	for _, item := range l.items {
+		if l.selected[item.Entry.Alias] {
+			aliases = append(aliases, item.Entry.Alias)
+		}
+	}
+	return aliases
+}
+
+// HasPending reports whether any item is waiting on the daemon.
+func (l *ListView) HasPending() bool {
+	for _, item := range l.items {
+		if item.Pending {
+			return true
+		}
+	}
+	return false
+}
+
 // SetPending marks an item as pending.
 func (l *ListView) SetPending(alias string, pending bool) {
 	for i := range l.items {
@@ -129,6 +469,7 @@ func (l *ListView) UpdateEntry(alias string, enabled bool) {
 	for i := range l.items {
 		if l.items[i].Entry.Alias == alias {
 			l.items[i].Entry.Enabled = enabled
+			l.items[i].Entry.Synced = true
 			l.items[i].Pending = false
 			l.items[i].HasError = false
 			break
@@ -152,6 +493,21 @@ func (l *ListView) ActiveCount() int {
 	return count
 }
 
+// GroupCounts returns the number of active and total entries per group.
+func (l *ListView) GroupCounts() map[string]GroupCount {
+	counts := make(map[string]GroupCount, len(l.groups))
+	for group, indices := range l.groups {
+		count := GroupCount{Total: len(indices)}
+		for _, idx := range indices {
+			if l.items[idx].Entry.Enabled {
+				count.Active++
+			}
+		}
+		counts[group] = count
+	}
+	return counts
+}
+
 // FindByAlias finds an item by alias.
 func (l *ListView) FindByAlias(alias string) *EntryItem {
 	for i := range l.items {
@@ -164,33 +520,47 @@ func (l *ListView) FindByAlias(alias string) *EntryItem {
 
 // Filter filters items by search term.
 func (l *ListView) Filter(term string) []EntryItem {
-	if term == "" {
+	return l.FilterBy(term, FilterAll)
+}
+
+// FilterBy filters items by search term and status.
+func (l *ListView) FilterBy(term string, status StatusFilter) []EntryItem {
+	if term == "" && status == FilterAll {
 		return l.items
 	}
 
-	term = strings.ToLower(term)
 	var filtered []EntryItem
 	for _, item := range l.items {
-		if strings.Contains(strings.ToLower(item.Entry.Domain), term) ||
-			strings.Contains(strings.ToLower(item.Entry.Alias), term) ||
-			strings.Contains(strings.ToLower(item.Entry.IP), term) ||
-			strings.Contains(strings.ToLower(item.Entry.Group), term) {
+		if (term == "" || item.Entry.Matches(term)) && status.matches(item.Entry) {
 			filtered = append(filtered, item)
 		}
 	}
 	return filtered
 }
 
-// ViewFiltered renders the list filtered by search term.
-func (l *ListView) ViewFiltered(searchTerm string) string {
-	if searchTerm == "" {
+// ViewFiltered renders the list filtered by search term and status.
+func (l *ListView) ViewFiltered(searchTerm string, status StatusFilter) string {
+	if searchTerm == "" && status == FilterAll {
 		return l.View()
 	}
 
-	filtered := l.Filter(searchTerm)
+	var indicator, empty string
+	switch {
+	case searchTerm == "":
+		indicator = fmt.Sprintf("  Showing %s entries", status)
+		empty = fmt.Sprintf("  No %s entries. Press 1 to show all.", status)
+	case status == FilterAll:
+		indicator = fmt.Sprintf("  Search: %s", searchTerm)
+		empty = fmt.Sprintf("  No results for '%s'. Press Esc to clear search.", searchTerm)
+	default:
+		indicator = fmt.Sprintf("  Search: %s, %s only", searchTerm, status)
+		empty = fmt.Sprintf("  No %s results for '%s'. Press Esc to clear search or 1 to show all.", status, searchTerm)
+	}
+
+	filtered := l.FilterBy(searchTerm, status)
 	if len(filtered) == 0 {
 		emptyStyle := lipgloss.NewStyle().Foreground(colorMuted)
-		return "\n" + emptyStyle.Render(fmt.Sprintf("  No results for '%s'. Press Esc to clear search.", searchTerm)) + "\n"
+		return "\n" + emptyStyle.Render(empty) + "\n"
 	}
 
 	var sb strings.Builder
@@ -199,18 +569,10 @@ func (l *ListView) ViewFiltered(searchTerm string) string {
 	searchIndicator := lipgloss.NewStyle().
 		Foreground(colorWarning).
 		Bold(true).
-		Render(fmt.Sprintf("  Search: %s (%d results)", searchTerm, len(filtered)))
+		Render(fmt.Sprintf("%s (%d results)", indicator, len(filtered)))
 	sb.WriteString(searchIndicator)
 	sb.WriteString("\n")
 
-	// Group header style - bright colors for dark terminals
-	groupHeaderStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(colorGroupHeader).
-		Background(lipgloss.Color("238")).
-		Padding(0, 1).
-		MarginTop(1)
-
 	// Organize filtered items by group
 	groupItems := make(map[string][]EntryItem)
 	var groupOrder []string
@@ -231,18 +593,19 @@ func (l *ListView) ViewFiltered(searchTerm string) string {
 			continue
 		}
 
-		// Group header
-		headerText := fmt.Sprintf(" %s (%d)", strings.ToUpper(groupName), len(items))
-		sb.WriteString(groupHeaderStyle.Render(headerText))
+		sb.WriteString(l.groupHeader(groupName, items))
 		sb.WriteString("\n")
+		if l.collapsed[groupName] {
+			continue
+		}
 
 		// Build rows for this group's table
 		var rows [][]string
 		for _, item := range items {
 			status := l.getStatusString(item)
 			rows = append(rows, []string{
-				truncate(item.Entry.Domain, 30),
-				truncate(item.Entry.IP, 15),
+				l.domainCell(item),
+				truncate(item.Entry.FormatIPs(), 15),
 				status,
 			})
 		}
@@ -276,7 +639,7 @@ func (l *ListView) ViewFiltered(searchTerm string) string {
 						if item.HasError {
 							return baseStyle.Foreground(colorError)
 						}
-						if item.Pending {
+						if item.Pending || (item.Entry.Enabled && !item.Entry.Synced) {
 							return baseStyle.Foreground(colorWarning)
 						}
 						if item.Entry.Enabled {
@@ -309,24 +672,21 @@ func (l *ListView) View() string {
 
 	var sb strings.Builder
 
-	// Group header style - bright colors for dark terminals
-	groupHeaderStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(colorGroupHeader).
-		Background(lipgloss.Color("238")).
-		Padding(0, 1).
-		MarginTop(1)
-
 	for _, groupName := range l.groupOrder {
 		indices := l.groups[groupName]
 		if len(indices) == 0 {
 			continue
 		}
 
-		// Group header
-		headerText := fmt.Sprintf(" %s (%d)", strings.ToUpper(groupName), len(indices))
-		sb.WriteString(groupHeaderStyle.Render(headerText))
+		items := make([]EntryItem, len(indices))
+		for i, idx := range indices {
+			items[i] = l.items[idx]
+		}
+		sb.WriteString(l.groupHeader(groupName, items))
 		sb.WriteString("\n")
+		if l.collapsed[groupName] {
+			continue
+		}
 
 		// Build rows for this group's table
 		var rows [][]string
@@ -338,8 +698,8 @@ func (l *ListView) View() string {
 			item := l.items[idx]
 			status := l.getStatusString(item)
 			rows = append(rows, []string{
-				truncate(item.Entry.Domain, 30),
-				truncate(item.Entry.IP, 15),
+				l.domainCell(item),
+				truncate(item.Entry.FormatIPs(), 15),
 				status,
 			})
 		}
@@ -383,7 +743,7 @@ func (l *ListView) View() string {
 						if item.HasError {
 							return baseStyle.Foreground(colorError)
 						}
-						if item.Pending {
+						if item.Pending || (item.Entry.Enabled && !item.Entry.Synced) {
 							return baseStyle.Foreground(colorWarning)
 						}
 						if item.Entry.Enabled {
@@ -402,13 +762,93 @@ func (l *ListView) View() string {
 	return sb.String()
 }
 
+// groupHeader renders a group's header row. Collapsed groups show how many
+// of their entries are active, since their rows are hidden.
+func (l *ListView) groupHeader(name string, items []EntryItem) string {
+	text := fmt.Sprintf("%s (%d)", strings.ToUpper(name), len(items))
+	if l.collapsed[name] {
+		active := 0
+		for _, item := range items {
+			if item.Entry.Enabled {
+				active++
+			}
+		}
+		text = fmt.Sprintf("%s (%d/%d) [collapsed]", strings.ToUpper(name), active, len(items))
+	}
+
+	prefix := " "
+	if l.headerCursor == name {
+		prefix = "▸"
+	}
+	return groupHeaderStyle(l.groupColors[name]).Render(prefix + text)
+}
+
+// selectedMarker is prepended to the domain of selected entries.
+const selectedMarker = "» "
+
+// domainCell renders the domain column, with the entry's description as a
+// dimmed second line when it has one.
+func (l *ListView) domainCell(item EntryItem) string {
+	domain := truncate(item.Entry.Domain, 30)
+	if l.selected[item.Entry.Alias] {
+		domain = selectedMarker + domain
+	}
+	if item.Entry.Description == "" {
+		return domain
+	}
+	return domain + "\n" + descriptionStyle.Render(truncate(item.Entry.Description, 30))
+}
+
+// stickyMarker is appended to the status of entries presets can't disable.
+const stickyMarker = " 📌"
+
 func (l *ListView) getStatusString(item EntryItem) string {
+	status := l.baseStatusString(item)
+	if item.Entry.Sticky {
+		status += stickyMarker
+	}
+	if item.Entry.Enabled && item.Entry.ExpiresAt > 0 {
+		status += " · " + formatExpiry(item.Entry.ExpiresAt, time.Now())
+	}
+	return status
+}
+
+// formatExpiry renders the time left before the daemon disables an entry.
+func formatExpiry(expiresAt int64, now time.Time) string {
+	left := time.Unix(expiresAt, 0).Sub(now)
+	switch {
+	case left <= 0:
+		return "expiring"
+	case left < time.Minute:
+		return "expires in <1m"
+	case left < time.Hour:
+		return fmt.Sprintf("expires in %dm", int(left.Minutes()))
+	default:
+		return fmt.Sprintf("expires in %dh%02dm", int(left.Hours()), int(left.Minutes())%60)
+	}
+}
+
+// HasExpired reports whether an enabled entry's expiry has passed, meaning
+// the daemon has disabled it or is about to and the list is stale.
+func (l *ListView) HasExpired(now time.Time) bool {
+	for _, item := range l.items {
+		if item.Entry.Enabled && item.Entry.ExpiresAt > 0 && item.Entry.ExpiresAt <= now.Unix() {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *ListView) baseStatusString(item EntryItem) string {
 	if item.HasError {
 		return "✗ Error"
 	}
 	if item.Pending {
 		return "◐ Pending"
 	}
+	if item.Entry.Enabled && !item.Entry.Synced {
+		return "⚠ Not in hosts file"
+	}
 	if item.Entry.Enabled {
 		return "● Active"
 	}
@@ -2,7 +2,9 @@ package tui
 
 import (
 	"testing"
+	"time"
 
+	"github.com/charmbracelet/lipgloss"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -114,12 +116,15 @@ func TestListView_SetPending(t *testing.T) {
 	lv.SetItems(entries)
 
 	assert.False(t, lv.items[0].Pending)
+	assert.False(t, lv.HasPending())
 
 	lv.SetPending("a", true)
 	assert.True(t, lv.items[0].Pending)
+	assert.True(t, lv.HasPending())
 
 	lv.SetPending("a", false)
 	assert.False(t, lv.items[0].Pending)
+	assert.False(t, lv.HasPending())
 
 	// Non-existent alias should not panic
 	lv.SetPending("nonexistent", true)
@@ -169,6 +174,216 @@ func TestListView_ActiveCount(t *testing.T) {
 	assert.Equal(t, 2, lv.ActiveCount())
 }
 
+func TestListView_StickyMarker(t *testing.T) {
+	lv := NewListView()
+
+	sticky := EntryItem{Entry: protocol.HostEntry{Alias: "a", Enabled: true, Synced: true, Sticky: true}}
+	plain := EntryItem{Entry: protocol.HostEntry{Alias: "b", Enabled: true, Synced: true}}
+
+	assert.Contains(t, lv.getStatusString(sticky), stickyMarker)
+	assert.NotContains(t, lv.getStatusString(plain), stickyMarker)
+}
+
+func TestFormatExpiry(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	assert.Equal(t, "expiring", formatExpiry(now.Unix(), now))
+	assert.Equal(t, "expires in <1m", formatExpiry(now.Add(30*time.Second).Unix(), now))
+	assert.Equal(t, "expires in 12m", formatExpiry(now.Add(12*time.Minute+10*time.Second).Unix(), now))
+	assert.Equal(t, "expires in 2h05m", formatExpiry(now.Add(2*time.Hour+5*time.Minute).Unix(), now))
+}
+
+func TestListView_Expiry(t *testing.T) {
+	lv := NewListView()
+	now := time.Now()
+
+	lv.SetItems([]protocol.HostEntry{
+		{Alias: "a", Enabled: true, Synced: true, ExpiresAt: now.Add(10 * time.Minute).Unix()},
+		{Alias: "b", Enabled: true, Synced: true},
+	})
+	assert.Contains(t, lv.getStatusString(lv.items[0]), "expires in")
+	assert.NotContains(t, lv.getStatusString(lv.items[1]), "expires in")
+	assert.False(t, lv.HasExpired(now))
+	assert.True(t, lv.HasExpired(now.Add(10*time.Minute)))
+}
+
+func TestListView_Selection(t *testing.T) {
+	lv := NewListView()
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "a.local", Alias: "a", Group: "dev"},
+		{Domain: "b.local", Alias: "b", Group: "dev", Description: "backend"},
+		{Domain: "c.local", Alias: "c", Group: "prod"},
+	})
+
+	lv.ToggleSelection()
+	assert.Equal(t, []string{"a"}, lv.SelectedAliases())
+	assert.Contains(t, lv.View(), selectedMarker+"a.local")
+
+	lv.ToggleSelection()
+	assert.Zero(t, lv.SelectionCount())
+
+	// Select the group, narrowed by the search term
+	lv.SelectGroup("backend", FilterAll)
+	assert.Equal(t, []string{"b"}, lv.SelectedAliases())
+	lv.SelectGroup("", FilterAll)
+	assert.Equal(t, []string{"a", "b"}, lv.SelectedAliases())
+	lv.ClearSelection()
+	lv.SelectGroup("", FilterActive)
+	assert.Zero(t, lv.SelectionCount())
+	lv.SelectGroup("", FilterAll)
+
+	// A refresh keeps the selection but drops entries that are gone
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "b.local", Alias: "b", Group: "dev"},
+		{Domain: "c.local", Alias: "c", Group: "prod"},
+	})
+	assert.Equal(t, []string{"b"}, lv.SelectedAliases())
+
+	lv.ClearSelection()
+	assert.Zero(t, lv.SelectionCount())
+}
+
+func TestListView_Sort(t *testing.T) {
+	lv := NewListView()
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "c.local", IP: "10.0.0.2", Alias: "zed", Group: "dev"},
+		{Domain: "a.local", IP: "10.0.0.10", Alias: "yak", Group: "dev", Enabled: true},
+		{Domain: "b.local", IP: "10.0.0.1", Alias: "xen", Group: "dev"},
+		{Domain: "d.local", IP: "127.0.0.1", Alias: "wok", Group: "prod"},
+	})
+	lv.MoveDown() // on yak
+
+	aliases := func() []string {
+		var out []string
+		for _, item := range lv.items {
+			out = append(out, item.Entry.Alias)
+		}
+		return out
+	}
+
+	tests := []struct {
+		mode SortMode
+		want []string
+	}{
+		{SortDomain, []string{"yak", "xen", "zed", "wok"}},
+		{SortAlias, []string{"xen", "yak", "zed", "wok"}},
+		{SortStatus, []string{"yak", "xen", "zed", "wok"}},
+		{SortIP, []string{"xen", "zed", "yak", "wok"}},
+		{SortConfig, []string{"zed", "yak", "xen", "wok"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			lv.SetSortMode(tt.mode)
+			assert.Equal(t, tt.want, aliases())
+			assert.Equal(t, "yak", lv.SelectedAlias())
+		})
+	}
+
+	// The mode sticks across refreshes
+	lv.SetSortMode(SortAlias)
+	lv.SetItems([]protocol.HostEntry{
+		{Alias: "b", Group: "dev"},
+		{Alias: "a", Group: "dev"},
+	})
+	assert.Equal(t, []string{"a", "b"}, aliases())
+	assert.Equal(t, SortConfig, SortIP.Next())
+}
+
+func TestListView_FilterByStatus(t *testing.T) {
+	lv := NewListView()
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "api.local", Alias: "api", Group: "dev", Enabled: true, Synced: true},
+		{Domain: "api.staging", Alias: "api-staging", Group: "dev"},
+		{Domain: "web.local", Alias: "web", Group: "dev", Enabled: true, Synced: true},
+	})
+
+	assert.Len(t, lv.FilterBy("", FilterAll), 3)
+	assert.Len(t, lv.FilterBy("", FilterActive), 2)
+	assert.Len(t, lv.FilterBy("", FilterDisabled), 1)
+
+	filtered := lv.FilterBy("api", FilterActive)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "api", filtered[0].Entry.Alias)
+
+	view := lv.ViewFiltered("", FilterDisabled)
+	assert.Contains(t, view, "Showing disabled entries")
+	assert.Contains(t, view, "api.staging")
+	assert.NotContains(t, view, "web.local")
+
+	assert.Contains(t, lv.ViewFiltered("web", FilterDisabled), "No disabled results for 'web'")
+	assert.Equal(t, lv.View(), lv.ViewFiltered("", FilterAll))
+}
+
+func TestListView_Collapse(t *testing.T) {
+	lv := NewListView()
+	entries := []protocol.HostEntry{
+		{Domain: "a.local", Alias: "a", Group: "dev", Enabled: true},
+		{Domain: "b.local", Alias: "b", Group: "prod", Enabled: true},
+		{Domain: "c.local", Alias: "c", Group: "prod"},
+		{Domain: "d.local", Alias: "d", Group: "qa"},
+	}
+	lv.SetItems(entries)
+
+	lv.MoveDown() // on b
+	lv.ToggleCollapsed()
+	assert.True(t, lv.IsCollapsed("prod"))
+	assert.True(t, lv.OnHeader())
+	assert.Nil(t, lv.Selected())
+	assert.Equal(t, "prod", lv.SelectedGroup())
+
+	view := lv.View()
+	assert.Contains(t, view, "PROD (1/2) [collapsed]")
+	assert.NotContains(t, view, "b.local")
+	assert.Contains(t, view, "d.local")
+
+	// Navigation stops on the header and skips the hidden rows
+	lv.MoveDown()
+	assert.Equal(t, "d", lv.SelectedAlias())
+	lv.MoveUp()
+	assert.True(t, lv.OnHeader())
+	lv.MoveUp()
+	assert.Equal(t, "a", lv.SelectedAlias())
+
+	// The state survives a refresh
+	lv.SetItems(entries)
+	assert.True(t, lv.IsCollapsed("prod"))
+
+	lv.MoveDown()
+	lv.ToggleCollapsed()
+	assert.False(t, lv.IsCollapsed("prod"))
+	assert.Equal(t, "b", lv.SelectedAlias())
+}
+
+func TestListView_Description(t *testing.T) {
+	lv := NewListView()
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "api.local", IP: "127.0.0.1", Alias: "api", Group: "dev", Description: "staging API"},
+		{Domain: "web.local", IP: "127.0.0.1", Alias: "web", Group: "dev"},
+	})
+
+	assert.Equal(t, "web.local", lv.domainCell(EntryItem{Entry: protocol.HostEntry{Domain: "web.local"}}))
+	assert.Contains(t, lv.View(), "staging API")
+
+	filtered := lv.Filter("staging")
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "api", filtered[0].Entry.Alias)
+}
+
+func TestListView_GroupCounts(t *testing.T) {
+	lv := NewListView()
+	entries := []protocol.HostEntry{
+		{Domain: "a.com", IP: "127.0.0.1", Alias: "a", Enabled: true, Group: "dev"},
+		{Domain: "b.com", IP: "127.0.0.1", Alias: "b", Enabled: false, Group: "dev"},
+		{Domain: "c.com", IP: "192.168.1.1", Alias: "c", Enabled: false, Group: "staging"},
+	}
+	lv.SetItems(entries)
+
+	assert.Equal(t, map[string]GroupCount{
+		"dev":     {Active: 1, Total: 2},
+		"staging": {Active: 0, Total: 1},
+	}, lv.GroupCounts())
+}
+
 func TestListView_FindByAlias(t *testing.T) {
 	lv := NewListView()
 	entries := []protocol.HostEntry{
@@ -241,7 +456,7 @@ func TestListView_View(t *testing.T) {
 	t.Run("with items", func(t *testing.T) {
 		lv := NewListView()
 		entries := []protocol.HostEntry{
-			{Domain: "example.com", IP: "127.0.0.1", Alias: "example", Enabled: true, Group: "dev"},
+			{Domain: "example.com", IP: "127.0.0.1", Alias: "example", Enabled: true, Group: "dev", Synced: true},
 		}
 		lv.SetItems(entries)
 
@@ -257,6 +472,17 @@ func TestListView_View(t *testing.T) {
 		assert.Contains(t, view, "127.0.0.1")
 		assert.Contains(t, view, "Active")
 	})
+
+	t.Run("enabled but unsynced", func(t *testing.T) {
+		lv := NewListView()
+		lv.SetItems([]protocol.HostEntry{
+			{Domain: "example.com", IP: "127.0.0.1", Alias: "example", Enabled: true, Group: "dev"},
+		})
+		assert.Contains(t, lv.View(), "Not in hosts file")
+
+		lv.UpdateEntry("example", true)
+		assert.Contains(t, lv.View(), "Active")
+	})
 }
 
 func TestListView_SetSize(t *testing.T) {
@@ -476,3 +702,75 @@ func TestListView_SetItemsWithEmptySlice(t *testing.T) {
 	assert.Equal(t, 0, lv.Len())
 	assert.Equal(t, 0, lv.cursor)
 }
+
+func TestFormatCreated(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "unknown", formatCreated(0, now))
+	assert.Contains(t, formatCreated(now.Add(-10*time.Minute).Unix(), now), "(just now)")
+	assert.Contains(t, formatCreated(now.Add(-5*time.Hour).Unix(), now), "(5h ago)")
+	assert.Contains(t, formatCreated(now.Add(-72*time.Hour).Unix(), now), "(3d ago)")
+}
+
+func TestListView_SelectedDetail(t *testing.T) {
+	lv := NewListView()
+	assert.Empty(t, lv.SelectedDetail(time.Now()))
+
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "a.local", IP: "127.0.0.1", Alias: "a-local", Group: "dev"},
+	})
+	detail := lv.SelectedDetail(time.Now())
+	assert.Contains(t, detail, "a-local")
+	assert.Contains(t, detail, "group dev")
+	assert.Contains(t, detail, "added unknown")
+
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "a.local", Alias: "a-local", Group: "dev", Metadata: map[string]string{"ticket": "OPS-1", "owner": "alice"}},
+	})
+	assert.Contains(t, lv.SelectedDetail(time.Now()), "owner=alice, ticket=OPS-1")
+}
+
+func TestListView_FilterMetadata(t *testing.T) {
+	lv := NewListView()
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "a.local", Alias: "a", Group: "dev", Metadata: map[string]string{"owner": "Alice"}},
+		{Domain: "b.local", Alias: "b", Group: "dev"},
+	})
+
+	filtered := lv.Filter("alice")
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "a", filtered[0].Entry.Alias)
+	assert.Len(t, lv.Filter("owner"), 1)
+}
+
+func TestParseMetadata(t *testing.T) {
+	metadata, err := parseMetadata(" owner=alice , ticket=https://jira/OPS-1?x=y,, ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "alice", "ticket": "https://jira/OPS-1?x=y"}, metadata)
+	assert.Equal(t, "owner=alice, ticket=https://jira/OPS-1?x=y", protocol.FormatMetadata(metadata))
+
+	empty, err := parseMetadata("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = parseMetadata("owner")
+	assert.Error(t, err)
+	_, err = parseMetadata("bad key=x")
+	assert.Error(t, err)
+}
+
+func TestListView_GroupColors(t *testing.T) {
+	lv := NewListView()
+	lv.SetItems([]protocol.HostEntry{
+		{Domain: "a.com", Alias: "a", Group: "dev", GroupColor: "33"},
+		{Domain: "b.com", Alias: "b", Group: "staging"},
+	})
+
+	assert.Equal(t, "33", lv.groupColors["dev"])
+	assert.Equal(t, lipgloss.Color("33"), groupHeaderStyle(lv.groupColors["dev"]).GetBackground())
+	assert.Equal(t, lipgloss.Color("238"), groupHeaderStyle(lv.groupColors["staging"]).GetBackground())
+	assert.Equal(t, lipgloss.Color("238"), groupHeaderStyle("not-a-color").GetBackground())
+
+	assert.Equal(t, lipgloss.Color("16"), groupHeaderStyle("yellow").GetForeground())
+	assert.Equal(t, lipgloss.Color("255"), groupHeaderStyle("blue").GetForeground())
+}
@@ -2,6 +2,7 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -19,8 +20,13 @@ const (
 	PresetModeConfirmDelete
 	PresetModePickEnable  // Multi-select picker for enable aliases
 	PresetModePickDisable // Multi-select picker for disable aliases
+	PresetModeConfirmApply
 )
 
+// PresetConfirmThreshold is the number of host changes above which applying
+// a preset asks for confirmation first.
+const PresetConfirmThreshold = 5
+
 // PresetFormField represents a form field index.
 type PresetFormField int
 
@@ -48,6 +54,9 @@ type PresetPicker struct {
 	pickerCursor    int
 	selectedEnable  map[string]bool
 	selectedDisable map[string]bool
+
+	// Preview of the preset awaiting apply confirmation
+	pendingApply *protocol.PresetData
 }
 
 // NewPresetPicker creates a new preset picker.
@@ -212,10 +221,25 @@ func (p *PresetPicker) InitDelete() {
 	p.mode = PresetModeConfirmDelete
 }
 
+// InitConfirmApply asks for confirmation before applying the previewed preset.
+func (p *PresetPicker) InitConfirmApply(preview *protocol.PresetData) {
+	p.pendingApply = preview
+	p.mode = PresetModeConfirmApply
+}
+
+// PendingApply returns the name of the preset awaiting apply confirmation.
+func (p *PresetPicker) PendingApply() string {
+	if p.pendingApply == nil {
+		return ""
+	}
+	return p.pendingApply.Preset
+}
+
 // CancelForm cancels the current form operation.
 func (p *PresetPicker) CancelForm() {
 	p.mode = PresetModeSelect
 	p.editName = ""
+	p.pendingApply = nil
 	for i := range p.fields {
 		p.fields[i].Reset()
 		p.fields[i].Blur()
@@ -370,6 +394,8 @@ func (p *PresetPicker) View() string {
 		return p.pickerView()
 	case PresetModeConfirmDelete:
 		return p.deleteView()
+	case PresetModeConfirmApply:
+		return p.confirmApplyView()
 	default:
 		return p.selectView()
 	}
@@ -600,3 +626,30 @@ func (p *PresetPicker) deleteView() string {
 
 	return dialogStyle.Render(sb.String())
 }
+
+func (p *PresetPicker) confirmApplyView() string {
+	var sb strings.Builder
+
+	preview := p.pendingApply
+	if preview == nil {
+		preview = &protocol.PresetData{}
+	}
+
+	sb.WriteString(titleStyle.Render("Apply Preset"))
+	sb.WriteString("\n\n")
+	sb.WriteString(errorMsgStyle.Render(fmt.Sprintf("Preset '%s' will enable %d and disable %d hosts.",
+		preview.Preset, len(preview.Enable), len(preview.Disable))))
+	sb.WriteString("\n\n")
+	if len(preview.Enable) > 0 {
+		sb.WriteString(enabledStyle.Render("● Enable: " + strings.Join(preview.Enable, ", ")))
+		sb.WriteString("\n")
+	}
+	if len(preview.Disable) > 0 {
+		sb.WriteString(disabledStyle.Render("○ Disable: " + strings.Join(preview.Disable, ", ")))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(helpDescStyle.Render("y apply • n/Esc cancel"))
+
+	return dialogStyle.Render(sb.String())
+}
@@ -2,9 +2,11 @@
 package tui
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/lukaszraczylo/lolcathost/internal/config"
 )
 
 // Colors - matching kportal style, optimized for dark terminals
@@ -21,6 +23,47 @@ var (
 	colorGroupHeader = lipgloss.Color("213") // Light pink for group headers
 )
 
+// groupHeaderStyle returns the header style for a group, using the group's
+// configured color as the background when it has a valid one.
+func groupHeaderStyle(color string) lipgloss.Style {
+	style := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(colorGroupHeader).
+		Background(lipgloss.Color("238")).
+		Padding(0, 1).
+		MarginTop(1)
+
+	code, ok := config.ColorCode(color)
+	if color == "" || !ok {
+		return style
+	}
+
+	fg := lipgloss.Color("255")
+	if isLightColor(code) {
+		fg = lipgloss.Color("16")
+	}
+	return style.Background(lipgloss.Color(strconv.Itoa(code))).Foreground(fg)
+}
+
+// isLightColor reports whether dark text reads better than light text on the
+// given 256-color background.
+func isLightColor(code int) bool {
+	switch {
+	case code < 16:
+		switch code {
+		case 2, 3, 6, 7, 10, 11, 14, 15:
+			return true
+		}
+		return false
+	case code < 232:
+		c := code - 16
+		r, g, b := c/36, (c/6)%6, c%6
+		return 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) > 2.5
+	default:
+		return code >= 244
+	}
+}
+
 // Title and header styles
 var (
 	titleStyle = lipgloss.NewStyle().
@@ -37,6 +80,10 @@ var (
 
 	disabledStyle = lipgloss.NewStyle().
 			Foreground(colorMuted)
+
+	// descriptionStyle dims the description line under a domain.
+	descriptionStyle = lipgloss.NewStyle().
+				Faint(true)
 )
 
 // Status bar and help
@@ -44,6 +91,9 @@ var (
 	statusBarStyle = lipgloss.NewStyle().
 			Foreground(colorMuted)
 
+	spinnerStyle = lipgloss.NewStyle().
+			Foreground(colorPrimary)
+
 	connectedStyle = lipgloss.NewStyle().
 			Foreground(colorSuccess).
 			SetString("Connected")
@@ -74,6 +124,10 @@ var (
 			Foreground(colorSuccess).
 			MarginTop(1)
 
+	warningMsgStyle = lipgloss.NewStyle().
+			Foreground(colorWarning).
+			MarginTop(1)
+
 	updateStyle = lipgloss.NewStyle().
 			Foreground(colorSuccess).
 			Bold(true)
@@ -112,6 +166,15 @@ var (
 				Padding(0, 1)
 )
 
+// Diff styles
+var (
+	diffAddedStyle = lipgloss.NewStyle().
+			Foreground(colorSuccess)
+
+	diffRemovedStyle = lipgloss.NewStyle().
+				Foreground(colorError)
+)
+
 // WrapHelpText wraps help text to fit within maxWidth, splitting on bullet separators.
 // If maxWidth is 0 or negative, returns the original text.
 func WrapHelpText(text string, maxWidth int) string {
@@ -22,6 +22,7 @@ type ReleaseInfo struct {
 	TagName string `json:"tag_name"`
 	HTMLURL string `json:"html_url"`
 	Name    string `json:"name"`
+	Body    string `json:"body"`
 }
 
 // UpdateInfo contains information about an available update
@@ -30,14 +31,16 @@ type UpdateInfo struct {
 	LatestVersion  string
 	ReleaseURL     string
 	ReleaseName    string
+	ReleaseNotes   string
 }
 
 // Checker checks for new versions on GitHub
 type Checker struct {
-	owner   string
-	repo    string
-	current string
-	client  *http.Client
+	owner       string
+	repo        string
+	current     string
+	releasesURL string
+	client      *http.Client
 }
 
 // NewChecker creates a new version checker
@@ -46,6 +49,8 @@ func NewChecker(owner, repo, currentVersion string) *Checker {
 		owner:   owner,
 		repo:    repo,
 		current: normalizeVersion(currentVersion),
+		// #nosec G107 -- URL is constructed from hardcoded constant and validated owner/repo
+		releasesURL: fmt.Sprintf(githubReleasesURL, owner, repo),
 		client: &http.Client{
 			Timeout: requestTimeout,
 		},
@@ -68,6 +73,7 @@ func (c *Checker) CheckForUpdate(ctx context.Context) *UpdateInfo {
 			LatestVersion:  latestVersion,
 			ReleaseURL:     release.HTMLURL,
 			ReleaseName:    release.Name,
+			ReleaseNotes:   strings.TrimSpace(release.Body),
 		}
 	}
 
@@ -76,10 +82,7 @@ func (c *Checker) CheckForUpdate(ctx context.Context) *UpdateInfo {
 
 // fetchLatestRelease fetches the latest release info from GitHub API
 func (c *Checker) fetchLatestRelease(ctx context.Context) (*ReleaseInfo, error) {
-	// #nosec G107 -- URL is constructed from hardcoded constant and validated owner/repo
-	url := fmt.Sprintf(githubReleasesURL, c.owner, c.repo)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.releasesURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1,9 +1,13 @@
 package version
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNormalizeVersion(t *testing.T) {
@@ -84,3 +88,45 @@ func TestNewChecker(t *testing.T) {
 	assert.Equal(t, "1.0.0", checker.current) // Should be normalized
 	assert.NotNil(t, checker.client)
 }
+
+func TestCheckForUpdate_ReleaseNotes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v1.2.0","html_url":"https://example.com/v1.2.0","body":"\n- Faster sync\n- New flag\n"}`))
+	}))
+	defer server.Close()
+
+	checker := NewChecker("lukaszraczylo", "lolcathost", "v1.0.0")
+	checker.releasesURL = server.URL
+
+	update := checker.CheckForUpdate(context.Background())
+	require.NotNil(t, update)
+	assert.Equal(t, "1.2.0", update.LatestVersion)
+	assert.Equal(t, "https://example.com/v1.2.0", update.ReleaseURL)
+	assert.Equal(t, "- Faster sync\n- New flag", update.ReleaseNotes)
+}
+
+func TestCheckForUpdate_NoNotes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v1.2.0","html_url":"https://example.com/v1.2.0"}`))
+	}))
+	defer server.Close()
+
+	checker := NewChecker("lukaszraczylo", "lolcathost", "v1.0.0")
+	checker.releasesURL = server.URL
+
+	// Just the URL is left to show
+	update := checker.CheckForUpdate(context.Background())
+	require.NotNil(t, update)
+	assert.Empty(t, update.ReleaseNotes)
+	assert.Equal(t, "https://example.com/v1.2.0", update.ReleaseURL)
+}
+
+func TestCheckForUpdate_NetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	checker := NewChecker("lukaszraczylo", "lolcathost", "v1.0.0")
+	checker.releasesURL = server.URL
+
+	assert.Nil(t, checker.CheckForUpdate(context.Background()))
+}